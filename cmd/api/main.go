@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -14,6 +15,11 @@ import (
 	"github.com/gocomet/ride-hailing/internal/api/handlers"
 	"github.com/gocomet/ride-hailing/internal/api/routes"
 	"github.com/gocomet/ride-hailing/internal/config"
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/service/location"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/gocomet/ride-hailing/internal/service/pricing"
+	"github.com/gocomet/ride-hailing/internal/service/scheduling"
 	"github.com/gocomet/ride-hailing/pkg/cache"
 	"github.com/gocomet/ride-hailing/pkg/database"
 	"github.com/gocomet/ride-hailing/pkg/logger"
@@ -30,14 +36,20 @@ func main() {
 
 	// Initialize logger
 	appLogger, err := logger.New(logger.Config{
-		Level:  cfg.Log.Level,
-		Format: cfg.Log.Format,
-		Output: cfg.Log.Output,
+		Level:              cfg.Log.Level,
+		Format:             cfg.Log.Format,
+		Output:             cfg.Log.Output,
+		MaxSizeMB:          cfg.Log.MaxSizeMB,
+		MaxBackups:         cfg.Log.MaxBackups,
+		MaxAgeDays:         cfg.Log.MaxAgeDays,
+		SamplingInitial:    cfg.Log.SamplingInitial,
+		SamplingThereafter: cfg.Log.SamplingThereafter,
 	})
 	if err != nil {
 		log.Fatalf("Failed to create logger: %v", err)
 	}
 	defer appLogger.Sync()
+	logger.SetDefault(appLogger)
 
 	appLogger.Info("Starting GoComet Ride-Hailing Application",
 		logger.String("env", cfg.Server.Env),
@@ -64,14 +76,16 @@ func main() {
 
 	// Initialize Redis
 	redisClient, err := cache.NewRedisClient(cache.Config{
-		Host:        cfg.Redis.Host,
-		Port:        cfg.Redis.Port,
-		Password:    cfg.Redis.Password,
-		DB:          cfg.Redis.DB,
-		MaxRetries:  cfg.Redis.MaxRetries,
-		PoolSize:    cfg.Redis.PoolSize,
-		DialTimeout: cfg.Redis.DialTimeout,
-		ReadTimeout: cfg.Redis.ReadTimeout,
+		Host:         cfg.Redis.Host,
+		Port:         cfg.Redis.Port,
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		MaxRetries:   cfg.Redis.MaxRetries,
+		PoolSize:     cfg.Redis.PoolSize,
+		MinIdleConn:  cfg.Redis.MinIdleConn,
+		DialTimeout:  cfg.Redis.DialTimeout,
+		ReadTimeout:  cfg.Redis.ReadTimeout,
+		WriteTimeout: cfg.Redis.WriteTimeout,
 	})
 	if err != nil {
 		appLogger.Fatal("Failed to connect to Redis", logger.Err(err))
@@ -81,15 +95,18 @@ func main() {
 	appLogger.Info("Connected to Redis successfully")
 
 	// Initialize PostgreSQL
+	// Validate has already checked that DB_PORT is numeric
+	dbPort, _ := strconv.Atoi(cfg.Database.Port)
 	postgresDB, err := database.NewPostgresDB(database.Config{
-		Host:     "localhost",
-		Port:     5432,
-		User:     "postgres",
-		Password: "postgres",
-		DBName:   "gocomet",
-		SSLMode:  "disable",
-		MaxConns: 25,
-		MaxIdle:  5,
+		Host:        cfg.Database.Host,
+		Port:        dbPort,
+		User:        cfg.Database.User,
+		Password:    cfg.Database.Password,
+		DBName:      cfg.Database.Name,
+		SSLMode:     cfg.Database.SSLMode,
+		MaxConns:    cfg.Database.MaxConnections,
+		MaxIdle:     cfg.Database.MaxIdleConns,
+		MaxLifetime: cfg.Database.MaxLifetime,
 	})
 	if err != nil {
 		appLogger.Fatal("Failed to connect to PostgreSQL", logger.Err(err))
@@ -98,12 +115,63 @@ func main() {
 
 	appLogger.Info("Connected to PostgreSQL successfully")
 
-	// Initialize WebSocket hub
-	wsHub := websocket.NewHub(appLogger)
+	// Background workers stop when the server shuts down
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// Initialize pricing
+	pricingService := pricing.NewService(redisClient, newPricingConfig(cfg))
+
+	// Batch driver location writes to PostgreSQL
+	locationBatcher := location.NewBatcher(postgresDB, appLogger, nrApp, cfg.Database.LocationFlushInterval)
+	go locationBatcher.Run(workerCtx)
+
+	// Initialize WebSocket hub; per-user history lets reconnecting clients resume
+	wsHistory := websocket.NewRedisHistory(redisClient, cfg.WebSocket.HistorySize, cfg.WebSocket.HistoryTTL)
+	wsHub := websocket.NewHub(appLogger, wsHistory, websocket.HubConfig{
+		BroadcastBufferSize: cfg.WebSocket.BroadcastBufferSize,
+		SendTimeout:         cfg.WebSocket.SendTimeout,
+		HistoryTimeout:      cfg.WebSocket.HistoryTimeout,
+	})
+
+	// Prometheus metrics are exposed on /metrics when enabled
+	var metrics *monitoring.PrometheusMetrics
+	if cfg.Features.EnablePrometheusMetrics {
+		metrics = monitoring.NewPrometheus(wsHub.GetActiveConnections, wsHub.GetClientsByUserType)
+		wsHub.SetMetrics(metrics)
+	}
 	go wsHub.Run()
 
 	// Initialize handlers with dependencies
-	h := handlers.NewHandlers(postgresDB, redisClient, appLogger, wsHub)
+	h := handlers.NewHandlers(postgresDB, redisClient, appLogger, wsHub, cfg, pricingService, locationBatcher, nrApp, metrics)
+
+	// Clients may only follow rides they are the rider or driver on
+	wsHub.SetRideAccess(h)
+
+	// Recompute surge from live demand, pushing large changes to subscribed riders
+	if cfg.Features.EnableSurgePricing {
+		surgeWorker := pricing.NewSurgeWorker(postgresDB, redisClient, pricingService, appLogger, cfg.Pricing.SurgeRecomputeInterval)
+		surgeWorker.SetNotifier(h, cfg.Pricing.SurgeNotifyThreshold)
+		go surgeWorker.Run(workerCtx)
+	} else {
+		appLogger.Info("Surge pricing disabled")
+	}
+
+	// Dispatch advance bookings shortly before their pickup time
+	rideScheduler := scheduling.NewScheduler(h.Rides, h, appLogger, cfg.Scheduling.PollInterval, cfg.Scheduling.DispatchLeadTime)
+	go rideScheduler.Run(workerCtx)
+
+	// Return drivers stranded by abandoned matching claims to the available pool
+	claimReconciler := matching.NewClaimReconciler(postgresDB, redisClient, appLogger, metrics, cfg.Matching.ClaimReconcileInterval)
+	go claimReconciler.Run(workerCtx)
+
+	// Re-offer rides whose assigned driver didn't accept in time
+	assignmentWorker := matching.NewAssignmentTimeoutWorker(redisClient, h, appLogger, cfg.Matching.AssignmentCheckInterval)
+	go assignmentWorker.Run(workerCtx)
+
+	// Drop drivers who stopped reporting their location from the geo index
+	staleSweeper := location.NewStaleSweeper(redisClient, appLogger, metrics, cfg.Matching.StaleDriverSweepInterval, cfg.Matching.StaleDriverThreshold)
+	go staleSweeper.Run(workerCtx)
 
 	// Initialize Gin router
 	if cfg.Server.Env == "production" {
@@ -113,11 +181,7 @@ func main() {
 	router := gin.Default()
 
 	// Setup all routes
-	var nrApplication *monitoring.NewRelicApp
-	if nrApp.IsEnabled() {
-		nrApplication = nrApp
-	}
-	routes.SetupRoutes(router, h, nrApplication.Application)
+	routes.SetupRoutes(router, h, nrApp.App())
 
 	appLogger.Info("Routes configured successfully")
 
@@ -159,6 +223,7 @@ func main() {
 	<-quit
 
 	appLogger.Info("Shutting down server...")
+	stopWorkers()
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -170,3 +235,42 @@ func main() {
 
 	appLogger.Info("Server stopped gracefully")
 }
+
+// newPricingConfig converts the loaded pricing settings into the pricing service config
+func newPricingConfig(cfg *config.Config) pricing.Config {
+	p := cfg.Pricing
+	return pricing.Config{
+		BaseFare: map[driver.VehicleType]float64{
+			driver.VehicleEconomy: float64(p.BaseFare.Economy),
+			driver.VehiclePremium: float64(p.BaseFare.Premium),
+			driver.VehicleLuxury:  float64(p.BaseFare.Luxury),
+		},
+		PerKMRate: map[driver.VehicleType]float64{
+			driver.VehicleEconomy: float64(p.PerKMRate.Economy),
+			driver.VehiclePremium: float64(p.PerKMRate.Premium),
+			driver.VehicleLuxury:  float64(p.PerKMRate.Luxury),
+		},
+		PerMinuteRate: map[driver.VehicleType]float64{
+			driver.VehicleEconomy: float64(p.PerMinuteRate.Economy),
+			driver.VehiclePremium: float64(p.PerMinuteRate.Premium),
+			driver.VehicleLuxury:  float64(p.PerMinuteRate.Luxury),
+		},
+		PerWaitMinuteRate: map[driver.VehicleType]float64{
+			driver.VehicleEconomy: float64(p.PerWaitMinuteRate.Economy),
+			driver.VehiclePremium: float64(p.PerWaitMinuteRate.Premium),
+			driver.VehicleLuxury:  float64(p.PerWaitMinuteRate.Luxury),
+		},
+		MinimumFare: map[driver.VehicleType]float64{
+			driver.VehicleEconomy: float64(p.MinimumFare.Economy),
+			driver.VehiclePremium: float64(p.MinimumFare.Premium),
+			driver.VehicleLuxury:  float64(p.MinimumFare.Luxury),
+		},
+		MaxSurgeMultiplier:      p.MaxSurgeMultiplier,
+		MinSurgeMultiplier:      p.MinSurgeMultiplier,
+		SurgeOverrideTTL:        p.SurgeOverrideTTL,
+		RegionPrecision:         p.SurgeRegionPrecision,
+		CancellationFee:         float64(p.CancellationFee),
+		CancellationGracePeriod: p.CancellationGracePeriod,
+		PoolDiscount:            float64(p.PoolDiscountPercent) / 100,
+	}
+}
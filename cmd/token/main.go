@@ -0,0 +1,33 @@
+// Command token signs an access token with the configured JWT_SECRET. It is how operators
+// get admin tokens, which the development /v1/auth/token endpoint refuses to issue.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/gocomet/ride-hailing/internal/config"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+)
+
+func main() {
+	userID := flag.String("user", "", "user ID to put in the token subject")
+	userType := flag.String("type", auth.UserTypeAdmin, "user type: rider, driver, dashboard or admin")
+	flag.Parse()
+
+	if *userID == "" {
+		log.Fatal("-user is required")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	token, err := auth.GenerateToken(cfg.JWT.Secret, *userID, *userType, cfg.JWT.Expiry)
+	if err != nil {
+		log.Fatalf("Failed to issue token: %v", err)
+	}
+	fmt.Println(token)
+}
@@ -1,21 +1,55 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"time"
 
-// CreateRideRequest represents a request to create a new ride
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/google/uuid"
+)
+
+// CreateRideRequest represents a request to create a new ride. Coordinates are pointers
+// because 0.0 is a real latitude/longitude; see ValidateCoordinates.
 type CreateRideRequest struct {
-	RiderID          string  `json:"rider_id" binding:"required"`
-	PickupLatitude   float64 `json:"pickup_latitude" binding:"required"`
-	PickupLongitude  float64 `json:"pickup_longitude" binding:"required"`
-	DropoffLatitude  float64 `json:"dropoff_latitude" binding:"required"`
-	DropoffLongitude float64 `json:"dropoff_longitude" binding:"required"`
-	VehicleType      string  `json:"vehicle_type" binding:"required,oneof=economy premium luxury"`
+	RiderID          string   `json:"rider_id" binding:"required"`
+	PickupLatitude   *float64 `json:"pickup_latitude"`
+	PickupLongitude  *float64 `json:"pickup_longitude"`
+	DropoffLatitude  *float64 `json:"dropoff_latitude"`
+	DropoffLongitude *float64 `json:"dropoff_longitude"`
+	VehicleType      string   `json:"vehicle_type" binding:"required,oneof=economy premium luxury"`
+
+	// Seats is the minimum passenger capacity needed; defaults to 1. A larger vehicle type
+	// may be matched when the requested one can't carry this many.
+	Seats int `json:"seats" binding:"omitempty,min=1"`
+
+	// ScheduledAt books the ride in advance; omitted for an immediate ride
+	ScheduledAt *time.Time `json:"scheduled_at"`
+
+	// Waypoints are ordered intermediate stops between pickup and dropoff
+	Waypoints []LocationPoint `json:"waypoints" binding:"omitempty,dive"`
+
+	// Pool opts into a discounted shared ride with other riders heading the same way
+	Pool bool `json:"pool"`
+
+	// AllowUpgrade lets matching fall back to a larger vehicle type when none of the requested
+	// type is nearby; the ride is still charged at the requested type's fare
+	AllowUpgrade bool `json:"allow_upgrade"`
+}
+
+// EstimateFareRequest represents a fare preview; vehicle_type is optional since
+// every vehicle type is quoted
+type EstimateFareRequest struct {
+	PickupLatitude   *float64        `json:"pickup_latitude"`
+	PickupLongitude  *float64        `json:"pickup_longitude"`
+	DropoffLatitude  *float64        `json:"dropoff_latitude"`
+	DropoffLongitude *float64        `json:"dropoff_longitude"`
+	VehicleType      string          `json:"vehicle_type" binding:"omitempty,oneof=economy premium luxury"`
+	Waypoints        []LocationPoint `json:"waypoints" binding:"omitempty,dive"`
 }
 
 // UpdateLocationRequest represents a driver location update
 type UpdateLocationRequest struct {
-	Latitude  float64 `json:"latitude" binding:"required"`
-	Longitude float64 `json:"longitude" binding:"required"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
 
 // AcceptRideRequest represents a driver accepting a ride
@@ -23,11 +57,81 @@ type AcceptRideRequest struct {
 	RideID string `json:"ride_id" binding:"required"`
 }
 
-// EndTripRequest represents ending a trip
+// RejectRideRequest represents a driver declining an offered ride
+type RejectRideRequest struct {
+	RideID string `json:"ride_id" binding:"required"`
+}
+
+// UpdateDriverStatusRequest represents a driver going online or offline
+type UpdateDriverStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=online offline"`
+}
+
+// CancelRideRequest represents a rider cancelling a ride
+type CancelRideRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// EndTripRequest represents ending a trip; the route taken may be sent as an
+// encoded polyline or as raw GPS breadcrumbs, but not both. Distance and duration
+// are pointers since a very short trip can legitimately report 0; see Validate.
 type EndTripRequest struct {
-	DriverID        string  `json:"driver_id" binding:"required"`
-	DistanceKm      float64 `json:"distance_km" binding:"required"`
-	DurationMinutes int     `json:"duration_minutes" binding:"required"`
+	DriverID        string          `json:"driver_id" binding:"required"`
+	DistanceKm      *float64        `json:"distance_km"`
+	DurationMinutes *int            `json:"duration_minutes"`
+	WaitingMinutes  int             `json:"waiting_minutes" binding:"min=0"`
+	RoutePolyline   string          `json:"route_polyline"`
+	Breadcrumbs     []LocationPoint `json:"breadcrumbs" binding:"omitempty,max=10000,dive"`
+}
+
+// LocationPoint is a single GPS coordinate
+type LocationPoint struct {
+	Latitude  float64 `json:"latitude" binding:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" binding:"min=-180,max=180"`
+}
+
+// ValidCoordinates reports whether lat is within [-90, 90] and lng within [-180, 180]
+func ValidCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// validatePoints checks that each lat/lng pair is present and in range
+func validatePoints(coords ...*float64) error {
+	for i := 0; i < len(coords); i += 2 {
+		if coords[i] == nil || coords[i+1] == nil {
+			return apperrors.ErrMissingCoordinates
+		}
+		if !ValidCoordinates(*coords[i], *coords[i+1]) {
+			return apperrors.ErrInvalidCoordinates
+		}
+	}
+	return nil
+}
+
+// ValidateCoordinates checks that pickup and dropoff are present and in range
+func (r *CreateRideRequest) ValidateCoordinates() error {
+	return validatePoints(r.PickupLatitude, r.PickupLongitude, r.DropoffLatitude, r.DropoffLongitude)
+}
+
+// ValidateCoordinates checks that pickup and dropoff are present and in range
+func (r *EstimateFareRequest) ValidateCoordinates() error {
+	return validatePoints(r.PickupLatitude, r.PickupLongitude, r.DropoffLatitude, r.DropoffLongitude)
+}
+
+// ValidateCoordinates checks that the reported position is present and in range
+func (r *UpdateLocationRequest) ValidateCoordinates() error {
+	return validatePoints(r.Latitude, r.Longitude)
+}
+
+// Validate checks that distance and duration are present and not negative
+func (r *EndTripRequest) Validate() error {
+	if r.DistanceKm == nil || r.DurationMinutes == nil {
+		return apperrors.BadRequest("distance_km and duration_minutes are required", nil)
+	}
+	if *r.DistanceKm < 0 || *r.DurationMinutes < 0 {
+		return apperrors.BadRequest("distance_km and duration_minutes must not be negative", nil)
+	}
+	return nil
 }
 
 // CreatePaymentRequest represents a payment request
@@ -37,6 +141,64 @@ type CreatePaymentRequest struct {
 	Amount        float64 `json:"amount" binding:"required"`
 }
 
+// RefundPaymentRequest represents a refund; Amount defaults to the full refundable balance
+type RefundPaymentRequest struct {
+	Amount *float64 `json:"amount" binding:"omitempty,gt=0"`
+	Reason string   `json:"reason"`
+}
+
+// WalletTopUpRequest adds funds to a rider's wallet
+type WalletTopUpRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+}
+
+// CreatePayoutsRequest settles driver earnings dated within [PeriodStart, PeriodEnd] (YYYY-MM-DD)
+type CreatePayoutsRequest struct {
+	PeriodStart string `json:"period_start" binding:"required"`
+	PeriodEnd   string `json:"period_end" binding:"required"`
+}
+
+// SetSurgeRequest sets a manual surge override; TTLMinutes defaults to SURGE_OVERRIDE_TTL_MINUTES
+// and 0 makes the override permanent
+type SetSurgeRequest struct {
+	Multiplier float64 `json:"multiplier" binding:"required,gt=0"`
+	TTLMinutes *int    `json:"ttl_minutes" binding:"omitempty,min=0"`
+}
+
+// RegisterDriverRequest onboards a new driver; fields are checked by driver.IsValid
+type RegisterDriverRequest struct {
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	VehicleType string `json:"vehicle_type"`
+}
+
+// BulkCreateDriversRequest seeds Count online drivers at random positions inside the bounding box
+type BulkCreateDriversRequest struct {
+	Count        int      `json:"count" binding:"required,min=1,max=1000"`
+	MinLatitude  *float64 `json:"min_latitude"`
+	MaxLatitude  *float64 `json:"max_latitude"`
+	MinLongitude *float64 `json:"min_longitude"`
+	MaxLongitude *float64 `json:"max_longitude"`
+}
+
+// ValidateBounds checks that both corners are present and in range and that min does not exceed max
+func (r *BulkCreateDriversRequest) ValidateBounds() error {
+	if err := validatePoints(r.MinLatitude, r.MinLongitude, r.MaxLatitude, r.MaxLongitude); err != nil {
+		return err
+	}
+	if *r.MinLatitude > *r.MaxLatitude || *r.MinLongitude > *r.MaxLongitude {
+		return apperrors.BadRequest("min_latitude/min_longitude must not exceed max_latitude/max_longitude", nil)
+	}
+	return nil
+}
+
+// IssueTokenRequest represents a request for a development access token
+type IssueTokenRequest struct {
+	UserID   string `json:"user_id" binding:"required"`
+	UserType string `json:"user_type" binding:"required,oneof=rider driver dashboard admin"`
+}
+
 // Ride response
 type RideResponse struct {
 	ID                  uuid.UUID        `json:"id"`
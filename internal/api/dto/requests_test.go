@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"encoding/json"
+	"testing"
+
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUpdateLocationRequest_ValidateCoordinates tests that zero is accepted while missing or out-of-range values are not
+func TestUpdateLocationRequest_ValidateCoordinates(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{"equator and prime meridian", `{"latitude":0,"longitude":0}`, nil},
+		{"range boundaries", `{"latitude":-90,"longitude":180}`, nil},
+		{"missing longitude", `{"latitude":12.97}`, apperrors.ErrMissingCoordinates},
+		{"latitude out of range", `{"latitude":500,"longitude":0}`, apperrors.ErrInvalidCoordinates},
+		{"longitude out of range", `{"latitude":0,"longitude":-180.5}`, apperrors.ErrInvalidCoordinates},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req UpdateLocationRequest
+			require.NoError(t, json.Unmarshal([]byte(tt.body), &req))
+			assert.Equal(t, tt.wantErr, req.ValidateCoordinates())
+		})
+	}
+}
+
+// TestCreateRideRequest_ValidateCoordinates tests that a ride from 0,0 is valid
+func TestCreateRideRequest_ValidateCoordinates(t *testing.T) {
+	var req CreateRideRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"pickup_latitude":0,"pickup_longitude":0,"dropoff_latitude":0.01,"dropoff_longitude":0}`), &req))
+	assert.NoError(t, req.ValidateCoordinates())
+
+	req.DropoffLatitude = nil
+	assert.Equal(t, apperrors.ErrMissingCoordinates, req.ValidateCoordinates())
+}
+
+// TestEndTripRequest_Validate tests that a zero-length trip is accepted
+func TestEndTripRequest_Validate(t *testing.T) {
+	var req EndTripRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"driver_id":"d1","distance_km":0,"duration_minutes":0}`), &req))
+	assert.NoError(t, req.Validate())
+
+	req.DurationMinutes = nil
+	assert.Error(t, req.Validate())
+
+	negative := -1.0
+	req.DistanceKm, req.DurationMinutes = &negative, new(int)
+	assert.Error(t, req.Validate())
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// rideSearchFilter builds the WHERE clause of an admin ride search. Only the conditions
+// actually requested are included, each a fixed SQL fragment with a placeholder, so user
+// input never reaches the SQL text and the planner can use the matching index.
+type rideSearchFilter struct {
+	conditions []string
+	args       []interface{}
+}
+
+// add appends a condition whose single %d is replaced by the placeholder for value
+func (f *rideSearchFilter) add(condition string, value interface{}) {
+	f.args = append(f.args, value)
+	f.conditions = append(f.conditions, fmt.Sprintf(condition, len(f.args)))
+}
+
+// where returns the WHERE clause, or an empty string when nothing is filtered
+func (f *rideSearchFilter) where() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(f.conditions, " AND ")
+}
+
+// parseRideSearchFilter reads the status, driver_id, rider_id, from and to query parameters;
+// from and to are RFC 3339 timestamps bounding requested_at, from inclusive and to exclusive
+func parseRideSearchFilter(c *gin.Context) (*rideSearchFilter, error) {
+	filter := &rideSearchFilter{}
+
+	if v := c.Query("status"); v != "" {
+		if !ride.Status(v).IsValid() {
+			return nil, apperrors.BadRequest("Invalid status filter", nil)
+		}
+		filter.add("r.status = $%d", v)
+	}
+	for _, param := range []string{"driver_id", "rider_id"} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, apperrors.BadRequest(fmt.Sprintf("Invalid '%s'", param), err)
+		}
+		filter.add("r."+param+" = $%d", id)
+	}
+
+	var from, to time.Time
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, apperrors.BadRequest("Invalid 'from' time, expected RFC 3339", err)
+		}
+		from = t
+		filter.add("r.requested_at >= $%d", from)
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, apperrors.BadRequest("Invalid 'to' time, expected RFC 3339", err)
+		}
+		to = t
+		filter.add("r.requested_at < $%d", to)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, apperrors.BadRequest("'from' must be before 'to'", nil)
+	}
+
+	return filter, nil
+}
+
+// SearchRides handles GET /v1/admin/rides
+// Lists rides newest first, filtered by status, driver_id, rider_id and a requested_at window,
+// each with its driver and trip when it has them.
+func (h *Handlers) SearchRides(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+	ctx := context.Background()
+
+	limit, offset, err := parsePagination(c, 50, 200)
+	if err != nil {
+		respondError(c, apperrors.BadRequest("Invalid pagination parameters", err))
+		return
+	}
+	filter, err := parseRideSearchFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	where := filter.where()
+
+	var total int
+	if err := h.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM rides r "+where, filter.args...).Scan(&total); err != nil {
+		log.Error("Failed to count rides", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to search rides", err))
+		return
+	}
+
+	args := append(filter.args, limit, offset)
+	rows, err := h.DB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT r.id, r.rider_id, r.status, r.vehicle_type,
+		       r.pickup_latitude, r.pickup_longitude, r.dropoff_latitude, r.dropoff_longitude,
+		       r.estimated_fare, r.requested_at, r.completed_at, r.cancelled_at, r.cancellation_reason,
+		       r.driver_id, d.name, d.phone, d.vehicle_type,
+		       t.id, t.status, t.distance_km, t.duration_minutes, t.total_fare, t.started_at, t.ended_at
+		FROM rides r
+		LEFT JOIN drivers d ON r.driver_id = d.id
+		LEFT JOIN trips t ON t.ride_id = r.id
+		%s
+		ORDER BY r.requested_at DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)-1, len(args)), args...)
+	if err != nil {
+		log.Error("Failed to search rides", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to search rides", err))
+		return
+	}
+	defer rows.Close()
+
+	rides := []gin.H{}
+	for rows.Next() {
+		var (
+			id, riderID, status, vehicleType       string
+			pickupLat, pickupLng, dropLat, dropLng float64
+			estimatedFare                          sql.NullFloat64
+			requestedAt                            time.Time
+			completedAt, cancelledAt               sql.NullTime
+			cancellationReason                     sql.NullString
+			driverID, driverName, driverPhone      sql.NullString
+			driverVehicle                          sql.NullString
+			tripID, tripStatus                     sql.NullString
+			tripDistance, tripFare                 sql.NullFloat64
+			tripDuration                           sql.NullInt64
+			tripStartedAt, tripEndedAt             sql.NullTime
+		)
+		if err := rows.Scan(&id, &riderID, &status, &vehicleType,
+			&pickupLat, &pickupLng, &dropLat, &dropLng,
+			&estimatedFare, &requestedAt, &completedAt, &cancelledAt, &cancellationReason,
+			&driverID, &driverName, &driverPhone, &driverVehicle,
+			&tripID, &tripStatus, &tripDistance, &tripDuration, &tripFare, &tripStartedAt, &tripEndedAt); err != nil {
+			log.Error("Failed to scan ride row", logger.Err(err))
+			respondError(c, apperrors.Internal("Failed to search rides", err))
+			return
+		}
+
+		r := gin.H{
+			"id":                id,
+			"rider_id":          riderID,
+			"status":            status,
+			"vehicle_type":      vehicleType,
+			"pickup_latitude":   pickupLat,
+			"pickup_longitude":  pickupLng,
+			"dropoff_latitude":  dropLat,
+			"dropoff_longitude": dropLng,
+			"requested_at":      requestedAt,
+			"driver":            nil,
+			"trip":              nil,
+		}
+		if estimatedFare.Valid {
+			r["estimated_fare"] = estimatedFare.Float64
+		}
+		if completedAt.Valid {
+			r["completed_at"] = completedAt.Time
+		}
+		if cancelledAt.Valid {
+			r["cancelled_at"] = cancelledAt.Time
+		}
+		if cancellationReason.Valid {
+			r["cancellation_reason"] = cancellationReason.String
+		}
+		if driverID.Valid {
+			r["driver"] = gin.H{
+				"id":           driverID.String,
+				"name":         driverName.String,
+				"phone":        driverPhone.String,
+				"vehicle_type": driverVehicle.String,
+			}
+		}
+		if tripID.Valid {
+			trip := gin.H{
+				"id":     tripID.String,
+				"status": tripStatus.String,
+			}
+			if tripDistance.Valid {
+				trip["distance_km"] = tripDistance.Float64
+			}
+			if tripDuration.Valid {
+				trip["duration_minutes"] = tripDuration.Int64
+			}
+			if tripFare.Valid {
+				trip["total_fare"] = tripFare.Float64
+			}
+			if tripStartedAt.Valid {
+				trip["started_at"] = tripStartedAt.Time
+			}
+			if tripEndedAt.Valid {
+				trip["ended_at"] = tripEndedAt.Time
+			}
+			r["trip"] = trip
+		}
+		rides = append(rides, r)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("Failed to read rides", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to search rides", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rides":  rides,
+		"limit":  limit,
+		"offset": offset,
+		"total":  total,
+	})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSearchRidesRequest builds a GET /v1/admin/rides context with the given query string
+func newSearchRidesRequest(query string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/admin/rides?"+query, nil)
+	return c, w
+}
+
+// TestSearchRides_FiltersAndJoins tests that only the requested filters reach the query as
+// parameters and that the driver and trip are nested in each ride
+func TestSearchRides_FiltersAndJoins(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	driverID := uuid.New()
+	from := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	requestedAt := from.Add(time.Hour)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM rides r WHERE r.status = \$1 AND r.driver_id = \$2 AND r.requested_at >= \$3$`).
+		WithArgs("completed", driverID, from).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`LEFT JOIN trips t ON t.ride_id = r.id\s+WHERE r.status = \$1 AND r.driver_id = \$2 AND r.requested_at >= \$3\s+ORDER BY r.requested_at DESC\s+LIMIT \$4 OFFSET \$5`).
+		WithArgs("completed", driverID, from, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "rider_id", "status", "vehicle_type",
+			"pickup_latitude", "pickup_longitude", "dropoff_latitude", "dropoff_longitude",
+			"estimated_fare", "requested_at", "completed_at", "cancelled_at", "cancellation_reason",
+			"driver_id", "name", "phone", "vehicle_type",
+			"id", "status", "distance_km", "duration_minutes", "total_fare", "started_at", "ended_at",
+		}).AddRow("ride-1", uuid.New().String(), "completed", "economy",
+			12.97, 77.59, 12.93, 77.62,
+			250.0, requestedAt, requestedAt.Add(30*time.Minute), nil, nil,
+			driverID.String(), "Asha", "+919800000001", "economy",
+			"trip-1", "completed", 6.4, 22, 268.5, requestedAt.Add(5*time.Minute), requestedAt.Add(27*time.Minute)))
+
+	c, w := newSearchRidesRequest("status=completed&driver_id=" + driverID.String() + "&from=2026-01-05T00:00:00Z&limit=10")
+	h.SearchRides(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"total":1`)
+	assert.Contains(t, w.Body.String(), `"name":"Asha"`)
+	assert.Contains(t, w.Body.String(), `"total_fare":268.5`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSearchRides_RejectsInvalidFilters tests that malformed filters are refused before querying
+func TestSearchRides_RejectsInvalidFilters(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	for _, query := range []string{
+		"status=lost",
+		"driver_id=not-a-uuid",
+		"from=yesterday",
+		"from=2026-01-05T00:00:00Z&to=2026-01-04T00:00:00Z",
+	} {
+		c, w := newSearchRidesRequest(query)
+		h.SearchRides(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+// TestSearchRides_FailsOnUnreadableRow tests that a row that can't be scanned fails the
+// search instead of leaving the page short
+func TestSearchRides_FailsOnUnreadableRow(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM rides r`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`LEFT JOIN trips t ON t.ride_id = r.id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("ride-1"))
+
+	c, w := newSearchRidesRequest("")
+	h.SearchRides(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
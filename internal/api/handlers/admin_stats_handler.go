@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+)
+
+// Bounds for the window_hours parameter of GET /v1/admin/stats
+const (
+	defaultStatsWindowHours = 24
+	maxStatsWindowHours     = 30 * 24
+)
+
+// GetStats handles GET /v1/admin/stats?window_hours=24
+// Aggregates rides requested in the window from Postgres alongside the current surge
+// multipliers from Redis. Rates are fractions of all rides requested in the window.
+func (h *Handlers) GetStats(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+	ctx := context.Background()
+
+	windowHours := defaultStatsWindowHours
+	if v := c.Query("window_hours"); v != "" {
+		hours, err := strconv.Atoi(v)
+		if err != nil || hours <= 0 || hours > maxStatsWindowHours {
+			respondError(c, apperrors.BadRequest("'window_hours' must be between 1 and "+strconv.Itoa(maxStatsWindowHours), err))
+			return
+		}
+		windowHours = hours
+	}
+	since := time.Now().UTC().Add(-time.Duration(windowHours) * time.Hour)
+
+	// Match latency is how long a ride waited for its first driver assignment
+	var total, completed, cancelled int
+	var avgFare, avgMatchSeconds sql.NullFloat64
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE r.status = 'completed'),
+		       COUNT(*) FILTER (WHERE r.status = 'cancelled'),
+		       AVG(t.total_fare),
+		       AVG(EXTRACT(EPOCH FROM (r.assigned_at - r.requested_at))) FILTER (WHERE r.assigned_at IS NOT NULL)
+		FROM rides r
+		LEFT JOIN trips t ON t.ride_id = r.id AND t.status = 'completed'
+		WHERE r.requested_at >= $1
+	`, since).Scan(&total, &completed, &cancelled, &avgFare, &avgMatchSeconds)
+	if err != nil {
+		log.Error("Failed to aggregate ride stats", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to get stats", err))
+		return
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT date_trunc('hour', requested_at) AS hour, COUNT(*)
+		FROM rides
+		WHERE requested_at >= $1
+		GROUP BY hour
+		ORDER BY hour
+	`, since)
+	if err != nil {
+		log.Error("Failed to query hourly rides", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to get stats", err))
+		return
+	}
+	defer rows.Close()
+
+	hourly := []gin.H{}
+	for rows.Next() {
+		var hour time.Time
+		var count int
+		if err := rows.Scan(&hour, &count); err != nil {
+			log.Error("Failed to scan hourly rides", logger.Err(err))
+			respondError(c, apperrors.Internal("Failed to get stats", err))
+			return
+		}
+		hourly = append(hourly, gin.H{"hour": hour, "rides": count})
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("Failed to read hourly rides", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to get stats", err))
+		return
+	}
+
+	// Surge is only informational here, so a Redis failure doesn't fail the request
+	surge := []gin.H{}
+	regions, err := h.Pricing.ListSurgeMultipliers(ctx)
+	if err != nil {
+		log.Warn("Failed to list surge multipliers", logger.Err(err))
+	}
+	for _, r := range regions {
+		surge = append(surge, gin.H{
+			"region":     r.Region,
+			"multiplier": r.Multiplier,
+			"override":   r.Override,
+		})
+	}
+
+	var completionRate, cancellationRate float64
+	if total > 0 {
+		completionRate = float64(completed) / float64(total)
+		cancellationRate = float64(cancelled) / float64(total)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"window_hours":             windowHours,
+		"since":                    since,
+		"total_rides":              total,
+		"completed_rides":          completed,
+		"cancelled_rides":          cancelled,
+		"rides_per_hour":           roundToCents(float64(total) / float64(windowHours)),
+		"hourly_rides":             hourly,
+		"completion_rate":          roundRate(completionRate),
+		"cancellation_rate":        roundRate(cancellationRate),
+		"average_fare":             roundToCents(avgFare.Float64),
+		"average_match_latency_ms": int64(avgMatchSeconds.Float64 * 1000),
+		"surge_by_region":          surge,
+	})
+}
+
+// roundRate rounds a fraction to three decimal places (a tenth of a percent)
+func roundRate(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
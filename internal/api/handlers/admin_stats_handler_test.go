@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStatsRequest builds a GET /v1/admin/stats context with the given query string
+func newStatsRequest(query string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/admin/stats?"+query, nil)
+	return c, w
+}
+
+// TestGetStats_AggregatesWindow tests that ride aggregates are turned into rates and that
+// surge comes from Redis
+func TestGetStats_AggregatesWindow(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+	require.NoError(t, h.Pricing.SetSurgeMultiplier(context.Background(), "tdr1v", 1.5))
+
+	mock.ExpectQuery("FROM rides r").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "completed", "cancelled", "avg_fare", "avg_match"}).
+			AddRow(8, 6, 1, 212.456, 4.25))
+	hour := time.Now().UTC().Truncate(time.Hour)
+	mock.ExpectQuery("date_trunc\\('hour', requested_at\\)").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"hour", "count"}).
+			AddRow(hour.Add(-time.Hour), 5).
+			AddRow(hour, 3))
+
+	c, w := newStatsRequest("window_hours=2")
+	h.GetStats(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	body := w.Body.String()
+	assert.Contains(t, body, `"rides_per_hour":4`)
+	assert.Contains(t, body, `"completion_rate":0.75`)
+	assert.Contains(t, body, `"cancellation_rate":0.125`)
+	assert.Contains(t, body, `"average_fare":212.46`)
+	assert.Contains(t, body, `"average_match_latency_ms":4250`)
+	assert.Contains(t, body, `"region":"tdr1v"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetStats_FailsOnUnreadableHour tests that an hourly bucket that can't be scanned fails
+// the request instead of leaving a gap in the series
+func TestGetStats_FailsOnUnreadableHour(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	mock.ExpectQuery("FROM rides r").
+		WillReturnRows(sqlmock.NewRows([]string{"count", "completed", "cancelled", "avg_fare", "avg_match"}).
+			AddRow(8, 6, 1, 212.456, 4.25))
+	mock.ExpectQuery("date_trunc\\('hour', requested_at\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"hour", "count"}).
+			AddRow(time.Now().UTC().Truncate(time.Hour), "many"))
+
+	c, w := newStatsRequest("window_hours=2")
+	h.GetStats(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetStats_RejectsInvalidWindow tests that an out-of-range window is refused
+func TestGetStats_RejectsInvalidWindow(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	for _, query := range []string{"window_hours=0", "window_hours=abc", "window_hours=100000"} {
+		c, w := newStatsRequest(query)
+		h.GetStats(c)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
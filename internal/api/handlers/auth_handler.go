@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/dto"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+)
+
+// IssueToken handles POST /v1/auth/token
+// Development only: issues a token for any rider, driver or dashboard without checking
+// credentials. Admin tokens are never issued here; mint them with cmd/token.
+func (h *Handlers) IssueToken(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	if !h.Config.IsDevelopment() {
+		respondError(c, apperrors.NotFound("Not found", nil))
+		return
+	}
+
+	var req dto.IssueTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+	if req.UserType == auth.UserTypeAdmin {
+		respondError(c, apperrors.Forbidden("Admin tokens are not issued by this endpoint", nil))
+		return
+	}
+
+	token, err := auth.GenerateToken(h.Config.JWT.Secret, req.UserID, req.UserType, h.Config.JWT.Expiry)
+	if err != nil {
+		log.Error("Failed to issue token", logger.Err(err), logger.String("user_id", req.UserID))
+		respondError(c, apperrors.Internal("Failed to issue token", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"token_type": "Bearer",
+		"expires_in": int(h.Config.JWT.Expiry.Seconds()),
+	})
+}
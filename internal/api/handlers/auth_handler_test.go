@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestIssueToken_DevelopmentOnlyAndNoAdmin tests that tokens are only issued in development
+// and never for admins
+func TestIssueToken_DevelopmentOnlyAndNoAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    string
+		body   string
+		status int
+	}{
+		{"development rider", "development", `{"user_id":"rider-1","user_type":"rider"}`, http.StatusOK},
+		{"development admin", "development", `{"user_id":"ops-1","user_type":"admin"}`, http.StatusForbidden},
+		{"staging", "staging", `{"user_id":"rider-1","user_type":"rider"}`, http.StatusNotFound},
+		{"mistyped env", "develop", `{"user_id":"rider-1","user_type":"rider"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+			h.Config.Server.Env = tt.env
+			h.Config.JWT.Secret = "test-secret"
+			h.Config.JWT.Expiry = time.Hour
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/auth/token", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			h.IssueToken(c)
+
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
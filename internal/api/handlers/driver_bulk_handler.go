@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/dto"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/service/location"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	seedFirstNames = []string{"Aarav", "Vivaan", "Aditya", "Arjun", "Rohan", "Priya", "Ananya", "Diya", "Kavya", "Meera", "Rahul", "Sneha", "Karan", "Neha", "Vikram"}
+	seedLastNames  = []string{"Sharma", "Verma", "Patel", "Reddy", "Iyer", "Nair", "Gupta", "Singh", "Kumar", "Das", "Rao", "Mehta", "Joshi", "Kapoor", "Bose"}
+)
+
+// seedVehicleTypes weights the fleet towards economy the way a real city looks
+var seedVehicleTypes = []driver.VehicleType{
+	driver.VehicleEconomy, driver.VehicleEconomy, driver.VehicleEconomy,
+	driver.VehiclePremium, driver.VehiclePremium,
+	driver.VehicleLuxury,
+}
+
+// randomSeedDriver builds an online driver at a random point inside the bounding box
+func randomSeedDriver(minLat, maxLat, minLng, maxLng float64) *driver.Driver {
+	id := uuid.New()
+	lat := minLat + rand.Float64()*(maxLat-minLat)
+	lng := minLng + rand.Float64()*(maxLng-minLng)
+	return &driver.Driver{
+		ID:               id,
+		Name:             seedFirstNames[rand.Intn(len(seedFirstNames))] + " " + seedLastNames[rand.Intn(len(seedLastNames))],
+		Email:            fmt.Sprintf("driver-%s@seed.local", id),
+		Phone:            fmt.Sprintf("+91%d%09d", 6+rand.Intn(4), rand.Intn(1_000_000_000)),
+		Status:           driver.StatusOnline,
+		VehicleType:      seedVehicleTypes[rand.Intn(len(seedVehicleTypes))],
+		CurrentLatitude:  &lat,
+		CurrentLongitude: &lng,
+		Rating:           math.Round((3.5+rand.Float64()*1.5)*100) / 100,
+	}
+}
+
+// BulkCreateDrivers handles POST /v1/drivers/bulk
+// Development only: creates count online drivers with random profiles inside the bounding box
+// and seeds them into the Redis geo index and available pool so they can be matched right away.
+// Seeded drivers are swept like any other once they stop reporting locations.
+func (h *Handlers) BulkCreateDrivers(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	if h.Config.Server.Env == "production" {
+		respondError(c, apperrors.NotFound("Not found", nil))
+		return
+	}
+
+	var req dto.BulkCreateDriversRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+	if err := req.ValidateBounds(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var (
+		ids, names, emails, phones, vehicleTypes []string
+		lats, lngs, ratings                      []float64
+	)
+	byID := make(map[string]*driver.Driver, req.Count)
+	for i := 0; i < req.Count; i++ {
+		d := randomSeedDriver(*req.MinLatitude, *req.MaxLatitude, *req.MinLongitude, *req.MaxLongitude)
+		byID[d.ID.String()] = d
+		ids = append(ids, d.ID.String())
+		names = append(names, d.Name)
+		emails = append(emails, d.Email)
+		phones = append(phones, d.Phone)
+		vehicleTypes = append(vehicleTypes, string(d.VehicleType))
+		lats = append(lats, *d.CurrentLatitude)
+		lngs = append(lngs, *d.CurrentLongitude)
+		ratings = append(ratings, d.Rating)
+	}
+
+	ctx := context.Background()
+
+	// One round trip for the whole batch; a random phone that collides with an existing
+	// driver only skips that row instead of failing the request
+	rows, err := h.DB.QueryContext(ctx, `
+		INSERT INTO drivers (id, name, email, phone, status, vehicle_type, current_latitude, current_longitude, rating)
+		SELECT id, name, email, phone, 'online'::driver_status, vehicle_type::vehicle_type, lat, lng, rating
+		FROM unnest($1::uuid[], $2::text[], $3::text[], $4::text[], $5::text[], $6::float8[], $7::float8[], $8::float8[])
+			AS t(id, name, email, phone, vehicle_type, lat, lng, rating)
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	`, pq.Array(ids), pq.Array(names), pq.Array(emails), pq.Array(phones), pq.Array(vehicleTypes),
+		pq.Array(lats), pq.Array(lngs), pq.Array(ratings))
+	if err != nil {
+		log.Error("Failed to insert drivers", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to create drivers", err))
+		return
+	}
+	defer rows.Close()
+
+	created := make([]*driver.Driver, 0, req.Count)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			log.Error("Failed to scan driver id", logger.Err(err))
+			respondError(c, apperrors.Internal("Failed to create drivers", err))
+			return
+		}
+		if d, ok := byID[id]; ok {
+			created = append(created, d)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("Failed to read created drivers", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to create drivers", err))
+		return
+	}
+
+	// Mirror what a location update does so matching sees the drivers immediately
+	now := time.Now().Unix()
+	pipe := h.Redis.Pipeline()
+	for _, d := range created {
+		id := d.ID.String()
+		matching.IndexDriverLocation(ctx, pipe, id, d.VehicleType, *d.CurrentLatitude, *d.CurrentLongitude)
+		pipe.Set(ctx, location.LastSeenKey(id), now, 0)
+		pipe.HSet(ctx, matching.DriverMetaKey(id),
+			"name", d.Name, "phone", d.Phone, "vehicle_type", string(d.VehicleType), "rating", d.Rating)
+		pipe.SAdd(ctx, "drivers:available", id)
+	}
+	if len(created) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Error("Failed to seed drivers in Redis", logger.Err(err))
+			respondError(c, apperrors.Internal("Drivers created but could not be made available", err))
+			return
+		}
+	}
+
+	log.Info("Bulk created drivers",
+		logger.Int("requested", req.Count),
+		logger.Int("created", len(created)),
+	)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"requested": req.Count,
+		"created":   len(created),
+		"skipped":   req.Count - len(created),
+		"drivers":   created,
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/service/location"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBulkDriversRequest builds a POST /v1/drivers/bulk context with the given body
+func newBulkDriversRequest(body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/drivers/bulk", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, w
+}
+
+// TestBulkCreateDrivers_SeedsInsertedDrivers tests that drivers the insert returns are placed
+// inside the box and made available in Redis, while rows skipped on conflict are not
+func TestBulkCreateDrivers_SeedsInsertedDrivers(t *testing.T) {
+	h, client := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	// Pin the UUID source so the test knows which ids the handler generates
+	uuid.SetRand(rand.New(rand.NewSource(1)))
+	first, second := uuid.New().String(), uuid.New().String()
+	uuid.SetRand(rand.New(rand.NewSource(1)))
+	t.Cleanup(func() { uuid.SetRand(nil) })
+
+	mock.ExpectQuery("INSERT INTO drivers").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(first))
+
+	c, w := newBulkDriversRequest(`{"count":2,"min_latitude":12.9,"max_latitude":13.0,"min_longitude":77.5,"max_longitude":77.7}`)
+	h.BulkCreateDrivers(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"created":1`)
+	assert.Contains(t, w.Body.String(), `"skipped":1`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	ctx := context.Background()
+	available, err := client.SMembers(ctx, "drivers:available").Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{first}, available)
+
+	pos, err := client.GeoPos(ctx, "drivers:locations", first).Result()
+	require.NoError(t, err)
+	require.NotNil(t, pos[0])
+	assert.InDelta(t, 12.95, pos[0].Latitude, 0.051)
+	assert.InDelta(t, 77.6, pos[0].Longitude, 0.101)
+
+	vehicleType, err := client.HGet(ctx, matching.DriverMetaKey(first), "vehicle_type").Result()
+	require.NoError(t, err)
+	assert.Contains(t, []string{"economy", "premium", "luxury"}, vehicleType)
+	assert.NotEmpty(t, client.HGet(ctx, matching.DriverMetaKey(first), "name").Val())
+	assert.Equal(t, int64(1), client.Exists(ctx, location.LastSeenKey(first)).Val())
+	assert.Equal(t, int64(0), client.Exists(ctx, location.LastSeenKey(second)).Val())
+}
+
+// TestBulkCreateDrivers_RejectsInvertedBox tests that a box whose min exceeds its max is a 400
+func TestBulkCreateDrivers_RejectsInvertedBox(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	c, w := newBulkDriversRequest(`{"count":5,"min_latitude":13.0,"max_latitude":12.9,"min_longitude":77.5,"max_longitude":77.7}`)
+	h.BulkCreateDrivers(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestBulkCreateDrivers_DisabledInProduction tests that seeding is not exposed in production
+func TestBulkCreateDrivers_DisabledInProduction(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	h.Config.Server.Env = "production"
+
+	c, w := newBulkDriversRequest(`{"count":5,"min_latitude":12.9,"max_latitude":13.0,"min_longitude":77.5,"max_longitude":77.7}`)
+	h.BulkCreateDrivers(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -2,164 +2,903 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gocomet/ride-hailing/internal/api/dto"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/service/eta"
+	"github.com/gocomet/ride-hailing/internal/service/location"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/gocomet/ride-hailing/internal/service/routing"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	"github.com/gocomet/ride-hailing/pkg/cache"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
 	"github.com/gocomet/ride-hailing/pkg/logger"
 	"github.com/gocomet/ride-hailing/pkg/websocket"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 )
 
+// earningsDateLayout is the date format accepted by the earnings endpoint
+const earningsDateLayout = "2006-01-02"
+
 // UpdateDriverLocation handles POST /v1/drivers/:id/location
 func (h *Handlers) UpdateDriverLocation(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
 	driverID := c.Param("id")
 	ctx := context.Background()
 
+	if middleware.GetUserType(c) == auth.UserTypeDriver && middleware.GetUserID(c) != driverID {
+		respondError(c, apperrors.Forbidden("Drivers may only report their own location", nil))
+		return
+	}
+
 	var req dto.UpdateLocationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload", "details": err.Error()})
+		respondError(c, invalidPayload(err))
+		return
+	}
+	if err := req.ValidateCoordinates(); err != nil {
+		respondError(c, err)
 		return
 	}
+	lat, lng := *req.Latitude, *req.Longitude
 
-	h.Logger.Info("Driver location update",
+	log.Info("Driver location update",
 		logger.String("driver_id", driverID),
-		logger.Float64("latitude", req.Latitude),
-		logger.Float64("longitude", req.Longitude),
+		logger.Float64("latitude", lat),
+		logger.Float64("longitude", lng),
 	)
 
-	// Update Redis geo-spatial index for fast lookups
-	_, err := h.Redis.GeoAdd(ctx, "drivers:locations", &redis.GeoLocation{
-		Name:      driverID,
-		Longitude: req.Longitude,
-		Latitude:  req.Latitude,
-	}).Result()
+	// Cache the driver's profile so matching can filter, rank and return candidates without a
+	// DB hit. Deleting a driver clears this cache, so a deleted driver is turned away here.
+	metaKey := matching.DriverMetaKey(driverID)
+	var vehicleType string
+	meta, _ := h.Redis.HMGet(ctx, metaKey, "name", "vehicle_type").Result()
+	if len(meta) == 2 && meta[1] != nil {
+		vehicleType, _ = meta[1].(string)
+	}
+	if len(meta) != 2 || meta[0] == nil {
+		var name, phone string
+		var rating float64
+		err := h.DB.QueryRowContext(ctx, "SELECT name, phone, vehicle_type, rating FROM drivers WHERE id = $1 AND deleted_at IS NULL", driverID).
+			Scan(&name, &phone, &vehicleType, &rating)
+		if err == sql.ErrNoRows {
+			respondError(c, apperrors.ErrDriverNotFound)
+			return
+		}
+		if err != nil {
+			log.Warn("Failed to load driver metadata", logger.String("driver_id", driverID), logger.Err(err))
+		} else {
+			h.Redis.HSet(ctx, metaKey, "name", name, "phone", phone, "vehicle_type", vehicleType, "rating", rating)
+		}
+	}
+
+	// Update the Redis geo-spatial indexes for fast lookups: the shared one, plus the one
+	// for the driver's vehicle type that matching searches
+	pipe := h.Redis.TxPipeline()
+	matching.IndexDriverLocation(ctx, pipe, driverID, driver.VehicleType(vehicleType), lat, lng)
+	_, err := pipe.Exec(ctx)
 
 	if err != nil {
-		h.Logger.Error("Failed to update Redis location", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update location"})
+		log.Error("Failed to update Redis location", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to update location", err))
 		return
 	}
 
+	// Refresh the heartbeat the stale sweeper checks
+	h.Redis.Set(ctx, location.LastSeenKey(driverID), time.Now().Unix(), 0)
+
 	// Add driver to available set if not currently on a ride
 	currentRideKey := fmt.Sprintf("driver:%s:current_ride", driverID)
 	currentRide, _ := h.Redis.Get(ctx, currentRideKey).Result()
 	if currentRide == "" {
 		h.Redis.SAdd(ctx, "drivers:available", driverID)
-		h.Logger.Info("Driver added to available pool", logger.String("driver_id", driverID))
+		log.Info("Driver added to available pool", logger.String("driver_id", driverID))
+	} else if currentRide != matching.ClaimingMarker {
+		h.trackLocation(ctx, log, currentRide, driverID, lat, lng)
 	}
 
-	// Also update PostgreSQL (debounced in production)
-	_, err = h.DB.ExecContext(ctx, `
-		UPDATE drivers
-		SET current_latitude = $1,
-		    current_longitude = $2,
-		    updated_at = NOW()
-		WHERE id = $3
-	`, req.Latitude, req.Longitude, driverID)
+	// Queue the PostgreSQL write; the batcher flushes coalesced positions periodically
+	h.Locations.Add(driverID, lat, lng)
 
-	if err != nil {
-		h.Logger.Warn("Failed to update PostgreSQL location", logger.Err(err))
-		// Don't fail the request - Redis is more critical
+	// Dashboards following this driver get every position, on a ride or not
+	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
+		wsHub.BroadcastToDriverSubscribers(driverID, websocket.Message{
+			Type: "driver_location",
+			Data: map[string]interface{}{
+				"driver_id": driverID,
+				"latitude":  lat,
+				"longitude": lng,
+				"timestamp": time.Now().UTC(),
+			},
+		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "success",
 		"driver_id": driverID,
-		"latitude":  req.Latitude,
-		"longitude": req.Longitude,
+		"latitude":  lat,
+		"longitude": lng,
 		"timestamp": time.Now().UTC(),
 	})
 }
 
+// Breadcrumb trail limits for ride:<id>:track
+const (
+	maxTrackPoints = 1000
+	trackTTL       = 24 * time.Hour
+)
+
+// etaPushInterval is the minimum gap between eta_update pushes for one ride
+const etaPushInterval = 5 * time.Second
+
+// rideTrackKey holds the driver's breadcrumb trail for a ride
+func rideTrackKey(rideID string) string {
+	return fmt.Sprintf("ride:%s:track", rideID)
+}
+
+// trackLocation appends a breadcrumb to the ride's trail and pushes the new
+// position to clients subscribed to the ride
+func (h *Handlers) trackLocation(ctx context.Context, log *logger.Logger, rideID, driverID string, lat, lng float64) {
+	point := map[string]interface{}{
+		"ride_id":   rideID,
+		"driver_id": driverID,
+		"latitude":  lat,
+		"longitude": lng,
+		"timestamp": time.Now().UTC(),
+	}
+
+	data, err := json.Marshal(point)
+	if err != nil {
+		log.Warn("Failed to encode breadcrumb", logger.Err(err))
+		return
+	}
+
+	// Keep only the most recent points so long trips don't grow the list unbounded
+	key := rideTrackKey(rideID)
+	pipe := h.Redis.TxPipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.LTrim(ctx, key, -maxTrackPoints, -1)
+	pipe.Expire(ctx, key, trackTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Warn("Failed to record breadcrumb", logger.Err(err), logger.String("ride_id", rideID))
+	}
+
+	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
+		wsHub.BroadcastToRide(rideID, websocket.Message{
+			Type: "driver_location",
+			Data: point,
+		})
+	}
+
+	h.pushETA(ctx, log, rideID, lat, lng)
+}
+
+// pushETA recomputes the ETA to pickup (or to dropoff once the trip has started)
+// and broadcasts it to the ride, at most once per etaPushInterval
+func (h *Handlers) pushETA(ctx context.Context, log *logger.Logger, rideID string, lat, lng float64) {
+	debounceKey := fmt.Sprintf("ride:%s:eta_pushed", rideID)
+	if ok, err := h.Redis.SetNX(ctx, debounceKey, 1, etaPushInterval).Result(); err != nil || !ok {
+		return
+	}
+
+	rd, err := h.Rides.GetByID(ctx, rideID)
+	if err != nil {
+		log.Warn("Failed to load ride for ETA", logger.Err(err), logger.String("ride_id", rideID))
+		return
+	}
+
+	var target routing.Point
+	var leg string
+	switch rd.Status {
+	case ride.StatusAssigned, ride.StatusAccepted:
+		target, leg = routing.Point{Latitude: rd.PickupLatitude, Longitude: rd.PickupLongitude}, "pickup"
+	case ride.StatusStarted:
+		target, leg = routing.Point{Latitude: rd.DropoffLatitude, Longitude: rd.DropoffLongitude}, "dropoff"
+	default:
+		return
+	}
+
+	distance, minutes, _, err := h.Router.EstimateRoute(ctx, routing.Point{Latitude: lat, Longitude: lng}, target)
+	if err != nil {
+		log.Warn("Failed to estimate ETA", logger.Err(err), logger.String("ride_id", rideID))
+		return
+	}
+
+	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
+		wsHub.BroadcastToRide(rideID, websocket.Message{
+			Type: "eta_update",
+			Data: map[string]interface{}{
+				"ride_id":     rideID,
+				"leg":         leg,
+				"distance_km": roundToCents(distance),
+				"eta_minutes": minutes,
+				"eta":         eta.Format(minutes),
+			},
+		})
+	}
+}
+
 // AcceptRide handles POST /v1/drivers/:id/accept
 func (h *Handlers) AcceptRide(c *gin.Context) {
 	driverID := c.Param("id")
 
+	if middleware.GetUserType(c) == auth.UserTypeDriver && middleware.GetUserID(c) != driverID {
+		respondError(c, apperrors.Forbidden("Drivers may only accept their own ride offers", nil))
+		return
+	}
+
 	var req dto.AcceptRideRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		respondError(c, invalidPayload(err))
 		return
 	}
 
-	h.Logger.Info("Driver accepting ride",
+	log := middleware.WithLogFields(c, h.Logger,
 		logger.String("driver_id", driverID),
 		logger.String("ride_id", req.RideID),
 	)
+	log.Info("Driver accepting ride")
 
-	// Store current ride in Redis
 	ctx := context.Background()
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Failed to begin transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Database error", err))
+		return
+	}
+	defer tx.Rollback()
+
+	// Validate the ride is assigned to this driver and may be accepted
+	var status, riderID string
+	var assignedDriverID sql.NullString
+	var pickupLat, pickupLng float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, driver_id, rider_id, pickup_latitude, pickup_longitude
+		FROM rides WHERE id = $1 FOR UPDATE
+	`, req.RideID).Scan(&status, &assignedDriverID, &riderID, &pickupLat, &pickupLng)
+
+	if err == sql.ErrNoRows {
+		respondError(c, apperrors.ErrRideNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Error("Failed to get ride", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to accept ride", err))
+		return
+	}
+
+	if assignedDriverID.String != driverID {
+		respondError(c, apperrors.Conflict("Ride is not assigned to this driver", nil))
+		return
+	}
+
+	if err := ride.Transition(ride.Status(status), ride.StatusAccepted); err != nil {
+		log.Warn("Rejected ride status transition", logger.Err(err))
+		respondError(c, invalidTransition("accepted", ride.Status(status), err))
+		return
+	}
+
+	// Persist acceptance so the ride can move on to started
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rides
+		SET status = 'accepted', accepted_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`, req.RideID)
+	if err != nil {
+		log.Error("Failed to update ride", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to accept ride", err))
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("Failed to commit transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to accept ride", err))
+		return
+	}
+
+	h.clearAssignment(ctx, log, req.RideID)
+	actorType, actorID := requestActor(c)
+	h.recordRideEvent(ctx, log, req.RideID, ride.EventAccepted, actorType, actorID, map[string]interface{}{
+		"driver_id": driverID,
+	})
+
+	// Store current ride in Redis
 	currentRideKey := fmt.Sprintf("driver:%s:current_ride", driverID)
 	// Store with 24 hour expiry (in case trip never completes, auto-cleanup)
 	h.Redis.Set(ctx, currentRideKey, req.RideID, 24*time.Hour)
-	h.Logger.Info("Stored current ride for driver", logger.String("driver_id", driverID), logger.String("ride_id", req.RideID))
+	log.Info("Stored current ride for driver")
+	h.markDriverBusy(ctx, log, driverID)
+
+	// Estimate arrival from the driver's last known position to the pickup, slowed by any surge
+	etaMinutes := 0
+	positions, err := h.Redis.GeoPos(ctx, "drivers:locations", driverID).Result()
+	if err == nil && len(positions) > 0 && positions[0] != nil {
+		surge := h.currentSurge(ctx, h.Pricing.RegionForCoordinates(pickupLat, pickupLng))
+		etaMinutes = h.ETA.ArrivalInTraffic(
+			routing.Point{Latitude: positions[0].Latitude, Longitude: positions[0].Longitude},
+			routing.Point{Latitude: pickupLat, Longitude: pickupLng},
+			surge,
+		)
+	} else {
+		log.Warn("Driver location unavailable for ETA", logger.Err(err))
+	}
+
+	acceptedData := map[string]interface{}{
+		"ride_id":     req.RideID,
+		"driver_id":   driverID,
+		"status":      "accepted",
+		"message":     "Driver is on the way!",
+		"eta":         eta.Format(etaMinutes),
+		"eta_minutes": etaMinutes,
+	}
+
+	// Notify the ride's rider, plus any dashboards subscribed to the ride
+	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
+		wsHub.SendToUser(riderID, map[string]interface{}{
+			"type": "ride_accepted",
+			"data": acceptedData,
+		})
+		wsHub.BroadcastToRide(req.RideID, websocket.Message{
+			Type: "ride_accepted",
+			Data: acceptedData,
+		})
+	}
 
-	// Send notification to rider
-	riderNotification := map[string]interface{}{
-		"type": "ride_accepted",
+	c.JSON(http.StatusOK, gin.H{
+		"status":      "accepted",
+		"ride_id":     req.RideID,
+		"eta":         eta.Format(etaMinutes),
+		"eta_minutes": etaMinutes,
+		"message":     "Ride accepted successfully",
+	})
+}
+
+// RejectRide handles POST /v1/drivers/:id/reject
+// The driver is released and the ride is offered to the next nearest driver who
+// hasn't already rejected it. After MaxRematchAttempts rejections the ride goes
+// back to requested.
+func (h *Handlers) RejectRide(c *gin.Context) {
+	driverID := c.Param("id")
+
+	if middleware.GetUserType(c) == auth.UserTypeDriver && middleware.GetUserID(c) != driverID {
+		respondError(c, apperrors.Forbidden("Drivers may only reject their own ride offers", nil))
+		return
+	}
+
+	var req dto.RejectRideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	// Only the ride is scoped; re-offer logs name both the previous and the next driver
+	log := middleware.WithLogFields(c, h.Logger, logger.String("ride_id", req.RideID))
+	log.Info("Driver rejecting ride", logger.String("driver_id", driverID))
+
+	result, err := h.reofferRide(traceContext(c), log, req.RideID, driverID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if result.candidate == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "requested",
+			"ride_id": req.RideID,
+			"message": "Ride rejected, no other drivers available",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":             "assigned",
+		"ride_id":            req.RideID,
+		"driver_id":          result.candidate.Driver.ID.String(),
+		"driver_distance_km": result.candidate.Distance,
+		"message":            "Ride offered to next driver",
+	})
+}
+
+// ExpireAssignment re-offers a ride whose driver let the offer lapse without accepting,
+// exactly as if they had rejected it. Rides that were accepted, cancelled or re-assigned
+// since are left alone.
+func (h *Handlers) ExpireAssignment(ctx context.Context, rideID string) error {
+	log := h.Logger.With(logger.String("ride_id", rideID))
+
+	result, err := h.reofferRide(ctx, log, rideID, "")
+	if errors.Is(err, errOfferStillOpen) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info("Ride offer timed out",
+		logger.String("previous_driver_id", result.previousDriverID),
+		logger.Bool("reassigned", result.candidate != nil),
+	)
+	return nil
+}
+
+// errOfferStillOpen means the ride's current offer hasn't lapsed, so there's nothing to expire
+var errOfferStillOpen = errors.New("ride offer still open")
+
+// reoffer is the outcome of taking a ride away from its assigned driver
+type reoffer struct {
+	previousDriverID string
+	// candidate is the driver now offered the ride; nil when it went back to requested
+	candidate *matching.DriverCandidate
+}
+
+// reofferRide takes an assigned ride away from its driver and offers it to the next nearest
+// driver who hasn't declined it. With driverID set the ride must be assigned to that driver
+// (a rejection); with it empty the current offer must have lapsed (a timeout).
+// The ride goes back to requested before matching, so the row lock isn't held during the
+// search, and the next driver is only assigned if nothing else changed the ride meanwhile.
+// log is expected to be scoped to the ride.
+func (h *Handlers) reofferRide(ctx context.Context, log *logger.Logger, rideID, driverID string) (*reoffer, error) {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Failed to begin transaction", logger.Err(err))
+		return nil, apperrors.Internal("Database error", err)
+	}
+	defer tx.Rollback()
+
+	var status, riderID, vehicleType string
+	var seats int
+	var allowUpgrade bool
+	var assignedDriverID sql.NullString
+	var assignedAt sql.NullTime
+	var pickupLat, pickupLng, dropoffLat, dropoffLng float64
+	var estimatedFare sql.NullFloat64
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, driver_id, assigned_at, rider_id, vehicle_type, seats, allow_upgrade,
+		       pickup_latitude, pickup_longitude, dropoff_latitude, dropoff_longitude,
+		       estimated_fare
+		FROM rides WHERE id = $1 FOR UPDATE
+	`, rideID).Scan(&status, &assignedDriverID, &assignedAt, &riderID, &vehicleType, &seats, &allowUpgrade,
+		&pickupLat, &pickupLng, &dropoffLat, &dropoffLng, &estimatedFare)
+
+	if err == sql.ErrNoRows {
+		return nil, apperrors.ErrRideNotFound
+	}
+
+	if err != nil {
+		log.Error("Failed to get ride", logger.Err(err))
+		return nil, apperrors.Internal("Failed to reject ride", err)
+	}
+
+	// A rejection is the driver's doing; a lapsed offer is noticed by the system
+	actorType, actorID, reason := auth.UserTypeDriver, driverID, "rejected"
+	if driverID == "" {
+		actorType, actorID, reason = ride.ActorSystem, "", "offer_timeout"
+
+		// A timeout only applies to a ride still waiting on the offer it was tracked for
+		lapsed := assignedAt.Valid && time.Since(assignedAt.Time) >= h.Config.Matching.AssignmentTimeout
+		if ride.Status(status) != ride.StatusAssigned || !lapsed {
+			return nil, errOfferStillOpen
+		}
+		driverID = assignedDriverID.String
+	} else if assignedDriverID.String != driverID {
+		return nil, apperrors.Conflict("Ride is not assigned to this driver", nil)
+	}
+
+	// Only an assigned (not yet accepted) ride can be declined
+	if err := ride.Transition(ride.Status(status), ride.StatusRequested); err != nil {
+		log.Warn("Rejected ride status transition", logger.Err(err))
+		return nil, invalidTransition("rejected", ride.Status(status), err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rides
+		SET status = 'requested', driver_id = NULL, assigned_at = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, rideID)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Error("Failed to release ride", logger.Err(err))
+		return nil, apperrors.Internal("Failed to reject ride", err)
+	}
+
+	// The declining driver is free for other rides
+	pool := h.releaseDriverFromRide(ctx, driverID, rideID)
+
+	// Remember who declined so they aren't offered the same ride again
+	rejectedKey := fmt.Sprintf("ride:%s:rejected_drivers", rideID)
+	h.Redis.SAdd(ctx, rejectedKey, driverID)
+	h.Redis.Expire(ctx, rejectedKey, 24*time.Hour)
+
+	rejectedIDs, err := h.Redis.SMembers(ctx, rejectedKey).Result()
+	if err != nil {
+		log.Error("Failed to load rejected drivers", logger.Err(err))
+		rejectedIDs = []string{driverID}
+	}
+	excluded := make(map[string]bool, len(rejectedIDs))
+	for _, id := range rejectedIDs {
+		excluded[id] = true
+	}
+
+	// Try the next nearest driver unless the ride has been declined too many times
+	var candidate *matching.DriverCandidate
+	if len(rejectedIDs) < h.Config.Matching.MaxRematchAttempts {
+		candidate, err = h.newMatchingService(log).FindNearestDriverForSeats(ctx, riderID, pickupLat, pickupLng, driver.VehicleType(vehicleType), seats, allowUpgrade, excluded)
+		if err != nil {
+			log.Warn("No replacement driver found", logger.Err(err))
+			candidate = nil
+		}
+	} else {
+		log.Warn("Re-match attempts exhausted", logger.Int("rejections", len(rejectedIDs)))
+	}
+
+	// Assign only if the ride is still waiting, e.g. the rider didn't cancel during the search
+	if candidate != nil {
+		result, err := h.DB.ExecContext(ctx, `
+			UPDATE rides
+			SET status = 'assigned', driver_id = $2, assigned_at = NOW(), updated_at = NOW()
+			WHERE id = $1 AND status = 'requested' AND driver_id IS NULL
+		`, rideID, candidate.Driver.ID.String())
+		var assigned int64
+		if err == nil {
+			assigned, err = result.RowsAffected()
+		}
+		if err != nil || assigned == 0 {
+			log.Warn("Ride not reassigned", logger.String("driver_id", candidate.Driver.ID.String()), logger.Err(err))
+			h.releaseDriver(ctx, candidate.Driver.ID.String())
+			candidate = nil
+		}
+	}
+
+	details := map[string]interface{}{
+		"previous_driver_id": driverID,
+		"reason":             reason,
+	}
+	if candidate != nil {
+		details["driver_id"] = candidate.Driver.ID.String()
+		details["distance_km"] = candidate.Distance
+	}
+	h.recordRideEvent(ctx, log, rideID, ride.EventRematched, actorType, actorID, details)
+
+	result := &reoffer{previousDriverID: driverID, candidate: candidate}
+	if candidate == nil {
+		h.clearAssignment(ctx, log, rideID)
+		return result, nil
+	}
+
+	newDriverID := candidate.Driver.ID.String()
+	h.holdDriver(ctx, log, newDriverID, &ride.Ride{
+		ID:               rideID,
+		Pool:             pool,
+		DropoffLatitude:  dropoffLat,
+		DropoffLongitude: dropoffLng,
+	}, false)
+	h.trackAssignment(ctx, log, rideID)
+
+	log.Info("Ride re-offered to next driver",
+		logger.String("previous_driver_id", driverID),
+		logger.String("driver_id", newDriverID),
+	)
+
+	driverNotification := map[string]interface{}{
+		"type": "ride_request",
 		"data": map[string]interface{}{
-			"ride_id":   req.RideID,
-			"driver_id": driverID,
-			"status":    "accepted",
-			"message":   "Driver is on the way!",
-			"eta":       "5 mins",
+			"ride_id":           rideID,
+			"driver_id":         newDriverID,
+			"rider_id":          riderID,
+			"pickup_latitude":   pickupLat,
+			"pickup_longitude":  pickupLng,
+			"dropoff_latitude":  dropoffLat,
+			"dropoff_longitude": dropoffLng,
+			"vehicle_type":      candidate.Driver.VehicleType,
+			"seats":             seats,
+			"distance":          fmt.Sprintf("%.2f km", candidate.Distance),
+			"distance_km":       candidate.Distance,
+			"estimated_fare":    estimatedFare.Float64,
 		},
 	}
-
-	// Broadcast to all riders (in production, send to specific rider)
 	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
-		wsHub.BroadcastToType("rider", riderNotification)
+		wsHub.BroadcastToType("dashboard", driverNotification)
+	}
+
+	return result, nil
+}
+
+// trackAssignment starts the acceptance countdown for the ride's newly assigned driver;
+// log is expected to be scoped to the ride
+func (h *Handlers) trackAssignment(ctx context.Context, log *logger.Logger, rideID string) {
+	deadline := time.Now().Add(h.Config.Matching.AssignmentTimeout)
+	if err := matching.TrackAssignment(ctx, h.Redis, rideID, deadline); err != nil {
+		log.Warn("Failed to track ride assignment", logger.Err(err))
+	}
+}
+
+// clearAssignment stops the acceptance countdown for a ride; log is expected to be scoped to the ride
+func (h *Handlers) clearAssignment(ctx context.Context, log *logger.Logger, rideID string) {
+	if err := matching.ClearAssignment(ctx, h.Redis, rideID); err != nil {
+		log.Warn("Failed to clear ride assignment", logger.Err(err))
+	}
+}
+
+// releaseDriver clears a driver's ride claim and returns them to the available pool
+func (h *Handlers) releaseDriver(ctx context.Context, driverID string) {
+	h.Redis.Del(ctx, fmt.Sprintf("driver:%s:current_ride", driverID))
+	h.Redis.SAdd(ctx, "drivers:available", driverID)
+	h.markDriverFree(ctx, h.Logger, driverID)
+}
+
+// releaseDriverFromRide frees a driver once rideID no longer needs them. A driver still
+// carrying other pool riders stays busy and follows one of them instead. It reports whether
+// rideID was one of the driver's pool rides.
+func (h *Handlers) releaseDriverFromRide(ctx context.Context, driverID, rideID string) (pooled bool) {
+	remaining, pooled, err := matching.LeavePool(ctx, h.Redis, driverID, rideID)
+	if err != nil || len(remaining) == 0 {
+		h.releaseDriver(ctx, driverID)
+		return pooled
+	}
+
+	currentRideKey := fmt.Sprintf("driver:%s:current_ride", driverID)
+	if currentRide, _ := h.Redis.Get(ctx, currentRideKey).Result(); currentRide == rideID {
+		h.Redis.Set(ctx, currentRideKey, remaining[0], redis.KeepTTL)
+	}
+	return pooled
+}
+
+// GetNearbyDrivers handles GET /v1/drivers/nearby?lat=&lng=&radius_km=&vehicle_type=
+// It is read-only: drivers are listed but never claimed.
+func (h *Handlers) GetNearbyDrivers(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	ctx := context.Background()
+
+	lat, latErr := strconv.ParseFloat(c.Query("lat"), 64)
+	lng, lngErr := strconv.ParseFloat(c.Query("lng"), 64)
+	if latErr != nil || lngErr != nil || !dto.ValidCoordinates(lat, lng) {
+		respondError(c, apperrors.BadRequest("lat and lng must be valid coordinates", nil))
+		return
+	}
+
+	maxRadius := h.Config.Matching.NearbyMaxRadiusKM
+	radius := h.Config.Matching.MaxRadiusKM
+	if v := c.Query("radius_km"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed <= 0 {
+			respondError(c, apperrors.BadRequest("radius_km must be a positive number", err))
+			return
+		}
+		radius = parsed
+	}
+	if radius > maxRadius {
+		radius = maxRadius
+	}
+
+	vehicleType := driver.VehicleType(c.Query("vehicle_type"))
+	if vehicleType != "" && !vehicleType.IsValid() {
+		respondError(c, apperrors.BadRequest("Invalid vehicle_type", nil))
+		return
+	}
+
+	nearby, err := h.newMatchingService(log).FindNearbyDrivers(ctx, lat, lng, radius, vehicleType, h.Config.Matching.NearbyMaxResults)
+	if err != nil {
+		log.Error("Failed to search nearby drivers", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to search nearby drivers", err))
+		return
+	}
+
+	drivers := make([]gin.H, 0, len(nearby))
+	for _, d := range nearby {
+		drivers = append(drivers, gin.H{
+			"id":           d.ID,
+			"vehicle_type": d.VehicleType,
+			"latitude":     d.Latitude,
+			"longitude":    d.Longitude,
+			"distance_km":  roundToCents(d.Distance),
+		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"status":  "accepted",
-		"ride_id": req.RideID,
-		"message": "Ride accepted successfully",
+		"drivers":   drivers,
+		"count":     len(drivers),
+		"radius_km": radius,
 	})
 }
 
 // GetRandomDriver handles GET /v1/drivers/random (for testing)
 func (h *Handlers) GetRandomDriver(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
 	ctx := context.Background()
 
 	// Get a random online driver
-	var driverID, name string
-	var rating float64
-	var latitude, longitude *float64
+	onlineDrivers, err := h.Drivers.GetAvailableDrivers(ctx, "")
+	if err != nil || len(onlineDrivers) == 0 {
+		log.Error("Failed to get random driver", logger.Err(err))
+		respondError(c, apperrors.NotFound("No drivers available", err))
+		return
+	}
 
-	err := h.DB.QueryRowContext(ctx, `
-		SELECT id, name, rating, current_latitude, current_longitude
-		FROM drivers
-		WHERE status = 'online'
-		ORDER BY RANDOM()
-		LIMIT 1
-	`).Scan(&driverID, &name, &rating, &latitude, &longitude)
+	d := onlineDrivers[rand.Intn(len(onlineDrivers))]
+	c.JSON(http.StatusOK, gin.H{
+		"id":        d.ID.String(),
+		"name":      d.Name,
+		"rating":    d.Rating,
+		"latitude":  d.CurrentLatitude,
+		"longitude": d.CurrentLongitude,
+	})
+}
+
+// driverValidationErrors maps driver.IsValid failures to client-facing messages
+var driverValidationErrors = map[error]string{
+	driver.ErrInvalidDriverName:  "Driver name is required",
+	driver.ErrInvalidDriverEmail: "Driver email is required",
+	driver.ErrInvalidDriverPhone: "Driver phone is required",
+	driver.ErrInvalidVehicleType: "Invalid vehicle type, expected economy, premium or luxury",
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// RegisterDriver handles POST /v1/drivers
+// New drivers start offline with the default rating and go online with their first location update.
+func (h *Handlers) RegisterDriver(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	var req dto.RegisterDriverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	d := &driver.Driver{
+		Name:        strings.TrimSpace(req.Name),
+		Email:       strings.ToLower(strings.TrimSpace(req.Email)),
+		Phone:       strings.TrimSpace(req.Phone),
+		Status:      driver.StatusOffline,
+		VehicleType: driver.VehicleType(req.VehicleType),
+		Rating:      5.0,
+	}
+	if err := d.IsValid(); err != nil {
+		message, ok := driverValidationErrors[err]
+		if !ok {
+			message = "Invalid driver"
+		}
+		respondError(c, apperrors.BadRequest(message, err))
+		return
+	}
+
+	ctx := context.Background()
+
+	if _, err := h.Drivers.GetByEmail(ctx, d.Email); err == nil {
+		respondError(c, apperrors.Conflict("A driver with this email is already registered", nil))
+		return
+	} else if !errors.Is(err, driver.ErrDriverNotFound) {
+		log.Error("Failed to look up driver email", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to register driver", err))
+		return
+	}
 
+	// The lookup can't see a concurrent registration, and phone is unique too,
+	// so the constraints still have the final say
+	if err := h.Drivers.Create(ctx, d); err != nil {
+		if isUniqueViolation(err) {
+			respondError(c, apperrors.Conflict("A driver with this email or phone is already registered", err))
+			return
+		}
+		log.Error("Failed to create driver", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to register driver", err))
+		return
+	}
+
+	log.Info("Driver registered",
+		logger.String("driver_id", d.ID.String()),
+		logger.String("vehicle_type", string(d.VehicleType)),
+	)
+
+	c.JSON(http.StatusCreated, d)
+}
+
+// DeleteDriver handles DELETE /v1/drivers/:id
+// Soft-deletes the driver and drops them from matching; their rides and earnings are kept.
+// Drivers may only delete themselves, and not while a ride is assigned to them.
+func (h *Handlers) DeleteDriver(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	driverID := c.Param("id")
+	ctx := context.Background()
+
+	if middleware.GetUserType(c) == auth.UserTypeDriver && middleware.GetUserID(c) != driverID {
+		respondError(c, apperrors.Forbidden("Drivers may only delete their own account", nil))
+		return
+	}
+	id, err := uuid.Parse(driverID)
 	if err != nil {
-		h.Logger.Error("Failed to get random driver", logger.Err(err))
-		c.JSON(http.StatusNotFound, gin.H{"error": "No drivers available"})
+		respondError(c, apperrors.ErrDriverNotFound)
+		return
+	}
+
+	activeRide, err := h.Rides.GetActiveRideByDriver(ctx, id)
+	if err == nil {
+		respondError(c, apperrors.Conflict(fmt.Sprintf("Driver has a ride in progress (%s)", activeRide.ID), nil))
+		return
+	}
+	if !errors.Is(err, ride.ErrRideNotFound) {
+		log.Error("Failed to check active rides", logger.Err(err), logger.String("driver_id", driverID))
+		respondError(c, apperrors.Internal("Failed to delete driver", err))
+		return
+	}
+
+	if err := h.Drivers.Delete(ctx, id); err != nil {
+		if errors.Is(err, driver.ErrDriverNotFound) {
+			respondError(c, apperrors.ErrDriverNotFound)
+			return
+		}
+		log.Error("Failed to delete driver", logger.Err(err), logger.String("driver_id", driverID))
+		respondError(c, apperrors.Internal("Failed to delete driver", err))
 		return
 	}
+	if err := h.Sessions.End(ctx, id, time.Now().UTC()); err != nil {
+		log.Warn("Failed to end driver session", logger.Err(err), logger.String("driver_id", driverID))
+	}
+
+	// Clearing the vehicle type cache makes the next location update check the database,
+	// which keeps a deleted driver from rejoining the pool
+	pipe := h.Redis.Pipeline()
+	matching.RemoveDriverLocations(ctx, pipe, driverID)
+	pipe.SRem(ctx, "drivers:available", driverID)
+	pipe.Del(ctx, matching.DriverMetaKey(driverID), location.LastSeenKey(driverID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Warn("Failed to remove deleted driver from Redis", logger.Err(err), logger.String("driver_id", driverID))
+	}
+
+	log.Info("Driver deleted", logger.String("driver_id", driverID))
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":        driverID,
-		"name":      name,
-		"rating":    rating,
-		"latitude":  latitude,
-		"longitude": longitude,
+		"driver_id": driverID,
+		"status":    "deleted",
 	})
 }
 
-// GetAllDrivers handles GET /v1/drivers/all
-func (h *Handlers) GetAllDrivers(c *gin.Context) {
+// GetDriver handles GET /v1/drivers/:id
+func (h *Handlers) GetDriver(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	driverID := c.Param("id")
 	ctx := context.Background()
 
-	// Query all drivers with earnings
-	rows, err := h.DB.QueryContext(ctx, `
+	id, err := uuid.Parse(driverID)
+	if err != nil {
+		respondError(c, apperrors.ErrDriverNotFound)
+		return
+	}
+
+	var (
+		name, phone, status, vehicleType string
+		rating, totalEarnings            float64
+		latitude, longitude              *float64
+		totalRides                       int
+	)
+	err = h.DB.QueryRowContext(ctx, `
 		SELECT
-			d.id,
 			d.name,
 			d.phone,
 			d.status,
@@ -167,56 +906,182 @@ func (h *Handlers) GetAllDrivers(c *gin.Context) {
 			d.rating,
 			d.current_latitude,
 			d.current_longitude,
-			COALESCE(SUM(de.total_earnings), 0) as total_earnings,
-			COUNT(r.id) as total_rides
+			(SELECT COUNT(*) FROM rides WHERE driver_id = d.id AND status = 'completed'),
+			COALESCE((SELECT SUM(total_earnings) FROM driver_earnings WHERE driver_id = d.id), 0)
 		FROM drivers d
-		LEFT JOIN driver_earnings de ON d.id = de.driver_id
-		LEFT JOIN rides r ON d.id = r.driver_id AND r.status = 'completed'
-		GROUP BY d.id, d.name, d.phone, d.status, d.vehicle_type, d.rating, d.current_latitude, d.current_longitude
-		ORDER BY d.name
-	`)
+		WHERE d.id = $1 AND d.deleted_at IS NULL
+	`, driverID).Scan(&name, &phone, &status, &vehicleType, &rating,
+		&latitude, &longitude, &totalRides, &totalEarnings)
+
+	if err == sql.ErrNoRows {
+		respondError(c, apperrors.ErrDriverNotFound)
+		return
+	}
 
 	if err != nil {
-		h.Logger.Error("Failed to query drivers", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get drivers"})
+		log.Error("Failed to get driver", logger.Err(err), logger.String("driver_id", driverID))
+		respondError(c, apperrors.Internal("Failed to get driver", err))
 		return
 	}
-	defer rows.Close()
 
-	var drivers []gin.H
-	for rows.Next() {
-		var (
-			id, name, phone, status, vehicleType string
-			rating, totalEarnings                float64
-			latitude, longitude                  *float64
-			totalRides                           int
-		)
+	currentRide, _ := h.Redis.Get(ctx, fmt.Sprintf("driver:%s:current_ride", driverID)).Result()
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":             driverID,
+		"name":           name,
+		"phone":          phone,
+		"status":         status,
+		"vehicle_type":   vehicleType,
+		"rating":         rating,
+		"latitude":       latitude,
+		"longitude":      longitude,
+		"total_rides":    totalRides,
+		"total_earnings": totalEarnings,
+		"current_ride":   currentRide,
+		"session":        h.driverUtilization(ctx, log, id),
+	})
+}
+
+// GetDriverCurrentRide handles GET /v1/drivers/:id/current-ride
+// Lets a reconnecting driver app recover the ride it is on. Responds 204 when the driver
+// has no ride, is mid-claim, or the Redis pointer refers to a ride that has since ended.
+func (h *Handlers) GetDriverCurrentRide(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
 
-		if err := rows.Scan(&id, &name, &phone, &status, &vehicleType, &rating,
-			&latitude, &longitude, &totalEarnings, &totalRides); err != nil {
-			h.Logger.Error("Failed to scan driver row", logger.Err(err))
-			continue
+	driverID := c.Param("id")
+	ctx := context.Background()
+
+	if middleware.GetUserType(c) == auth.UserTypeDriver && middleware.GetUserID(c) != driverID {
+		respondError(c, apperrors.Forbidden("Drivers may only view their own ride", nil))
+		return
+	}
+
+	rideID, err := h.Redis.Get(ctx, fmt.Sprintf("driver:%s:current_ride", driverID)).Result()
+	if err != nil && err != redis.Nil {
+		log.Error("Failed to get current ride", logger.Err(err), logger.String("driver_id", driverID))
+		respondError(c, apperrors.Internal("Failed to get current ride", err))
+		return
+	}
+	if rideID == "" || rideID == matching.ClaimingMarker {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	rd, err := h.Rides.GetByID(ctx, rideID)
+	if errors.Is(err, ride.ErrRideNotFound) {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		log.Error("Failed to get ride", logger.Err(err), logger.String("ride_id", rideID))
+		respondError(c, apperrors.Internal("Failed to get current ride", err))
+		return
+	}
+	if !rd.Status.IsActive() || rd.DriverID == nil || rd.DriverID.String() != driverID {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	var name, phone string
+	var rating float64
+	err = h.DB.QueryRowContext(ctx, "SELECT name, phone, rating FROM riders WHERE id = $1", rd.RiderID).
+		Scan(&name, &phone, &rating)
+	if err != nil && err != sql.ErrNoRows {
+		log.Error("Failed to get rider", logger.Err(err), logger.String("rider_id", rd.RiderID.String()))
+		respondError(c, apperrors.Internal("Failed to get current ride", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ride": rd,
+		"rider": gin.H{
+			"id":     rd.RiderID,
+			"name":   name,
+			"phone":  phone,
+			"rating": rating,
+		},
+	})
+}
+
+// GetAllDrivers handles GET /v1/drivers/all
+func (h *Handlers) GetAllDrivers(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	ctx := context.Background()
+
+	limit, offset, err := parsePagination(c, 50, 200)
+	if err != nil {
+		respondError(c, apperrors.BadRequest("Invalid pagination parameters", err))
+		return
+	}
+
+	filter := driver.ListFilter{
+		Status:      driver.Status(c.Query("status")),
+		VehicleType: driver.VehicleType(c.Query("vehicle_type")),
+		Limit:       limit,
+		Offset:      offset,
+	}
+	if filter.Status != "" && !filter.Status.IsValid() {
+		respondError(c, apperrors.BadRequest("Invalid status filter", nil))
+		return
+	}
+	if filter.VehicleType != "" && !filter.VehicleType.IsValid() {
+		respondError(c, apperrors.BadRequest("Invalid vehicle_type filter", nil))
+		return
+	}
+
+	// Load a page of drivers with their earnings totals
+	fleet, total, err := h.Drivers.List(ctx, filter)
+	if err != nil {
+		log.Error("Failed to query drivers", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to get drivers", err))
+		return
+	}
+
+	ids := make([]uuid.UUID, len(fleet))
+	for i, d := range fleet {
+		ids[i] = d.ID
+	}
+	summaries, err := h.Drivers.GetSummaries(ctx, ids)
+	if err != nil {
+		log.Error("Failed to query driver summaries", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to get drivers", err))
+		return
+	}
+
+	// Fetch every driver's current ride in one round trip
+	currentRides := make([]interface{}, len(fleet))
+	if len(fleet) > 0 {
+		keys := make([]string, len(fleet))
+		for i, d := range fleet {
+			keys[i] = fmt.Sprintf("driver:%s:current_ride", d.ID)
+		}
+		if values, err := cache.GetMultiple(ctx, h.Redis, keys); err == nil {
+			currentRides = values
+		} else {
+			log.Warn("Failed to load current rides", logger.Err(err))
 		}
+	}
 
-		// Get current ride from Redis
-		currentRideKey := fmt.Sprintf("driver:%s:current_ride", id)
-		currentRide, _ := h.Redis.Get(ctx, currentRideKey).Result()
+	drivers := []gin.H{}
+	for i, d := range fleet {
+		id := d.ID.String()
+		currentRide, _ := currentRides[i].(string)
 
-		driver := gin.H{
+		summary := summaries[d.ID]
+		drivers = append(drivers, gin.H{
 			"id":             id,
-			"name":           name,
-			"phone":          phone,
-			"status":         status,
-			"vehicle_type":   vehicleType,
-			"rating":         rating,
-			"latitude":       latitude,
-			"longitude":      longitude,
-			"total_earnings": totalEarnings,
-			"total_rides":    totalRides,
+			"name":           d.Name,
+			"phone":          d.Phone,
+			"status":         d.Status,
+			"vehicle_type":   d.VehicleType,
+			"rating":         d.Rating,
+			"latitude":       d.CurrentLatitude,
+			"longitude":      d.CurrentLongitude,
+			"total_earnings": summary.TotalEarnings,
+			"total_rides":    summary.CompletedRides,
 			"current_ride":   currentRide,
-		}
-
-		drivers = append(drivers, driver)
+		})
 	}
 
 	// Get fleet statistics
@@ -227,6 +1092,7 @@ func (h *Handlers) GetAllDrivers(c *gin.Context) {
 			COUNT(CASE WHEN status = 'busy' THEN 1 END) as busy,
 			COUNT(CASE WHEN status = 'offline' THEN 1 END) as offline
 		FROM drivers
+		WHERE deleted_at IS NULL
 	`).Scan(&onlineCount, &busyCount, &offlineCount)
 
 	// Get active rides count
@@ -245,8 +1111,11 @@ func (h *Handlers) GetAllDrivers(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"drivers": drivers,
+		"limit":   limit,
+		"offset":  offset,
+		"total":   total,
 		"overview": gin.H{
-			"total_drivers":  len(drivers),
+			"total_drivers":  onlineCount + busyCount + offlineCount,
 			"online":         onlineCount,
 			"busy":           busyCount,
 			"offline":        offlineCount,
@@ -255,3 +1124,96 @@ func (h *Handlers) GetAllDrivers(c *gin.Context) {
 		},
 	})
 }
+
+// GetDriverEarnings handles GET /v1/drivers/:id/earnings?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *Handlers) GetDriverEarnings(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	driverID := c.Param("id")
+	ctx := context.Background()
+
+	if middleware.GetUserType(c) == auth.UserTypeDriver && middleware.GetUserID(c) != driverID {
+		respondError(c, apperrors.Forbidden("Drivers may only view their own earnings", nil))
+		return
+	}
+
+	// Default to the last 7 days (inclusive of today)
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -6)
+
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse(earningsDateLayout, fromStr)
+		if err != nil {
+			respondError(c, apperrors.BadRequest("Invalid 'from' date, expected YYYY-MM-DD", err))
+			return
+		}
+		from = parsed
+	}
+
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse(earningsDateLayout, toStr)
+		if err != nil {
+			respondError(c, apperrors.BadRequest("Invalid 'to' date, expected YYYY-MM-DD", err))
+			return
+		}
+		to = parsed
+	}
+
+	if from.After(to) {
+		respondError(c, apperrors.BadRequest("'from' date must not be after 'to' date", nil))
+		return
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT date, total_rides, total_earnings
+		FROM driver_earnings
+		WHERE driver_id = $1 AND date BETWEEN $2 AND $3
+		ORDER BY date
+	`, driverID, from.Format(earningsDateLayout), to.Format(earningsDateLayout))
+
+	if err != nil {
+		log.Error("Failed to query driver earnings", logger.Err(err), logger.String("driver_id", driverID))
+		respondError(c, apperrors.Internal("Failed to get driver earnings", err))
+		return
+	}
+	defer rows.Close()
+
+	daily := []gin.H{}
+	var totalRides int
+	var totalEarnings float64
+	for rows.Next() {
+		var (
+			date     time.Time
+			rides    int
+			earnings float64
+		)
+
+		if err := rows.Scan(&date, &rides, &earnings); err != nil {
+			log.Error("Failed to scan earnings row", logger.Err(err), logger.String("driver_id", driverID))
+			respondError(c, apperrors.Internal("Failed to get driver earnings", err))
+			return
+		}
+
+		daily = append(daily, gin.H{
+			"date":           date.Format(earningsDateLayout),
+			"total_rides":    rides,
+			"total_earnings": earnings,
+		})
+		totalRides += rides
+		totalEarnings += earnings
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("Failed to read driver earnings", logger.Err(err), logger.String("driver_id", driverID))
+		respondError(c, apperrors.Internal("Failed to get driver earnings", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"driver_id":      driverID,
+		"from":           from.Format(earningsDateLayout),
+		"to":             to.Format(earningsDateLayout),
+		"daily":          daily,
+		"total_rides":    totalRides,
+		"total_earnings": totalEarnings,
+	})
+}
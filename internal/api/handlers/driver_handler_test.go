@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/repository/postgres"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var reofferColumns = []string{
+	"status", "driver_id", "assigned_at", "rider_id", "vehicle_type", "seats", "allow_upgrade",
+	"pickup_latitude", "pickup_longitude", "dropoff_latitude", "dropoff_longitude", "estimated_fare",
+}
+
+// TestExpireAssignment_ReturnsRideToRequested tests that a lapsed offer releases the driver,
+// counts as a rejection and, with re-matching exhausted, puts the ride back to requested
+func TestExpireAssignment_ReturnsRideToRequested(t *testing.T) {
+	h, client := newTestHandlers(t, &fakeRides{})
+	h.Config.Matching.AssignmentTimeout = 15 * time.Second
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM rides WHERE id = \\$1 FOR UPDATE").
+		WithArgs("ride-1").
+		WillReturnRows(sqlmock.NewRows(reofferColumns).AddRow(
+			"assigned", "driver-1", time.Now().Add(-time.Minute), "rider-1", "economy", 1, false,
+			12.97, 77.59, 12.93, 77.62, 120.0))
+	mock.ExpectExec("SET status = 'requested'").
+		WithArgs("ride-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ctx := context.Background()
+	require.NoError(t, h.ExpireAssignment(ctx, "ride-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	available, err := client.SIsMember(ctx, "drivers:available", "driver-1").Result()
+	require.NoError(t, err)
+	assert.True(t, available)
+
+	rejected, err := client.SIsMember(ctx, "ride:ride-1:rejected_drivers", "driver-1").Result()
+	require.NoError(t, err)
+	assert.True(t, rejected)
+}
+
+// TestExpireAssignment_ReleasesCandidateWhenRideChanged tests that matching runs after the
+// ride is released, and that a driver found for a ride cancelled meanwhile is freed again
+func TestExpireAssignment_ReleasesCandidateWhenRideChanged(t *testing.T) {
+	h, client := newTestHandlers(t, &fakeRides{})
+	h.Config.Matching.AssignmentTimeout = 15 * time.Second
+	h.Config.Matching.MaxRematchAttempts = 3
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	ctx := context.Background()
+	nextDriver := uuid.New().String()
+	indexTestDriver(t, client, nextDriver, "economy", 12.971, 77.591)
+	client.SAdd(ctx, "drivers:available", nextDriver)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM rides WHERE id = \\$1 FOR UPDATE").
+		WithArgs("ride-1").
+		WillReturnRows(sqlmock.NewRows(reofferColumns).AddRow(
+			"assigned", "driver-1", time.Now().Add(-time.Minute), "rider-1", "economy", 1, false,
+			12.97, 77.59, 12.93, 77.62, 120.0))
+	mock.ExpectExec("SET status = 'requested'").
+		WithArgs("ride-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// The rider cancelled while the next driver was being found
+	mock.ExpectExec("SET status = 'assigned'.*AND status = 'requested'").
+		WithArgs("ride-1", nextDriver).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, h.ExpireAssignment(ctx, "ride-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	available, err := client.SIsMember(ctx, "drivers:available", nextDriver).Result()
+	require.NoError(t, err)
+	assert.True(t, available)
+	assert.Zero(t, client.Exists(ctx, "driver:"+nextDriver+":current_ride").Val())
+}
+
+// TestExpireAssignment_IgnoresAcceptedRide tests that a ride accepted before the deadline
+// fired is left alone
+func TestExpireAssignment_IgnoresAcceptedRide(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	h.Config.Matching.AssignmentTimeout = 15 * time.Second
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM rides WHERE id = \\$1 FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows(reofferColumns).AddRow(
+			"accepted", "driver-1", time.Now().Add(-time.Minute), "rider-1", "economy", 1, false,
+			12.97, 77.59, 12.93, 77.62, 120.0))
+	mock.ExpectRollback()
+
+	require.NoError(t, h.ExpireAssignment(context.Background(), "ride-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+var driverRowColumns = []string{
+	"id", "name", "email", "phone", "status", "vehicle_type",
+	"current_latitude", "current_longitude", "rating", "total_rides", "created_at", "updated_at",
+}
+
+// newRegisterDriverRequest builds a POST /v1/drivers context with the given body
+func newRegisterDriverRequest(body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/drivers", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, w
+}
+
+// TestRegisterDriver_CreatesOfflineDriver tests that a valid registration is stored offline
+// with a normalized email
+func TestRegisterDriver_CreatesOfflineDriver(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.Drivers = postgres.NewDriverRepository(db)
+
+	mock.ExpectQuery("FROM drivers WHERE email = \\$1").
+		WithArgs("asha@example.com").
+		WillReturnRows(sqlmock.NewRows(driverRowColumns))
+	mock.ExpectQuery("INSERT INTO drivers").
+		WithArgs(sqlmock.AnyArg(), "Asha Rao", "asha@example.com", "+919800000001", "offline", "premium",
+			nil, nil, 5.0, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(time.Now(), time.Now()))
+
+	c, w := newRegisterDriverRequest(`{"name":" Asha Rao ","email":"Asha@Example.com","phone":"+919800000001","vehicle_type":"premium"}`)
+	h.RegisterDriver(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"offline"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRegisterDriver_RejectsInvalidFields tests that IsValid failures are 400s
+func TestRegisterDriver_RejectsInvalidFields(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	tests := map[string]string{
+		"missing email":   `{"name":"Asha","phone":"+919800000001","vehicle_type":"economy"}`,
+		"unknown vehicle": `{"name":"Asha","email":"asha@example.com","phone":"+919800000001","vehicle_type":"bike"}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, w := newRegisterDriverRequest(body)
+			h.RegisterDriver(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+// TestRegisterDriver_ConflictOnDuplicate tests that an existing email, or a unique violation
+// from a concurrent registration, is a 409
+func TestRegisterDriver_ConflictOnDuplicate(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.Drivers = postgres.NewDriverRepository(db)
+
+	body := `{"name":"Asha","email":"asha@example.com","phone":"+919800000001","vehicle_type":"economy"}`
+
+	mock.ExpectQuery("FROM drivers WHERE email = \\$1").
+		WillReturnRows(sqlmock.NewRows(driverRowColumns).
+			AddRow("9b2f3c1e-0000-4000-8000-000000000001", "Asha", "asha@example.com", "+919800000001",
+				"online", "economy", nil, nil, 4.9, 12, time.Now(), time.Now()))
+	c, w := newRegisterDriverRequest(body)
+	h.RegisterDriver(c)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	mock.ExpectQuery("FROM drivers WHERE email = \\$1").
+		WillReturnRows(sqlmock.NewRows(driverRowColumns))
+	mock.ExpectQuery("INSERT INTO drivers").
+		WillReturnError(&pq.Error{Code: "23505"})
+	c, w = newRegisterDriverRequest(body)
+	h.RegisterDriver(c)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// newDeleteDriverRequest builds a DELETE /v1/drivers/:id context for the given caller
+func newDeleteDriverRequest(driverID, userID, userType string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/v1/drivers/"+driverID, nil)
+	c.Params = gin.Params{{Key: "id", Value: driverID}}
+	c.Set("user_id", userID)
+	c.Set("user_type", userType)
+	return c, w
+}
+
+// TestDeleteDriver_RemovesFromMatching tests that a soft-deleted driver is dropped from the
+// geo index, the available pool and the vehicle type cache
+func TestDeleteDriver_RemovesFromMatching(t *testing.T) {
+	h, client := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.Drivers = postgres.NewDriverRepository(db)
+
+	ctx := context.Background()
+	driverID := "9b2f3c1e-0000-4000-8000-000000000001"
+	require.NoError(t, client.GeoAdd(ctx, "drivers:locations", &redis.GeoLocation{Name: driverID, Latitude: 12.97, Longitude: 77.59}).Err())
+	require.NoError(t, client.SAdd(ctx, "drivers:available", driverID).Err())
+	require.NoError(t, client.HSet(ctx, matching.DriverMetaKey(driverID), "vehicle_type", "economy").Err())
+
+	mock.ExpectExec("UPDATE drivers SET deleted_at").
+		WithArgs(driverID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, w := newDeleteDriverRequest(driverID, driverID, "driver")
+	h.DeleteDriver(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.False(t, client.SIsMember(ctx, "drivers:available", driverID).Val())
+	assert.Equal(t, int64(0), client.Exists(ctx, matching.DriverMetaKey(driverID)).Val())
+	pos, err := client.GeoPos(ctx, "drivers:locations", driverID).Result()
+	require.NoError(t, err)
+	assert.Nil(t, pos[0])
+}
+
+// TestDeleteDriver_ForbidsOtherDrivers tests that a driver can't delete someone else
+func TestDeleteDriver_ForbidsOtherDrivers(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	c, w := newDeleteDriverRequest("9b2f3c1e-0000-4000-8000-000000000001", "9b2f3c1e-0000-4000-8000-000000000002", "driver")
+	h.DeleteDriver(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// TestDriverActions_ForbidOtherDrivers tests that a driver can't move, accept or reject
+// offers for another driver
+func TestDriverActions_ForbidOtherDrivers(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	driverID, callerID := uuid.NewString(), uuid.NewString()
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		handler gin.HandlerFunc
+	}{
+		{"location", "/location", `{"latitude":12.97,"longitude":77.59}`, h.UpdateDriverLocation},
+		{"accept", "/accept", `{"ride_id":"ride-1"}`, h.AcceptRide},
+		{"reject", "/reject", `{"ride_id":"ride-1"}`, h.RejectRide},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/drivers/"+driverID+tt.path, bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: driverID}}
+			c.Set("user_id", callerID)
+			c.Set("user_type", "driver")
+			tt.handler(c)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
+
+// newDriverCurrentRideRequest builds a GET /v1/drivers/:id/current-ride context for that driver
+func newDriverCurrentRideRequest(driverID string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/drivers/"+driverID+"/current-ride", nil)
+	c.Params = gin.Params{{Key: "id", Value: driverID}}
+	c.Set("user_id", driverID)
+	c.Set("user_type", "driver")
+	return c, w
+}
+
+// TestGetDriverCurrentRide_ReturnsRideWithRider tests that the ride in the driver's Redis
+// pointer is resolved and returned with the rider's details
+func TestGetDriverCurrentRide_ReturnsRideWithRider(t *testing.T) {
+	driverID := uuid.New()
+	riderID := uuid.New()
+	h, client := newTestHandlers(t, &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", RiderID: riderID, DriverID: &driverID, Status: ride.StatusAccepted},
+	}})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	require.NoError(t, client.Set(context.Background(), "driver:"+driverID.String()+":current_ride", "ride-1", 0).Err())
+	mock.ExpectQuery("FROM riders WHERE id = \\$1").
+		WithArgs(riderID).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "phone", "rating"}).AddRow("Meera", "+919800000002", 4.7))
+
+	c, w := newDriverCurrentRideRequest(driverID.String())
+	h.GetDriverCurrentRide(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"accepted"`)
+	assert.Contains(t, w.Body.String(), `"name":"Meera"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetDriverCurrentRide_NoContent tests that no pointer, the claiming marker and a
+// pointer to a finished ride all report no current ride
+func TestGetDriverCurrentRide_NoContent(t *testing.T) {
+	driverID := uuid.New()
+	h, client := newTestHandlers(t, &fakeRides{rides: map[string]*ride.Ride{
+		"ride-done": {ID: "ride-done", RiderID: uuid.New(), DriverID: &driverID, Status: ride.StatusCompleted},
+	}})
+	key := "driver:" + driverID.String() + ":current_ride"
+
+	for _, value := range []string{"", matching.ClaimingMarker, "ride-done"} {
+		if value != "" {
+			require.NoError(t, client.Set(context.Background(), key, value, 0).Err())
+		}
+		c, w := newDriverCurrentRideRequest(driverID.String())
+		h.GetDriverCurrentRide(c)
+		c.Writer.WriteHeaderNow()
+		assert.Equal(t, http.StatusNoContent, w.Code, value)
+	}
+}
+
+// TestGetDriverEarnings_OnlyOwnForDrivers tests that a driver can't read another driver's
+// earnings, while dashboards can read anyone's
+func TestGetDriverEarnings_OnlyOwnForDrivers(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		userType string
+		want     int
+	}{
+		{"own earnings", "driver-1", "driver", http.StatusOK},
+		{"dashboard", "dash-1", "dashboard", http.StatusOK},
+		{"other driver", "driver-2", "driver", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			h.DB = db
+
+			if tt.want == http.StatusOK {
+				mock.ExpectQuery("FROM driver_earnings").
+					WillReturnRows(sqlmock.NewRows([]string{"date", "total_rides", "total_earnings"}))
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/v1/drivers/driver-1/earnings", nil)
+			c.Params = gin.Params{{Key: "id", Value: "driver-1"}}
+			c.Set("user_id", tt.userID)
+			c.Set("user_type", tt.userType)
+			h.GetDriverEarnings(c)
+
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestGetDriverEarnings_FailsOnBadRows tests that an unreadable earnings row fails the
+// request instead of silently dropping out of the totals
+func TestGetDriverEarnings_FailsOnBadRows(t *testing.T) {
+	columns := []string{"date", "total_rides", "total_earnings"}
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+	}{
+		{"scan error", sqlmock.NewRows(columns).AddRow(day, 3, 450.0).AddRow(day, "three", 450.0)},
+		{"row error", sqlmock.NewRows(columns).AddRow(day, 3, 450.0).AddRow(day, 2, 300.0).
+			RowError(1, errors.New("connection reset"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			h.DB = db
+
+			mock.ExpectQuery("FROM driver_earnings").WillReturnRows(tt.rows)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/v1/drivers/driver-1/earnings", nil)
+			c.Params = gin.Params{{Key: "id", Value: "driver-1"}}
+			h.GetDriverEarnings(c)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
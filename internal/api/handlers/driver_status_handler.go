@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/dto"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// utilizationWindow is how far back GET /v1/drivers/:id looks for online sessions
+const utilizationWindow = 24 * time.Hour
+
+// UpdateDriverStatus handles PUT /v1/drivers/:id/status
+// Going online opens a session for utilization tracking; going offline closes it and drops
+// the driver from matching. Drivers may only change their own status, and can't go offline
+// while a ride is assigned to them.
+func (h *Handlers) UpdateDriverStatus(c *gin.Context) {
+	driverID := c.Param("id")
+	log := middleware.WithLogFields(c, h.Logger, logger.String("driver_id", driverID))
+	ctx := context.Background()
+
+	var req dto.UpdateDriverStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	if middleware.GetUserType(c) == auth.UserTypeDriver && middleware.GetUserID(c) != driverID {
+		respondError(c, apperrors.Forbidden("Drivers may only change their own status", nil))
+		return
+	}
+	id, err := uuid.Parse(driverID)
+	if err != nil {
+		respondError(c, apperrors.ErrDriverNotFound)
+		return
+	}
+	status := driver.Status(req.Status)
+
+	if status == driver.StatusOffline {
+		activeRide, err := h.Rides.GetActiveRideByDriver(ctx, id)
+		if err == nil {
+			respondError(c, apperrors.Conflict(fmt.Sprintf("Driver has a ride in progress (%s)", activeRide.ID), nil))
+			return
+		}
+		if !errors.Is(err, ride.ErrRideNotFound) {
+			log.Error("Failed to check active rides", logger.Err(err))
+			respondError(c, apperrors.Internal("Failed to update driver status", err))
+			return
+		}
+	}
+
+	if err := h.Drivers.UpdateStatus(ctx, id, status); err != nil {
+		if errors.Is(err, driver.ErrDriverNotFound) {
+			respondError(c, apperrors.ErrDriverNotFound)
+			return
+		}
+		log.Error("Failed to update driver status", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to update driver status", err))
+		return
+	}
+
+	// Session bookkeeping feeds reporting only, so a failure doesn't undo the status change
+	now := time.Now().UTC()
+	if status == driver.StatusOnline {
+		if err := h.Sessions.Start(ctx, id, now); err != nil {
+			log.Warn("Failed to start driver session", logger.Err(err))
+		}
+	} else {
+		if err := h.Sessions.End(ctx, id, now); err != nil {
+			log.Warn("Failed to end driver session", logger.Err(err))
+		}
+
+		pipe := h.Redis.Pipeline()
+		matching.RemoveDriverLocations(ctx, pipe, driverID)
+		pipe.SRem(ctx, "drivers:available", driverID)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Warn("Failed to remove offline driver from Redis", logger.Err(err))
+		}
+	}
+
+	log.Info("Driver status updated", logger.String("status", string(status)))
+
+	c.JSON(http.StatusOK, gin.H{
+		"driver_id":  driverID,
+		"status":     status,
+		"updated_at": now,
+	})
+}
+
+// markDriverBusy starts the busy clock of the driver's open session
+func (h *Handlers) markDriverBusy(ctx context.Context, log *logger.Logger, driverID string) {
+	id, err := uuid.Parse(driverID)
+	if err != nil {
+		return
+	}
+	if err := h.Sessions.StartBusy(ctx, id, time.Now().UTC()); err != nil {
+		log.Warn("Failed to mark driver session busy", logger.String("driver_id", driverID), logger.Err(err))
+	}
+}
+
+// markDriverFree adds the ride just finished to the driver's busy time
+func (h *Handlers) markDriverFree(ctx context.Context, log *logger.Logger, driverID string) {
+	id, err := uuid.Parse(driverID)
+	if err != nil {
+		return
+	}
+	if err := h.Sessions.EndBusy(ctx, id, time.Now().UTC()); err != nil {
+		log.Warn("Failed to mark driver session free", logger.String("driver_id", driverID), logger.Err(err))
+	}
+}
+
+// driverUtilization reports a driver's online time and utilization over utilizationWindow;
+// it returns nil when the sessions can't be read
+func (h *Handlers) driverUtilization(ctx context.Context, log *logger.Logger, id uuid.UUID) gin.H {
+	now := time.Now().UTC()
+	u, err := h.Sessions.Utilization(ctx, id, now.Add(-utilizationWindow), now)
+	if err != nil {
+		log.Warn("Failed to load driver utilization", logger.String("driver_id", id.String()), logger.Err(err))
+		return nil
+	}
+	return gin.H{
+		"online_since":   u.OnlineSince,
+		"window_hours":   int(utilizationWindow.Hours()),
+		"online_seconds": math.Round(u.OnlineSeconds),
+		"busy_seconds":   math.Round(u.BusySeconds),
+		"utilization":    roundRate(u.Rate()),
+	}
+}
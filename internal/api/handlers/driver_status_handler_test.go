@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/repository/postgres"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDriverStatusRequest builds a PUT /v1/drivers/:id/status context for that driver
+func newDriverStatusRequest(driverID, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/v1/drivers/"+driverID+"/status", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: driverID}}
+	c.Set("user_id", driverID)
+	c.Set("user_type", "driver")
+	return c, w
+}
+
+// TestUpdateDriverStatus_OnlineOpensSession tests that going online updates the driver and
+// starts a session
+func TestUpdateDriverStatus_OnlineOpensSession(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.Drivers = postgres.NewDriverRepository(db)
+	sessions := &fakeSessions{}
+	h.Sessions = sessions
+
+	driverID := uuid.NewString()
+	mock.ExpectExec("UPDATE drivers SET status").
+		WithArgs(driverID, "online").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, w := newDriverStatusRequest(driverID, `{"status":"online"}`)
+	h.UpdateDriverStatus(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []string{"start:" + driverID}, sessions.events)
+}
+
+// TestUpdateDriverStatus_OfflineClosesSession tests that going offline ends the session and
+// drops the driver from matching
+func TestUpdateDriverStatus_OfflineClosesSession(t *testing.T) {
+	h, client := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.Drivers = postgres.NewDriverRepository(db)
+	sessions := &fakeSessions{}
+	h.Sessions = sessions
+
+	ctx := context.Background()
+	driverID := uuid.NewString()
+	indexTestDriver(t, client, driverID, driver.VehicleEconomy, 12.97, 77.59)
+	require.NoError(t, client.SAdd(ctx, "drivers:available", driverID).Err())
+
+	mock.ExpectExec("UPDATE drivers SET status").
+		WithArgs(driverID, "offline").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, w := newDriverStatusRequest(driverID, `{"status":"offline"}`)
+	h.UpdateDriverStatus(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []string{"end:" + driverID}, sessions.events)
+	assert.False(t, client.SIsMember(ctx, "drivers:available", driverID).Val())
+	pos, err := client.GeoPos(ctx, matching.VehicleLocationsKey(driver.VehicleEconomy), driverID).Result()
+	require.NoError(t, err)
+	assert.Nil(t, pos[0])
+}
+
+// TestUpdateDriverStatus_OfflineRejectedDuringRide tests that a driver on a ride can't go offline
+func TestUpdateDriverStatus_OfflineRejectedDuringRide(t *testing.T) {
+	driverID := uuid.New()
+	h, _ := newTestHandlers(t, &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", DriverID: &driverID, Status: ride.StatusStarted},
+	}})
+	sessions := &fakeSessions{}
+	h.Sessions = sessions
+
+	c, w := newDriverStatusRequest(driverID.String(), `{"status":"offline"}`)
+	h.UpdateDriverStatus(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Empty(t, sessions.events)
+}
+
+// TestUpdateDriverStatus_RejectsUnknownStatus tests that only online and offline are accepted
+func TestUpdateDriverStatus_RejectsUnknownStatus(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	c, w := newDriverStatusRequest(uuid.NewString(), `{"status":"busy"}`)
+	h.UpdateDriverStatus(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// TestReleaseDriver_EndsBusyTime tests that freeing a driver settles their busy time
+func TestReleaseDriver_EndsBusyTime(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	sessions := &fakeSessions{}
+	h.Sessions = sessions
+
+	driverID := uuid.NewString()
+	h.markDriverBusy(context.Background(), h.Logger, driverID)
+	h.releaseDriver(context.Background(), driverID)
+
+	assert.Equal(t, []string{"busy:" + driverID, "free:" + driverID}, sessions.events)
+}
@@ -1,26 +1,120 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/config"
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/payment"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/domain/rider"
+	"github.com/gocomet/ride-hailing/internal/repository/postgres"
+	"github.com/gocomet/ride-hailing/internal/service/eta"
+	"github.com/gocomet/ride-hailing/internal/service/location"
+	"github.com/gocomet/ride-hailing/internal/service/pricing"
+	"github.com/gocomet/ride-hailing/internal/service/routing"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
 	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/gocomet/ride-hailing/pkg/monitoring"
+	"github.com/newrelic/go-agent/v3/integrations/nrgin"
+	"github.com/newrelic/go-agent/v3/newrelic"
 	"github.com/redis/go-redis/v9"
 )
 
 // Handlers holds all handler dependencies
 type Handlers struct {
-	DB     *sql.DB
-	Redis  *redis.Client
-	Logger *logger.Logger
-	Hub    interface{} // WebSocket hub (interface to avoid circular dependency)
+	DB        *sql.DB
+	Redis     *redis.Client
+	Logger    *logger.Logger
+	Hub       interface{} // WebSocket hub (interface to avoid circular dependency)
+	Config    *config.Config
+	Pricing   *pricing.Service
+	Locations *location.Batcher
+	NewRelic  *monitoring.NewRelicApp
+	Metrics   *monitoring.PrometheusMetrics
+	Router    routing.Router
+	ETA       *eta.Estimator
+	Gateway   payment.Gateway
+	Payments  payment.Repository
+	Drivers   driver.Repository
+	Sessions  driver.SessionRepository
+	Rides     ride.Repository
+	Events    ride.EventRepository
+	Favorites rider.FavoriteRepository
 }
 
 // NewHandlers creates a new Handlers instance
-func NewHandlers(db *sql.DB, redisClient *redis.Client, logger *logger.Logger, hub interface{}) *Handlers {
+func NewHandlers(db *sql.DB, redisClient *redis.Client, logger *logger.Logger, hub interface{}, cfg *config.Config, pricingService *pricing.Service, locations *location.Batcher, nrApp *monitoring.NewRelicApp, metrics *monitoring.PrometheusMetrics) *Handlers {
 	return &Handlers{
-		DB:     db,
-		Redis:  redisClient,
-		Logger: logger,
-		Hub:    hub,
+		DB:        db,
+		Redis:     redisClient,
+		Logger:    logger,
+		Hub:       hub,
+		Config:    cfg,
+		Pricing:   pricingService,
+		Locations: locations,
+		NewRelic:  nrApp,
+		Metrics:   metrics,
+		Router:    routing.NewHaversineRouter(cfg.Routing.WindingFactor, cfg.Matching.AvgCitySpeedKMH),
+		ETA:       eta.NewEstimator(cfg.Matching.AvgCitySpeedKMH, cfg.Matching.ETASurgeSlowdown),
+		Gateway:   payment.NewMockGateway(100*time.Millisecond, cfg.Payment.MockFailureRate),
+		Payments:  postgres.NewPaymentRepository(db),
+		Drivers:   postgres.NewDriverRepository(db),
+		Sessions:  postgres.NewDriverSessionRepository(db),
+		Rides:     postgres.NewRideRepository(db),
+		Events:    postgres.NewRideEventRepository(db),
+		Favorites: postgres.NewRiderFavoriteRepository(db),
 	}
 }
+
+// parsePagination reads limit/offset query params, applying a default and upper bound to limit
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) (limit, offset int, err error) {
+	limit = defaultLimit
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("limit must be a positive integer")
+		}
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	if v := c.Query("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// respondError writes err as a {code, message} JSON body with the AppError's status;
+// errors that aren't AppErrors become a generic 500
+func respondError(c *gin.Context, err error) {
+	appErr := apperrors.GetAppError(err)
+	c.JSON(appErr.Status, appErr)
+}
+
+// invalidPayload reports a request body that failed binding, keeping the validator's reason
+func invalidPayload(err error) *apperrors.AppError {
+	return apperrors.BadRequest(fmt.Sprintf("Invalid request payload: %v", err), err)
+}
+
+// invalidTransition reports a ride whose status doesn't allow the requested change, e.g.
+// "accepted" or "started". Every ride transition endpoint answers with this 409.
+func invalidTransition(action string, from ride.Status, err error) *apperrors.AppError {
+	return apperrors.Conflict(fmt.Sprintf("Ride cannot be %s from status '%s'", action, from), err)
+}
+
+// traceContext returns a background context carrying the request's New Relic transaction,
+// so services can add segments to it; without New Relic the transaction is nil and ignored
+func traceContext(c *gin.Context) context.Context {
+	return newrelic.NewContext(context.Background(), nrgin.Transaction(c))
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRespondError tests that AppErrors keep their status and other errors become a generic 500
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"app error", apperrors.ErrRideNotFound, http.StatusNotFound, "NOT_FOUND"},
+		{"wrapped app error", apperrors.Wrap(apperrors.Conflict("busy", nil), "accept"), http.StatusConflict, "CONFLICT"},
+		{"plain error", errors.New("pq: connection refused"), http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			respondError(c, tt.err)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			var body map[string]string
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantCode, body["code"])
+			assert.NotEmpty(t, body["message"])
+			assert.NotContains(t, body["message"], "pq:", "internal details must not leak")
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/pkg/cache"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+)
+
+// readinessTimeout bounds each dependency ping so a hung backend fails the probe quickly
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck handles GET /health/ready
+func (h *Handlers) ReadinessCheck(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), readinessTimeout)
+	defer cancel()
+
+	checks := gin.H{}
+	ready := true
+
+	if err := h.DB.PingContext(ctx); err != nil {
+		log.Error("Readiness check failed for postgres", logger.Err(err))
+		checks["postgres"] = gin.H{"status": "down", "error": err.Error()}
+		ready = false
+	} else {
+		checks["postgres"] = gin.H{"status": "up"}
+	}
+
+	if err := h.Redis.Ping(ctx).Err(); err != nil {
+		log.Error("Readiness check failed for redis", logger.Err(err))
+		checks["redis"] = gin.H{"status": "down", "error": err.Error()}
+		ready = false
+	} else {
+		checks["redis"] = gin.H{"status": "up"}
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not_ready",
+			"checks": checks,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ready",
+		"checks": checks,
+	})
+}
+
+// RedisStats handles GET /debug/redis
+func (h *Handlers) RedisStats(c *gin.Context) {
+	stats := cache.GetClientStats(h.Redis)
+
+	// Forward the snapshot to New Relic so pool health shows up alongside other custom metrics
+	h.NewRelic.RecordRedisPoolStats(stats)
+
+	c.JSON(http.StatusOK, stats)
+}
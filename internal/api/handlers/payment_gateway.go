@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gocomet/ride-hailing/internal/domain/payment"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+)
+
+// chargeWithRetry charges through the gateway, retrying transient failures
+func (h *Handlers) chargeWithRetry(ctx context.Context, log *logger.Logger, amount float64, method payment.Method) (string, error) {
+	return h.withGatewayRetry(ctx, log, "charge", func() (string, error) {
+		return h.Gateway.Charge(ctx, amount, method)
+	})
+}
+
+// refundWithRetry refunds a charge through the gateway, retrying transient failures
+func (h *Handlers) refundWithRetry(ctx context.Context, log *logger.Logger, txnID string, amount float64) (string, error) {
+	return h.withGatewayRetry(ctx, log, "refund", func() (string, error) {
+		return h.Gateway.Refund(ctx, txnID, amount)
+	})
+}
+
+// withGatewayRetry runs a gateway call, retrying failures that wrap payment.ErrGatewayUnavailable
+// with exponential backoff until Payment.ChargeMaxAttempts is used up. Other failures are
+// returned at once.
+func (h *Handlers) withGatewayRetry(ctx context.Context, log *logger.Logger, action string, call func() (string, error)) (string, error) {
+	backoff := h.Config.Payment.ChargeBackoff
+	for attempt := 1; ; attempt++ {
+		txnID, err := call()
+		if err == nil {
+			return txnID, nil
+		}
+		if !errors.Is(err, payment.ErrGatewayUnavailable) || attempt >= h.Config.Payment.ChargeMaxAttempts {
+			return "", err
+		}
+
+		log.Warn("Payment gateway call failed, retrying",
+			logger.String("action", action),
+			logger.Int("attempt", attempt),
+			logger.Duration("backoff", backoff),
+			logger.Err(err),
+		)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -2,107 +2,222 @@ package handlers
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gocomet/ride-hailing/internal/api/dto"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/internal/domain/payment"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	"github.com/gocomet/ride-hailing/pkg/cache"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
 	"github.com/gocomet/ride-hailing/pkg/logger"
 )
 
 // ProcessPayment handles POST /v1/payments
 func (h *Handlers) ProcessPayment(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
 	ctx := context.Background()
 
 	var req dto.CreatePaymentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload", "details": err.Error()})
+		respondError(c, invalidPayload(err))
 		return
 	}
 
 	// Check idempotency
 	idempotencyKey := c.GetHeader("Idempotency-Key")
 	if idempotencyKey == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key header required"})
+		respondError(c, apperrors.BadRequest("Idempotency-Key header required", nil))
+		return
+	}
+
+	// Validate trip exists and amount matches
+	// req.TripID is actually the ride_id, get the actual trip UUID
+	var tripAmount float64
+	var tripUUID, riderID string
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT t.id, t.total_fare, r.rider_id
+		FROM trips t
+		JOIN rides r ON r.id = t.ride_id
+		WHERE t.ride_id = $1 AND t.status = 'completed'
+	`, req.TripID).Scan(&tripUUID, &tripAmount, &riderID)
+
+	if err == sql.ErrNoRows {
+		respondError(c, apperrors.NotFound("Trip not found or not completed", err))
+		return
+	}
+
+	if err != nil {
+		log.Error("Failed to validate trip", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to process payment", err))
+		return
+	}
+
+	// Only the trip's rider pays for it, and the check comes before any cached response is
+	// replayed so a key can't be used to read someone else's payment
+	if !h.canAccessRider(c, riderID) {
+		respondError(c, apperrors.Forbidden("Cannot pay for another rider's trip", nil))
 		return
 	}
 
-	// Check if payment already processed
+	// Check if payment already processed; the key only replays the request it was first used for
 	cacheKey := fmt.Sprintf("payment:idempotency:%s", idempotencyKey)
-	cachedResponse, err := h.Redis.Get(ctx, cacheKey).Result()
-	if err == nil {
-		h.Logger.Info("Returning cached payment response", logger.String("idempotency_key", idempotencyKey))
-		var response map[string]interface{}
-		if err := json.Unmarshal([]byte(cachedResponse), &response); err == nil {
-			c.JSON(http.StatusOK, response)
+	reqHash := requestHash(req)
+	var cached idempotentResponse
+	if found, _ := cache.GetJSON(ctx, h.Redis, cacheKey, &cached); found {
+		if cached.RequestHash != reqHash {
+			log.Warn("Idempotency key reused with a different payment request",
+				logger.String("idempotency_key", idempotencyKey))
+			respondError(c, apperrors.ErrDuplicateRequest)
 			return
 		}
+		log.Info("Returning cached payment response", logger.String("idempotency_key", idempotencyKey))
+		c.JSON(http.StatusOK, cached.Response)
+		return
 	}
 
-	h.Logger.Info("Processing payment",
+	log.Info("Processing payment",
 		logger.String("trip_id", req.TripID),
 		logger.Float64("amount", req.Amount),
 		logger.String("payment_method", req.PaymentMethod),
 	)
 
-	// Validate trip exists and amount matches
-	// req.TripID is actually the ride_id, get the actual trip UUID
-	var tripAmount float64
-	var tripUUID string
-	err = h.DB.QueryRowContext(ctx, `
-		SELECT id, total_fare
-		FROM trips
-		WHERE ride_id = $1 AND status = 'completed'
-	`, req.TripID).Scan(&tripUUID, &tripAmount)
-
-	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Trip not found or not completed"})
+	if tripAmount != req.Amount {
+		respondError(c, apperrors.BadRequest(fmt.Sprintf("Amount mismatch: expected %.2f, provided %.2f", tripAmount, req.Amount), nil))
 		return
 	}
 
+	// The rider must have set up the requested method
+	var hasMethod bool
+	err = h.DB.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM rider_payment_methods
+			WHERE rider_id = $1 AND payment_method = $2
+		)
+	`, riderID, req.PaymentMethod).Scan(&hasMethod)
 	if err != nil {
-		h.Logger.Error("Failed to validate trip", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process payment"})
+		log.Error("Failed to check rider payment methods", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to process payment", err))
 		return
 	}
-
-	if tripAmount != req.Amount {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":    "Amount mismatch",
-			"expected": tripAmount,
-			"provided": req.Amount,
-		})
+	if !hasMethod {
+		log.Warn("Payment method not set up for rider",
+			logger.String("rider_id", riderID),
+			logger.String("payment_method", req.PaymentMethod),
+		)
+		respondError(c, apperrors.ErrInvalidPaymentMethod)
 		return
 	}
 
-	// Generate external transaction ID (mock PSP)
-	externalTransactionID := fmt.Sprintf("txn_%d_%s", time.Now().Unix(), generateRideID())
+	// Wallet payments settle against the rider's balance right away; every other method is
+	// charged through the PSP, so its payment stays processing until the charge goes through
+	method := payment.Method(req.PaymentMethod)
+	paymentID := uuid.New().String()
+	status := payment.StatusProcessing
+	externalTransactionID := ""
+	if method == payment.MethodWallet {
+		status = payment.StatusCompleted
+		externalTransactionID = "wallet_" + paymentID
+	}
 
-	// Mock PSP processing (simulate delay)
-	time.Sleep(100 * time.Millisecond)
+	// The payment row and any wallet debit commit together
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Failed to begin transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Database error", err))
+		return
+	}
+	defer tx.Rollback()
 
-	// Insert payment record
-	paymentID := uuid.New().String()
-	_, err = h.DB.ExecContext(ctx, `
+	// Insert payment record. Retrying a failed charge with the same key and method takes
+	// over its row; only failed payments free their trip for another attempt
+	err = tx.QueryRowContext(ctx, `
 		INSERT INTO payments (
 			id, trip_id, amount, status, payment_method,
 			external_transaction_id, idempotency_key, created_at
-		) VALUES ($1, $2, $3, 'completed', $4, $5, $6, NOW())
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, NOW())
 		ON CONFLICT (idempotency_key) DO UPDATE SET
+			status = EXCLUDED.status,
+			failure_reason = NULL,
 			updated_at = NOW()
+		WHERE payments.status = 'failed'
+			AND payments.trip_id = EXCLUDED.trip_id
+			AND payments.payment_method = EXCLUDED.payment_method
 		RETURNING id
-	`, paymentID, tripUUID, req.Amount, req.PaymentMethod, externalTransactionID, idempotencyKey)
+	`, paymentID, tripUUID, req.Amount, status, req.PaymentMethod, externalTransactionID, idempotencyKey).Scan(&paymentID)
+	// The key was already used for a payment whose cached response has expired; charging
+	// again would bill the rider twice
+	if err == sql.ErrNoRows {
+		log.Warn("Idempotency key already used for a payment", logger.String("idempotency_key", idempotencyKey))
+		respondError(c, apperrors.ErrDuplicateRequest)
+		return
+	}
+	// Another payment for the trip is completed or still in flight
+	if isUniqueViolation(err) {
+		log.Warn("Trip already paid", logger.String("trip_id", req.TripID))
+		respondError(c, apperrors.ErrTripAlreadyPaid)
+		return
+	}
+	if err == nil && method == payment.MethodWallet {
+		err = debitWallet(ctx, tx, riderID, paymentID, req.Amount)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
 
+	if errors.Is(err, apperrors.ErrInsufficientBalance) {
+		log.Warn("Insufficient wallet balance", logger.String("rider_id", riderID), logger.Float64("amount", req.Amount))
+		h.NewRelic.RecordPaymentProcessed(req.Amount, req.PaymentMethod, "failed")
+		respondError(c, apperrors.ErrInsufficientBalance)
+		return
+	}
 	if err != nil {
-		h.Logger.Error("Failed to create payment record", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process payment"})
+		log.Error("Failed to create payment record", logger.Err(err))
+		h.NewRelic.RecordPaymentProcessed(req.Amount, req.PaymentMethod, "failed")
+		respondError(c, apperrors.Internal("Failed to process payment", err))
 		return
 	}
 
+	if status == payment.StatusProcessing {
+		externalTransactionID, err = h.chargeWithRetry(ctx, log, req.Amount, method)
+		if err != nil {
+			log.Error("Payment charge failed", logger.String("payment_id", paymentID), logger.Err(err))
+			h.failPayment(ctx, log, paymentID, err)
+			h.NewRelic.RecordPaymentProcessed(req.Amount, req.PaymentMethod, "failed")
+			respondError(c, apperrors.ErrPaymentFailed)
+			return
+		}
+
+		_, err = h.DB.ExecContext(ctx, `
+			UPDATE payments
+			SET status = 'completed', external_transaction_id = $2, processed_at = NOW(), updated_at = NOW()
+			WHERE id = $1
+		`, paymentID, externalTransactionID)
+		if err != nil {
+			// The rider has been charged; the row is left processing for reconciliation
+			log.Error("Failed to record completed payment",
+				logger.String("payment_id", paymentID),
+				logger.String("transaction_id", externalTransactionID),
+				logger.Err(err),
+			)
+			respondError(c, apperrors.Internal("Payment charged but could not be recorded", err))
+			return
+		}
+	}
+	h.NewRelic.RecordPaymentProcessed(req.Amount, req.PaymentMethod, "completed")
+
 	response := gin.H{
 		"payment_id":     paymentID,
 		"trip_id":        req.TripID,
@@ -114,10 +229,9 @@ func (h *Handlers) ProcessPayment(c *gin.Context) {
 	}
 
 	// Cache response for idempotency
-	responseJSON, _ := json.Marshal(response)
-	h.Redis.Set(ctx, cacheKey, responseJSON, 24*time.Hour)
+	cache.SetJSON(ctx, h.Redis, cacheKey, idempotentResponse{RequestHash: reqHash, Response: response}, 24*time.Hour)
 
-	h.Logger.Info("Payment processed successfully",
+	log.Info("Payment processed successfully",
 		logger.String("payment_id", paymentID),
 		logger.String("trip_id", req.TripID),
 		logger.Float64("amount", req.Amount),
@@ -125,3 +239,440 @@ func (h *Handlers) ProcessPayment(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// failPayment marks a payment failed with the PSP's reason once its charge can't go through
+func (h *Handlers) failPayment(ctx context.Context, log *logger.Logger, paymentID string, chargeErr error) {
+	_, err := h.DB.ExecContext(ctx, `
+		UPDATE payments
+		SET status = 'failed', failure_reason = $2, updated_at = NOW()
+		WHERE id = $1
+	`, paymentID, chargeErr.Error())
+	if err != nil {
+		log.Error("Failed to mark payment failed", logger.String("payment_id", paymentID), logger.Err(err))
+	}
+}
+
+// idempotentResponse is what ProcessPayment caches per Idempotency-Key: the response plus a
+// hash of the request that produced it, so the key can't replay a different request
+type idempotentResponse struct {
+	RequestHash string                 `json:"request_hash"`
+	Response    map[string]interface{} `json:"response"`
+}
+
+// requestHash fingerprints a bound request body
+func requestHash(req interface{}) string {
+	body, _ := json.Marshal(req)
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// RefundPayment handles POST /v1/payments/:id/refund
+// An optional amount issues a partial refund; the payment is marked refunded
+// once the full amount has been returned. A card refund is recorded as pending
+// before the PSP is called, so an Idempotency-Key reaches the PSP at most once.
+func (h *Handlers) RefundPayment(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	paymentID := c.Param("id")
+	ctx := context.Background()
+
+	var req dto.RefundPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if idempotencyKey == "" {
+		respondError(c, apperrors.BadRequest("Idempotency-Key header required", nil))
+		return
+	}
+
+	cacheKey := fmt.Sprintf("payment:refund:idempotency:%s", idempotencyKey)
+	var cached map[string]interface{}
+	if found, _ := cache.GetJSON(ctx, h.Redis, cacheKey, &cached); found {
+		log.Info("Returning cached refund response", logger.String("idempotency_key", idempotencyKey))
+		c.JSON(http.StatusOK, cached)
+		return
+	}
+
+	if _, err := uuid.Parse(paymentID); err != nil {
+		respondError(c, apperrors.ErrPaymentNotFound)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Failed to begin transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Database error", err))
+		return
+	}
+	defer tx.Rollback()
+
+	// Refunds still waiting on the PSP are held back from what can be refunded
+	var amount, refundedAmount, pendingAmount float64
+	var status, method, riderID string
+	var chargeTxnID sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT p.amount, p.refunded_amount, p.status, p.payment_method, p.external_transaction_id, r.rider_id,
+		       (SELECT COALESCE(SUM(pr.amount), 0) FROM payment_refunds pr
+		        WHERE pr.payment_id = p.id AND pr.status = 'pending')
+		FROM payments p
+		JOIN trips t ON t.id = p.trip_id
+		JOIN rides r ON r.id = t.ride_id
+		WHERE p.id = $1
+		FOR UPDATE OF p
+	`, paymentID).Scan(&amount, &refundedAmount, &status, &method, &chargeTxnID, &riderID, &pendingAmount)
+
+	if err == sql.ErrNoRows {
+		respondError(c, apperrors.ErrPaymentNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Error("Failed to get payment", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to refund payment", err))
+		return
+	}
+
+	// The key may have been used after its cached response expired; replay a finished refund
+	// of this payment rather than refunding it again
+	var prior refundRecord
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, payment_id, amount, status, COALESCE(external_transaction_id, '')
+		FROM payment_refunds
+		WHERE idempotency_key = $1
+	`, idempotencyKey).Scan(&prior.ID, &prior.PaymentID, &prior.Amount, &prior.Status, &prior.TransactionID)
+	if err == nil {
+		if prior.PaymentID != paymentID || payment.Status(prior.Status) != payment.StatusCompleted {
+			log.Warn("Idempotency key already used for a refund",
+				logger.String("idempotency_key", idempotencyKey),
+				logger.String("refund_status", prior.Status),
+			)
+			respondError(c, apperrors.ErrDuplicateRequest)
+			return
+		}
+		log.Info("Replaying recorded refund", logger.String("idempotency_key", idempotencyKey))
+		c.JSON(http.StatusOK, refundResponse(prior, amount, refundedAmount, payment.Status(status)))
+		return
+	}
+	if err != sql.ErrNoRows {
+		log.Error("Failed to look up refund", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to refund payment", err))
+		return
+	}
+
+	if payment.Status(status) != payment.StatusCompleted {
+		respondError(c, apperrors.Conflict(fmt.Sprintf("Payment is %s and cannot be refunded", status), nil))
+		return
+	}
+
+	refundable := roundToCents(amount - refundedAmount - pendingAmount)
+	refundAmount := refundable
+	if req.Amount != nil {
+		refundAmount = roundToCents(*req.Amount)
+	}
+	if refundAmount <= 0 || refundAmount > refundable {
+		respondError(c, apperrors.BadRequest(fmt.Sprintf("Refund amount must be between 0 and %.2f", refundable), nil))
+		return
+	}
+
+	// Wallet refunds settle at once. Anything else goes back through the PSP that took the
+	// charge, so the refund is recorded as pending and the lock released before calling it.
+	refund := refundRecord{
+		ID:        uuid.New().String(),
+		PaymentID: paymentID,
+		Amount:    refundAmount,
+		Status:    string(payment.StatusPending),
+	}
+	isWallet := payment.Method(method) == payment.MethodWallet
+	if isWallet {
+		refund.Status = string(payment.StatusCompleted)
+		refund.TransactionID = "wallet_" + refund.ID
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO payment_refunds (
+			id, payment_id, amount, reason, external_transaction_id, idempotency_key, status, created_at
+		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, NOW())
+		ON CONFLICT (idempotency_key) DO NOTHING
+	`, refund.ID, paymentID, refundAmount, sql.NullString{String: req.Reason, Valid: req.Reason != ""},
+		refund.TransactionID, idempotencyKey, refund.Status)
+	var inserted int64
+	if err == nil {
+		inserted, err = result.RowsAffected()
+	}
+	if err != nil {
+		log.Error("Failed to record refund", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to refund payment", err))
+		return
+	}
+	// A concurrent request claimed the key for another payment
+	if inserted == 0 {
+		log.Warn("Idempotency key already used for a refund", logger.String("idempotency_key", idempotencyKey))
+		respondError(c, apperrors.ErrDuplicateRequest)
+		return
+	}
+
+	if isWallet {
+		if err := creditWallet(ctx, tx, riderID, paymentID, refundAmount); err != nil {
+			log.Error("Failed to credit wallet", logger.Err(err), logger.String("rider_id", riderID))
+			respondError(c, apperrors.Internal("Failed to refund payment", err))
+			return
+		}
+		refundedAmount, status, err = applyRefund(ctx, tx, paymentID, refundAmount)
+		if err != nil {
+			log.Error("Failed to update payment", logger.Err(err))
+			respondError(c, apperrors.Internal("Failed to refund payment", err))
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("Failed to commit transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to refund payment", err))
+		return
+	}
+
+	if !isWallet {
+		refund.TransactionID, err = h.refundWithRetry(ctx, log, chargeTxnID.String, refundAmount)
+		if err != nil {
+			log.Error("Payment refund failed", logger.String("payment_id", paymentID), logger.Err(err))
+			h.failRefund(ctx, log, refund.ID)
+			respondError(c, apperrors.ErrRefundFailed)
+			return
+		}
+
+		refundedAmount, status, err = h.settleRefund(ctx, refund)
+		if err != nil {
+			// The PSP has refunded the rider; the row is left pending for reconciliation
+			log.Error("Failed to record completed refund",
+				logger.String("refund_id", refund.ID),
+				logger.String("transaction_id", refund.TransactionID),
+				logger.Err(err),
+			)
+			respondError(c, apperrors.Internal("Refund issued but could not be recorded", err))
+			return
+		}
+		refund.Status = string(payment.StatusCompleted)
+	}
+
+	h.NewRelic.RecordPaymentRefunded(paymentID, refundAmount, method)
+
+	response := refundResponse(refund, amount, refundedAmount, payment.Status(status))
+	cache.SetJSON(ctx, h.Redis, cacheKey, response, 24*time.Hour)
+
+	log.Info("Payment refunded",
+		logger.String("payment_id", paymentID),
+		logger.Float64("amount", refundAmount),
+		logger.String("status", status),
+	)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// refundRecord is one row of payment_refunds
+type refundRecord struct {
+	ID            string
+	PaymentID     string
+	Amount        float64
+	Status        string
+	TransactionID string
+}
+
+// refundResponse describes a refund along with the payment's refund totals
+func refundResponse(refund refundRecord, paymentAmount, refundedTotal float64, status payment.Status) gin.H {
+	return gin.H{
+		"refund_id":      refund.ID,
+		"payment_id":     refund.PaymentID,
+		"amount":         refund.Amount,
+		"refunded_total": roundToCents(refundedTotal),
+		"remaining":      roundToCents(paymentAmount - refundedTotal),
+		"status":         status,
+		"transaction_id": refund.TransactionID,
+		"refunded_at":    time.Now(),
+	}
+}
+
+// applyRefund adds a settled refund to the payment's total, marking it refunded once nothing
+// is left, and returns the new total and status
+func applyRefund(ctx context.Context, tx *sql.Tx, paymentID string, amount float64) (float64, string, error) {
+	var refundedTotal float64
+	var status string
+	err := tx.QueryRowContext(ctx, `
+		UPDATE payments
+		SET refunded_amount = refunded_amount + $2,
+		    status = CASE WHEN refunded_amount + $2 >= amount THEN 'refunded' ELSE status END,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING refunded_amount, status
+	`, paymentID, amount).Scan(&refundedTotal, &status)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to update payment: %w", err)
+	}
+	return refundedTotal, status, nil
+}
+
+// settleRefund marks a pending refund completed with the PSP's transaction and applies it to
+// the payment
+func (h *Handlers) settleRefund(ctx context.Context, refund refundRecord) (float64, string, error) {
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE payment_refunds
+		SET status = 'completed', external_transaction_id = $2
+		WHERE id = $1 AND status = 'pending'
+	`, refund.ID, refund.TransactionID)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to complete refund: %w", err)
+	}
+
+	refundedTotal, status, err := applyRefund(ctx, tx, refund.PaymentID, refund.Amount)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, "", fmt.Errorf("failed to commit refund: %w", err)
+	}
+	return refundedTotal, status, nil
+}
+
+// failRefund marks a pending refund failed once the PSP has turned it down, freeing its amount
+func (h *Handlers) failRefund(ctx context.Context, log *logger.Logger, refundID string) {
+	_, err := h.DB.ExecContext(ctx, `
+		UPDATE payment_refunds SET status = 'failed' WHERE id = $1 AND status = 'pending'
+	`, refundID)
+	if err != nil {
+		log.Error("Failed to mark refund failed", logger.String("refund_id", refundID), logger.Err(err))
+	}
+}
+
+// roundToCents rounds a currency amount to two decimal places
+func roundToCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// canAccessTrip reports whether the caller is the trip's rider, its driver or an admin
+func (h *Handlers) canAccessTrip(ctx context.Context, c *gin.Context, tripID uuid.UUID) (bool, error) {
+	userType := middleware.GetUserType(c)
+	if userType == auth.UserTypeAdmin {
+		return true, nil
+	}
+
+	var riderID string
+	var driverID sql.NullString
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT r.rider_id, r.driver_id
+		FROM trips t
+		JOIN rides r ON r.id = t.ride_id
+		WHERE t.id = $1
+	`, tripID).Scan(&riderID, &driverID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get trip participants: %w", err)
+	}
+
+	userID := middleware.GetUserID(c)
+	switch userType {
+	case auth.UserTypeRider:
+		return userID == riderID, nil
+	case auth.UserTypeDriver:
+		return driverID.Valid && userID == driverID.String, nil
+	default:
+		return false, nil
+	}
+}
+
+// GetPayment handles GET /v1/payments/:id
+func (h *Handlers) GetPayment(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, apperrors.ErrPaymentNotFound)
+		return
+	}
+
+	ctx := context.Background()
+
+	p, err := h.Payments.GetByID(ctx, paymentID)
+	if errors.Is(err, payment.ErrPaymentNotFound) {
+		respondError(c, apperrors.ErrPaymentNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Error("Failed to get payment", logger.Err(err), logger.String("payment_id", paymentID.String()))
+		respondError(c, apperrors.Internal("Failed to get payment", err))
+		return
+	}
+
+	allowed, err := h.canAccessTrip(ctx, c, p.TripID)
+	if err != nil {
+		log.Error("Failed to check payment access", logger.Err(err), logger.String("payment_id", paymentID.String()))
+		respondError(c, apperrors.Internal("Failed to get payment", err))
+		return
+	}
+	if !allowed {
+		respondError(c, apperrors.Forbidden("Only the trip's rider or driver may view its payment", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// GetPaymentByTrip handles GET /v1/trips/:id/payment
+// The ID may be the trip UUID or, like the other /trips routes, the ride ID.
+func (h *Handlers) GetPaymentByTrip(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	id := c.Param("id")
+	ctx := context.Background()
+
+	tripID, err := uuid.Parse(id)
+	if err != nil {
+		var tripUUID string
+		err = h.DB.QueryRowContext(ctx, `SELECT id FROM trips WHERE ride_id = $1`, id).Scan(&tripUUID)
+		if err == sql.ErrNoRows {
+			respondError(c, apperrors.ErrTripNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("Failed to resolve trip", logger.Err(err), logger.String("ride_id", id))
+			respondError(c, apperrors.Internal("Failed to get payment", err))
+			return
+		}
+		tripID = uuid.MustParse(tripUUID)
+	}
+
+	allowed, err := h.canAccessTrip(ctx, c, tripID)
+	if err != nil {
+		log.Error("Failed to check payment access", logger.Err(err), logger.String("trip_id", tripID.String()))
+		respondError(c, apperrors.Internal("Failed to get payment", err))
+		return
+	}
+	if !allowed {
+		respondError(c, apperrors.Forbidden("Only the trip's rider or driver may view its payment", nil))
+		return
+	}
+
+	p, err := h.Payments.GetByTripID(ctx, tripID)
+	if errors.Is(err, payment.ErrPaymentNotFound) {
+		respondError(c, apperrors.ErrPaymentNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Error("Failed to get payment", logger.Err(err), logger.String("trip_id", tripID.String()))
+		respondError(c, apperrors.Internal("Failed to get payment", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
@@ -0,0 +1,556 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/domain/payment"
+	"github.com/gocomet/ride-hailing/internal/repository/postgres"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPaymentRequest builds a POST /v1/payments context for ride-1 with an Idempotency-Key
+func newPaymentRequest(method string, amount string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"trip_id":"ride-1","payment_method":"` + method + `","amount":` + amount + `}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/payments", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Idempotency-Key", "pay-1")
+	c.Set("user_id", "rider-1")
+	c.Set("user_type", "rider")
+	return c, w
+}
+
+// paymentIDRows is the id ProcessPayment's insert returns for a new payment
+func paymentIDRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id"}).AddRow(uuid.New().String())
+}
+
+// TestProcessPayment_RejectsMethodNotSetUp tests that a rider can't pay with a method they haven't added
+func TestProcessPayment_RejectsMethodNotSetUp(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	mock.ExpectQuery("FROM trips t").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total_fare", "rider_id"}).AddRow("trip-1", 250.0, "rider-1"))
+	mock.ExpectQuery("FROM rider_payment_methods").
+		WithArgs("rider-1", "card").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	c, w := newPaymentRequest("card", "250")
+	h.ProcessPayment(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), apperrors.ErrInvalidPaymentMethod.Message)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessPayment_WalletInsufficientBalance tests that a short wallet rolls the payment back
+func TestProcessPayment_WalletInsufficientBalance(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	mock.ExpectQuery("FROM trips t").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total_fare", "rider_id"}).AddRow("trip-1", 250.0, "rider-1"))
+	mock.ExpectQuery("FROM rider_payment_methods").
+		WithArgs("rider-1", "wallet").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO payments").WillReturnRows(paymentIDRows())
+	mock.ExpectQuery("UPDATE rider_wallets").
+		WithArgs("rider-1", 250.0).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}))
+	mock.ExpectRollback()
+
+	c, w := newPaymentRequest("wallet", "250")
+	h.ProcessPayment(c)
+
+	assert.Equal(t, http.StatusPaymentRequired, w.Code)
+	assert.Contains(t, w.Body.String(), "INSUFFICIENT_BALANCE")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessPayment_WalletDebitIsLedgered tests that a wallet payment debits the balance and
+// records a ledger entry in the same transaction
+func TestProcessPayment_WalletDebitIsLedgered(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	mock.ExpectQuery("FROM trips t").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total_fare", "rider_id"}).AddRow("trip-1", 250.0, "rider-1"))
+	mock.ExpectQuery("FROM rider_payment_methods").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO payments").WillReturnRows(paymentIDRows())
+	mock.ExpectQuery("UPDATE rider_wallets").
+		WithArgs("rider-1", 250.0).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(50.0))
+	mock.ExpectExec("INSERT INTO wallet_transactions").
+		WithArgs(sqlmock.AnyArg(), "rider-1", "payment", -250.0, 50.0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	c, w := newPaymentRequest("wallet", "250")
+	h.ProcessPayment(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessPayment_IdempotencyKeyReplay tests that a replayed Idempotency-Key returns the
+// original response for the same request and a 409 for a different one
+func TestProcessPayment_IdempotencyKeyReplay(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	mock.ExpectQuery("FROM trips t").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total_fare", "rider_id"}).AddRow("trip-1", 250.0, "rider-1"))
+	mock.ExpectQuery("FROM rider_payment_methods").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO payments").WillReturnRows(paymentIDRows())
+	mock.ExpectCommit()
+	mock.ExpectExec("UPDATE payments").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, first := newPaymentRequest("card", "250")
+	h.ProcessPayment(c)
+	require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+
+	tripRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "total_fare", "rider_id"}).AddRow("trip-1", 250.0, "rider-1")
+	}
+	mock.ExpectQuery("FROM trips t").WillReturnRows(tripRows())
+	mock.ExpectQuery("FROM trips t").WillReturnRows(tripRows())
+
+	c, replay := newPaymentRequest("card", "250")
+	h.ProcessPayment(c)
+	assert.Equal(t, http.StatusOK, replay.Code)
+	assert.JSONEq(t, first.Body.String(), replay.Body.String())
+
+	c, changed := newPaymentRequest("card", "300")
+	h.ProcessPayment(c)
+	assert.Equal(t, http.StatusConflict, changed.Code)
+	assert.Contains(t, changed.Body.String(), apperrors.ErrDuplicateRequest.Message)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessPayment_RejectsOtherRidersTrip tests that only the trip's rider or an admin can pay,
+// and that a cached response is never replayed to anyone else
+func TestProcessPayment_RejectsOtherRidersTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		userType string
+		want     int
+	}{
+		{"trip's rider", "rider-1", "rider", http.StatusOK},
+		{"admin", "ops-1", "admin", http.StatusOK},
+		{"other rider", "rider-2", "rider", http.StatusForbidden},
+		{"driver", "driver-1", "driver", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			h.DB = db
+
+			// The rider's own payment is already cached under the key
+			c, first := newPaymentRequest("card", "250")
+			mock.ExpectQuery("FROM trips t").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "total_fare", "rider_id"}).AddRow("trip-1", 250.0, "rider-1"))
+			mock.ExpectQuery("FROM rider_payment_methods").
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			mock.ExpectBegin()
+			mock.ExpectQuery("INSERT INTO payments").WillReturnRows(paymentIDRows())
+			mock.ExpectCommit()
+			mock.ExpectExec("UPDATE payments").WillReturnResult(sqlmock.NewResult(0, 1))
+			h.ProcessPayment(c)
+			require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+
+			mock.ExpectQuery("FROM trips t").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "total_fare", "rider_id"}).AddRow("trip-1", 250.0, "rider-1"))
+			c, w := newPaymentRequest("card", "250")
+			c.Set("user_id", tt.userID)
+			c.Set("user_type", tt.userType)
+			h.ProcessPayment(c)
+
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusForbidden {
+				assert.NotContains(t, w.Body.String(), "transaction_id")
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// scriptedGateway returns errs in order, one per call, then succeeds
+type scriptedGateway struct {
+	errs  []error
+	calls int
+}
+
+func (g *scriptedGateway) Charge(ctx context.Context, amount float64, method payment.Method) (string, error) {
+	return g.next("txn-ok")
+}
+
+func (g *scriptedGateway) Refund(ctx context.Context, txnID string, amount float64) (string, error) {
+	return g.next("rfnd-ok")
+}
+
+func (g *scriptedGateway) next(txnID string) (string, error) {
+	g.calls++
+	if g.calls <= len(g.errs) {
+		return "", g.errs[g.calls-1]
+	}
+	return txnID, nil
+}
+
+// expectProcessingPayment sets up a card payment for ride-1 that is recorded as processing
+func expectProcessingPayment(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("FROM trips t").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "total_fare", "rider_id"}).AddRow("trip-1", 250.0, "rider-1"))
+	mock.ExpectQuery("FROM rider_payment_methods").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO payments").
+		WithArgs(sqlmock.AnyArg(), "trip-1", 250.0, payment.StatusProcessing, "card", "", "pay-1").
+		WillReturnRows(paymentIDRows())
+	mock.ExpectCommit()
+}
+
+// TestProcessPayment_RetriesTransientGatewayFailure tests that a charge failing transiently is
+// retried and the payment completes with the eventual transaction ID
+func TestProcessPayment_RetriesTransientGatewayFailure(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+	h.Config.Payment.ChargeMaxAttempts = 3
+	gateway := &scriptedGateway{errs: []error{fmt.Errorf("timeout: %w", payment.ErrGatewayUnavailable)}}
+	h.Gateway = gateway
+
+	expectProcessingPayment(mock)
+	mock.ExpectExec("UPDATE payments").
+		WithArgs(sqlmock.AnyArg(), "txn-ok").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, w := newPaymentRequest("card", "250")
+	h.ProcessPayment(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"transaction_id":"txn-ok"`)
+	assert.Equal(t, 2, gateway.calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessPayment_FailsAfterExhaustingRetries tests that a payment is marked failed with the
+// gateway's reason once every attempt has failed
+func TestProcessPayment_FailsAfterExhaustingRetries(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+	h.Config.Payment.ChargeMaxAttempts = 2
+	unavailable := fmt.Errorf("timeout: %w", payment.ErrGatewayUnavailable)
+	gateway := &scriptedGateway{errs: []error{unavailable, unavailable, unavailable}}
+	h.Gateway = gateway
+
+	expectProcessingPayment(mock)
+	mock.ExpectExec("UPDATE payments").
+		WithArgs(sqlmock.AnyArg(), unavailable.Error()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, w := newPaymentRequest("card", "250")
+	h.ProcessPayment(c)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "PAYMENT_FAILED")
+	assert.Equal(t, 2, gateway.calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessPayment_PaysAgainAfterGatewayFailure tests that a rider whose charge failed can pay
+// for the trip again, with the same key or a new one, and that a paid trip is a 409
+func TestProcessPayment_PaysAgainAfterGatewayFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		retryKey  string
+		insertErr error
+		want      int
+		wantBody  string
+	}{
+		{"same key", "pay-1", nil, http.StatusOK, `"transaction_id":"txn-ok"`},
+		{"new key", "pay-2", nil, http.StatusOK, `"transaction_id":"txn-ok"`},
+		{"already paid", "pay-2", &pq.Error{Code: "23505"}, http.StatusConflict, apperrors.ErrTripAlreadyPaid.Message},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			h.DB = db
+			h.Config.Payment.ChargeMaxAttempts = 1
+			declined := errors.New("card declined")
+			h.Gateway = &scriptedGateway{errs: []error{declined}}
+
+			failedID := uuid.New().String()
+			mock.ExpectQuery("FROM trips t").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "total_fare", "rider_id"}).AddRow("trip-1", 250.0, "rider-1"))
+			mock.ExpectQuery("FROM rider_payment_methods").
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			mock.ExpectBegin()
+			mock.ExpectQuery("INSERT INTO payments").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(failedID))
+			mock.ExpectCommit()
+			mock.ExpectExec("UPDATE payments").
+				WithArgs(failedID, declined.Error()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			c, w := newPaymentRequest("card", "250")
+			h.ProcessPayment(c)
+			require.Equal(t, http.StatusBadGateway, w.Code, w.Body.String())
+
+			// The same key takes over the failed row; a new key gets a row of its own
+			retryID := uuid.New().String()
+			if tt.retryKey == "pay-1" {
+				retryID = failedID
+			}
+			mock.ExpectQuery("FROM trips t").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "total_fare", "rider_id"}).AddRow("trip-1", 250.0, "rider-1"))
+			mock.ExpectQuery("FROM rider_payment_methods").
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			mock.ExpectBegin()
+			insert := mock.ExpectQuery("INSERT INTO payments").
+				WithArgs(sqlmock.AnyArg(), "trip-1", 250.0, payment.StatusProcessing, "card", "", tt.retryKey)
+			if tt.insertErr != nil {
+				insert.WillReturnError(tt.insertErr)
+				mock.ExpectRollback()
+			} else {
+				insert.WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(retryID))
+				mock.ExpectCommit()
+				mock.ExpectExec("UPDATE payments").
+					WithArgs(retryID, "txn-ok").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			c, w = newPaymentRequest("card", "250")
+			c.Request.Header.Set("Idempotency-Key", tt.retryKey)
+			h.ProcessPayment(c)
+
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+			if tt.want == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"payment_id":"`+retryID+`"`)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// paymentColumns are the columns the payment repository reads
+var paymentColumns = []string{
+	"id", "trip_id", "amount", "refunded_amount", "status", "payment_method",
+	"external_transaction_id", "payment_gateway_response", "failure_reason",
+	"idempotency_key", "processed_at", "created_at", "updated_at",
+}
+
+// TestGetPayment_OnlyTripParticipants tests that a payment, looked up by ID or by trip, is
+// only shown to the trip's rider, its driver or an admin
+func TestGetPayment_OnlyTripParticipants(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		userType string
+		want     int
+	}{
+		{"rider", "rider-1", "rider", http.StatusOK},
+		{"driver", "driver-1", "driver", http.StatusOK},
+		{"admin", "ops-1", "admin", http.StatusOK},
+		{"other rider", "rider-2", "rider", http.StatusForbidden},
+		{"other driver", "driver-2", "driver", http.StatusForbidden},
+		{"rider id as driver", "rider-1", "driver", http.StatusForbidden},
+	}
+	for _, byTrip := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/by trip %v", tt.name, byTrip), func(t *testing.T) {
+				h, _ := newTestHandlers(t, &fakeRides{})
+				db, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				defer db.Close()
+				h.DB = db
+				h.Payments = postgres.NewPaymentRepository(db)
+
+				paymentID, tripID := uuid.New(), uuid.New()
+				now := time.Now()
+				paymentRow := sqlmock.NewRows(paymentColumns).
+					AddRow(paymentID, tripID, 250.0, 0.0, "completed", "card", "txn-1", nil, nil, "pay-1", now, now, now)
+				if !byTrip {
+					mock.ExpectQuery("FROM payments WHERE id").WillReturnRows(paymentRow)
+				}
+				if tt.userType != "admin" {
+					mock.ExpectQuery("FROM trips t").
+						WithArgs(tripID).
+						WillReturnRows(sqlmock.NewRows([]string{"rider_id", "driver_id"}).AddRow("rider-1", "driver-1"))
+				}
+				if byTrip && tt.want == http.StatusOK {
+					mock.ExpectQuery("FROM payments WHERE trip_id").WillReturnRows(paymentRow)
+				}
+
+				w := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(w)
+				c.Set("user_id", tt.userID)
+				c.Set("user_type", tt.userType)
+				if byTrip {
+					c.Request = httptest.NewRequest(http.MethodGet, "/v1/trips/"+tripID.String()+"/payment", nil)
+					c.Params = gin.Params{{Key: "id", Value: tripID.String()}}
+					h.GetPaymentByTrip(c)
+				} else {
+					c.Request = httptest.NewRequest(http.MethodGet, "/v1/payments/"+paymentID.String(), nil)
+					c.Params = gin.Params{{Key: "id", Value: paymentID.String()}}
+					h.GetPayment(c)
+				}
+
+				assert.Equal(t, tt.want, w.Code, w.Body.String())
+				if tt.want == http.StatusForbidden {
+					assert.NotContains(t, w.Body.String(), "txn-1")
+				}
+				assert.NoError(t, mock.ExpectationsWereMet())
+			})
+		}
+	}
+}
+
+// refundColumns are the columns of the locked payment lookup in RefundPayment
+var refundColumns = []string{"amount", "refunded_amount", "status", "payment_method", "external_transaction_id", "rider_id", "pending"}
+
+// newRefundRequest builds a POST /v1/payments/:id/refund context with the given idempotency key
+func newRefundRequest(paymentID, idempotencyKey string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: paymentID}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/payments/"+paymentID+"/refund", bytes.NewBufferString(`{}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Idempotency-Key", idempotencyKey)
+	return c, w
+}
+
+// TestRefundPayment_RefundsThroughGateway tests that a card refund is recorded as pending and
+// the lock released before going to the PSP, retrying a transient failure, and is then
+// settled with the PSP's refund transaction
+func TestRefundPayment_RefundsThroughGateway(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+	h.Config.Payment.ChargeMaxAttempts = 2
+	gateway := &scriptedGateway{errs: []error{fmt.Errorf("timeout: %w", payment.ErrGatewayUnavailable)}}
+	h.Gateway = gateway
+
+	paymentID := uuid.New().String()
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM payments p").
+		WithArgs(paymentID).
+		WillReturnRows(sqlmock.NewRows(refundColumns).AddRow(250.0, 0.0, "completed", "card", "txn-1", "rider-1", 0.0))
+	mock.ExpectQuery("FROM payment_refunds").
+		WithArgs("refund-1").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO payment_refunds").
+		WithArgs(sqlmock.AnyArg(), paymentID, 250.0, sqlmock.AnyArg(), "", "refund-1", "pending").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE payment_refunds").
+		WithArgs(sqlmock.AnyArg(), "rfnd-ok").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE payments").
+		WithArgs(paymentID, 250.0).
+		WillReturnRows(sqlmock.NewRows([]string{"refunded_amount", "status"}).AddRow(250.0, "refunded"))
+	mock.ExpectCommit()
+
+	c, w := newRefundRequest(paymentID, "refund-1")
+	h.RefundPayment(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"status":"refunded"`)
+	assert.Equal(t, 2, gateway.calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRefundPayment_ReplaysRecordedRefund tests that reusing a key after its cached response
+// expired answers with the recorded refund instead of calling the PSP again, and that a key
+// whose refund is still in flight is a conflict
+func TestRefundPayment_ReplaysRecordedRefund(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   int
+	}{
+		{"completed", "completed", http.StatusOK},
+		{"in flight", "pending", http.StatusConflict},
+		{"failed", "failed", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			h.DB = db
+			gateway := &scriptedGateway{}
+			h.Gateway = gateway
+
+			paymentID := uuid.New().String()
+			mock.ExpectBegin()
+			mock.ExpectQuery("FROM payments p").
+				WithArgs(paymentID).
+				WillReturnRows(sqlmock.NewRows(refundColumns).AddRow(250.0, 100.0, "completed", "card", "txn-1", "rider-1", 0.0))
+			mock.ExpectQuery("FROM payment_refunds").
+				WithArgs("refund-1").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "payment_id", "amount", "status", "external_transaction_id"}).
+					AddRow("refund-row-1", paymentID, 100.0, tt.status, "rfnd-1"))
+			mock.ExpectRollback()
+
+			c, w := newRefundRequest(paymentID, "refund-1")
+			h.RefundPayment(c)
+
+			assert.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want == http.StatusOK {
+				assert.Contains(t, w.Body.String(), `"amount":100`)
+				assert.Contains(t, w.Body.String(), `"transaction_id":"rfnd-1"`)
+			}
+			assert.Zero(t, gateway.calls)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/dto"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/lib/pq"
+)
+
+// driverSettlement accumulates one driver's unsettled earnings days for a payout
+type driverSettlement struct {
+	driverID   string
+	earningIDs []string
+	rides      int
+	amount     float64
+}
+
+// CreatePayouts handles POST /v1/admin/payouts
+// Settles every driver's unsettled earnings in the period. Days already covered by a payout
+// are skipped, so re-running a period never pays a driver twice.
+func (h *Handlers) CreatePayouts(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	var req dto.CreatePayoutsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	start, err := time.Parse(earningsDateLayout, req.PeriodStart)
+	if err != nil {
+		respondError(c, apperrors.BadRequest("Invalid 'period_start' date, expected YYYY-MM-DD", err))
+		return
+	}
+	end, err := time.Parse(earningsDateLayout, req.PeriodEnd)
+	if err != nil {
+		respondError(c, apperrors.BadRequest("Invalid 'period_end' date, expected YYYY-MM-DD", err))
+		return
+	}
+	if start.After(end) {
+		respondError(c, apperrors.BadRequest("'period_start' must not be after 'period_end'", nil))
+		return
+	}
+
+	// Today's earnings are still accruing, so only closed days can be settled
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if !end.Before(today) {
+		respondError(c, apperrors.BadRequest("Payout period must end before today", nil))
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Failed to begin transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Database error", err))
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the unsettled days so a concurrent run over an overlapping period waits and then
+	// finds them settled
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, driver_id, total_rides, total_earnings
+		FROM driver_earnings
+		WHERE date BETWEEN $1 AND $2 AND payout_id IS NULL AND total_earnings > 0
+		ORDER BY driver_id, date
+		FOR UPDATE
+	`, req.PeriodStart, req.PeriodEnd)
+	if err != nil {
+		log.Error("Failed to query unsettled earnings", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to create payouts", err))
+		return
+	}
+
+	var settlements []*driverSettlement
+	for rows.Next() {
+		var (
+			earningID, driverID string
+			rides               int
+			earnings            float64
+		)
+		if err := rows.Scan(&earningID, &driverID, &rides, &earnings); err != nil {
+			rows.Close()
+			log.Error("Failed to scan driver earnings", logger.Err(err))
+			respondError(c, apperrors.Internal("Failed to create payouts", err))
+			return
+		}
+
+		if len(settlements) == 0 || settlements[len(settlements)-1].driverID != driverID {
+			settlements = append(settlements, &driverSettlement{driverID: driverID})
+		}
+		s := settlements[len(settlements)-1]
+		s.earningIDs = append(s.earningIDs, earningID)
+		s.rides += rides
+		s.amount += earnings
+	}
+	rows.Close()
+
+	payouts := []gin.H{}
+	skipped := []string{}
+	var totalAmount float64
+	for _, s := range settlements {
+		amount := roundToCents(s.amount)
+
+		var payoutID string
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO driver_payouts (driver_id, period_start, period_end, total_rides, amount)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (driver_id, period_start, period_end) DO NOTHING
+			RETURNING id
+		`, s.driverID, req.PeriodStart, req.PeriodEnd, s.rides, amount).Scan(&payoutID)
+
+		if err == sql.ErrNoRows {
+			// The period was settled before these days accrued; leave them for a later run
+			log.Warn("Driver already paid out for period, skipping",
+				logger.String("driver_id", s.driverID),
+				logger.Float64("unsettled_amount", amount),
+			)
+			skipped = append(skipped, s.driverID)
+			continue
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE driver_earnings SET payout_id = $1, updated_at = NOW()
+				WHERE id = ANY($2)
+			`, payoutID, pq.Array(s.earningIDs))
+		}
+		if err != nil {
+			log.Error("Failed to settle driver earnings", logger.Err(err), logger.String("driver_id", s.driverID))
+			respondError(c, apperrors.Internal("Failed to create payouts", err))
+			return
+		}
+
+		totalAmount += amount
+		payouts = append(payouts, gin.H{
+			"payout_id":   payoutID,
+			"driver_id":   s.driverID,
+			"total_rides": s.rides,
+			"amount":      amount,
+		})
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error("Failed to commit payouts", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to create payouts", err))
+		return
+	}
+
+	log.Info("Driver payouts created",
+		logger.String("period_start", req.PeriodStart),
+		logger.String("period_end", req.PeriodEnd),
+		logger.Int("drivers_paid", len(payouts)),
+		logger.Float64("total_amount", roundToCents(totalAmount)),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"period_start":    req.PeriodStart,
+		"period_end":      req.PeriodEnd,
+		"drivers_paid":    len(payouts),
+		"total_amount":    roundToCents(totalAmount),
+		"payouts":         payouts,
+		"skipped_drivers": skipped,
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPayoutRequest builds a POST /v1/admin/payouts context for the given period
+func newPayoutRequest(start, end string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"period_start":"` + start + `","period_end":"` + end + `"}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/admin/payouts", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, w
+}
+
+// TestCreatePayouts_SettlesPerDriver tests that each driver's unsettled days become one payout
+// and are marked settled, and that a driver already paid for the period is skipped
+func TestCreatePayouts_SettlesPerDriver(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM driver_earnings").
+		WithArgs("2026-01-05", "2026-01-11").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "driver_id", "total_rides", "total_earnings"}).
+			AddRow("e-1", "driver-1", 3, 300.0).
+			AddRow("e-2", "driver-1", 2, 150.5).
+			AddRow("e-3", "driver-2", 1, 80.0))
+	mock.ExpectQuery("INSERT INTO driver_payouts").
+		WithArgs("driver-1", "2026-01-05", "2026-01-11", 5, 450.5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("payout-1"))
+	mock.ExpectExec("UPDATE driver_earnings SET payout_id").
+		WithArgs("payout-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("INSERT INTO driver_payouts").
+		WithArgs("driver-2", "2026-01-05", "2026-01-11", 1, 80.0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	c, w := newPayoutRequest("2026-01-05", "2026-01-11")
+	h.CreatePayouts(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"drivers_paid":1`)
+	assert.Contains(t, w.Body.String(), `"total_amount":450.5`)
+	assert.Contains(t, w.Body.String(), `"skipped_drivers":["driver-2"]`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestCreatePayouts_RejectsOpenPeriod tests that a period reaching today can't be settled
+func TestCreatePayouts_RejectsOpenPeriod(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	today := time.Now().UTC().Format(earningsDateLayout)
+	c, w := newPayoutRequest("2026-01-05", today)
+	h.CreatePayouts(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
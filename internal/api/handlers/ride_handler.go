@@ -3,34 +3,138 @@ package handlers
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gocomet/ride-hailing/internal/api/dto"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
 	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/service/eta"
 	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/gocomet/ride-hailing/internal/service/pricing"
+	"github.com/gocomet/ride-hailing/internal/service/routing"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	"github.com/gocomet/ride-hailing/pkg/cache"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
 	"github.com/gocomet/ride-hailing/pkg/logger"
 	"github.com/gocomet/ride-hailing/pkg/websocket"
+	"github.com/google/uuid"
 )
 
 // CreateRide handles POST /v1/rides
 func (h *Handlers) CreateRide(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
 	var req dto.CreateRideRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload", "details": err.Error()})
+		respondError(c, invalidPayload(err))
+		return
+	}
+	if err := req.ValidateCoordinates(); err != nil {
+		respondError(c, err)
+		return
+	}
+	pickupLat, pickupLng := *req.PickupLatitude, *req.PickupLongitude
+	dropoffLat, dropoffLng := *req.DropoffLatitude, *req.DropoffLongitude
+
+	// Riders may only request rides for themselves
+	if middleware.GetUserType(c) == auth.UserTypeRider && middleware.GetUserID(c) != req.RiderID {
+		respondError(c, apperrors.Forbidden("Cannot create a ride for another rider", nil))
+		return
+	}
+	log = middleware.WithLogFields(c, h.Logger, logger.String("rider_id", req.RiderID))
+
+	// Return the original response if this request was already processed
+	ctx := traceContext(c)
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if idempotencyKey != "" {
+		var cached map[string]interface{}
+		if found, _ := cache.GetJSON(ctx, h.Redis, rideIdempotencyKey(req.RiderID, idempotencyKey), &cached); found {
+			log.Info("Returning cached ride response", logger.String("idempotency_key", idempotencyKey))
+			c.JSON(http.StatusOK, cached)
+			return
+		}
+	}
+
+	riderUUID, err := uuid.Parse(req.RiderID)
+	if err != nil {
+		respondError(c, apperrors.BadRequest("Invalid rider_id", err))
+		return
+	}
+
+	// Multi-stop rides are capped at MaxWaypoints intermediate stops
+	if len(req.Waypoints) > h.Config.Routing.MaxWaypoints {
+		respondError(c, apperrors.BadRequest(fmt.Sprintf("At most %d waypoints are allowed", h.Config.Routing.MaxWaypoints), nil))
+		return
+	}
+
+	// Advance bookings must be for a future pickup time
+	scheduled := req.ScheduledAt != nil
+	if scheduled && !req.ScheduledAt.After(time.Now()) {
+		respondError(c, apperrors.BadRequest("scheduled_at must be in the future", nil))
+		return
+	}
+
+	// Serialize ride creation per rider so two concurrent requests can't both pass the active
+	// ride check and match drivers. The lock outlives a slow match before it expires.
+	unlock, err := cache.Lock(ctx, h.Redis, rideCreationLockKey(req.RiderID), h.Config.Matching.MaxTimeout+30*time.Second)
+	if errors.Is(err, cache.ErrLockHeld) {
+		respondError(c, apperrors.Conflict("A ride request for this rider is already in progress", nil))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to lock ride creation", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to create ride", err))
 		return
 	}
+	defer unlock()
+
+	// The cached response may have expired while the ride it created is still on record
+	if idempotencyKey != "" {
+		existing, err := h.Rides.GetByIdempotencyKey(ctx, riderUUID, idempotencyKey)
+		if err == nil {
+			log.Info("Returning ride already created for idempotency key",
+				logger.String("idempotency_key", idempotencyKey),
+				logger.String("ride_id", existing.ID),
+			)
+			c.JSON(http.StatusOK, h.existingRideResponse(existing))
+			return
+		}
+		if !errors.Is(err, ride.ErrRideNotFound) {
+			log.Error("Failed to look up ride by idempotency key", logger.Err(err))
+			respondError(c, apperrors.Internal("Failed to create ride", err))
+			return
+		}
+	}
+
+	// A rider may only have one ride in progress at a time; advance bookings don't count
+	if !scheduled {
+		activeRide, err := h.Rides.GetActiveRideByRider(ctx, riderUUID)
+		if err == nil {
+			respondError(c, apperrors.Conflict(fmt.Sprintf("Rider already has an active ride (%s)", activeRide.ID), nil))
+			return
+		}
+		if !errors.Is(err, ride.ErrRideNotFound) {
+			log.Error("Failed to check active rides", logger.Err(err))
+			respondError(c, apperrors.Internal("Failed to create ride", err))
+			return
+		}
+	}
 
 	// Generate ride ID
 	rideID := generateRideID()
+	region := h.Pricing.RegionForCoordinates(pickupLat, pickupLng)
+	log = middleware.WithLogFields(c, h.Logger, logger.String("ride_id", rideID))
 
-	h.Logger.Info("Ride request received",
-		logger.String("ride_id", rideID),
-		logger.String("rider_id", req.RiderID),
-		logger.Float64("pickup_lat", req.PickupLatitude),
-		logger.Float64("pickup_lng", req.PickupLongitude),
+	log.Info("Ride request received",
+		logger.Float64("pickup_lat", pickupLat),
+		logger.Float64("pickup_lng", pickupLng),
+		logger.String("region", region),
 	)
 
 	// Parse vehicle type
@@ -46,61 +150,178 @@ func (h *Handlers) CreateRide(c *gin.Context) {
 		vehicleType = driver.VehicleEconomy
 	}
 
+	// Riders who don't ask for seats need just one
+	seats := req.Seats
+	if seats == 0 {
+		seats = 1
+	}
+	if seats > driver.MaxSeats() {
+		respondError(c, apperrors.BadRequest(fmt.Sprintf("At most %d seats can be requested", driver.MaxSeats()), nil))
+		return
+	}
+	// A pool ride shares the car, so each rider books a single seat
+	if req.Pool && seats > 1 {
+		respondError(c, apperrors.BadRequest("Pool rides carry a single seat", nil))
+		return
+	}
+
+	// Quote the fare up front, including any surge in the pickup region
+	quotedSurge := h.currentSurge(ctx, region)
+	tripDistance, tripMinutes := h.estimateTrip(ctx, log,
+		rideStops(pickupLat, pickupLng, dropoffLat, dropoffLng, req.Waypoints))
+	waypoints := rideWaypoints(req.Waypoints)
+	fare := h.Pricing.CalculateFareWithSurge(vehicleType, tripDistance, tripMinutes, 0, quotedSurge)
+	if req.Pool {
+		fare = h.Pricing.ApplyPoolDiscount(fare)
+	}
+	estimatedFare := roundToCents(fare.Total)
+	estimatedDistance := roundToCents(tripDistance)
+
+	rd := &ride.Ride{
+		ID:                       rideID,
+		RiderID:                  riderUUID,
+		Status:                   ride.StatusRequested,
+		VehicleType:              ride.VehicleType(vehicleType),
+		Seats:                    seats,
+		Pool:                     req.Pool,
+		AllowUpgrade:             req.AllowUpgrade,
+		PickupLatitude:           pickupLat,
+		PickupLongitude:          pickupLng,
+		DropoffLatitude:          dropoffLat,
+		DropoffLongitude:         dropoffLng,
+		EstimatedFare:            &estimatedFare,
+		EstimatedDistanceKM:      &estimatedDistance,
+		EstimatedDurationMinutes: &tripMinutes,
+		QuotedSurge:              &quotedSurge,
+		Waypoints:                waypoints,
+		RequestedAt:              time.Now(),
+		IdempotencyKey:           idempotencyKey,
+	}
+
+	// Advance bookings are saved without a driver; the scheduler matches them shortly before pickup
+	if scheduled {
+		scheduledAt := req.ScheduledAt.UTC()
+		rd.Status = ride.StatusScheduled
+		rd.ScheduledAt = &scheduledAt
+		if err := h.Rides.Create(ctx, rd); err != nil {
+			log.Error("Failed to save scheduled ride", logger.Err(err))
+			respondError(c, apperrors.Internal("Failed to create ride", err))
+			return
+		}
+
+		log.Info("Ride scheduled", logger.String("scheduled_at", scheduledAt.Format(time.RFC3339)))
+		actorType, actorID := requestActor(c)
+		h.recordRideEvent(ctx, log, rideID, ride.EventRequested, actorType, actorID, map[string]interface{}{
+			"scheduled_at": scheduledAt,
+		})
+
+		response := gin.H{
+			"id":               rideID,
+			"rider_id":         req.RiderID,
+			"status":           "scheduled",
+			"region":           region,
+			"scheduled_at":     scheduledAt,
+			"seats":            seats,
+			"pool":             req.Pool,
+			"estimated_fare":   estimatedFare,
+			"surge_multiplier": quotedSurge,
+		}
+		h.cacheRideResponse(ctx, req.RiderID, idempotencyKey, response)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
 	// Create matching service with progressive radius expansion
-	// Starts at 5km, expands to 10km, 20km, up to 50km if no drivers found
-	matchingService := matching.NewService(h.Redis, h.Logger, matching.Config{
-		MaxRadiusKM:       5.0,  // Initial search radius
-		MaxExpandedRadius: 50.0, // Maximum expanded radius
-		MaxTimeout:        30,
-		MaxCandidates:     50,   // Check up to 50 candidates to handle concurrent requests
-	})
+	// Starts at MaxRadiusKM and expands up to MaxExpandedRadius if no drivers found
+	matchingService := h.newMatchingService(log)
 
 	// Find nearest driver
-	ctx := context.Background()
-	foundDriver, err := matchingService.FindNearestDriver(ctx, req.PickupLatitude, req.PickupLongitude, vehicleType)
+	h.Metrics.RecordRideRequested(req.VehicleType)
+	matchStart := time.Now()
+	candidate, pooled, err := h.matchDriver(ctx, matchingService, rd)
+	matchLatency := time.Since(matchStart)
+	h.Metrics.RecordMatchLatency(matchLatency)
+	matchMode := "full"
+	if matchingService.BatchesCandidates() {
+		matchMode = "batched"
+	}
+	h.NewRelic.RecordMatchingLatency(float64(matchLatency)/float64(time.Millisecond), matchMode)
+	if err != nil {
+		h.Metrics.RecordMatchFailed(req.VehicleType)
+	}
+	if errors.Is(err, matching.ErrMatchingTimeout) {
+		log.Error("Driver matching timed out", logger.Err(err), logger.String("region", region))
+		respondError(c, apperrors.ErrMatchingTimeout)
+		return
+	}
 	if err != nil {
-		h.Logger.Warn("No drivers available", logger.Err(err))
+		maxRadius := matchingService.MaxSearchRadiusKM()
+		log.Warn("No drivers available",
+			logger.Err(err),
+			logger.String("region", region),
+			logger.String("vehicle_type", req.VehicleType),
+			logger.Float64("max_radius_km", maxRadius),
+			logger.Float64("pickup_lat", pickupLat),
+			logger.Float64("pickup_lng", pickupLng),
+		)
+		if errors.Is(err, driver.ErrDriverNotAvailable) {
+			h.NewRelic.RecordNoDriverFound(req.VehicleType, maxRadius, region)
+		}
 		c.JSON(http.StatusOK, gin.H{
-			"id":       rideID,
-			"rider_id": req.RiderID,
-			"status":   "requested",
-			"message":  "Searching for drivers...",
-			"driver":   nil,
+			"id":               rideID,
+			"rider_id":         req.RiderID,
+			"status":           "requested",
+			"message":          "Searching for drivers...",
+			"driver":           nil,
+			"pool":             req.Pool,
+			"estimated_fare":   estimatedFare,
+			"surge_multiplier": quotedSurge,
 		})
 		return
 	}
+	foundDriver := candidate.Driver
+	driverIDStr := foundDriver.ID.String()
+	log = middleware.WithLogFields(c, h.Logger, logger.String("driver_id", driverIDStr))
+	// The fare stays quoted for the requested type even when a larger vehicle was matched
+	matchedVehicle := foundDriver.VehicleType
+
+	// Estimate arrival from the driver's actual distance to pickup, slowed by any surge
+	etaMinutes := h.ETA.ArrivalForDistance(candidate.Distance, quotedSurge)
 
 	// Save ride to PostgreSQL
-	_, err = h.DB.ExecContext(ctx, `
-		INSERT INTO rides (
-			id, rider_id, driver_id, status, vehicle_type,
-			pickup_latitude, pickup_longitude,
-			dropoff_latitude, dropoff_longitude,
-			estimated_fare, requested_at, assigned_at
-		) VALUES ($1, $2, $3, 'assigned', $4, $5, $6, $7, $8, $9, NOW(), NOW())
-	`, rideID, req.RiderID, foundDriver.ID.String(), req.VehicleType,
-		req.PickupLatitude, req.PickupLongitude,
-		req.DropoffLatitude, req.DropoffLongitude, 250.00)
+	now := time.Now()
+	rd.DriverID = &foundDriver.ID
+	rd.Status = ride.StatusAssigned
+	rd.RequestedAt = now
+	rd.AssignedAt = &now
+	err = h.Rides.Create(ctx, rd)
 
 	if err != nil {
-		h.Logger.Error("Failed to save ride to PostgreSQL", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ride"})
+		log.Error("Failed to save ride to PostgreSQL", logger.Err(err))
+		// Matching already claimed the driver; hand them back so they aren't stranded
+		h.releaseDriverFromRide(ctx, driverIDStr, rideID)
+		log.Info("Released claimed driver after failed save")
+		respondError(c, apperrors.Internal("Failed to create ride", err))
 		return
 	}
 
-	h.Logger.Info("Ride saved to PostgreSQL",
-		logger.String("ride_id", rideID),
-		logger.String("driver_id", foundDriver.ID.String()),
-	)
+	log.Info("Ride saved to PostgreSQL")
+	h.NewRelic.RecordRideCreated(req.VehicleType)
+
+	actorType, actorID := requestActor(c)
+	h.recordRideEvent(ctx, log, rideID, ride.EventRequested, actorType, actorID, nil)
+	h.recordRideEvent(ctx, log, rideID, ride.EventAssigned, ride.ActorSystem, "", map[string]interface{}{
+		"driver_id":    driverIDStr,
+		"distance_km":  candidate.Distance,
+		"vehicle_type": matchedVehicle,
+		"pooled":       pooled,
+	})
 
 	// Set actual ride ID for driver (matching service already removed from available set)
-	driverIDStr := foundDriver.ID.String()
-	h.Redis.Set(ctx, fmt.Sprintf("driver:%s:current_ride", driverIDStr), rideID, 0)
+	h.holdDriver(ctx, log, driverIDStr, rd, pooled)
+	h.trackAssignment(ctx, log, rideID)
 
-	h.Logger.Info("Driver marked as busy",
-		logger.String("driver_id", driverIDStr),
-		logger.String("ride_id", rideID),
-	)
+	log.Info("Driver marked as busy", logger.Bool("pooled", pooled))
 
 	// Send WebSocket notification to dashboard
 	driverNotification := map[string]interface{}{
@@ -109,13 +330,18 @@ func (h *Handlers) CreateRide(c *gin.Context) {
 			"ride_id":           rideID,
 			"driver_id":         foundDriver.ID.String(),
 			"rider_id":          req.RiderID,
-			"pickup_latitude":   req.PickupLatitude,
-			"pickup_longitude":  req.PickupLongitude,
-			"dropoff_latitude":  req.DropoffLatitude,
-			"dropoff_longitude": req.DropoffLongitude,
-			"vehicle_type":      req.VehicleType,
-			"distance":          "2.5 km",
-			"estimated_fare":    250.00,
+			"pickup_latitude":   pickupLat,
+			"pickup_longitude":  pickupLng,
+			"dropoff_latitude":  dropoffLat,
+			"dropoff_longitude": dropoffLng,
+			"vehicle_type":      matchedVehicle,
+			"seats":             seats,
+			"pool":              req.Pool,
+			"pooled":            pooled,
+			"distance":          fmt.Sprintf("%.2f km", candidate.Distance),
+			"distance_km":       candidate.Distance,
+			"estimated_fare":    estimatedFare,
+			"surge_multiplier":  quotedSurge,
 		},
 	}
 	// Broadcast to all dashboard users
@@ -123,33 +349,242 @@ func (h *Handlers) CreateRide(c *gin.Context) {
 		wsHub.BroadcastToType("dashboard", driverNotification)
 	}
 
-	h.Logger.Info("Driver matched and dashboard notified",
-		logger.String("ride_id", rideID),
-		logger.String("driver_id", foundDriver.ID.String()),
-	)
+	log.Info("Driver matched and dashboard notified")
 
 	// Return response to rider
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"id":        rideID,
 		"rider_id":  req.RiderID,
 		"status":    "assigned",
+		"region":    region,
 		"driver_id": foundDriver.ID.String(),
 		"driver_name": foundDriver.Name,
 		"driver": map[string]interface{}{
 			"id":        foundDriver.ID.String(),
 			"name":      foundDriver.Name,
+			"phone":     foundDriver.Phone,
 			"rating":    foundDriver.Rating,
-			"vehicle":   req.VehicleType,
+			"vehicle":   matchedVehicle,
+			"seats":     matchedVehicle.Seats(),
 			"latitude":  foundDriver.CurrentLatitude,
 			"longitude": foundDriver.CurrentLongitude,
 		},
-		"estimated_arrival": "5 mins",
-		"estimated_fare":    250.00,
+		"vehicle_type":              matchedVehicle,
+		"requested_vehicle_type":    vehicleType,
+		"upgraded":                  matchedVehicle != vehicleType,
+		"vehicle_seats":             matchedVehicle.Seats(),
+		"driver_distance_km":        candidate.Distance,
+		"estimated_arrival":         eta.Format(etaMinutes),
+		"estimated_arrival_minutes": etaMinutes,
+		"estimated_fare":            estimatedFare,
+		"surge_multiplier":          quotedSurge,
+		"pool":                      req.Pool,
+		"pooled":                    pooled,
+	}
+
+	h.cacheRideResponse(ctx, req.RiderID, idempotencyKey, response)
+	c.JSON(http.StatusOK, response)
+}
+
+// EstimateFare handles POST /v1/rides/estimate
+// Quotes every vehicle type for the route without creating a ride or claiming a driver.
+func (h *Handlers) EstimateFare(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	var req dto.EstimateFareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+	if err := req.ValidateCoordinates(); err != nil {
+		respondError(c, err)
+		return
+	}
+	pickupLat, pickupLng := *req.PickupLatitude, *req.PickupLongitude
+	dropoffLat, dropoffLng := *req.DropoffLatitude, *req.DropoffLongitude
+
+	if len(req.Waypoints) > h.Config.Routing.MaxWaypoints {
+		respondError(c, apperrors.BadRequest(fmt.Sprintf("At most %d waypoints are allowed", h.Config.Routing.MaxWaypoints), nil))
+		return
+	}
+
+	ctx := context.Background()
+
+	// One route estimate and one surge lookup cover all vehicle types
+	region := h.Pricing.RegionForCoordinates(pickupLat, pickupLng)
+	surge := h.currentSurge(ctx, region)
+	tripDistance, tripMinutes := h.estimateTrip(ctx, log,
+		rideStops(pickupLat, pickupLng, dropoffLat, dropoffLng, req.Waypoints))
+
+	estimates := gin.H{}
+	for _, vt := range []driver.VehicleType{driver.VehicleEconomy, driver.VehiclePremium, driver.VehicleLuxury} {
+		fare := h.Pricing.CalculateFareWithSurge(vt, tripDistance, tripMinutes, 0, surge)
+		estimates[string(vt)] = pricing.FareBreakdown{
+			BaseFare:        roundToCents(fare.BaseFare),
+			DistanceFare:    roundToCents(fare.DistanceFare),
+			TimeFare:        roundToCents(fare.TimeFare),
+			SurgeMultiplier: fare.SurgeMultiplier,
+			Subtotal:        roundToCents(fare.Subtotal),
+			Total:           roundToCents(fare.Total),
+		}
+	}
+
+	response := gin.H{
+		"region":                     region,
+		"distance_km":                roundToCents(tripDistance),
+		"estimated_duration_minutes": tripMinutes,
+		"surge_multiplier":           surge,
+		"estimates":                  estimates,
+	}
+	if req.VehicleType != "" {
+		response["vehicle_type"] = req.VehicleType
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// estimateTrip returns the road distance and duration through stops, falling back to
+// straight-line distance so a routing outage doesn't block ride requests
+func (h *Handlers) estimateTrip(ctx context.Context, log *logger.Logger, stops []routing.Point) (float64, int) {
+	distance, minutes, _, err := routing.EstimateRouteVia(ctx, h.Router, stops)
+	if err == nil {
+		return distance, minutes
+	}
+
+	log.Warn("Route estimate failed", logger.Err(err))
+	distance = 0
+	for i := 1; i < len(stops); i++ {
+		distance += matching.CalculateDistance(stops[i-1].Latitude, stops[i-1].Longitude, stops[i].Latitude, stops[i].Longitude)
+	}
+	return distance, h.ETA.ArrivalForDistance(distance, 1)
+}
+
+// rideStops returns a route in order: pickup, any waypoints, dropoff
+func rideStops(pickupLat, pickupLng, dropoffLat, dropoffLng float64, waypoints []dto.LocationPoint) []routing.Point {
+	stops := make([]routing.Point, 0, len(waypoints)+2)
+	stops = append(stops, routing.Point{Latitude: pickupLat, Longitude: pickupLng})
+	for _, wp := range waypoints {
+		stops = append(stops, routing.Point{Latitude: wp.Latitude, Longitude: wp.Longitude})
+	}
+	return append(stops, routing.Point{Latitude: dropoffLat, Longitude: dropoffLng})
+}
+
+// rideWaypoints numbers requested waypoints from 1 in the order given
+func rideWaypoints(points []dto.LocationPoint) []ride.Waypoint {
+	if len(points) == 0 {
+		return nil
+	}
+	waypoints := make([]ride.Waypoint, len(points))
+	for i, p := range points {
+		waypoints[i] = ride.Waypoint{Sequence: i + 1, Latitude: p.Latitude, Longitude: p.Longitude}
+	}
+	return waypoints
+}
+
+// cacheRideResponse stores a ride creation response so retries with the same
+// Idempotency-Key don't create a second ride
+func (h *Handlers) cacheRideResponse(ctx context.Context, riderID, idempotencyKey string, response gin.H) {
+	if idempotencyKey == "" {
+		return
+	}
+	cache.SetJSON(ctx, h.Redis, rideIdempotencyKey(riderID, idempotencyKey), response, h.Config.Cache.TTLIdempotency)
+}
+
+// rideCreationLockKey guards a rider's ride creation against concurrent requests
+func rideCreationLockKey(riderID string) string {
+	return fmt.Sprintf("rider:%s:ride_creation_lock", riderID)
+}
+
+// rideIdempotencyKey holds the cached response for a rider's ride creation request; keys are
+// client-chosen, so they are scoped to the rider
+func rideIdempotencyKey(riderID, idempotencyKey string) string {
+	return fmt.Sprintf("ride:idempotency:%s:%s", riderID, idempotencyKey)
+}
+
+// existingRideResponse describes a ride found by its idempotency key after the cached
+// creation response expired
+func (h *Handlers) existingRideResponse(rd *ride.Ride) gin.H {
+	response := gin.H{
+		"id":       rd.ID,
+		"rider_id": rd.RiderID.String(),
+		"status":   rd.Status,
+		"region":   h.Pricing.RegionForCoordinates(rd.PickupLatitude, rd.PickupLongitude),
+		"seats":    rd.Seats,
+		"pool":     rd.Pool,
+	}
+	if rd.EstimatedFare != nil {
+		response["estimated_fare"] = *rd.EstimatedFare
+	}
+	if rd.QuotedSurge != nil {
+		response["surge_multiplier"] = *rd.QuotedSurge
+	}
+	if rd.ScheduledAt != nil {
+		response["scheduled_at"] = *rd.ScheduledAt
+	}
+	if rd.DriverID != nil {
+		response["driver_id"] = rd.DriverID.String()
+	}
+	return response
+}
+
+// currentSurge returns the live surge multiplier for region, or 1.0 when surge pricing is disabled
+func (h *Handlers) currentSurge(ctx context.Context, region string) float64 {
+	if !h.Config.Features.EnableSurgePricing {
+		return 1.0
+	}
+	return h.Pricing.GetSurgeMultiplier(ctx, region)
+}
+
+// newMatchingService builds a matching service from the loaded matching config
+func (h *Handlers) newMatchingService(log *logger.Logger) *matching.Service {
+	return matching.NewService(h.Redis, h.Rides, h.Favorites, log, matching.Config{
+		MaxRadiusKM:       h.Config.Matching.MaxRadiusKM,
+		MaxExpandedRadius: h.Config.Matching.MaxExpandedRadius,
+		SearchRadiiKM:     h.Config.Matching.SearchRadiiKM,
+		MaxTimeout:        h.Config.Matching.MaxTimeout,
+		MaxCandidates:     h.Config.Matching.MaxCandidates,
+		InitialBatchSize:  h.Config.Matching.InitialBatchSize,
+		PoolCapacity:      h.Config.Matching.PoolCapacity,
+		PoolMaxDetourKM:   h.Config.Matching.PoolMaxDetourKM,
+		FavoriteBandKM:    h.Config.Matching.FavoriteDriverBandKM,
+		RatingBucketKM:    h.Config.Matching.RatingBucketKM,
 	})
 }
 
+// matchDriver finds the nearest driver for a ride. Pool rides first try to share a driver
+// already carrying pool riders; pooled reports that this worked, in which case the ride has
+// already joined that driver's pool.
+func (h *Handlers) matchDriver(ctx context.Context, svc *matching.Service, rd *ride.Ride) (candidate *matching.DriverCandidate, pooled bool, err error) {
+	vehicleType := driver.VehicleType(rd.VehicleType)
+	if rd.Pool {
+		candidate, err = svc.FindPoolDriver(ctx, rd.ID, rd.PickupLatitude, rd.PickupLongitude, rd.DropoffLatitude, rd.DropoffLongitude, vehicleType, nil)
+		if err == nil {
+			return candidate, true, nil
+		}
+	}
+	candidate, err = svc.FindNearestDriverForSeats(ctx, rd.RiderID.String(), rd.PickupLatitude, rd.PickupLongitude, vehicleType, rd.Seats, rd.AllowUpgrade, nil)
+	return candidate, false, err
+}
+
+// holdDriver marks a driver busy with a ride they were just assigned. A driver who took the
+// ride into their pool keeps following the ride already aboard, while a pool ride matched to
+// a free driver opens that driver's pool to other riders; log is expected to be scoped to the ride.
+func (h *Handlers) holdDriver(ctx context.Context, log *logger.Logger, driverID string, rd *ride.Ride, pooled bool) {
+	if pooled {
+		return
+	}
+	h.Redis.Set(ctx, fmt.Sprintf("driver:%s:current_ride", driverID), rd.ID, 0)
+	if rd.Pool {
+		if err := matching.JoinPool(ctx, h.Redis, driverID, rd.ID, rd.DropoffLatitude, rd.DropoffLongitude); err != nil {
+			log.Warn("Failed to open driver pool", logger.Err(err))
+		}
+	}
+}
+
 // GetRide handles GET /v1/rides/:id
 func (h *Handlers) GetRide(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
 	rideID := c.Param("id")
 	ctx := context.Background()
 
@@ -159,7 +594,7 @@ func (h *Handlers) GetRide(c *gin.Context) {
 		       r.pickup_latitude, r.pickup_longitude,
 		       r.dropoff_latitude, r.dropoff_longitude,
 		       r.estimated_fare, r.requested_at, r.assigned_at,
-		       r.accepted_at, r.completed_at,
+		       r.accepted_at, r.completed_at, r.scheduled_at,
 		       d.name as driver_name, d.rating as driver_rating,
 		       d.phone as driver_phone
 		FROM rides r
@@ -182,6 +617,7 @@ func (h *Handlers) GetRide(c *gin.Context) {
 		AssignedAt        sql.NullTime
 		AcceptedAt        sql.NullTime
 		CompletedAt       sql.NullTime
+		ScheduledAt       sql.NullTime
 		DriverName        sql.NullString
 		DriverRating      sql.NullFloat64
 		DriverPhone       sql.NullString
@@ -192,18 +628,18 @@ func (h *Handlers) GetRide(c *gin.Context) {
 		&ride.PickupLatitude, &ride.PickupLongitude,
 		&ride.DropoffLatitude, &ride.DropoffLongitude,
 		&ride.EstimatedFare, &ride.RequestedAt, &ride.AssignedAt,
-		&ride.AcceptedAt, &ride.CompletedAt,
+		&ride.AcceptedAt, &ride.CompletedAt, &ride.ScheduledAt,
 		&ride.DriverName, &ride.DriverRating, &ride.DriverPhone,
 	)
 
 	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		respondError(c, apperrors.ErrRideNotFound)
 		return
 	}
 
 	if err != nil {
-		h.Logger.Error("Failed to get ride", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ride"})
+		log.Error("Failed to get ride", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to get ride", err))
 		return
 	}
 
@@ -224,6 +660,17 @@ func (h *Handlers) GetRide(c *gin.Context) {
 		response["estimated_fare"] = ride.EstimatedFare.Float64
 	}
 
+	if ride.ScheduledAt.Valid {
+		response["scheduled_at"] = ride.ScheduledAt.Time
+	}
+
+	waypoints, err := h.Rides.GetWaypoints(ctx, rideID)
+	if err != nil {
+		log.Warn("Failed to load ride waypoints", logger.Err(err), logger.String("ride_id", rideID))
+	} else if len(waypoints) > 0 {
+		response["waypoints"] = waypoints
+	}
+
 	if ride.DriverID.Valid {
 		response["driver_id"] = ride.DriverID.String
 		response["driver"] = gin.H{
@@ -250,13 +697,14 @@ func (h *Handlers) GetRide(c *gin.Context) {
 			DistanceKm      float64
 			DurationMinutes int
 			TotalFare       float64
+			RoutePolyline   sql.NullString
 		}
 
 		err = h.DB.QueryRowContext(ctx, `
-			SELECT id, distance_km, duration_minutes, total_fare
+			SELECT id, distance_km, duration_minutes, total_fare, route_polyline
 			FROM trips
 			WHERE ride_id = $1 AND status = 'completed'
-		`, rideID).Scan(&trip.ID, &trip.DistanceKm, &trip.DurationMinutes, &trip.TotalFare)
+		`, rideID).Scan(&trip.ID, &trip.DistanceKm, &trip.DurationMinutes, &trip.TotalFare, &trip.RoutePolyline)
 
 		if err == nil {
 			response["trip"] = gin.H{
@@ -264,6 +712,7 @@ func (h *Handlers) GetRide(c *gin.Context) {
 				"distance_km":      trip.DistanceKm,
 				"duration_minutes": trip.DurationMinutes,
 				"total_fare":       trip.TotalFare,
+				"route_polyline":   trip.RoutePolyline.String,
 			}
 		}
 	}
@@ -271,7 +720,120 @@ func (h *Handlers) GetRide(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// Helper function to generate ride ID
+// CancelRide handles POST /v1/rides/:id/cancel
+// Cancelling is free until the driver has accepted and CancellationGracePeriod has
+// passed since assignment; after that the flat cancellation fee is charged.
+func (h *Handlers) CancelRide(c *gin.Context) {
+	rideID := c.Param("id")
+	log := middleware.WithLogFields(c, h.Logger, logger.String("ride_id", rideID))
+	ctx := context.Background()
+
+	var req dto.CancelRideRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	rd, err := h.Rides.GetByID(ctx, rideID)
+	if errors.Is(err, ride.ErrRideNotFound) {
+		respondError(c, apperrors.ErrRideNotFound)
+		return
+	}
+	if err != nil {
+		log.Error("Failed to get ride", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to cancel ride", err))
+		return
+	}
+
+	// Riders may only cancel their own rides
+	if middleware.GetUserType(c) == auth.UserTypeRider && middleware.GetUserID(c) != rd.RiderID.String() {
+		respondError(c, apperrors.Forbidden("Cannot cancel another rider's ride", nil))
+		return
+	}
+
+	if err := ride.Transition(rd.Status, ride.StatusCancelled); err != nil {
+		respondError(c, invalidTransition("cancelled", rd.Status, err))
+		return
+	}
+
+	from := rd.Status
+	now := time.Now()
+	var elapsedSinceAssign time.Duration
+	if rd.AssignedAt != nil {
+		elapsedSinceAssign = now.Sub(*rd.AssignedAt)
+	}
+	fee := roundToCents(h.Pricing.CalculateCancellationFee(ctx, rd, elapsedSinceAssign))
+
+	rd.Status = ride.StatusCancelled
+	rd.CancelledAt = &now
+	rd.CancellationReason = req.Reason
+	if rd.CancellationReason == "" {
+		rd.CancellationReason = "Cancelled by rider"
+	}
+	if fee > 0 {
+		rd.CancellationFee = &fee
+	}
+	// The ride may have been accepted, started or completed since it was read
+	err = h.Rides.Update(ctx, rd, from)
+	if errors.Is(err, ride.ErrStatusChanged) {
+		respondError(c, apperrors.Conflict("Ride status changed while cancelling", err))
+		return
+	}
+	if err != nil {
+		log.Error("Failed to cancel ride", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to cancel ride", err))
+		return
+	}
+
+	log.Info("Ride cancelled", logger.Float64("cancellation_fee", fee))
+	h.NewRelic.RecordRideCancelled(rideID, fee, rd.CancellationReason)
+	actorType, actorID := requestActor(c)
+	h.recordRideEvent(ctx, log, rideID, ride.EventCancelled, actorType, actorID, map[string]interface{}{
+		"reason":           rd.CancellationReason,
+		"cancellation_fee": fee,
+	})
+
+	cancelledData := map[string]interface{}{
+		"ride_id":          rideID,
+		"reason":           rd.CancellationReason,
+		"cancellation_fee": fee,
+	}
+
+	h.clearAssignment(ctx, log, rideID)
+
+	// Free the driver, unless they've already moved on to another ride
+	if rd.DriverID != nil {
+		driverID := rd.DriverID.String()
+		if currentRide, _ := h.Redis.Get(ctx, fmt.Sprintf("driver:%s:current_ride", driverID)).Result(); currentRide == rideID {
+			h.releaseDriverFromRide(ctx, driverID, rideID)
+		} else if _, _, err := matching.LeavePool(ctx, h.Redis, driverID, rideID); err != nil {
+			// A pool rider dropping out leaves the driver with the rest of the pool
+			log.Warn("Failed to remove ride from driver pool", logger.Err(err))
+		}
+		if wsHub, ok := h.Hub.(*websocket.Hub); ok {
+			wsHub.SendToUser(driverID, map[string]interface{}{
+				"type": "ride_cancelled",
+				"data": cancelledData,
+			})
+		}
+	}
+	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
+		wsHub.BroadcastToRide(rideID, websocket.Message{
+			Type: "ride_cancelled",
+			Data: cancelledData,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":               rideID,
+		"status":           "cancelled",
+		"reason":           rd.CancellationReason,
+		"cancelled_at":     now,
+		"cancellation_fee": fee,
+	})
+}
+
+// generateRideID returns a random, unguessable ride ID
 func generateRideID() string {
-	return fmt.Sprintf("ride-%d", time.Now().UnixNano())
+	return uuid.New().String()
 }
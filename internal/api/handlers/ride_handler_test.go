@@ -0,0 +1,554 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/config"
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/payment"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/service/eta"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/gocomet/ride-hailing/internal/service/pricing"
+	"github.com/gocomet/ride-hailing/internal/service/routing"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRides is an in-memory ride repository over rides; Create fails with createErr
+type fakeRides struct {
+	ride.Repository
+	createErr error
+	updateErr error
+	rides     map[string]*ride.Ride
+	updated   []*ride.Ride
+}
+
+func (f *fakeRides) GetByID(ctx context.Context, id string) (*ride.Ride, error) {
+	if rd, ok := f.rides[id]; ok {
+		copied := *rd
+		return &copied, nil
+	}
+	return nil, ride.ErrRideNotFound
+}
+
+func (f *fakeRides) GetWaypoints(ctx context.Context, rideID string) ([]ride.Waypoint, error) {
+	return nil, nil
+}
+
+func (f *fakeRides) Update(ctx context.Context, rd *ride.Ride, from ride.Status) error {
+	if f.updateErr != nil {
+		return f.updateErr
+	}
+	f.updated = append(f.updated, rd)
+	return nil
+}
+
+func (f *fakeRides) UpdateStatus(ctx context.Context, id string, from, to ride.Status) error {
+	rd, ok := f.rides[id]
+	if !ok || rd.Status != from {
+		return ride.ErrStatusChanged
+	}
+	rd.Status = to
+	return nil
+}
+
+func (f *fakeRides) GetByIdempotencyKey(ctx context.Context, riderID uuid.UUID, key string) (*ride.Ride, error) {
+	for _, rd := range f.rides {
+		if rd.RiderID == riderID && rd.IdempotencyKey == key {
+			copied := *rd
+			return &copied, nil
+		}
+	}
+	return nil, ride.ErrRideNotFound
+}
+
+func (f *fakeRides) GetActiveRideByRider(ctx context.Context, riderID uuid.UUID) (*ride.Ride, error) {
+	for _, rd := range f.rides {
+		if rd.RiderID == riderID && rd.Status.IsActive() {
+			copied := *rd
+			return &copied, nil
+		}
+	}
+	return nil, ride.ErrRideNotFound
+}
+
+func (f *fakeRides) GetActiveRideByDriver(ctx context.Context, driverID uuid.UUID) (*ride.Ride, error) {
+	for _, rd := range f.rides {
+		if rd.DriverID != nil && *rd.DriverID == driverID && rd.Status.IsActive() {
+			copied := *rd
+			return &copied, nil
+		}
+	}
+	return nil, ride.ErrRideNotFound
+}
+
+func (f *fakeRides) Create(ctx context.Context, rd *ride.Ride) error {
+	return f.createErr
+}
+
+func (f *fakeRides) ListScheduledByRider(ctx context.Context, riderID uuid.UUID) ([]*ride.Ride, error) {
+	var scheduled []*ride.Ride
+	for _, rd := range f.rides {
+		if rd.RiderID == riderID && rd.Status == ride.StatusScheduled {
+			copied := *rd
+			scheduled = append(scheduled, &copied)
+		}
+	}
+	return scheduled, nil
+}
+
+// fakeSessions records driver session transitions as "<kind>:<driver id>"
+type fakeSessions struct {
+	events      []string
+	utilization driver.Utilization
+}
+
+func (f *fakeSessions) Start(ctx context.Context, driverID uuid.UUID, at time.Time) error {
+	f.events = append(f.events, "start:"+driverID.String())
+	return nil
+}
+
+func (f *fakeSessions) End(ctx context.Context, driverID uuid.UUID, at time.Time) error {
+	f.events = append(f.events, "end:"+driverID.String())
+	return nil
+}
+
+func (f *fakeSessions) StartBusy(ctx context.Context, driverID uuid.UUID, at time.Time) error {
+	f.events = append(f.events, "busy:"+driverID.String())
+	return nil
+}
+
+func (f *fakeSessions) EndBusy(ctx context.Context, driverID uuid.UUID, at time.Time) error {
+	f.events = append(f.events, "free:"+driverID.String())
+	return nil
+}
+
+func (f *fakeSessions) Utilization(ctx context.Context, driverID uuid.UUID, since, until time.Time) (driver.Utilization, error) {
+	return f.utilization, nil
+}
+
+// fakeEvents is an in-memory ride event log
+type fakeEvents struct {
+	events []*ride.Event
+}
+
+func (f *fakeEvents) Record(ctx context.Context, e *ride.Event) error {
+	e.CreatedAt = time.Now()
+	f.events = append(f.events, e)
+	return nil
+}
+
+func (f *fakeEvents) ListByRide(ctx context.Context, rideID string) ([]*ride.Event, error) {
+	events := []*ride.Event{}
+	for _, e := range f.events {
+		if e.RideID == rideID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// indexTestDriver places a driver of vehicleType in the geo indexes matching searches
+func indexTestDriver(t *testing.T, client *redis.Client, driverID string, vehicleType driver.VehicleType, lat, lng float64) {
+	t.Helper()
+	ctx := context.Background()
+	pipe := client.Pipeline()
+	matching.IndexDriverLocation(ctx, pipe, driverID, vehicleType, lat, lng)
+	pipe.HSet(ctx, matching.DriverMetaKey(driverID), "vehicle_type", string(vehicleType))
+	_, err := pipe.Exec(ctx)
+	require.NoError(t, err)
+}
+
+// newTestHandlers returns handlers backed by miniredis and the given ride repository
+func newTestHandlers(t *testing.T, rides ride.Repository) (*Handlers, *redis.Client) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Matching: config.MatchingConfig{
+			MaxRadiusKM:       5,
+			MaxExpandedRadius: 10,
+			MaxTimeout:        5 * time.Second,
+			MaxCandidates:     10,
+			AvgCitySpeedKMH:   30,
+		},
+		Routing: config.RoutingConfig{WindingFactor: 1.3, MaxWaypoints: 5},
+	}
+
+	return &Handlers{
+		Redis:  client,
+		Logger: log,
+		Config: cfg,
+		Pricing: pricing.NewService(client, pricing.Config{
+			CancellationFee:         50,
+			CancellationGracePeriod: 2 * time.Minute,
+		}),
+		Router:   routing.NewHaversineRouter(cfg.Routing.WindingFactor, cfg.Matching.AvgCitySpeedKMH),
+		ETA:      eta.NewEstimator(cfg.Matching.AvgCitySpeedKMH, cfg.Matching.ETASurgeSlowdown),
+		Gateway:  payment.NewMockGateway(0, 0),
+		Sessions: &fakeSessions{},
+		Rides:    rides,
+		Events:   &fakeEvents{},
+	}, client
+}
+
+// TestCreateRide_ReleasesDriverWhenSaveFails tests that a driver claimed by matching is
+// returned to the available pool when the ride INSERT fails
+func TestCreateRide_ReleasesDriverWhenSaveFails(t *testing.T) {
+	h, client := newTestHandlers(t, &fakeRides{createErr: errors.New("connection reset")})
+	ctx := context.Background()
+
+	driverID := uuid.New().String()
+	indexTestDriver(t, client, driverID, driver.VehicleEconomy, 12.9716, 77.5946)
+	require.NoError(t, client.SAdd(ctx, "drivers:available", driverID).Err())
+
+	body := `{"rider_id":"` + uuid.New().String() + `","pickup_latitude":12.9716,"pickup_longitude":77.5946,` +
+		`"dropoff_latitude":12.9352,"dropoff_longitude":77.6245,"vehicle_type":"economy"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CreateRide(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	available, err := client.SIsMember(ctx, "drivers:available", driverID).Result()
+	require.NoError(t, err)
+	assert.True(t, available, "driver should be back in the available set")
+
+	exists, err := client.Exists(ctx, "driver:"+driverID+":current_ride").Result()
+	require.NoError(t, err)
+	assert.Zero(t, exists, "claiming marker should be cleared")
+}
+
+// newCreateRideRequest builds a POST /v1/rides context for riderID with an idempotency key
+func newCreateRideRequest(riderID, idempotencyKey string) (*gin.Context, *httptest.ResponseRecorder) {
+	body := `{"rider_id":"` + riderID + `","pickup_latitude":12.9716,"pickup_longitude":77.5946,` +
+		`"dropoff_latitude":12.9352,"dropoff_longitude":77.6245,"vehicle_type":"economy"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Idempotency-Key", idempotencyKey)
+	return c, w
+}
+
+// TestCreateRide_IdempotencyKeyScopedToRider tests that another rider reusing a key gets a ride
+// of their own, not the first rider's cached response
+func TestCreateRide_IdempotencyKeyScopedToRider(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	firstRider, secondRider := uuid.NewString(), uuid.NewString()
+	h.cacheRideResponse(context.Background(), firstRider, "key-1", gin.H{"id": "ride-1", "rider_id": firstRider})
+
+	c, w := newCreateRideRequest(firstRider, "key-1")
+	h.CreateRide(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"ride-1"`)
+
+	// No drivers are indexed, so the second rider's request goes on to matching and fails there
+	c, w = newCreateRideRequest(secondRider, "key-1")
+	h.CreateRide(c)
+	assert.NotContains(t, w.Body.String(), "ride-1")
+}
+
+// TestCreateRide_ReplaysRideFromDatabase tests that a retry whose cached response has expired
+// returns the ride already created instead of claiming another driver
+func TestCreateRide_ReplaysRideFromDatabase(t *testing.T) {
+	riderID, driverID := uuid.New(), uuid.New()
+	rides := &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", RiderID: riderID, DriverID: &driverID, Status: ride.StatusAssigned, IdempotencyKey: "key-1"},
+	}}
+	h, client := newTestHandlers(t, rides)
+	ctx := context.Background()
+
+	otherDriverID := uuid.NewString()
+	indexTestDriver(t, client, otherDriverID, driver.VehicleEconomy, 12.9716, 77.5946)
+	require.NoError(t, client.SAdd(ctx, "drivers:available", otherDriverID).Err())
+
+	c, w := newCreateRideRequest(riderID.String(), "key-1")
+	h.CreateRide(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"id":"ride-1"`)
+	assert.Contains(t, w.Body.String(), driverID.String())
+	assert.True(t, client.SIsMember(ctx, "drivers:available", otherDriverID).Val(), "No driver is claimed")
+}
+
+// TestCreateRide_RejectsOutOfRangeCoordinates tests that impossible coordinates are refused before matching
+func TestCreateRide_RejectsOutOfRangeCoordinates(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	body := `{"rider_id":"` + uuid.New().String() + `","pickup_latitude":500,"pickup_longitude":77.5946,` +
+		`"dropoff_latitude":12.9352,"dropoff_longitude":77.6245,"vehicle_type":"economy"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CreateRide(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), apperrors.ErrInvalidCoordinates.Message)
+}
+
+// TestCreateRide_AcceptsZeroCoordinates tests that a pickup on the equator and prime meridian isn't rejected as missing
+func TestCreateRide_AcceptsZeroCoordinates(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	body := `{"rider_id":"` + uuid.New().String() + `","pickup_latitude":0,"pickup_longitude":0,` +
+		`"dropoff_latitude":0.02,"dropoff_longitude":0.01,"vehicle_type":"economy"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CreateRide(c)
+
+	// No drivers are online, so the ride is accepted and left searching
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"requested"`)
+}
+
+// TestCancelRide_ChargesFeeAfterGracePeriod tests that a late cancellation is charged and the driver freed
+func TestCancelRide_ChargesFeeAfterGracePeriod(t *testing.T) {
+	driverID := uuid.New()
+	assignedAt := time.Now().Add(-5 * time.Minute)
+	acceptedAt := assignedAt.Add(time.Minute)
+	rides := &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", RiderID: uuid.New(), DriverID: &driverID, Status: ride.StatusAccepted,
+			AssignedAt: &assignedAt, AcceptedAt: &acceptedAt},
+	}}
+	h, client := newTestHandlers(t, rides)
+	ctx := context.Background()
+	client.Set(ctx, "driver:"+driverID.String()+":current_ride", "ride-1", 0)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "ride-1"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides/ride-1/cancel", bytes.NewBufferString(`{"reason":"changed plans"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CancelRide(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, rides.updated, 1)
+	cancelled := rides.updated[0]
+	assert.Equal(t, ride.StatusCancelled, cancelled.Status)
+	assert.Equal(t, "changed plans", cancelled.CancellationReason)
+	require.NotNil(t, cancelled.CancellationFee)
+	assert.Equal(t, 50.0, *cancelled.CancellationFee)
+
+	available, err := client.SIsMember(ctx, "drivers:available", driverID.String()).Result()
+	require.NoError(t, err)
+	assert.True(t, available)
+}
+
+// TestCancelRide_RejectsCompletedRide tests that a finished ride can't be cancelled
+func TestCancelRide_RejectsCompletedRide(t *testing.T) {
+	rides := &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", RiderID: uuid.New(), Status: ride.StatusCompleted},
+	}}
+	h, _ := newTestHandlers(t, rides)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "ride-1"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides/ride-1/cancel", nil)
+
+	h.CancelRide(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Empty(t, rides.updated)
+}
+
+// TestCancelRide_ConflictWhenStatusChanged tests that a cancel losing a race with another
+// transition is a conflict rather than overwriting the newer status
+func TestCancelRide_ConflictWhenStatusChanged(t *testing.T) {
+	rides := &fakeRides{
+		rides: map[string]*ride.Ride{
+			"ride-1": {ID: "ride-1", RiderID: uuid.New(), Status: ride.StatusAccepted},
+		},
+		updateErr: ride.ErrStatusChanged,
+	}
+	h, _ := newTestHandlers(t, rides)
+	events := &fakeEvents{}
+	h.Events = events
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "ride-1"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides/ride-1/cancel", nil)
+
+	h.CancelRide(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Empty(t, events.events)
+}
+
+// TestCreateRide_RejectsTooManySeats tests that a seat count no vehicle carries is refused before matching
+func TestCreateRide_RejectsTooManySeats(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	body := `{"rider_id":"` + uuid.New().String() + `","pickup_latitude":12.9716,"pickup_longitude":77.5946,` +
+		`"dropoff_latitude":12.9352,"dropoff_longitude":77.6245,"vehicle_type":"economy","seats":12}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CreateRide(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "seats")
+}
+
+// TestCreateRide_JoinsDriverPool tests that a pool ride shares a driver already carrying a
+// pool rider heading the same way without moving the driver off that ride
+func TestCreateRide_JoinsDriverPool(t *testing.T) {
+	h, client := newTestHandlers(t, &fakeRides{})
+	h.Config.Matching.PoolCapacity = 2
+	h.Config.Matching.PoolMaxDetourKM = 2
+	ctx := context.Background()
+
+	driverID := uuid.New().String()
+	indexTestDriver(t, client, driverID, driver.VehicleEconomy, 12.9716, 77.5946)
+	require.NoError(t, client.Set(ctx, "driver:"+driverID+":current_ride", "ride-1", 0).Err())
+	require.NoError(t, matching.JoinPool(ctx, client, driverID, "ride-1", 12.9360, 77.6250))
+
+	body := `{"rider_id":"` + uuid.New().String() + `","pickup_latitude":12.9716,"pickup_longitude":77.5946,` +
+		`"dropoff_latitude":12.9352,"dropoff_longitude":77.6245,"vehicle_type":"economy","pool":true}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CreateRide(c)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"driver_id":"`+driverID+`"`)
+	assert.Contains(t, w.Body.String(), `"pooled":true`)
+
+	aboard, err := client.HLen(ctx, matching.PoolKey(driverID)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), aboard)
+	assert.Equal(t, "ride-1", client.Get(ctx, "driver:"+driverID+":current_ride").Val(),
+		"the driver keeps following the rider already aboard")
+}
+
+// TestCreateRide_RejectsMultiSeatPool tests that a pool ride can't book more than one seat
+func TestCreateRide_RejectsMultiSeatPool(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	body := `{"rider_id":"` + uuid.New().String() + `","pickup_latitude":12.9716,"pickup_longitude":77.5946,` +
+		`"dropoff_latitude":12.9352,"dropoff_longitude":77.6245,"vehicle_type":"economy","seats":2,"pool":true}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	h.CreateRide(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "single seat")
+}
+
+// blockingRides holds Create open until release is closed, closing entered once it is reached
+type blockingRides struct {
+	*fakeRides
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingRides) Create(ctx context.Context, rd *ride.Ride) error {
+	close(b.entered)
+	<-b.release
+	return nil
+}
+
+// TestCreateRide_ConcurrentRequestsForOneRider tests that a second request from a rider whose
+// first is still being created is turned away instead of matching another driver
+func TestCreateRide_ConcurrentRequestsForOneRider(t *testing.T) {
+	rides := &blockingRides{fakeRides: &fakeRides{}, entered: make(chan struct{}), release: make(chan struct{})}
+	h, client := newTestHandlers(t, rides)
+	ctx := context.Background()
+
+	for _, lat := range []float64{12.9716, 12.9720} {
+		driverID := uuid.New().String()
+		indexTestDriver(t, client, driverID, driver.VehicleEconomy, lat, 77.5946)
+		require.NoError(t, client.SAdd(ctx, "drivers:available", driverID).Err())
+	}
+
+	body := `{"rider_id":"` + uuid.New().String() + `","pickup_latitude":12.9716,"pickup_longitude":77.5946,` +
+		`"dropoff_latitude":12.9352,"dropoff_longitude":77.6245,"vehicle_type":"economy"}`
+	createRide := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		h.CreateRide(c)
+		return w
+	}
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- createRide() }()
+	<-rides.entered
+
+	second := createRide()
+	assert.Equal(t, http.StatusConflict, second.Code)
+	assert.Contains(t, second.Body.String(), "already in progress")
+
+	close(rides.release)
+	w := <-first
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	available, err := client.SCard(ctx, "drivers:available").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), available, "only the first request should have claimed a driver")
+}
+
+// TestCancelRide_PoolRiderLeavesDriverBusy tests that cancelling one pool ride keeps the
+// driver busy with the pool riders still aboard
+func TestCancelRide_PoolRiderLeavesDriverBusy(t *testing.T) {
+	driverID := uuid.New()
+	rides := &fakeRides{rides: map[string]*ride.Ride{
+		"ride-2": {ID: "ride-2", RiderID: uuid.New(), DriverID: &driverID, Status: ride.StatusAssigned, Pool: true},
+	}}
+	h, client := newTestHandlers(t, rides)
+	ctx := context.Background()
+	client.Set(ctx, "driver:"+driverID.String()+":current_ride", "ride-2", 0)
+	require.NoError(t, matching.JoinPool(ctx, client, driverID.String(), "ride-1", 12.9352, 77.6245))
+	require.NoError(t, matching.JoinPool(ctx, client, driverID.String(), "ride-2", 12.9400, 77.6200))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "ride-2"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides/ride-2/cancel", nil)
+
+	h.CancelRide(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, client.SIsMember(ctx, "drivers:available", driverID.String()).Val())
+	assert.Equal(t, "ride-1", client.Get(ctx, "driver:"+driverID.String()+":current_ride").Val())
+	assert.Equal(t, []string{"ride-1"}, client.HKeys(ctx, matching.PoolKey(driverID.String())).Val())
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRideTimelineRequest builds a GET /v1/rides/:id/timeline context for the given caller
+func newRideTimelineRequest(rideID, userID, userType string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/rides/"+rideID+"/timeline", nil)
+	c.Params = gin.Params{{Key: "id", Value: rideID}}
+	c.Set("user_id", userID)
+	c.Set("user_type", userType)
+	return c, w
+}
+
+// TestCancelRide_RecordsTimeline tests that a cancellation lands on the ride's timeline with
+// the caller as actor, and that the rider can read it back
+func TestCancelRide_RecordsTimeline(t *testing.T) {
+	riderID := uuid.New()
+	h, _ := newTestHandlers(t, &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", RiderID: riderID, Status: ride.StatusRequested},
+	}})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "ride-1"}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/rides/ride-1/cancel", bytes.NewBufferString(`{"reason":"too slow"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", riderID.String())
+	c.Set("user_type", "rider")
+	h.CancelRide(c)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	c, w = newRideTimelineRequest("ride-1", riderID.String(), "rider")
+	h.GetRideTimeline(c)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Events []ride.Event `json:"events"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, ride.EventCancelled, resp.Events[0].Type)
+	assert.Equal(t, "rider", resp.Events[0].ActorType)
+	assert.Equal(t, riderID.String(), resp.Events[0].ActorID)
+	assert.Equal(t, "too slow", resp.Events[0].Details["reason"])
+}
+
+// TestGetRideTimeline_Access tests that riders and drivers only see their own rides'
+// timelines while operators see any
+func TestGetRideTimeline_Access(t *testing.T) {
+	riderID, driverID := uuid.New(), uuid.New()
+	h, _ := newTestHandlers(t, &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", RiderID: riderID, DriverID: &driverID, Status: ride.StatusAccepted},
+	}})
+
+	tests := []struct {
+		name     string
+		userID   string
+		userType string
+		want     int
+	}{
+		{"own rider", riderID.String(), "rider", http.StatusOK},
+		{"other rider", uuid.NewString(), "rider", http.StatusForbidden},
+		{"assigned driver", driverID.String(), "driver", http.StatusOK},
+		{"other driver", uuid.NewString(), "driver", http.StatusForbidden},
+		{"dashboard", "dash-1", "dashboard", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newRideTimelineRequest("ride-1", tt.userID, tt.userType)
+			h.GetRideTimeline(c)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+
+	c, w := newRideTimelineRequest("ride-missing", riderID.String(), "rider")
+	h.GetRideTimeline(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
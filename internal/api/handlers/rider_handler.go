@@ -2,14 +2,27 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
 	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/google/uuid"
 )
 
 // GetRandomRider handles GET /v1/riders/random (for testing)
 func (h *Handlers) GetRandomRider(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
 	ctx := context.Background()
 
 	// Get a random rider
@@ -19,13 +32,14 @@ func (h *Handlers) GetRandomRider(c *gin.Context) {
 	err := h.DB.QueryRowContext(ctx, `
 		SELECT id, name, email, rating
 		FROM riders
+		WHERE deleted_at IS NULL
 		ORDER BY RANDOM()
 		LIMIT 1
 	`).Scan(&riderID, &name, &email, &rating)
 
 	if err != nil {
-		h.Logger.Error("Failed to get random rider", logger.Err(err))
-		c.JSON(http.StatusNotFound, gin.H{"error": "No riders available"})
+		log.Error("Failed to get random rider", logger.Err(err))
+		respondError(c, apperrors.NotFound("No riders available", err))
 		return
 	}
 
@@ -36,3 +50,402 @@ func (h *Handlers) GetRandomRider(c *gin.Context) {
 		"rating": rating,
 	})
 }
+
+// GetRiderRides handles GET /v1/riders/:id/rides
+func (h *Handlers) GetRiderRides(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	riderID := c.Param("id")
+	ctx := context.Background()
+
+	if !h.canAccessRider(c, riderID) {
+		respondError(c, apperrors.Forbidden("Riders may only view their own rides", nil))
+		return
+	}
+
+	limit, offset, err := parsePagination(c, 20, 100)
+	if err != nil {
+		respondError(c, apperrors.BadRequest("Invalid pagination parameters", err))
+		return
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT r.id, r.status, r.vehicle_type,
+		       r.pickup_latitude, r.pickup_longitude,
+		       r.dropoff_latitude, r.dropoff_longitude,
+		       r.estimated_fare, r.requested_at, r.completed_at, r.cancelled_at,
+		       r.driver_id, d.name as driver_name,
+		       t.total_fare, t.distance_km
+		FROM rides r
+		LEFT JOIN drivers d ON r.driver_id = d.id
+		LEFT JOIN trips t ON t.ride_id = r.id AND t.status = 'completed'
+		WHERE r.rider_id = $1
+		ORDER BY r.requested_at DESC
+		LIMIT $2 OFFSET $3
+	`, riderID, limit, offset)
+
+	if err != nil {
+		log.Error("Failed to query rider rides", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to get rides", err))
+		return
+	}
+	defer rows.Close()
+
+	rides := []gin.H{}
+	for rows.Next() {
+		var (
+			id, status, vehicleType                string
+			pickupLat, pickupLng, dropLat, dropLng float64
+			estimatedFare, totalFare, distanceKm   sql.NullFloat64
+			requestedAt                            time.Time
+			completedAt, cancelledAt               sql.NullTime
+			driverID, driverName                   sql.NullString
+		)
+
+		if err := rows.Scan(&id, &status, &vehicleType,
+			&pickupLat, &pickupLng, &dropLat, &dropLng,
+			&estimatedFare, &requestedAt, &completedAt, &cancelledAt,
+			&driverID, &driverName, &totalFare, &distanceKm); err != nil {
+			log.Error("Failed to scan ride row", logger.Err(err), logger.String("rider_id", riderID))
+			respondError(c, apperrors.Internal("Failed to get rides", err))
+			return
+		}
+
+		ride := gin.H{
+			"id":                id,
+			"status":            status,
+			"vehicle_type":      vehicleType,
+			"pickup_latitude":   pickupLat,
+			"pickup_longitude":  pickupLng,
+			"dropoff_latitude":  dropLat,
+			"dropoff_longitude": dropLng,
+			"requested_at":      requestedAt,
+		}
+
+		if driverID.Valid {
+			ride["driver_id"] = driverID.String
+			ride["driver_name"] = driverName.String
+		}
+		if estimatedFare.Valid {
+			ride["estimated_fare"] = estimatedFare.Float64
+		}
+		if totalFare.Valid {
+			ride["fare"] = totalFare.Float64
+		}
+		if distanceKm.Valid {
+			ride["distance_km"] = distanceKm.Float64
+		}
+		if completedAt.Valid {
+			ride["completed_at"] = completedAt.Time
+		}
+		if cancelledAt.Valid {
+			ride["cancelled_at"] = cancelledAt.Time
+		}
+
+		rides = append(rides, ride)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("Failed to read rider rides", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to get rides", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rider_id": riderID,
+		"rides":    rides,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// DeleteRider handles DELETE /v1/riders/:id
+// Soft-deletes the rider; their rides, payments and wallet ledger are kept.
+// Riders may only delete themselves, and not while a ride is in progress.
+func (h *Handlers) DeleteRider(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	riderID := c.Param("id")
+	ctx := context.Background()
+
+	if !h.canAccessRider(c, riderID) {
+		respondError(c, apperrors.Forbidden("Riders may only delete their own account", nil))
+		return
+	}
+	id, err := uuid.Parse(riderID)
+	if err != nil {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+
+	activeRide, err := h.Rides.GetActiveRideByRider(ctx, id)
+	if err == nil {
+		respondError(c, apperrors.Conflict(fmt.Sprintf("Rider has a ride in progress (%s)", activeRide.ID), nil))
+		return
+	}
+	if !errors.Is(err, ride.ErrRideNotFound) {
+		log.Error("Failed to check active rides", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to delete rider", err))
+		return
+	}
+
+	result, err := h.DB.ExecContext(ctx, `
+		UPDATE riders SET deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, riderID)
+	if err != nil {
+		log.Error("Failed to delete rider", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to delete rider", err))
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+
+	log.Info("Rider deleted", logger.String("rider_id", riderID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"rider_id": riderID,
+		"status":   "deleted",
+	})
+}
+
+// GetRiderActiveRide handles GET /v1/riders/:id/active-ride
+// Lets a rider reopening the app resume tracking: returns the in-progress ride with the
+// driver's details and last reported position, or 204 when there is none.
+func (h *Handlers) GetRiderActiveRide(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	riderID := c.Param("id")
+	ctx := context.Background()
+
+	// Dashboards track any rider's live ride
+	if middleware.GetUserType(c) != auth.UserTypeDashboard && !h.canAccessRider(c, riderID) {
+		respondError(c, apperrors.Forbidden("Riders may only view their own rides", nil))
+		return
+	}
+	id, err := uuid.Parse(riderID)
+	if err != nil {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+
+	rd, err := h.Rides.GetActiveRideByRider(ctx, id)
+	if errors.Is(err, ride.ErrRideNotFound) {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		log.Error("Failed to get active ride", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to get active ride", err))
+		return
+	}
+
+	response := gin.H{"ride": rd}
+	if rd.DriverID != nil {
+		response["driver"] = h.activeRideDriver(ctx, log, *rd.DriverID)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// activeRideDriver describes the driver on a rider's active ride, with their live position
+// from the geo index when they have reported one
+func (h *Handlers) activeRideDriver(ctx context.Context, log *logger.Logger, driverID uuid.UUID) gin.H {
+	info := gin.H{"id": driverID}
+
+	d, err := h.Drivers.GetByID(ctx, driverID)
+	if err != nil {
+		log.Warn("Failed to load driver for active ride", logger.Err(err), logger.String("driver_id", driverID.String()))
+	} else {
+		info["name"] = d.Name
+		info["phone"] = d.Phone
+		info["rating"] = d.Rating
+		info["vehicle_type"] = d.VehicleType
+	}
+
+	positions, err := h.Redis.GeoPos(ctx, "drivers:locations", driverID.String()).Result()
+	if err == nil && len(positions) > 0 && positions[0] != nil {
+		info["location"] = gin.H{
+			"latitude":  positions[0].Latitude,
+			"longitude": positions[0].Longitude,
+		}
+	}
+	return info
+}
+
+// AddFavoriteDriver handles POST /v1/riders/:id/favorites/:driverId
+// Marks a driver as one of the rider's favorites, so matching prefers them over a driver only
+// marginally closer. Adding a favorite twice is a no-op.
+func (h *Handlers) AddFavoriteDriver(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	riderID, driverID := c.Param("id"), c.Param("driverId")
+	ctx := context.Background()
+
+	if !h.canAccessRider(c, riderID) {
+		respondError(c, apperrors.Forbidden("Riders may only manage their own favorites", nil))
+		return
+	}
+	if _, err := uuid.Parse(riderID); err != nil {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+	driverUUID, err := uuid.Parse(driverID)
+	if err != nil {
+		respondError(c, apperrors.ErrDriverNotFound)
+		return
+	}
+
+	if _, err := h.Drivers.GetByID(ctx, driverUUID); errors.Is(err, driver.ErrDriverNotFound) {
+		respondError(c, apperrors.ErrDriverNotFound)
+		return
+	} else if err != nil {
+		log.Error("Failed to get driver", logger.Err(err), logger.String("driver_id", driverID))
+		respondError(c, apperrors.Internal("Failed to add favorite driver", err))
+		return
+	}
+
+	var riderExists bool
+	err = h.DB.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM riders WHERE id = $1 AND deleted_at IS NULL)
+	`, riderID).Scan(&riderExists)
+	if err != nil {
+		log.Error("Failed to get rider", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to add favorite driver", err))
+		return
+	}
+	if !riderExists {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+
+	result, err := h.DB.ExecContext(ctx, `
+		INSERT INTO rider_favorite_drivers (rider_id, driver_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, riderID, driverID)
+	if err != nil {
+		log.Error("Failed to add favorite driver", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to add favorite driver", err))
+		return
+	}
+
+	// Matching reads favorites from Redis and reloads them from Postgres when the set is
+	// incomplete, so a failed write only delays the preference
+	if err := h.Redis.SAdd(ctx, matching.FavoriteDriversKey(riderID), driverID).Err(); err != nil {
+		log.Warn("Failed to cache favorite driver", logger.Err(err), logger.String("rider_id", riderID))
+	}
+
+	status := http.StatusOK
+	if n, _ := result.RowsAffected(); n > 0 {
+		status = http.StatusCreated
+		log.Info("Favorite driver added", logger.String("rider_id", riderID), logger.String("driver_id", driverID))
+	}
+
+	c.JSON(status, gin.H{
+		"rider_id":  riderID,
+		"driver_id": driverID,
+		"favorite":  true,
+	})
+}
+
+// RemoveFavoriteDriver handles DELETE /v1/riders/:id/favorites/:driverId
+func (h *Handlers) RemoveFavoriteDriver(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	riderID, driverID := c.Param("id"), c.Param("driverId")
+	ctx := context.Background()
+
+	if !h.canAccessRider(c, riderID) {
+		respondError(c, apperrors.Forbidden("Riders may only manage their own favorites", nil))
+		return
+	}
+	if _, err := uuid.Parse(riderID); err != nil {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+	if _, err := uuid.Parse(driverID); err != nil {
+		respondError(c, apperrors.ErrDriverNotFound)
+		return
+	}
+
+	_, err := h.DB.ExecContext(ctx, `
+		DELETE FROM rider_favorite_drivers WHERE rider_id = $1 AND driver_id = $2
+	`, riderID, driverID)
+	if err != nil {
+		log.Error("Failed to remove favorite driver", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to remove favorite driver", err))
+		return
+	}
+	// A driver left in the cached set would keep being preferred, so this has to succeed
+	if err := h.Redis.SRem(ctx, matching.FavoriteDriversKey(riderID), driverID).Err(); err != nil {
+		log.Error("Failed to uncache favorite driver", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to remove favorite driver", err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetFavoriteDrivers handles GET /v1/riders/:id/favorites
+// Lists the rider's favorite drivers, most recently added first.
+func (h *Handlers) GetFavoriteDrivers(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	riderID := c.Param("id")
+	ctx := context.Background()
+
+	if !h.canAccessRider(c, riderID) {
+		respondError(c, apperrors.Forbidden("Riders may only view their own favorites", nil))
+		return
+	}
+	if _, err := uuid.Parse(riderID); err != nil {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT d.id, d.name, d.vehicle_type, d.rating, f.created_at
+		FROM rider_favorite_drivers f
+		JOIN drivers d ON d.id = f.driver_id AND d.deleted_at IS NULL
+		WHERE f.rider_id = $1
+		ORDER BY f.created_at DESC
+	`, riderID)
+	if err != nil {
+		log.Error("Failed to query favorite drivers", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to get favorite drivers", err))
+		return
+	}
+	defer rows.Close()
+
+	favorites := []gin.H{}
+	for rows.Next() {
+		var id, name, vehicleType string
+		var rating float64
+		var addedAt time.Time
+		if err := rows.Scan(&id, &name, &vehicleType, &rating, &addedAt); err != nil {
+			log.Error("Failed to scan favorite driver", logger.Err(err), logger.String("rider_id", riderID))
+			respondError(c, apperrors.Internal("Failed to get favorite drivers", err))
+			return
+		}
+		favorites = append(favorites, gin.H{
+			"driver_id":    id,
+			"name":         name,
+			"vehicle_type": vehicleType,
+			"rating":       rating,
+			"added_at":     addedAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("Failed to read favorite drivers", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to get favorite drivers", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rider_id":  riderID,
+		"favorites": favorites,
+		"count":     len(favorites),
+	})
+}
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/repository/postgres"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDeleteRiderRequest builds a DELETE /v1/riders/:id context for the given caller
+func newDeleteRiderRequest(riderID, userID, userType string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/v1/riders/"+riderID, nil)
+	c.Params = gin.Params{{Key: "id", Value: riderID}}
+	c.Set("user_id", userID)
+	c.Set("user_type", userType)
+	return c, w
+}
+
+// TestDeleteRider_SoftDeletes tests that deleting a rider only stamps deleted_at, and that
+// deleting them again is a 404
+func TestDeleteRider_SoftDeletes(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	riderID := "5d1e7a40-0000-4000-8000-000000000001"
+	mock.ExpectExec("UPDATE riders SET deleted_at").
+		WithArgs(riderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE riders SET deleted_at").
+		WithArgs(riderID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	c, w := newDeleteRiderRequest(riderID, riderID, "rider")
+	h.DeleteRider(c)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	c, w = newDeleteRiderRequest(riderID, "ops-1", "admin")
+	h.DeleteRider(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestDeleteRider_ForbidsOtherRiders tests that a rider can't delete someone else
+func TestDeleteRider_ForbidsOtherRiders(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	c, w := newDeleteRiderRequest("5d1e7a40-0000-4000-8000-000000000001", "5d1e7a40-0000-4000-8000-000000000002", "rider")
+	h.DeleteRider(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// newRiderRidesRequest builds a GET /v1/riders/:id/rides context for the given caller
+func newRiderRidesRequest(riderID, userID, userType string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/riders/"+riderID+"/rides", nil)
+	c.Params = gin.Params{{Key: "id", Value: riderID}}
+	c.Set("user_id", userID)
+	c.Set("user_type", userType)
+	return c, w
+}
+
+// TestGetRiderRides_ForbidsOthers tests that only the rider or an admin sees a ride history
+func TestGetRiderRides_ForbidsOthers(t *testing.T) {
+	for _, userType := range []string{"rider", "driver", "dashboard"} {
+		t.Run(userType, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+
+			c, w := newRiderRidesRequest("rider-1", "someone-else", userType)
+			h.GetRiderRides(c)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
+
+// TestGetRiderRides_FailsOnBadRows tests that an unreadable ride fails the request instead of
+// silently dropping out of the history
+func TestGetRiderRides_FailsOnBadRows(t *testing.T) {
+	columns := []string{"id", "status", "vehicle_type", "pickup_latitude", "pickup_longitude",
+		"dropoff_latitude", "dropoff_longitude", "estimated_fare", "requested_at", "completed_at",
+		"cancelled_at", "driver_id", "driver_name", "total_fare", "distance_km"}
+	row := func(rows *sqlmock.Rows, status interface{}) *sqlmock.Rows {
+		return rows.AddRow("ride-1", status, "economy", 12.97, 77.59, 12.93, 77.62,
+			250.0, time.Now(), nil, nil, nil, nil, nil, nil)
+	}
+
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+	}{
+		{"scan error", row(row(sqlmock.NewRows(columns), "completed"), nil)},
+		{"row error", row(row(sqlmock.NewRows(columns), "completed"), "completed").
+			RowError(1, errors.New("connection reset"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			h.DB = db
+
+			mock.ExpectQuery("FROM rides r").WillReturnRows(tt.rows)
+
+			c, w := newRiderRidesRequest("rider-1", "rider-1", "rider")
+			h.GetRiderRides(c)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// newActiveRideRequest builds a GET /v1/riders/:id/active-ride context for that rider
+func newActiveRideRequest(riderID string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/riders/"+riderID+"/active-ride", nil)
+	c.Params = gin.Params{{Key: "id", Value: riderID}}
+	c.Set("user_id", riderID)
+	c.Set("user_type", "rider")
+	return c, w
+}
+
+// TestGetRiderActiveRide_IncludesDriverLocation tests that the active ride comes back with
+// the driver's profile and live position
+func TestGetRiderActiveRide_IncludesDriverLocation(t *testing.T) {
+	riderID, driverID := uuid.New(), uuid.New()
+	h, client := newTestHandlers(t, &fakeRides{rides: map[string]*ride.Ride{
+		"ride-old": {ID: "ride-old", RiderID: riderID, Status: ride.StatusCompleted},
+		"ride-1":   {ID: "ride-1", RiderID: riderID, DriverID: &driverID, Status: ride.StatusAccepted},
+	}})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.Drivers = postgres.NewDriverRepository(db)
+
+	mock.ExpectQuery("FROM drivers WHERE id = \\$1").
+		WithArgs(driverID).
+		WillReturnRows(sqlmock.NewRows(driverRowColumns).
+			AddRow(driverID, "Arjun", "arjun@example.com", "+919800000003", "busy", "premium", nil, nil, 4.8, 40, time.Now(), time.Now()))
+	require.NoError(t, client.GeoAdd(context.Background(), "drivers:locations",
+		&redis.GeoLocation{Name: driverID.String(), Latitude: 12.97, Longitude: 77.59}).Err())
+
+	c, w := newActiveRideRequest(riderID.String())
+	h.GetRiderActiveRide(c)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"id":"ride-1"`)
+	assert.Contains(t, w.Body.String(), `"name":"Arjun"`)
+	assert.Contains(t, w.Body.String(), `"location":{"latitude":12.97`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetRiderActiveRide_NoContent tests that a rider with no ride in progress gets a 204
+func TestGetRiderActiveRide_NoContent(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	c, w := newActiveRideRequest(uuid.New().String())
+	h.GetRiderActiveRide(c)
+	c.Writer.WriteHeaderNow()
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+// newFavoriteRequest builds a /v1/riders/:id/favorites/:driverId context for that rider
+func newFavoriteRequest(method, riderID, driverID string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/v1/riders/"+riderID+"/favorites/"+driverID, nil)
+	c.Params = gin.Params{{Key: "id", Value: riderID}, {Key: "driverId", Value: driverID}}
+	c.Set("user_id", riderID)
+	c.Set("user_type", "rider")
+	return c, w
+}
+
+// TestAddFavoriteDriver_SavesAndCaches tests that a favorite is stored in Postgres and mirrored
+// into the Redis set matching reads, and that adding it again is not a new favorite
+func TestAddFavoriteDriver_SavesAndCaches(t *testing.T) {
+	h, client := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+	h.Drivers = postgres.NewDriverRepository(db)
+
+	riderID, driverID := uuid.New().String(), uuid.New()
+	for _, inserted := range []int64{1, 0} {
+		mock.ExpectQuery("FROM drivers WHERE id = \\$1").
+			WithArgs(driverID).
+			WillReturnRows(sqlmock.NewRows(driverRowColumns).
+				AddRow(driverID, "Arjun", "arjun@example.com", "+919800000003", "online", "economy", nil, nil, 4.9, 40, time.Now(), time.Now()))
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(riderID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO rider_favorite_drivers").
+			WithArgs(riderID, driverID.String()).
+			WillReturnResult(sqlmock.NewResult(0, inserted))
+	}
+
+	c, w := newFavoriteRequest(http.MethodPost, riderID, driverID.String())
+	h.AddFavoriteDriver(c)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	c, w = newFavoriteRequest(http.MethodPost, riderID, driverID.String())
+	h.AddFavoriteDriver(c)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	favorites, err := client.SMembers(context.Background(), matching.FavoriteDriversKey(riderID)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{driverID.String()}, favorites)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestAddFavoriteDriver_UnknownDriver tests that only existing drivers can be favorited
+func TestAddFavoriteDriver_UnknownDriver(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.Drivers = postgres.NewDriverRepository(db)
+
+	mock.ExpectQuery("FROM drivers WHERE id = \\$1").WillReturnRows(sqlmock.NewRows(driverRowColumns))
+
+	c, w := newFavoriteRequest(http.MethodPost, uuid.New().String(), uuid.New().String())
+	h.AddFavoriteDriver(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestRemoveFavoriteDriver_ForbidsOtherRiders tests that a rider can't edit someone else's favorites
+func TestRemoveFavoriteDriver_ForbidsOtherRiders(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+
+	c, w := newFavoriteRequest(http.MethodDelete, uuid.New().String(), uuid.New().String())
+	c.Set("user_id", uuid.New().String())
+	h.RemoveFavoriteDriver(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// TestRemoveFavoriteDriver_FailsWhenCacheNotUpdated tests that a removal the matching cache
+// didn't take is reported, since the driver would otherwise stay preferred
+func TestRemoveFavoriteDriver_FailsWhenCacheNotUpdated(t *testing.T) {
+	h, client := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	riderID, driverID := uuid.New().String(), uuid.New().String()
+	mock.ExpectExec("DELETE FROM rider_favorite_drivers").
+		WithArgs(riderID, driverID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// A key of the wrong type makes SREM fail
+	require.NoError(t, client.Set(context.Background(), matching.FavoriteDriversKey(riderID), "x", 0).Err())
+
+	c, w := newFavoriteRequest(http.MethodDelete, riderID, driverID)
+	h.RemoveFavoriteDriver(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetFavoriteDrivers_FailsOnBadRows tests that an unreadable favorite fails the request
+// instead of silently dropping out of the list
+func TestGetFavoriteDrivers_FailsOnBadRows(t *testing.T) {
+	columns := []string{"id", "name", "vehicle_type", "rating", "created_at"}
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+	}{
+		{"scan error", sqlmock.NewRows(columns).AddRow("driver-1", "Arjun", "economy", "great", time.Now())},
+		{"row error", sqlmock.NewRows(columns).
+			AddRow("driver-1", "Arjun", "economy", 4.9, time.Now()).
+			AddRow("driver-2", "Meera", "premium", 4.7, time.Now()).
+			RowError(1, errors.New("connection reset"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			h.DB = db
+
+			riderID := uuid.New().String()
+			mock.ExpectQuery("FROM rider_favorite_drivers f").WillReturnRows(tt.rows)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/v1/riders/"+riderID+"/favorites", nil)
+			c.Params = gin.Params{{Key: "id", Value: riderID}}
+			c.Set("user_id", riderID)
+			c.Set("user_type", "rider")
+			h.GetFavoriteDrivers(c)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/service/eta"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	"github.com/gocomet/ride-hailing/pkg/cache"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/gocomet/ride-hailing/pkg/websocket"
+	"github.com/google/uuid"
+)
+
+// scheduledDispatchLockTTL bounds how long one instance may hold a ride while matching it
+const scheduledDispatchLockTTL = time.Minute
+
+// GetScheduledRides handles GET /v1/rides/scheduled
+// Riders get their own bookings; admins name the rider with ?rider_id=.
+func (h *Handlers) GetScheduledRides(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	ctx := context.Background()
+
+	subject := middleware.GetUserID(c)
+	if middleware.GetUserType(c) == auth.UserTypeAdmin {
+		subject = c.Query("rider_id")
+	}
+	riderID, err := uuid.Parse(subject)
+	if err != nil {
+		respondError(c, apperrors.BadRequest("Invalid rider_id", err))
+		return
+	}
+
+	scheduled, err := h.Rides.ListScheduledByRider(ctx, riderID)
+	if err != nil {
+		log.Error("Failed to list scheduled rides", logger.Err(err), logger.String("rider_id", riderID.String()))
+		respondError(c, apperrors.Internal("Failed to get scheduled rides", err))
+		return
+	}
+
+	rides := []gin.H{}
+	for _, rd := range scheduled {
+		item := gin.H{
+			"id":                rd.ID,
+			"status":            rd.Status,
+			"vehicle_type":      rd.VehicleType,
+			"seats":             rd.Seats,
+			"pickup_latitude":   rd.PickupLatitude,
+			"pickup_longitude":  rd.PickupLongitude,
+			"dropoff_latitude":  rd.DropoffLatitude,
+			"dropoff_longitude": rd.DropoffLongitude,
+			"scheduled_at":      rd.ScheduledAt,
+			"requested_at":      rd.RequestedAt,
+		}
+		if rd.EstimatedFare != nil {
+			item["estimated_fare"] = *rd.EstimatedFare
+		}
+		rides = append(rides, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rider_id": riderID,
+		"rides":    rides,
+		"count":    len(rides),
+	})
+}
+
+// DispatchScheduledRide moves a due scheduled ride into matching. A ride that finds no
+// driver stays requested and is retried on later ticks until MatchGracePeriod after its
+// pickup time, when it is cancelled.
+func (h *Handlers) DispatchScheduledRide(ctx context.Context, rd *ride.Ride) error {
+	log := h.Logger.With(logger.String("ride_id", rd.ID))
+
+	// Every API instance runs the scheduler; only one may dispatch a given ride at a time
+	unlock, err := cache.Lock(ctx, h.Redis, fmt.Sprintf("ride:%s:dispatching", rd.ID), scheduledDispatchLockTTL)
+	if errors.Is(err, cache.ErrLockHeld) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock ride for dispatch: %w", err)
+	}
+	defer unlock()
+
+	if rd.Status == ride.StatusScheduled {
+		// Hold the booking until the rider's current ride has finished
+		active, err := h.Rides.GetActiveRideByRider(ctx, rd.RiderID)
+		if err == nil {
+			log.Info("Deferring scheduled ride until the rider's active ride ends", logger.String("active_ride_id", active.ID))
+			return nil
+		}
+		if !errors.Is(err, ride.ErrRideNotFound) {
+			return fmt.Errorf("failed to check active rides: %w", err)
+		}
+
+		if err := ride.Transition(rd.Status, ride.StatusRequested); err != nil {
+			return err
+		}
+		// The rider may have cancelled the booking since it was listed
+		err = h.Rides.UpdateStatus(ctx, rd.ID, ride.StatusScheduled, ride.StatusRequested)
+		if errors.Is(err, ride.ErrStatusChanged) {
+			log.Info("Scheduled ride changed before dispatch, skipping")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rd.Status = ride.StatusRequested
+		log.Info("Scheduled ride dispatched for matching")
+	}
+
+	h.Metrics.RecordRideRequested(string(rd.VehicleType))
+	matchStart := time.Now()
+	candidate, pooled, err := h.matchDriver(ctx, h.newMatchingService(log), rd)
+	h.Metrics.RecordMatchLatency(time.Since(matchStart))
+	if err != nil {
+		h.Metrics.RecordMatchFailed(string(rd.VehicleType))
+		if time.Now().Before(rd.ScheduledAt.Add(h.Config.Scheduling.MatchGracePeriod)) {
+			log.Info("No driver for scheduled ride yet, retrying on next tick", logger.Err(err))
+			return nil
+		}
+		return h.cancelUnmatchedRide(ctx, log, rd)
+	}
+	foundDriver := candidate.Driver
+	driverID := foundDriver.ID.String()
+
+	if err := h.Rides.AssignDriver(ctx, rd.ID, foundDriver.ID); err != nil {
+		h.releaseDriverFromRide(ctx, driverID, rd.ID)
+		return err
+	}
+	h.holdDriver(ctx, log, driverID, rd, pooled)
+	h.trackAssignment(ctx, log, rd.ID)
+
+	log.Info("Scheduled ride assigned", logger.String("driver_id", driverID))
+	h.recordRideEvent(ctx, log, rd.ID, ride.EventAssigned, ride.ActorSystem, "", map[string]interface{}{
+		"driver_id":    driverID,
+		"distance_km":  candidate.Distance,
+		"vehicle_type": foundDriver.VehicleType,
+		"pooled":       pooled,
+	})
+
+	surge := h.currentSurge(ctx, h.Pricing.RegionForCoordinates(rd.PickupLatitude, rd.PickupLongitude))
+	etaMinutes := h.ETA.ArrivalForDistance(candidate.Distance, surge)
+	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
+		driverData := map[string]interface{}{
+			"ride_id":           rd.ID,
+			"driver_id":         driverID,
+			"rider_id":          rd.RiderID.String(),
+			"pickup_latitude":   rd.PickupLatitude,
+			"pickup_longitude":  rd.PickupLongitude,
+			"dropoff_latitude":  rd.DropoffLatitude,
+			"dropoff_longitude": rd.DropoffLongitude,
+			"vehicle_type":      foundDriver.VehicleType,
+			"seats":             rd.Seats,
+			"distance":          fmt.Sprintf("%.2f km", candidate.Distance),
+			"distance_km":       candidate.Distance,
+		}
+		if rd.EstimatedFare != nil {
+			driverData["estimated_fare"] = *rd.EstimatedFare
+		}
+		wsHub.BroadcastToType("dashboard", map[string]interface{}{
+			"type": "ride_request",
+			"data": driverData,
+		})
+		wsHub.SendToUser(rd.RiderID.String(), map[string]interface{}{
+			"type": "ride_assigned",
+			"data": map[string]interface{}{
+				"ride_id":           rd.ID,
+				"driver_id":         driverID,
+				"driver_name":       foundDriver.Name,
+				"estimated_arrival": eta.Format(etaMinutes),
+			},
+		})
+	}
+
+	return nil
+}
+
+// cancelUnmatchedRide cancels a scheduled ride that found no driver in time and tells the rider
+func (h *Handlers) cancelUnmatchedRide(ctx context.Context, log *logger.Logger, rd *ride.Ride) error {
+	if err := ride.Transition(rd.Status, ride.StatusCancelled); err != nil {
+		return err
+	}
+
+	from := rd.Status
+	now := time.Now()
+	rd.Status = ride.StatusCancelled
+	rd.CancelledAt = &now
+	rd.CancellationReason = "No drivers available for scheduled pickup"
+	if err := h.Rides.Update(ctx, rd, from); err != nil {
+		return err
+	}
+
+	log.Warn("Scheduled ride cancelled, no driver found")
+	h.recordRideEvent(ctx, log, rd.ID, ride.EventCancelled, ride.ActorSystem, "", map[string]interface{}{
+		"reason": rd.CancellationReason,
+	})
+
+	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
+		wsHub.SendToUser(rd.RiderID.String(), map[string]interface{}{
+			"type": "ride_cancelled",
+			"data": map[string]interface{}{
+				"ride_id": rd.ID,
+				"reason":  rd.CancellationReason,
+			},
+		})
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDispatchScheduledRide_SkipsCancelledBooking tests that a booking the rider cancelled
+// after it was listed as due isn't brought back and dispatched
+func TestDispatchScheduledRide_SkipsCancelledBooking(t *testing.T) {
+	rides := &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", RiderID: uuid.New(), Status: ride.StatusCancelled},
+	}}
+	h, client := newTestHandlers(t, rides)
+	events := &fakeEvents{}
+	h.Events = events
+
+	scheduledAt := time.Now()
+	listed := &ride.Ride{ID: "ride-1", RiderID: rides.rides["ride-1"].RiderID, Status: ride.StatusScheduled, ScheduledAt: &scheduledAt}
+	require.NoError(t, h.DispatchScheduledRide(context.Background(), listed))
+
+	assert.Equal(t, ride.StatusCancelled, rides.rides["ride-1"].Status)
+	assert.Empty(t, events.events)
+	assert.Zero(t, client.Exists(context.Background(), "ride:ride-1:dispatching").Val(), "Lock is released")
+}
+
+// TestDispatchScheduledRide_LeavesOtherHoldersLock tests that a ride another instance is
+// dispatching is skipped without touching its lock
+func TestDispatchScheduledRide_LeavesOtherHoldersLock(t *testing.T) {
+	rides := &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", RiderID: uuid.New(), Status: ride.StatusScheduled},
+	}}
+	h, client := newTestHandlers(t, rides)
+	ctx := context.Background()
+	require.NoError(t, client.Set(ctx, "ride:ride-1:dispatching", "other-instance", time.Minute).Err())
+
+	require.NoError(t, h.DispatchScheduledRide(ctx, rides.rides["ride-1"]))
+
+	assert.Equal(t, ride.StatusScheduled, rides.rides["ride-1"].Status)
+	assert.Equal(t, "other-instance", client.Get(ctx, "ride:ride-1:dispatching").Val())
+}
+
+// TestGetScheduledRides_ListsCallersBookings tests that riders only ever see their own
+// bookings, whatever rider_id they pass, and that admins pick the rider
+func TestGetScheduledRides_ListsCallersBookings(t *testing.T) {
+	mine, theirs := uuid.New(), uuid.New()
+	scheduledAt := time.Now().Add(time.Hour)
+	rides := &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", RiderID: mine, Status: ride.StatusScheduled, ScheduledAt: &scheduledAt},
+		"ride-2": {ID: "ride-2", RiderID: theirs, Status: ride.StatusScheduled, ScheduledAt: &scheduledAt},
+	}}
+
+	tests := []struct {
+		name     string
+		userID   string
+		userType string
+		query    string
+		want     int
+		wantRide string
+	}{
+		{"rider", mine.String(), "rider", "", http.StatusOK, "ride-1"},
+		{"rider naming another rider", mine.String(), "rider", theirs.String(), http.StatusOK, "ride-1"},
+		{"admin naming a rider", "ops-1", "admin", theirs.String(), http.StatusOK, "ride-2"},
+		{"admin without rider_id", "ops-1", "admin", "", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, rides)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/v1/rides/scheduled?rider_id="+tt.query, nil)
+			c.Set("user_id", tt.userID)
+			c.Set("user_type", tt.userType)
+			h.GetScheduledRides(c)
+
+			require.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want != http.StatusOK {
+				return
+			}
+			var body struct {
+				Rides []struct {
+					ID string `json:"id"`
+				} `json:"rides"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			require.Len(t, body.Rides, 1)
+			assert.Equal(t, tt.wantRide, body.Rides[0].ID)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/dto"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/gocomet/ride-hailing/pkg/websocket"
+)
+
+// ListSurge handles GET /v1/admin/surge
+func (h *Handlers) ListSurge(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	regions, err := h.Pricing.ListSurgeMultipliers(context.Background())
+	if err != nil {
+		log.Error("Failed to list surge multipliers", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to list surge multipliers", err))
+		return
+	}
+
+	response := make([]gin.H, 0, len(regions))
+	for _, r := range regions {
+		entry := gin.H{
+			"region":     r.Region,
+			"multiplier": r.Multiplier,
+			"override":   r.Override,
+		}
+		if r.OverrideExpiresIn > 0 {
+			entry["override_expires_in_seconds"] = int(r.OverrideExpiresIn.Seconds())
+		}
+		response = append(response, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"regions": response,
+		"count":   len(response),
+	})
+}
+
+// SetSurge handles PUT /v1/admin/surge/:region
+// Sets a manual override that automatic recomputes leave alone until it expires.
+func (h *Handlers) SetSurge(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	region := c.Param("region")
+	if !h.Pricing.ValidRegion(region) {
+		respondError(c, apperrors.BadRequest(
+			fmt.Sprintf("Region must be a %d-character geohash", h.Pricing.RegionPrecision()), nil))
+		return
+	}
+
+	var req dto.SetSurgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+
+	minSurge, maxSurge := h.Config.Pricing.MinSurgeMultiplier, h.Config.Pricing.MaxSurgeMultiplier
+	if req.Multiplier < minSurge || req.Multiplier > maxSurge {
+		respondError(c, apperrors.BadRequest(
+			fmt.Sprintf("Multiplier must be between %.1f and %.1f", minSurge, maxSurge), nil))
+		return
+	}
+
+	ttl := h.Config.Pricing.SurgeOverrideTTL
+	if req.TTLMinutes != nil {
+		ttl = time.Duration(*req.TTLMinutes) * time.Minute
+	}
+
+	if err := h.Pricing.SetSurgeOverride(context.Background(), region, req.Multiplier, ttl); err != nil {
+		log.Error("Failed to set surge override", logger.Err(err), logger.String("region", region))
+		respondError(c, apperrors.Internal("Failed to set surge multiplier", err))
+		return
+	}
+
+	h.NewRelic.RecordSurgeMultiplier(region, req.Multiplier)
+
+	log.Info("Surge override set",
+		logger.String("region", region),
+		logger.Float64("multiplier", req.Multiplier),
+		logger.Duration("ttl", ttl),
+		logger.String("set_by", middleware.GetUserID(c)),
+	)
+
+	response := gin.H{
+		"region":     region,
+		"multiplier": req.Multiplier,
+		"override":   true,
+	}
+	if ttl > 0 {
+		response["override_expires_in_seconds"] = int(ttl.Seconds())
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// NotifySurgeChange tells clients subscribed to the region that its surge moved.
+// It implements pricing.SurgeNotifier for the surge worker.
+func (h *Handlers) NotifySurgeChange(region string, previous, multiplier float64) {
+	direction := "down"
+	if multiplier > previous {
+		direction = "up"
+	}
+
+	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
+		wsHub.BroadcastToRegion(region, websocket.Message{
+			Type: "surge_update",
+			Data: map[string]interface{}{
+				"region":              region,
+				"multiplier":          multiplier,
+				"previous_multiplier": previous,
+				"direction":           direction,
+			},
+		})
+	}
+
+	h.Logger.Info("Surge update broadcast",
+		logger.String("region", region),
+		logger.Float64("previous_multiplier", previous),
+		logger.Float64("multiplier", multiplier),
+	)
+}
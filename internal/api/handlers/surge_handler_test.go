@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/service/pricing"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSurgeTestHandlers returns handlers whose pricing allows surge between 1.0x and 3.0x
+func newSurgeTestHandlers(t *testing.T) *Handlers {
+	h, client := newTestHandlers(t, &fakeRides{})
+	h.Config.Pricing.MinSurgeMultiplier = 1.0
+	h.Config.Pricing.MaxSurgeMultiplier = 3.0
+	h.Config.Pricing.SurgeOverrideTTL = 30 * time.Minute
+	h.Pricing = pricing.NewService(client, pricing.Config{
+		MinSurgeMultiplier: 1.0,
+		MaxSurgeMultiplier: 3.0,
+		SurgeOverrideTTL:   30 * time.Minute,
+		RegionPrecision:    5,
+	})
+	return h
+}
+
+func putSurge(h *Handlers, region, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "region", Value: region}}
+	c.Request = httptest.NewRequest(http.MethodPut, "/v1/admin/surge/"+region, bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	h.SetSurge(c)
+	return w
+}
+
+// TestSetSurge_OverrideIsListed tests that an override is stored and listed with its expiry
+func TestSetSurge_OverrideIsListed(t *testing.T) {
+	h := newSurgeTestHandlers(t)
+
+	w := putSurge(h, "tdr1v", `{"multiplier":2.2,"ttl_minutes":10}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/admin/surge", nil)
+	h.ListSurge(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"count":1,"regions":[
+		{"region":"tdr1v","multiplier":2.2,"override":true,"override_expires_in_seconds":600}
+	]}`, w.Body.String())
+}
+
+// TestSetSurge_Validation tests that out-of-range multipliers and malformed regions are rejected
+func TestSetSurge_Validation(t *testing.T) {
+	h := newSurgeTestHandlers(t)
+
+	assert.Equal(t, http.StatusBadRequest, putSurge(h, "tdr1v", `{"multiplier":3.5}`).Code)
+	assert.Equal(t, http.StatusBadRequest, putSurge(h, "tdr1v", `{"multiplier":0.5}`).Code)
+	assert.Equal(t, http.StatusBadRequest, putSurge(h, "tdr1", `{"multiplier":2.0}`).Code)
+	assert.Equal(t, http.StatusBadRequest, putSurge(h, "tdr1a", `{"multiplier":2.0}`).Code)
+}
@@ -2,46 +2,66 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gocomet/ride-hailing/internal/api/dto"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/gocomet/ride-hailing/internal/service/routing"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
 	"github.com/gocomet/ride-hailing/pkg/logger"
 	"github.com/gocomet/ride-hailing/pkg/websocket"
 )
 
 // EndTrip handles POST /v1/trips/:id/end
 func (h *Handlers) EndTrip(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
 	rideID := c.Param("id")
 
 	var req dto.EndTripRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload", "details": err.Error()})
+		respondError(c, invalidPayload(err))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondError(c, err)
 		return
 	}
+	distanceKM, durationMinutes := *req.DistanceKm, *req.DurationMinutes
 
-	h.Logger.Info("Ending trip",
+	// Normalize the route taken to an encoded polyline
+	routePolyline := req.RoutePolyline
+	switch {
+	case routePolyline != "" && len(req.Breadcrumbs) > 0:
+		respondError(c, apperrors.BadRequest("Provide either route_polyline or breadcrumbs, not both", nil))
+		return
+	case routePolyline != "":
+		if _, err := routing.DecodePolyline(routePolyline); err != nil {
+			respondError(c, apperrors.BadRequest("Invalid route_polyline", err))
+			return
+		}
+	case len(req.Breadcrumbs) > 0:
+		points := make([]routing.Point, len(req.Breadcrumbs))
+		for i, p := range req.Breadcrumbs {
+			points[i] = routing.Point{Latitude: p.Latitude, Longitude: p.Longitude}
+		}
+		routePolyline = routing.EncodePolyline(points)
+	}
+
+	log.Info("Ending trip",
 		logger.String("ride_id", rideID),
 		logger.String("driver_id", req.DriverID),
-		logger.Float64("distance_km", req.DistanceKm),
-		logger.Int("duration_minutes", req.DurationMinutes),
-	)
-
-	// Calculate fare (simplified pricing)
-	baseFare := 50.0
-	perKmFare := 10.0
-	perMinuteFare := 2.0
-
-	distanceFare := req.DistanceKm * perKmFare
-	timeFare := float64(req.DurationMinutes) * perMinuteFare
-	totalFare := baseFare + distanceFare + timeFare
-
-	h.Logger.Info("Fare calculated",
-		logger.Float64("total_fare", totalFare),
-		logger.Float64("base_fare", baseFare),
-		logger.Float64("distance_fare", distanceFare),
-		logger.Float64("time_fare", timeFare),
+		logger.Float64("distance_km", distanceKM),
+		logger.Int("duration_minutes", durationMinutes),
+		logger.Int("waiting_minutes", req.WaitingMinutes),
 	)
 
 	ctx := context.Background()
@@ -49,12 +69,91 @@ func (h *Handlers) EndTrip(c *gin.Context) {
 	// Start PostgreSQL transaction
 	tx, err := h.DB.BeginTx(ctx, nil)
 	if err != nil {
-		h.Logger.Error("Failed to begin transaction", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		log.Error("Failed to begin transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Database error", err))
 		return
 	}
 	defer tx.Rollback()
 
+	// Only started rides may be completed
+	var status, riderID, vehicleType string
+	var driverID sql.NullString
+	var pool bool
+	var pickupLat, pickupLng, dropoffLat, dropoffLng float64
+	var quotedSurge sql.NullFloat64
+	var startedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, driver_id, rider_id, vehicle_type, pool, pickup_latitude, pickup_longitude,
+		       dropoff_latitude, dropoff_longitude, quoted_surge, started_at
+		FROM rides WHERE id = $1 FOR UPDATE
+	`, rideID).Scan(&status, &driverID, &riderID, &vehicleType, &pool, &pickupLat, &pickupLng,
+		&dropoffLat, &dropoffLng, &quotedSurge, &startedAt)
+
+	if err == sql.ErrNoRows {
+		respondError(c, apperrors.ErrRideNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Error("Failed to get ride", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to update ride", err))
+		return
+	}
+
+	// Earnings go to the driver on the ride, who must be the one ending it
+	if !driverID.Valid || driverID.String != req.DriverID || driverID.String != middleware.GetUserID(c) {
+		respondError(c, apperrors.Forbidden("Only the assigned driver can end this trip", nil))
+		return
+	}
+
+	if err := ride.Transition(ride.Status(status), ride.StatusCompleted); err != nil {
+		log.Warn("Rejected ride status transition", logger.Err(err), logger.String("ride_id", rideID))
+		respondError(c, invalidTransition("completed", ride.Status(status), err))
+		return
+	}
+
+	// Don't bill more distance than the booked route could plausibly cover by road
+	waypoints, err := h.Rides.GetWaypoints(ctx, rideID)
+	if err != nil {
+		log.Error("Failed to load ride waypoints", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to update ride", err))
+		return
+	}
+	stops := []routing.Point{{Latitude: pickupLat, Longitude: pickupLng}}
+	for _, wp := range waypoints {
+		stops = append(stops, routing.Point{Latitude: wp.Latitude, Longitude: wp.Longitude})
+	}
+	stops = append(stops, routing.Point{Latitude: dropoffLat, Longitude: dropoffLng})
+	distanceKM, distanceCapped := h.billableDistance(log, rideID, distanceKM, stops)
+
+	// Bill the time the ride was actually in progress rather than the driver's figure
+	if startedAt.Valid {
+		durationMinutes = h.billableDuration(log, rideID, durationMinutes, time.Since(startedAt.Time))
+	}
+
+	// Charge the surge quoted at request time; rides without a quote fall back to the live surge
+	region := h.Pricing.RegionForCoordinates(pickupLat, pickupLng)
+	surge := quotedSurge.Float64
+	if !quotedSurge.Valid {
+		surge = h.currentSurge(ctx, region)
+	}
+	fare := h.Pricing.CalculateFareWithSurge(driver.VehicleType(vehicleType), distanceKM, durationMinutes, req.WaitingMinutes, surge)
+	if pool {
+		fare = h.Pricing.ApplyPoolDiscount(fare)
+	}
+	baseFare, distanceFare, timeFare, waitingFare, totalFare := fare.BaseFare, fare.DistanceFare, fare.TimeFare, fare.WaitingFare, fare.Total
+
+	log.Info("Fare calculated",
+		logger.Float64("total_fare", totalFare),
+		logger.Float64("base_fare", baseFare),
+		logger.Float64("distance_fare", distanceFare),
+		logger.Float64("time_fare", timeFare),
+		logger.Float64("waiting_fare", waitingFare),
+		logger.Float64("surge_multiplier", fare.SurgeMultiplier),
+		logger.Float64("pool_discount", fare.PoolDiscount),
+		logger.String("region", region),
+	)
+
 	// Update ride status to completed
 	_, err = tx.ExecContext(ctx, `
 		UPDATE rides
@@ -62,32 +161,37 @@ func (h *Handlers) EndTrip(c *gin.Context) {
 		WHERE id = $1
 	`, rideID)
 	if err != nil {
-		h.Logger.Error("Failed to update ride", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ride"})
+		log.Error("Failed to update ride", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to update ride", err))
 		return
 	}
 
 	// Create or update trip record
 	_, err = tx.ExecContext(ctx, `
 		INSERT INTO trips (
-			ride_id, distance_km, duration_minutes,
-			base_fare, distance_fare, time_fare, total_fare,
-			status, ended_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, 'completed', NOW())
+			ride_id, distance_km, duration_minutes, waiting_minutes,
+			base_fare, distance_fare, time_fare, waiting_fare, surge_multiplier, total_fare,
+			route_polyline, status, ended_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 'completed', NOW())
 		ON CONFLICT (ride_id) DO UPDATE SET
 			distance_km = EXCLUDED.distance_km,
 			duration_minutes = EXCLUDED.duration_minutes,
+			waiting_minutes = EXCLUDED.waiting_minutes,
 			base_fare = EXCLUDED.base_fare,
 			distance_fare = EXCLUDED.distance_fare,
 			time_fare = EXCLUDED.time_fare,
+			waiting_fare = EXCLUDED.waiting_fare,
+			surge_multiplier = EXCLUDED.surge_multiplier,
 			total_fare = EXCLUDED.total_fare,
+			route_polyline = COALESCE(EXCLUDED.route_polyline, trips.route_polyline),
 			status = EXCLUDED.status,
 			ended_at = EXCLUDED.ended_at,
 			updated_at = NOW()
-	`, rideID, req.DistanceKm, req.DurationMinutes, baseFare, distanceFare, timeFare, totalFare)
+	`, rideID, distanceKM, durationMinutes, req.WaitingMinutes, baseFare, distanceFare, timeFare, waitingFare, fare.SurgeMultiplier, totalFare,
+		sql.NullString{String: routePolyline, Valid: routePolyline != ""})
 	if err != nil {
-		h.Logger.Error("Failed to create/update trip", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save trip"})
+		log.Error("Failed to create/update trip", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to save trip", err))
 		return
 	}
 
@@ -101,8 +205,8 @@ func (h *Handlers) EndTrip(c *gin.Context) {
 			updated_at = NOW()
 	`, req.DriverID, totalFare)
 	if err != nil {
-		h.Logger.Error("Failed to update driver earnings", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update earnings"})
+		log.Error("Failed to update driver earnings", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to update earnings", err))
 		return
 	}
 
@@ -110,32 +214,40 @@ func (h *Handlers) EndTrip(c *gin.Context) {
 	_, err = tx.ExecContext(ctx, `
 		UPDATE drivers
 		SET status = 'online', updated_at = NOW()
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`, req.DriverID)
 	if err != nil {
-		h.Logger.Warn("Failed to update driver status", logger.Err(err))
+		log.Warn("Failed to update driver status", logger.Err(err))
 		// Don't fail the request, just log
 	}
 
 	// Commit transaction
 	if err = tx.Commit(); err != nil {
-		h.Logger.Error("Failed to commit transaction", logger.Err(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete trip"})
+		log.Error("Failed to commit transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to complete trip", err))
 		return
 	}
 
-	h.Logger.Info("Trip completed in PostgreSQL",
+	log.Info("Trip completed in PostgreSQL",
 		logger.String("ride_id", rideID),
 		logger.String("driver_id", req.DriverID),
 		logger.Float64("fare", totalFare),
 	)
+	h.Metrics.RecordTripFare(totalFare)
+	h.NewRelic.RecordRideCompleted(rideID, totalFare, distanceKM, durationMinutes)
+	actorType, actorID := requestActor(c)
+	h.recordRideEvent(ctx, log, rideID, ride.EventCompleted, actorType, actorID, map[string]interface{}{
+		"driver_id":        req.DriverID,
+		"fare":             totalFare,
+		"distance_km":      distanceKM,
+		"duration_minutes": durationMinutes,
+	})
 
-	// Clear current ride from Redis and add driver back to available set
-	currentRideKey := fmt.Sprintf("driver:%s:current_ride", req.DriverID)
-	h.Redis.Del(ctx, currentRideKey)
-	h.Redis.SAdd(ctx, "drivers:available", req.DriverID)
+	// Clear current ride from Redis and add driver back to available set,
+	// unless other pool riders are still aboard
+	h.releaseDriverFromRide(ctx, req.DriverID, rideID)
 
-	h.Logger.Info("Driver returned to available pool",
+	log.Info("Driver returned to available pool",
 		logger.String("driver_id", req.DriverID),
 		logger.String("ride_id", rideID),
 	)
@@ -154,8 +266,8 @@ func (h *Handlers) EndTrip(c *gin.Context) {
 			"ride_id":          rideID,
 			"driver_id":        req.DriverID,
 			"driver_name":      driverName,
-			"distance_km":      req.DistanceKm,
-			"duration_minutes": req.DurationMinutes,
+			"distance_km":      distanceKM,
+			"duration_minutes": durationMinutes,
 			"total_fare":       totalFare,
 			"fare":             totalFare,
 		},
@@ -164,19 +276,25 @@ func (h *Handlers) EndTrip(c *gin.Context) {
 		wsHub.BroadcastToType("dashboard", tripCompletedNotification)
 	}
 
-	// Also notify rider
-	riderNotification := map[string]interface{}{
-		"type": "trip_completed",
-		"data": map[string]interface{}{
+	// Also notify the ride's rider; the fare is theirs alone
+	riderNotification := websocket.Message{
+		Type: "trip_completed",
+		Data: map[string]interface{}{
 			"ride_id":     rideID,
 			"status":      "completed",
 			"total_fare":  totalFare,
-			"distance_km": req.DistanceKm,
-			"duration":    req.DurationMinutes,
+			"distance_km": distanceKM,
+			"duration":    durationMinutes,
 		},
 	}
 	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
-		wsHub.BroadcastToType("rider", riderNotification)
+		wsHub.BroadcastToUser(riderID, "rider", riderNotification)
+	}
+
+	// Report the quote (null for rides requested before quotes were stored) next to what was charged
+	var quoted interface{}
+	if quotedSurge.Valid {
+		quoted = quotedSurge.Float64
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -184,12 +302,207 @@ func (h *Handlers) EndTrip(c *gin.Context) {
 		"ride_id":          rideID,
 		"total_fare":       totalFare,
 		"fare":             totalFare,
-		"distance_km":      req.DistanceKm,
-		"duration_minutes": req.DurationMinutes,
+		"distance_km":      distanceKM,
+		"duration_minutes": durationMinutes,
+		"distance_capped":  distanceCapped,
+		"region":           region,
+		"quoted_surge":     quoted,
+		"applied_surge":    fare.SurgeMultiplier,
 		"fare_breakdown": map[string]interface{}{
-			"base_fare":     baseFare,
-			"distance_fare": req.DistanceKm * perKmFare,
-			"time_fare":     float64(req.DurationMinutes) * perMinuteFare,
+			"base_fare":        baseFare,
+			"distance_fare":    distanceFare,
+			"time_fare":        timeFare,
+			"waiting_fare":     waitingFare,
+			"surge_multiplier": fare.SurgeMultiplier,
 		},
 	})
 }
+
+// billableDistance caps the reported distance at the straight-line length of the stops scaled
+// by MaxRoadFactor plus DistanceMarginKM, logging capped trips for fraud review
+func (h *Handlers) billableDistance(log *logger.Logger, rideID string, reportedKM float64, stops []routing.Point) (float64, bool) {
+	straightKM := 0.0
+	for i := 1; i < len(stops); i++ {
+		straightKM += matching.CalculateDistance(stops[i-1].Latitude, stops[i-1].Longitude, stops[i].Latitude, stops[i].Longitude)
+	}
+
+	maxKM := straightKM*h.Config.Trips.MaxRoadFactor + h.Config.Trips.DistanceMarginKM
+	if reportedKM <= maxKM {
+		return reportedKM, false
+	}
+
+	log.Warn("Reported trip distance exceeds plausible route, capping",
+		logger.String("ride_id", rideID),
+		logger.Float64("reported_km", reportedKM),
+		logger.Float64("straight_line_km", straightKM),
+		logger.Float64("capped_km", maxKM),
+	)
+	return maxKM, true
+}
+
+// billableDuration returns the server-measured trip length in whole minutes, rounded up, and
+// logs a warning for fraud review when the client-reported duration is far from it
+func (h *Handlers) billableDuration(log *logger.Logger, rideID string, reportedMinutes int, elapsed time.Duration) int {
+	measuredMinutes := int(math.Ceil(elapsed.Minutes()))
+	discrepancy := reportedMinutes - measuredMinutes
+	if discrepancy < 0 {
+		discrepancy = -discrepancy
+	}
+	if discrepancy > h.Config.Trips.DurationDiscrepancyMinutes {
+		log.Warn("Reported trip duration differs from measured duration",
+			logger.String("ride_id", rideID),
+			logger.Int("reported_minutes", reportedMinutes),
+			logger.Int("measured_minutes", measuredMinutes),
+		)
+	}
+	return measuredMinutes
+}
+
+// StartTrip handles POST /v1/trips/:id/start
+func (h *Handlers) StartTrip(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	rideID := c.Param("id")
+	ctx := context.Background()
+
+	log.Info("Starting trip", logger.String("ride_id", rideID))
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Failed to begin transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Database error", err))
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the ride row so concurrent start requests can't both succeed
+	var status, riderID string
+	var driverID sql.NullString
+	var pickup, dropoff routing.Point
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, rider_id, driver_id,
+		       pickup_latitude, pickup_longitude, dropoff_latitude, dropoff_longitude
+		FROM rides
+		WHERE id = $1
+		FOR UPDATE
+	`, rideID).Scan(&status, &riderID, &driverID,
+		&pickup.Latitude, &pickup.Longitude, &dropoff.Latitude, &dropoff.Longitude)
+
+	if err == sql.ErrNoRows {
+		respondError(c, apperrors.ErrRideNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Error("Failed to get ride", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to get ride", err))
+		return
+	}
+
+	// Only the driver assigned to the ride may start it
+	if !driverID.Valid || driverID.String != middleware.GetUserID(c) {
+		respondError(c, apperrors.Forbidden("Only the assigned driver can start this trip", nil))
+		return
+	}
+
+	if err := ride.Transition(ride.Status(status), ride.StatusStarted); err != nil {
+		respondError(c, invalidTransition("started", ride.Status(status), err))
+		return
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rides
+		SET status = 'started', started_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`, rideID)
+	if err != nil {
+		log.Error("Failed to update ride", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to update ride", err))
+		return
+	}
+
+	// Create the in-progress trip (fare fields are finalized in EndTrip); the route is stored
+	// after commit so the ride stays locked only as long as the status change needs
+	var tripID string
+	var startedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO trips (ride_id, base_fare, status, started_at, route_polyline)
+		VALUES ($1, 0, 'in_progress', NOW(), NULL)
+		ON CONFLICT (ride_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			started_at = EXCLUDED.started_at,
+			route_polyline = EXCLUDED.route_polyline,
+			updated_at = NOW()
+		RETURNING id, started_at
+	`, rideID).Scan(&tripID, &startedAt)
+	if err != nil {
+		log.Error("Failed to create trip", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to create trip", err))
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Error("Failed to commit transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to start trip", err))
+		return
+	}
+
+	log.Info("Trip started",
+		logger.String("ride_id", rideID),
+		logger.String("trip_id", tripID),
+		logger.String("rider_id", riderID),
+	)
+	actorType, actorID := requestActor(c)
+	h.recordRideEvent(ctx, log, rideID, ride.EventStarted, actorType, actorID, map[string]interface{}{
+		"trip_id": tripID,
+	})
+	h.storeTripRoute(ctx, log, rideID, tripID, pickup, dropoff)
+
+	// Notify the rider that the trip is underway
+	tripStartedNotification := map[string]interface{}{
+		"type": "trip_started",
+		"data": map[string]interface{}{
+			"ride_id":    rideID,
+			"trip_id":    tripID,
+			"driver_id":  driverID.String,
+			"status":     "started",
+			"started_at": startedAt,
+		},
+	}
+	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
+		wsHub.SendToUser(riderID, tripStartedNotification)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "started",
+		"ride_id":    rideID,
+		"trip_id":    tripID,
+		"started_at": startedAt,
+	})
+}
+
+// storeTripRoute saves the planned route from pickup through any waypoints to dropoff on
+// the trip; a failed estimate just leaves the polyline empty
+func (h *Handlers) storeTripRoute(ctx context.Context, log *logger.Logger, rideID, tripID string, pickup, dropoff routing.Point) {
+	stops := []routing.Point{pickup}
+	waypoints, err := h.Rides.GetWaypoints(ctx, rideID)
+	if err != nil {
+		log.Warn("Failed to load ride waypoints", logger.Err(err), logger.String("ride_id", rideID))
+	}
+	for _, wp := range waypoints {
+		stops = append(stops, routing.Point{Latitude: wp.Latitude, Longitude: wp.Longitude})
+	}
+	stops = append(stops, dropoff)
+
+	_, _, polyline, err := routing.EstimateRouteVia(ctx, h.Router, stops)
+	if err != nil {
+		log.Warn("Route estimate failed", logger.Err(err), logger.String("ride_id", rideID))
+		return
+	}
+
+	if _, err := h.DB.ExecContext(ctx, `
+		UPDATE trips SET route_polyline = $2, updated_at = NOW() WHERE id = $1
+	`, tripID, polyline); err != nil {
+		log.Warn("Failed to store trip route", logger.Err(err), logger.String("ride_id", rideID))
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/gocomet/ride-hailing/internal/service/routing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBillableDuration_UsesMeasuredMinutes tests that an inflated client duration is replaced
+// by the elapsed time since the trip started, rounded up to whole minutes
+func TestBillableDuration_UsesMeasuredMinutes(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	h.Config.Trips.DurationDiscrepancyMinutes = 5
+
+	assert.Equal(t, 13, h.billableDuration(h.Logger, "ride-1", 90, 12*time.Minute+10*time.Second))
+	assert.Equal(t, 12, h.billableDuration(h.Logger, "ride-1", 12, 12*time.Minute))
+}
+
+// TestBillableDistance_CapsImplausibleDistance tests that a reported distance far beyond the
+// straight-line route is capped while a plausible one is billed as reported
+func TestBillableDistance_CapsImplausibleDistance(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	h.Config.Trips.MaxRoadFactor = 2
+	h.Config.Trips.DistanceMarginKM = 1
+
+	// About 5 km apart in a straight line
+	stops := []routing.Point{{Latitude: 12.9716, Longitude: 77.5946}, {Latitude: 12.9352, Longitude: 77.6245}}
+
+	distance, capped := h.billableDistance(h.Logger, "ride-1", 7.5, stops)
+	assert.False(t, capped)
+	assert.Equal(t, 7.5, distance)
+
+	distance, capped = h.billableDistance(h.Logger, "ride-1", 80, stops)
+	assert.True(t, capped)
+	assert.InDelta(t, 2*matching.CalculateDistance(12.9716, 77.5946, 12.9352, 77.6245)+1, distance, 0.001)
+}
+
+// TestStartTrip_RejectsOtherDriver tests that a driver can't start a ride assigned to someone else
+func TestStartTrip_RejectsOtherDriver(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status, rider_id, driver_id").
+		WithArgs("ride-1").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "rider_id", "driver_id",
+			"pickup_latitude", "pickup_longitude", "dropoff_latitude", "dropoff_longitude"}).
+			AddRow("accepted", uuid.NewString(), uuid.NewString(), 12.97, 77.59, 12.93, 77.62))
+	mock.ExpectRollback()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/trips/ride-1/start", nil)
+	c.Params = gin.Params{{Key: "id", Value: "ride-1"}}
+	c.Set("user_id", uuid.NewString())
+	c.Set("user_type", "driver")
+	h.StartTrip(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestStartTrip_StoresRouteAfterCommit tests that the route estimate runs after the ride's
+// row lock is released
+func TestStartTrip_StoresRouteAfterCommit(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	driverID := uuid.NewString()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status, rider_id, driver_id").
+		WithArgs("ride-1").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "rider_id", "driver_id",
+			"pickup_latitude", "pickup_longitude", "dropoff_latitude", "dropoff_longitude"}).
+			AddRow("accepted", uuid.NewString(), driverID, 12.97, 77.59, 12.93, 77.62))
+	mock.ExpectExec("SET status = 'started'").
+		WithArgs("ride-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO trips").
+		WithArgs("ride-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "started_at"}).AddRow("trip-1", time.Now()))
+	mock.ExpectCommit()
+	mock.ExpectExec("UPDATE trips SET route_polyline").
+		WithArgs("trip-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/trips/ride-1/start", nil)
+	c.Params = gin.Params{{Key: "id", Value: "ride-1"}}
+	c.Set("user_id", driverID)
+	c.Set("user_type", "driver")
+	h.StartTrip(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestEndTrip_RejectsDriverNotOnRide tests that a trip can only be ended, and its fare
+// credited, by the driver assigned to the ride
+func TestEndTrip_RejectsDriverNotOnRide(t *testing.T) {
+	assignedID, otherID := uuid.NewString(), uuid.NewString()
+
+	tests := []struct {
+		name     string
+		driverID string
+		callerID string
+	}{
+		{"other driver in body", otherID, otherID},
+		{"other caller", assignedID, otherID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			h.DB = db
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT status, driver_id").
+				WithArgs("ride-1").
+				WillReturnRows(sqlmock.NewRows([]string{"status", "driver_id", "rider_id", "vehicle_type", "pool",
+					"pickup_latitude", "pickup_longitude", "dropoff_latitude", "dropoff_longitude", "quoted_surge", "started_at"}).
+					AddRow("started", assignedID, "rider-1", "economy", false, 12.97, 77.59, 12.93, 77.62, 1.0, time.Now()))
+			mock.ExpectRollback()
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			body := `{"driver_id":"` + tt.driverID + `","distance_km":5,"duration_minutes":15}`
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/trips/ride-1/end", bytes.NewBufferString(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: "ride-1"}}
+			c.Set("user_id", tt.callerID)
+			c.Set("user_type", "driver")
+			h.EndTrip(c)
+
+			assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// TestEndTrip_ConflictWhenNotStarted tests that ending a ride that never started is a 409,
+// like every other ride transition in the wrong state
+func TestEndTrip_ConflictWhenNotStarted(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	driverID := uuid.NewString()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT status, driver_id").
+		WithArgs("ride-1").
+		WillReturnRows(sqlmock.NewRows([]string{"status", "driver_id", "rider_id", "vehicle_type", "pool",
+			"pickup_latitude", "pickup_longitude", "dropoff_latitude", "dropoff_longitude", "quoted_surge", "started_at"}).
+			AddRow("accepted", driverID, "rider-1", "economy", false, 12.97, 77.59, 12.93, 77.62, 1.0, nil))
+	mock.ExpectRollback()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body := `{"driver_id":"` + driverID + `","distance_km":5,"duration_minutes":15}`
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/trips/ride-1/end", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "ride-1"}}
+	c.Set("user_id", driverID)
+	c.Set("user_type", "driver")
+	h.EndTrip(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"code":"CONFLICT","message":"Ride cannot be completed from status 'accepted'"}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
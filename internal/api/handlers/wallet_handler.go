@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/dto"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/internal/domain/payment"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// walletHistoryLimit is how many recent ledger entries GetWallet returns
+const walletHistoryLimit = 20
+
+// GetWallet handles GET /v1/riders/:id/wallet
+// Riders without a wallet yet have a zero balance.
+func (h *Handlers) GetWallet(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	riderID := c.Param("id")
+	ctx := context.Background()
+
+	if !h.canAccessRider(c, riderID) {
+		respondError(c, apperrors.Forbidden("Riders may only view their own wallet", nil))
+		return
+	}
+	if _, err := uuid.Parse(riderID); err != nil {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+
+	var balance float64
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(w.balance, 0)
+		FROM riders r
+		LEFT JOIN rider_wallets w ON w.rider_id = r.id
+		WHERE r.id = $1 AND r.deleted_at IS NULL
+	`, riderID).Scan(&balance)
+
+	if err == sql.ErrNoRows {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+
+	if err != nil {
+		log.Error("Failed to get wallet", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to get wallet", err))
+		return
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT id, type, amount, balance_after, payment_id, created_at
+		FROM wallet_transactions
+		WHERE rider_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, riderID, walletHistoryLimit)
+	if err != nil {
+		log.Error("Failed to query wallet transactions", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to get wallet", err))
+		return
+	}
+	defer rows.Close()
+
+	transactions := []gin.H{}
+	for rows.Next() {
+		var (
+			id, txType           string
+			amount, balanceAfter float64
+			paymentID            sql.NullString
+			createdAt            time.Time
+		)
+		if err := rows.Scan(&id, &txType, &amount, &balanceAfter, &paymentID, &createdAt); err != nil {
+			log.Error("Failed to scan wallet transaction", logger.Err(err), logger.String("rider_id", riderID))
+			respondError(c, apperrors.Internal("Failed to get wallet", err))
+			return
+		}
+
+		entry := gin.H{
+			"id":            id,
+			"type":          txType,
+			"amount":        amount,
+			"balance_after": balanceAfter,
+			"created_at":    createdAt,
+		}
+		if paymentID.Valid {
+			entry["payment_id"] = paymentID.String
+		}
+		transactions = append(transactions, entry)
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("Failed to read wallet transactions", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to get wallet", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rider_id":     riderID,
+		"balance":      balance,
+		"transactions": transactions,
+	})
+}
+
+// TopUpWallet handles POST /v1/riders/:id/wallet/topup
+// Creates the wallet on first top-up and enables wallet payments for the rider.
+// Nothing is charged to a funding source yet, so only admins may credit a wallet.
+func (h *Handlers) TopUpWallet(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	riderID := c.Param("id")
+	ctx := context.Background()
+
+	if middleware.GetUserType(c) != auth.UserTypeAdmin {
+		respondError(c, apperrors.Forbidden("Only admins may top up a wallet", nil))
+		return
+	}
+	if _, err := uuid.Parse(riderID); err != nil {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+
+	var req dto.WalletTopUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, invalidPayload(err))
+		return
+	}
+	amount := roundToCents(req.Amount)
+
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if idempotencyKey == "" {
+		respondError(c, apperrors.BadRequest("Idempotency-Key header required", nil))
+		return
+	}
+
+	tx, err := h.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Error("Failed to begin transaction", logger.Err(err))
+		respondError(c, apperrors.Internal("Database error", err))
+		return
+	}
+	defer tx.Rollback()
+
+	// A retried top-up returns the original entry instead of crediting twice
+	var transactionID string
+	var recordedAmount, balance float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, amount, balance_after FROM wallet_transactions
+		WHERE idempotency_key = $1 AND rider_id = $2
+	`, idempotencyKey, riderID).Scan(&transactionID, &recordedAmount, &balance)
+	if err == nil {
+		log.Info("Returning existing wallet top-up", logger.String("idempotency_key", idempotencyKey))
+		c.JSON(http.StatusOK, gin.H{
+			"rider_id":       riderID,
+			"transaction_id": transactionID,
+			"amount":         recordedAmount,
+			"balance":        balance,
+		})
+		return
+	}
+	if err != sql.ErrNoRows {
+		log.Error("Failed to check wallet top-up", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to top up wallet", err))
+		return
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM riders WHERE id = $1 AND deleted_at IS NULL)", riderID).Scan(&exists); err != nil {
+		log.Error("Failed to get rider", logger.Err(err))
+		respondError(c, apperrors.Internal("Failed to top up wallet", err))
+		return
+	}
+	if !exists {
+		respondError(c, apperrors.ErrRiderNotFound)
+		return
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO rider_wallets (rider_id, balance)
+		VALUES ($1, $2)
+		ON CONFLICT (rider_id) DO UPDATE SET
+			balance = rider_wallets.balance + EXCLUDED.balance,
+			updated_at = NOW()
+		RETURNING balance
+	`, riderID, amount).Scan(&balance)
+	if err == nil {
+		transactionID, err = recordWalletTransaction(ctx, tx, riderID, payment.WalletTopUp, amount, balance, "", idempotencyKey)
+	}
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO rider_payment_methods (rider_id, payment_method)
+			VALUES ($1, 'wallet')
+			ON CONFLICT (rider_id, payment_method) DO NOTHING
+		`, riderID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	// A concurrent retry with the same key got there first
+	if isUniqueViolation(err) {
+		respondError(c, apperrors.ErrDuplicateRequest)
+		return
+	}
+	if err != nil {
+		log.Error("Failed to top up wallet", logger.Err(err), logger.String("rider_id", riderID))
+		respondError(c, apperrors.Internal("Failed to top up wallet", err))
+		return
+	}
+
+	log.Info("Wallet topped up",
+		logger.String("rider_id", riderID),
+		logger.Float64("amount", amount),
+		logger.Float64("balance", balance),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"rider_id":       riderID,
+		"transaction_id": transactionID,
+		"amount":         amount,
+		"balance":        balance,
+	})
+}
+
+// canAccessRider lets riders reach only their own resources and admins reach any rider's
+func (h *Handlers) canAccessRider(c *gin.Context, riderID string) bool {
+	switch middleware.GetUserType(c) {
+	case auth.UserTypeAdmin:
+		return true
+	case auth.UserTypeRider:
+		return middleware.GetUserID(c) == riderID
+	}
+	return false
+}
+
+// debitWallet takes amount from the rider's wallet inside tx and records the ledger entry,
+// failing with ErrInsufficientBalance when the wallet is missing or short
+func debitWallet(ctx context.Context, tx *sql.Tx, riderID, paymentID string, amount float64) error {
+	var balance float64
+	err := tx.QueryRowContext(ctx, `
+		UPDATE rider_wallets
+		SET balance = balance - $2, updated_at = NOW()
+		WHERE rider_id = $1 AND balance >= $2
+		RETURNING balance
+	`, riderID, amount).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return apperrors.ErrInsufficientBalance
+	}
+	if err != nil {
+		return fmt.Errorf("failed to debit wallet: %w", err)
+	}
+
+	_, err = recordWalletTransaction(ctx, tx, riderID, payment.WalletPayment, -amount, balance, paymentID, "")
+	return err
+}
+
+// creditWallet returns amount to the rider's wallet inside tx and records the ledger entry
+func creditWallet(ctx context.Context, tx *sql.Tx, riderID, paymentID string, amount float64) error {
+	var balance float64
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO rider_wallets (rider_id, balance)
+		VALUES ($1, $2)
+		ON CONFLICT (rider_id) DO UPDATE SET
+			balance = rider_wallets.balance + EXCLUDED.balance,
+			updated_at = NOW()
+		RETURNING balance
+	`, riderID, amount).Scan(&balance)
+	if err != nil {
+		return fmt.Errorf("failed to credit wallet: %w", err)
+	}
+
+	_, err = recordWalletTransaction(ctx, tx, riderID, payment.WalletRefund, amount, balance, paymentID, "")
+	return err
+}
+
+// recordWalletTransaction appends a ledger entry and returns its ID
+func recordWalletTransaction(ctx context.Context, tx *sql.Tx, riderID string, txType payment.WalletTransactionType,
+	amount, balanceAfter float64, paymentID, idempotencyKey string) (string, error) {
+	id := uuid.New().String()
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO wallet_transactions (
+			id, rider_id, type, amount, balance_after, payment_id, idempotency_key, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+	`, id, riderID, string(txType), amount, balanceAfter,
+		sql.NullString{String: paymentID, Valid: paymentID != ""},
+		sql.NullString{String: idempotencyKey, Valid: idempotencyKey != ""})
+	if err != nil {
+		return "", fmt.Errorf("failed to record wallet transaction: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTopUpWallet_CreditsAndLedgers tests that a top-up credits the wallet, writes a ledger
+// entry and enables wallet payments, all in one transaction
+func TestTopUpWallet_CreditsAndLedgers(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	riderID := uuid.New().String()
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM wallet_transactions").
+		WithArgs("topup-1", riderID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "balance_after"}))
+	mock.ExpectQuery("FROM riders").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery("INSERT INTO rider_wallets").
+		WithArgs(riderID, 500.0).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(650.0))
+	mock.ExpectExec("INSERT INTO wallet_transactions").
+		WithArgs(sqlmock.AnyArg(), riderID, "topup", 500.0, 650.0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO rider_payment_methods").
+		WithArgs(riderID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: riderID}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/riders/"+riderID+"/wallet/topup", bytes.NewBufferString(`{"amount":500}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Idempotency-Key", "topup-1")
+	c.Set("user_type", "admin")
+
+	h.TopUpWallet(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"balance":650`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestTopUpWallet_ReplaysRecordedAmount tests that a retried top-up reports the amount that
+// was credited the first time, not the one in the retry's body
+func TestTopUpWallet_ReplaysRecordedAmount(t *testing.T) {
+	h, _ := newTestHandlers(t, &fakeRides{})
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	h.DB = db
+
+	riderID := uuid.New().String()
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM wallet_transactions").
+		WithArgs("topup-1", riderID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "amount", "balance_after"}).AddRow("txn-1", 500.0, 650.0))
+	mock.ExpectRollback()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: riderID}}
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/riders/"+riderID+"/wallet/topup", bytes.NewBufferString(`{"amount":200}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Idempotency-Key", "topup-1")
+	c.Set("user_type", "admin")
+
+	h.TopUpWallet(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"rider_id":"`+riderID+`","transaction_id":"txn-1","amount":500,"balance":650}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestWallet_RestrictsAccess tests that only the rider reads their wallet, other roles
+// can't reach it, and only admins may credit one
+func TestWallet_RestrictsAccess(t *testing.T) {
+	riderID := uuid.New().String()
+	tests := []struct {
+		name     string
+		topUp    bool
+		userID   string
+		userType string
+	}{
+		{"driver reads wallet", false, uuid.New().String(), "driver"},
+		{"dashboard reads wallet", false, uuid.New().String(), "dashboard"},
+		{"other rider reads wallet", false, uuid.New().String(), "rider"},
+		{"rider tops up own wallet", true, riderID, "rider"},
+		{"driver tops up wallet", true, uuid.New().String(), "driver"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: riderID}}
+			c.Set("user_id", tt.userID)
+			c.Set("user_type", tt.userType)
+			if tt.topUp {
+				c.Request = httptest.NewRequest(http.MethodPost, "/v1/riders/"+riderID+"/wallet/topup", bytes.NewBufferString(`{"amount":500}`))
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Idempotency-Key", "topup-1")
+				h.TopUpWallet(c)
+			} else {
+				c.Request = httptest.NewRequest(http.MethodGet, "/v1/riders/"+riderID+"/wallet", nil)
+				h.GetWallet(c)
+			}
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
+
+// TestGetWallet_FailsOnBadRows tests that an unreadable ledger entry fails the request
+// instead of silently dropping out of the history
+func TestGetWallet_FailsOnBadRows(t *testing.T) {
+	columns := []string{"id", "type", "amount", "balance_after", "payment_id", "created_at"}
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+	}{
+		{"scan error", sqlmock.NewRows(columns).AddRow("txn-1", "topup", "lots", 500.0, nil, time.Now())},
+		{"row error", sqlmock.NewRows(columns).
+			AddRow("txn-1", "topup", 500.0, 500.0, nil, time.Now()).
+			AddRow("txn-2", "payment", -250.0, 250.0, "pay-1", time.Now()).
+			RowError(1, errors.New("connection reset"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandlers(t, &fakeRides{})
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			h.DB = db
+
+			riderID := uuid.New().String()
+			mock.ExpectQuery("FROM riders r").
+				WithArgs(riderID).
+				WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(250.0))
+			mock.ExpectQuery("FROM wallet_transactions").WillReturnRows(tt.rows)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/v1/riders/"+riderID+"/wallet", nil)
+			c.Params = gin.Params{{Key: "id", Value: riderID}}
+			c.Set("user_id", riderID)
+			c.Set("user_type", "rider")
+			h.GetWallet(c)
+
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -1,9 +1,17 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
 	gorilla "github.com/gorilla/websocket"
 	"github.com/gocomet/ride-hailing/pkg/logger"
 	"github.com/gocomet/ride-hailing/pkg/websocket"
@@ -11,37 +19,81 @@ import (
 
 // HandleWebSocket handles GET /v1/ws
 func (h *Handlers) HandleWebSocket(c *gin.Context) {
+	log := middleware.Logger(c, h.Logger)
+
+	// Browsers send Origin on WebSocket handshakes; only allowed origins may connect
+	if !h.websocketOriginAllowed(c.Request) {
+		log.Warn("Rejected WebSocket connection from disallowed origin", logger.String("origin", c.GetHeader("Origin")))
+		respondError(c, apperrors.Forbidden("Origin not allowed", nil))
+		return
+	}
+
+	// Authenticate before upgrading so failures get a proper 401
+	claims, err := auth.ParseToken(h.Config.JWT.Secret, auth.TokenFromRequest(c.Request))
+	if err != nil {
+		log.Warn("Rejected unauthenticated WebSocket connection", logger.Err(err))
+		respondError(c, apperrors.Unauthorized("Invalid or missing token", err))
+		return
+	}
+
 	// Upgrade connection to WebSocket
 	upgrader := gorilla.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
+		ReadBufferSize:  h.Config.WebSocket.ReadBufferSize,
+		WriteBufferSize: h.Config.WebSocket.WriteBufferSize,
 		CheckOrigin: func(r *http.Request) bool {
-			return true // Allow all origins in development
+			return true // Origin already checked above
 		},
 	}
 
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		h.Logger.Error("Failed to upgrade to WebSocket", logger.Err(err))
-		return
-	}
-
-	// Get user info from query params
-	userID := c.Query("user_id")
-	userType := c.Query("user_type")
-
-	if userID == "" || userType == "" {
-		h.Logger.Warn("Missing user_id or user_type in WebSocket connection")
-		conn.Close()
+		log.Error("Failed to upgrade to WebSocket", logger.Err(err))
 		return
 	}
 
 	// Create client and register with hub
 	if wsHub, ok := h.Hub.(*websocket.Hub); ok {
-		client := websocket.NewClient(wsHub, conn, userID, userType, h.Logger)
+		client := websocket.NewClient(wsHub, conn, claims.UserID(), claims.UserType, log, websocket.ClientConfig{
+			SendBufferSize: h.Config.WebSocket.SendBufferSize,
+			WriteWait:      h.Config.WebSocket.WriteWait,
+			PongWait:       h.Config.WebSocket.PongWait,
+			PingPeriod:     h.Config.WebSocket.HeartbeatInterval,
+			MaxMessageSize: h.Config.WebSocket.MaxMessageSize,
+		})
 		wsHub.Register(client)
 
 		go client.WritePump()
 		go client.ReadPump()
 	}
 }
+
+// websocketOriginAllowed allows non-browser clients (no Origin), same-host pages and
+// CORS_ALLOWED_ORIGINS; development mode allows every origin
+func (h *Handlers) websocketOriginAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || h.Config.IsDevelopment() {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return h.Config.CORS.AllowsOrigin(origin)
+}
+
+// IsRideParticipant reports whether userID is the ride's rider or assigned driver.
+// It implements websocket.RideAccess so clients can only follow their own rides.
+func (h *Handlers) IsRideParticipant(ctx context.Context, rideID, userID string) (bool, error) {
+	rd, err := h.Rides.GetByID(ctx, rideID)
+	if errors.Is(err, ride.ErrRideNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if rd.RiderID.String() == userID {
+		return true, nil
+	}
+	return rd.DriverID != nil && rd.DriverID.String() == userID, nil
+}
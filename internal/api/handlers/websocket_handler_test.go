@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIsRideParticipant tests that only the ride's rider and driver may follow it
+func TestIsRideParticipant(t *testing.T) {
+	riderID, driverID := uuid.New(), uuid.New()
+	rides := &fakeRides{rides: map[string]*ride.Ride{
+		"ride-1": {ID: "ride-1", RiderID: riderID, DriverID: &driverID, Status: ride.StatusStarted},
+	}}
+	h, _ := newTestHandlers(t, rides)
+	ctx := context.Background()
+
+	for userID, want := range map[string]bool{
+		riderID.String():    true,
+		driverID.String():   true,
+		uuid.New().String(): false,
+	} {
+		ok, err := h.IsRideParticipant(ctx, "ride-1", userID)
+		require.NoError(t, err)
+		assert.Equal(t, want, ok, userID)
+	}
+
+	ok, err := h.IsRideParticipant(ctx, "missing", riderID.String())
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
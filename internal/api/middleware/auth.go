@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+)
+
+// Gin context keys set by Auth
+const (
+	userIDKey   = "user_id"
+	userTypeKey = "user_type"
+)
+
+// Auth requires a valid bearer token signed with secret and stores the
+// caller's subject and role in the Gin context.
+func Auth(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := auth.ParseToken(secret, auth.TokenFromRequest(c.Request))
+		if err != nil {
+			appErr := apperrors.Unauthorized("Invalid or missing token", err)
+			c.AbortWithStatusJSON(appErr.Status, appErr)
+			return
+		}
+
+		c.Set(userIDKey, claims.UserID())
+		c.Set(userTypeKey, claims.UserType)
+		c.Next()
+	}
+}
+
+// RequireUserType lets through only callers whose role is one of userTypes; it must run after Auth
+func RequireUserType(userTypes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userType := GetUserType(c)
+		for _, allowed := range userTypes {
+			if userType == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		appErr := apperrors.Forbidden("Insufficient permissions", nil)
+		c.AbortWithStatusJSON(appErr.Status, appErr)
+	}
+}
+
+// GetUserID returns the authenticated subject, or "" if Auth didn't run
+func GetUserID(c *gin.Context) string {
+	return c.GetString(userIDKey)
+}
+
+// GetUserType returns the authenticated role, or "" if Auth didn't run
+func GetUserType(c *gin.Context) string {
+	return c.GetString(userTypeKey)
+}
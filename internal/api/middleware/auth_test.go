@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAuth tests that only requests with a valid bearer token reach the handler
+func TestAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/rides", Auth("secret"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": GetUserID(c), "user_type": GetUserType(c)})
+	})
+
+	send := func(header string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/rides", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send("").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("Bearer not-a-jwt").Code)
+
+	forged, err := auth.GenerateToken("other-secret", "rider-1", auth.UserTypeRider, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, send("Bearer "+forged).Code)
+
+	token, err := auth.GenerateToken("secret", "rider-1", auth.UserTypeRider, time.Hour)
+	assert.NoError(t, err)
+	w := send("Bearer " + token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":"rider-1","user_type":"rider"}`, w.Body.String())
+}
+
+// TestRequireUserType tests that only the allowed roles get past the role check
+func TestRequireUserType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/admin/payouts", Auth("secret"), RequireUserType(auth.UserTypeAdmin), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(userType string) int {
+		token, err := auth.GenerateToken("secret", "user-1", userType, time.Hour)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/admin/payouts", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, send(auth.UserTypeRider))
+	assert.Equal(t, http.StatusForbidden, send(auth.UserTypeDashboard))
+	assert.Equal(t, http.StatusOK, send(auth.UserTypeAdmin))
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCORSRouter(allowAll bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORS(config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+	}, allowAll))
+	r.GET("/rides", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func corsRequest(r *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/rides", nil)
+	req.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestCORS_AllowedOrigin tests that allowed origins get CORS headers and preflights are answered
+func TestCORS_AllowedOrigin(t *testing.T) {
+	r := newCORSRouter(false)
+
+	w := corsRequest(r, http.MethodGet, "https://app.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = corsRequest(r, http.MethodOptions, "https://app.example.com")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+}
+
+// TestCORS_DisallowedOrigin tests that other origins get no CORS headers and preflights are refused
+func TestCORS_DisallowedOrigin(t *testing.T) {
+	r := newCORSRouter(false)
+
+	w := corsRequest(r, http.MethodGet, "https://evil.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = corsRequest(r, http.MethodOptions, "https://evil.example.com")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Development mode accepts any origin
+	w = corsRequest(newCORSRouter(true), http.MethodGet, "https://evil.example.com")
+	assert.Equal(t, "https://evil.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
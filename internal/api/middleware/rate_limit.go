@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/pkg/cache"
+	apperrors "github.com/gocomet/ride-hailing/pkg/errors"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyFunc extracts the identity a request is rate limited by
+type KeyFunc func(c *gin.Context) string
+
+// RateLimit enforces at most limit requests per window for each key using a
+// sliding-window counter: the previous fixed window's count is weighted by how
+// much of it still overlaps the sliding window. A limit <= 0 disables the check.
+// Requests are let through if Redis is unavailable.
+func RateLimit(client *redis.Client, log *logger.Logger, name string, limit int, window time.Duration, keyFn KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		allowed, err := allow(c.Request.Context(), client, fmt.Sprintf("ratelimit:%s:%s", name, keyFn(c)), limit, window, time.Now())
+		if err != nil {
+			log.Warn("Rate limiter unavailable, allowing request", logger.String("limiter", name), logger.Err(err))
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(window.Seconds()))))
+			c.AbortWithStatusJSON(apperrors.ErrRateLimitExceeded.Status, apperrors.ErrRateLimitExceeded)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SkipPaths wraps a limiter so it doesn't run for the given route patterns
+// (e.g. routes that already have a dedicated limit).
+func SkipPaths(limiter gin.HandlerFunc, paths ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		skip[p] = true
+	}
+	return func(c *gin.Context) {
+		if skip[c.Request.Method+" "+c.FullPath()] {
+			c.Next()
+			return
+		}
+		limiter(c)
+	}
+}
+
+// allow counts the request in the current window and reports whether the
+// weighted sliding-window total is still within limit.
+func allow(ctx context.Context, client *redis.Client, key string, limit int, window time.Duration, now time.Time) (bool, error) {
+	windowIndex := now.UnixNano() / int64(window)
+	currentKey := fmt.Sprintf("%s:%d", key, windowIndex)
+	previousKey := fmt.Sprintf("%s:%d", key, windowIndex-1)
+
+	current, err := cache.Incr(ctx, client, currentKey)
+	if err != nil {
+		return false, err
+	}
+	if current == 1 {
+		// Keep the counter around for the following window's weighting
+		if err := cache.Expire(ctx, client, currentKey, 2*window); err != nil {
+			return false, err
+		}
+	}
+
+	previous := 0
+	if v, err := cache.Get(ctx, client, previousKey); err == nil {
+		previous, _ = strconv.Atoi(v)
+	} else if err != redis.Nil {
+		return false, err
+	}
+
+	elapsed := float64(now.UnixNano()%int64(window)) / float64(window)
+	estimated := float64(previous)*(1-elapsed) + float64(current)
+
+	return estimated <= float64(limit), nil
+}
+
+// ParamKey limits by a path parameter such as a driver ID
+func ParamKey(param string) KeyFunc {
+	return func(c *gin.Context) string {
+		return param + ":" + c.Param(param)
+	}
+}
+
+// ClientIPKey limits by the caller's IP address
+func ClientIPKey(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RiderKey limits by the rider_id in a JSON body, falling back to the client IP.
+// The body is restored so the handler can still bind it.
+func RiderKey(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ClientIPKey(c)
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ClientIPKey(c)
+	}
+
+	var payload struct {
+		RiderID string `json:"rider_id"`
+	}
+	if json.Unmarshal(body, &payload) != nil || payload.RiderID == "" {
+		return ClientIPKey(c)
+	}
+	return "rider:" + payload.RiderID
+}
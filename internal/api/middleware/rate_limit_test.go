@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRateLimitedRouter(t *testing.T, limit int, keyFn KeyFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	assert.NoError(t, err)
+
+	r := gin.New()
+	r.POST("/drivers/:id/location", RateLimit(client, log, "test", limit, time.Minute, keyFn), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+// TestRateLimit_RejectsOverLimit tests that requests beyond the limit get 429 per key
+func TestRateLimit_RejectsOverLimit(t *testing.T) {
+	r := newRateLimitedRouter(t, 2, ParamKey("id"))
+
+	send := func(driverID string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/drivers/"+driverID+"/location", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("d1"))
+	assert.Equal(t, http.StatusOK, send("d1"))
+	assert.Equal(t, http.StatusTooManyRequests, send("d1"))
+
+	// Other drivers have their own budget
+	assert.Equal(t, http.StatusOK, send("d2"))
+}
+
+// TestRiderKey_PreservesBody tests that keying by rider_id leaves the body readable
+func TestRiderKey_PreservesBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/rides", strings.NewReader(`{"rider_id":"r-1"}`))
+
+	assert.Equal(t, "rider:r-1", RiderKey(c))
+
+	var body struct {
+		RiderID string `json:"rider_id"`
+	}
+	assert.NoError(t, c.ShouldBindJSON(&body))
+	assert.Equal(t, "r-1", body.RiderID)
+}
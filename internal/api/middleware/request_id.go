@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header used to propagate the correlation ID
+const RequestIDHeader = "X-Request-ID"
+
+// Gin context keys set by RequestID
+const (
+	requestIDKey = "request_id"
+	loggerKey    = "logger"
+)
+
+// RequestID reuses the caller's X-Request-ID (or generates one), echoes it in the
+// response and stores a logger scoped to that ID in the Gin and request contexts.
+func RequestID(base *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+
+		c.Set(requestIDKey, requestID)
+		setLogger(c, base.With(logger.String("request_id", requestID)))
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// GetRequestID returns the request ID stored by RequestID, or "" if the middleware didn't run
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Logger returns the request-scoped logger, falling back to fallback when none is set
+func Logger(c *gin.Context, fallback *logger.Logger) *logger.Logger {
+	if l, ok := c.Get(loggerKey); ok {
+		if scoped, ok := l.(*logger.Logger); ok {
+			return scoped
+		}
+	}
+	return fallback
+}
+
+// WithLogFields adds fields to the request-scoped logger for the rest of the request and
+// returns it, so IDs learned mid-handler appear on every later log line
+func WithLogFields(c *gin.Context, fallback *logger.Logger, fields ...zap.Field) *logger.Logger {
+	scoped := Logger(c, fallback).With(fields...)
+	setLogger(c, scoped)
+	return scoped
+}
+
+// setLogger stores l in the Gin context and in the request context for logger.FromContext
+func setLogger(c *gin.Context, l *logger.Logger) {
+	c.Set(loggerKey, l)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), l))
+	}
+}
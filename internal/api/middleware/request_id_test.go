@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(t *testing.T, seen *string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	base, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	assert.NoError(t, err)
+
+	r := gin.New()
+	r.Use(RequestID(base))
+	r.GET("/ping", func(c *gin.Context) {
+		*seen = GetRequestID(c)
+		assert.NotSame(t, base, Logger(c, base), "Handler should get a scoped logger")
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+// TestRequestID_GeneratesWhenMissing tests that a new ID is generated and echoed back
+func TestRequestID_GeneratesWhenMissing(t *testing.T) {
+	var seen string
+	r := newTestRouter(t, &seen)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	assert.NotEmpty(t, seen)
+	assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+}
+
+// TestRequestID_PropagatesIncoming tests that a caller-supplied ID is reused
+func TestRequestID_PropagatesIncoming(t *testing.T) {
+	var seen string
+	r := newTestRouter(t, &seen)
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "abc-123", seen)
+	assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+}
+
+// TestWithLogFields_ScopesRequestContext tests that the request context carries the scoped
+// logger and that fields added mid-request replace it for later lookups
+func TestWithLogFields_ScopesRequestContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	base, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	assert.NoError(t, err)
+
+	r := gin.New()
+	r.Use(RequestID(base))
+	r.GET("/rides/:id", func(c *gin.Context) {
+		scoped := Logger(c, base)
+		assert.Same(t, scoped, logger.FromContext(c.Request.Context()))
+
+		withRide := WithLogFields(c, base, logger.String("ride_id", c.Param("id")))
+		assert.NotSame(t, scoped, withRide)
+		assert.Same(t, withRide, Logger(c, base))
+		assert.Same(t, withRide, logger.FromContext(c.Request.Context()))
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rides/ride-1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -1,59 +1,134 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gocomet/ride-hailing/internal/api/handlers"
+	"github.com/gocomet/ride-hailing/internal/api/middleware"
+	"github.com/gocomet/ride-hailing/pkg/auth"
 	"github.com/newrelic/go-agent/v3/integrations/nrgin"
 	"github.com/newrelic/go-agent/v3/newrelic"
 )
 
 // SetupRoutes configures all API routes
 func SetupRoutes(r *gin.Engine, h *handlers.Handlers, nrApp *newrelic.Application) {
+	// Tag every request with a correlation ID and scoped logger
+	r.Use(middleware.RequestID(h.Logger))
+
+	// CORS is permissive in development and limited to CORS_ALLOWED_ORIGINS otherwise
+	r.Use(middleware.CORS(h.Config.CORS, h.Config.IsDevelopment()))
+
 	// Add New Relic middleware if enabled
 	if nrApp != nil {
 		r.Use(nrgin.Middleware(nrApp))
 	}
 
-	// Health check
+	// Prometheus request metrics and scrape endpoint
+	if h.Metrics != nil {
+		r.Use(h.Metrics.Middleware())
+		r.GET("/metrics", gin.WrapH(h.Metrics.Handler()))
+	}
+
+	// Health checks: /health is a cheap liveness probe, /health/ready verifies dependencies
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "healthy"})
 	})
+	r.GET("/health/ready", h.ReadinessCheck)
+
+	// Redis connection pool counters (hits, misses, timeouts)
+	r.GET("/debug/redis", h.RedisStats)
+
+	// Rate limits from RateLimitConfig; location updates and ride requests have their own budgets
+	limits := h.Config.RateLimit
+	locationLimit := middleware.RateLimit(h.Redis, h.Logger, "location", limits.LocationUpdatesPerSecond, time.Second, middleware.ParamKey("id"))
+	rideLimit := middleware.RateLimit(h.Redis, h.Logger, "rides", limits.RideRequestsPerMinute, time.Minute, middleware.RiderKey)
+	generalLimit := middleware.SkipPaths(
+		middleware.RateLimit(h.Redis, h.Logger, "general", limits.GeneralPerMinute, time.Minute, middleware.ClientIPKey),
+		"POST /v1/rides", "POST /v1/drivers/:id/location",
+	)
+
+	// Mutating ride, driver and payment endpoints require a bearer token
+	authRequired := middleware.Auth(h.Config.JWT.Secret)
 
 	// API v1 routes
-	v1 := r.Group("/v1")
+	v1 := r.Group("/v1", generalLimit)
 	{
+		// Development token issuance
+		if h.Config.IsDevelopment() {
+			v1.POST("/auth/token", h.IssueToken)
+		}
+
 		// WebSocket connection
 		v1.GET("/ws", h.HandleWebSocket)
 
 		// Ride endpoints
 		rides := v1.Group("/rides")
 		{
-			rides.POST("", h.CreateRide)
+			rides.POST("", authRequired, rideLimit, h.CreateRide)
+			rides.POST("/estimate", h.EstimateFare)
+			rides.GET("/scheduled", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeAdmin), h.GetScheduledRides)
 			rides.GET("/:id", h.GetRide)
+			rides.POST("/:id/cancel", authRequired, h.CancelRide)
+			rides.GET("/:id/timeline", authRequired, h.GetRideTimeline)
 		}
 
 		// Driver endpoints
 		drivers := v1.Group("/drivers")
 		{
+			drivers.POST("", h.RegisterDriver)
+			drivers.POST("/bulk", authRequired, middleware.RequireUserType(auth.UserTypeAdmin), h.BulkCreateDrivers)
 			drivers.GET("/all", h.GetAllDrivers)
+			drivers.GET("/nearby", h.GetNearbyDrivers)
 			drivers.GET("/random", h.GetRandomDriver)
-			drivers.POST("/:id/location", h.UpdateDriverLocation)
-			drivers.POST("/:id/accept", h.AcceptRide)
+			drivers.GET("/:id", h.GetDriver)
+			drivers.DELETE("/:id", authRequired, middleware.RequireUserType(auth.UserTypeDriver, auth.UserTypeAdmin), h.DeleteDriver)
+			drivers.POST("/:id/location", authRequired, middleware.RequireUserType(auth.UserTypeDriver, auth.UserTypeAdmin), locationLimit, h.UpdateDriverLocation)
+			drivers.PUT("/:id/status", authRequired, middleware.RequireUserType(auth.UserTypeDriver, auth.UserTypeAdmin), h.UpdateDriverStatus)
+			drivers.POST("/:id/accept", authRequired, middleware.RequireUserType(auth.UserTypeDriver, auth.UserTypeAdmin), h.AcceptRide)
+			drivers.POST("/:id/reject", authRequired, middleware.RequireUserType(auth.UserTypeDriver, auth.UserTypeAdmin), h.RejectRide)
+			drivers.GET("/:id/earnings", authRequired, middleware.RequireUserType(auth.UserTypeDriver, auth.UserTypeDashboard, auth.UserTypeAdmin), h.GetDriverEarnings)
+			drivers.GET("/:id/current-ride", authRequired, middleware.RequireUserType(auth.UserTypeDriver, auth.UserTypeDashboard, auth.UserTypeAdmin), h.GetDriverCurrentRide)
 		}
 
 		// Trip endpoints
 		trips := v1.Group("/trips")
 		{
-			trips.POST("/:id/end", h.EndTrip)
+			trips.POST("/:id/start", authRequired, middleware.RequireUserType(auth.UserTypeDriver), h.StartTrip)
+			trips.POST("/:id/end", authRequired, middleware.RequireUserType(auth.UserTypeDriver), h.EndTrip)
+			trips.GET("/:id/payment", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeDriver, auth.UserTypeAdmin), h.GetPaymentByTrip)
 		}
 
 		// Payment endpoints
-		v1.POST("/payments", h.ProcessPayment)
+		payments := v1.Group("/payments")
+		{
+			payments.POST("", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeAdmin), h.ProcessPayment)
+			payments.GET("/:id", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeDriver, auth.UserTypeAdmin), h.GetPayment)
+			payments.POST("/:id/refund", authRequired, middleware.RequireUserType(auth.UserTypeAdmin), h.RefundPayment)
+		}
 
 		// Rider endpoints (testing)
 		riders := v1.Group("/riders")
 		{
 			riders.GET("/random", h.GetRandomRider)
+			riders.DELETE("/:id", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeAdmin), h.DeleteRider)
+			riders.GET("/:id/rides", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeAdmin), h.GetRiderRides)
+			riders.GET("/:id/active-ride", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeDashboard, auth.UserTypeAdmin), h.GetRiderActiveRide)
+			riders.GET("/:id/favorites", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeAdmin), h.GetFavoriteDrivers)
+			riders.POST("/:id/favorites/:driverId", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeAdmin), h.AddFavoriteDriver)
+			riders.DELETE("/:id/favorites/:driverId", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeAdmin), h.RemoveFavoriteDriver)
+			riders.GET("/:id/wallet", authRequired, middleware.RequireUserType(auth.UserTypeRider, auth.UserTypeAdmin), h.GetWallet)
+			riders.POST("/:id/wallet/topup", authRequired, middleware.RequireUserType(auth.UserTypeAdmin), h.TopUpWallet)
+		}
+
+		// Admin endpoints
+		admin := v1.Group("/admin", authRequired, middleware.RequireUserType(auth.UserTypeAdmin))
+		{
+			admin.POST("/payouts", h.CreatePayouts)
+			admin.GET("/rides", h.SearchRides)
+			admin.GET("/stats", h.GetStats)
+			admin.GET("/surge", h.ListSurge)
+			admin.PUT("/surge/:region", h.SetSurge)
 		}
 	}
 }
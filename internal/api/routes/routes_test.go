@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/internal/api/handlers"
+	"github.com/gocomet/ride-hailing/internal/config"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/gocomet/ride-hailing/pkg/monitoring"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSetupRoutes_MonitoringDisabled tests that routing boots and serves requests without New Relic
+func TestSetupRoutes_MonitoringDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	assert.NoError(t, err)
+
+	nrApp, err := monitoring.New(monitoring.Config{Enabled: false})
+	assert.NoError(t, err)
+
+	h := &handlers.Handlers{Logger: log, Config: &config.Config{}, NewRelic: nrApp}
+	r := gin.New()
+	assert.NotPanics(t, func() {
+		SetupRoutes(r, h, nrApp.App())
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
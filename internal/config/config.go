@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -18,6 +19,10 @@ type Config struct {
 	JWT         JWTConfig
 	Pricing     PricingConfig
 	Matching    MatchingConfig
+	Routing     RoutingConfig
+	Scheduling  SchedulingConfig
+	Trips       TripsConfig
+	Payment     PaymentConfig
 	RateLimit   RateLimitConfig
 	WebSocket   WebSocketConfig
 	Cache       CacheConfig
@@ -33,15 +38,16 @@ type ServerConfig struct {
 }
 
 type DatabaseConfig struct {
-	Host            string
-	Port            string
-	Name            string
-	User            string
-	Password        string
-	SSLMode         string
-	MaxConnections  int
-	MaxIdleConns    int
-	MaxLifetime     time.Duration
+	Host                  string
+	Port                  string
+	Name                  string
+	User                  string
+	Password              string
+	SSLMode               string
+	MaxConnections        int
+	MaxIdleConns          int
+	MaxLifetime           time.Duration
+	LocationFlushInterval time.Duration // how often buffered driver locations are written
 }
 
 type RedisConfig struct {
@@ -52,8 +58,9 @@ type RedisConfig struct {
 	MaxRetries  int
 	PoolSize    int
 	MinIdleConn int
-	DialTimeout time.Duration
-	ReadTimeout time.Duration
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
 }
 
 type NewRelicConfig struct {
@@ -84,14 +91,102 @@ type PricingConfig struct {
 		Premium int
 		Luxury  int
 	}
+	PerWaitMinuteRate struct {
+		Economy int
+		Premium int
+		Luxury  int
+	}
+	MinimumFare struct {
+		Economy int
+		Premium int
+		Luxury  int
+	}
 	MaxSurgeMultiplier float64
 	MinSurgeMultiplier float64
+	SurgeRecomputeInterval  time.Duration
+	SurgeRegionPrecision    int
+	SurgeOverrideTTL        time.Duration
+	SurgeNotifyThreshold    float64 // smallest recomputed change pushed to subscribed riders
+	CancellationFee         int
+	CancellationGracePeriod time.Duration
+	PoolDiscountPercent     int // taken off the fare of a shared ride
 }
 
 type MatchingConfig struct {
-	MaxRadiusKM      float64
-	MaxTimeout       time.Duration
-	MaxCandidates    int
+	MaxRadiusKM        float64
+	MaxExpandedRadius  float64
+	MaxTimeout         time.Duration
+	MaxCandidates      int
+	AvgCitySpeedKMH    float64
+	MaxRematchAttempts int
+
+	// SearchRadiiKM are the ascending radii a driver search expands through, up to
+	// MaxExpandedRadius; empty keeps the default 1x/2x/4x/10x of MaxRadiusKM
+	SearchRadiiKM []float64
+
+	// InitialBatchSize candidates are fetched first in the smallest radius, the rest of
+	// MaxCandidates only if none of them can be claimed; 0 always fetches MaxCandidates
+	InitialBatchSize int
+
+	// ETAs slow by ETASurgeSlowdown for every 1.0x of surge above 1, surge standing in for traffic
+	ETASurgeSlowdown float64
+
+	NearbyMaxRadiusKM  float64
+	NearbyMaxResults   int
+
+	// Pool rides share a driver with up to PoolCapacity riders whose dropoffs are
+	// within PoolMaxDetourKM of each other
+	PoolCapacity    int
+	PoolMaxDetourKM float64
+
+	// A rider's favorite driver is matched over a stranger up to FavoriteDriverBandKM closer
+	FavoriteDriverBandKM float64
+
+	// Drivers within RatingBucketKM of the nearest candidate are matched best-rated first
+	RatingBucketKM float64
+
+	// ClaimReconcileInterval is how often orphaned driver claims are swept back into the pool
+	ClaimReconcileInterval time.Duration
+
+	// Drivers silent for longer than StaleDriverThreshold are dropped from the geo index
+	// by a sweep every StaleDriverSweepInterval
+	StaleDriverThreshold     time.Duration
+	StaleDriverSweepInterval time.Duration
+
+	// Assigned rides not accepted within AssignmentTimeout are re-offered to the next driver;
+	// lapsed offers are checked every AssignmentCheckInterval
+	AssignmentTimeout       time.Duration
+	AssignmentCheckInterval time.Duration
+}
+
+type RoutingConfig struct {
+	WindingFactor float64
+	MaxWaypoints  int
+}
+
+type SchedulingConfig struct {
+	DispatchLeadTime time.Duration
+	PollInterval     time.Duration
+	MatchGracePeriod time.Duration
+}
+
+type TripsConfig struct {
+	// Client-reported durations further than this from the measured trip are logged for review
+	DurationDiscrepancyMinutes int
+
+	// Reported distances are capped at the straight-line route x MaxRoadFactor + DistanceMarginKM
+	MaxRoadFactor    float64
+	DistanceMarginKM float64
+}
+
+type PaymentConfig struct {
+	// Transient PSP failures are retried up to ChargeMaxAttempts times in total, waiting
+	// ChargeBackoff before the first retry and doubling it each time
+	ChargeMaxAttempts int
+	ChargeBackoff     time.Duration
+
+	// MockFailureRate is the share of charges the mock PSP fails transiently, for testing retries
+	MockFailureRate float64
 }
 
 type RateLimitConfig struct {
@@ -104,6 +199,26 @@ type WebSocketConfig struct {
 	ReadBufferSize       int
 	WriteBufferSize      int
 	HeartbeatInterval    time.Duration
+
+	// A connection is closed when no pong arrives within PongWait of the last one, or a
+	// write takes longer than WriteWait. HeartbeatInterval (the ping period) must be
+	// shorter than PongWait; high-latency mobile networks need a longer PongWait.
+	PongWait       time.Duration
+	WriteWait      time.Duration
+	MaxMessageSize int64
+
+	// The last HistorySize messages per user are kept for HistoryTTL so reconnects can resume
+	HistorySize int
+	HistoryTTL  time.Duration
+	// HistoryTimeout bounds each history round trip while a message is sent or resumed
+	HistoryTimeout time.Duration
+
+	// SendTimeout is how long a message waits on a client's full buffer before the client is dropped
+	SendTimeout time.Duration
+	// Per-client and hub broadcast queue lengths; bigger buffers cost memory per connection
+	// but drop fewer clients during bursts
+	SendBufferSize      int
+	BroadcastBufferSize int
 }
 
 type CacheConfig struct {
@@ -113,9 +228,14 @@ type CacheConfig struct {
 }
 
 type LogConfig struct {
-	Level  string
-	Format string
-	Output string
+	Level              string
+	Format             string
+	Output             string
+	MaxSizeMB          int
+	MaxBackups         int
+	MaxAgeDays         int
+	SamplingInitial    int
+	SamplingThereafter int
 }
 
 type CORSConfig struct {
@@ -125,9 +245,10 @@ type CORSConfig struct {
 }
 
 type FeatureFlags struct {
-	EnableSurgePricing    bool
-	EnableAutoMatching    bool
-	EnableRealTimeUpdates bool
+	EnableSurgePricing      bool
+	EnableAutoMatching      bool
+	EnableRealTimeUpdates   bool
+	EnablePrometheusMetrics bool
 }
 
 // Load loads configuration from environment variables
@@ -142,15 +263,16 @@ func Load() (*Config, error) {
 			Host: getEnv("SERVER_HOST", "0.0.0.0"),
 		},
 		Database: DatabaseConfig{
-			Host:            getEnv("DB_HOST", "localhost"),
-			Port:            getEnv("DB_PORT", "5432"),
-			Name:            getEnv("DB_NAME", "gocomet"),
-			User:            getEnv("DB_USER", "postgres"),
-			Password:        getEnv("DB_PASSWORD", "postgres"),
-			SSLMode:         getEnv("DB_SSLMODE", "disable"),
-			MaxConnections:  getEnvAsInt("DB_MAX_CONNECTIONS", 100),
-			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNECTIONS", 10),
-			MaxLifetime:     time.Duration(getEnvAsInt("DB_MAX_LIFETIME_MINUTES", 30)) * time.Minute,
+			Host:                  getEnv("DB_HOST", "localhost"),
+			Port:                  getEnv("DB_PORT", "5432"),
+			Name:                  getEnv("DB_NAME", "gocomet"),
+			User:                  getEnv("DB_USER", "postgres"),
+			Password:              getEnv("DB_PASSWORD", "postgres"),
+			SSLMode:               getEnv("DB_SSLMODE", "disable"),
+			MaxConnections:        getEnvAsInt("DB_MAX_CONNECTIONS", 100),
+			MaxIdleConns:          getEnvAsInt("DB_MAX_IDLE_CONNECTIONS", 10),
+			MaxLifetime:           time.Duration(getEnvAsInt("DB_MAX_LIFETIME_MINUTES", 30)) * time.Minute,
+			LocationFlushInterval: time.Duration(getEnvAsInt("DB_LOCATION_FLUSH_INTERVAL_SECONDS", 5)) * time.Second,
 		},
 		Redis: RedisConfig{
 			Host:        getEnv("REDIS_HOST", "localhost"),
@@ -159,9 +281,10 @@ func Load() (*Config, error) {
 			DB:          getEnvAsInt("REDIS_DB", 0),
 			MaxRetries:  getEnvAsInt("REDIS_MAX_RETRIES", 3),
 			PoolSize:    getEnvAsInt("REDIS_POOL_SIZE", 100),
-			MinIdleConn: 10,
-			DialTimeout: 5 * time.Second,
-			ReadTimeout: 3 * time.Second,
+			MinIdleConn:  getEnvAsInt("REDIS_MIN_IDLE_CONNECTIONS", 10),
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  3 * time.Second,
+			WriteTimeout: time.Duration(getEnvAsInt("REDIS_WRITE_TIMEOUT_SECONDS", 3)) * time.Second,
 		},
 		NewRelic: NewRelicConfig{
 			LicenseKey: getEnv("NEW_RELIC_LICENSE_KEY", ""),
@@ -174,9 +297,52 @@ func Load() (*Config, error) {
 			Expiry: parseDuration(getEnv("JWT_EXPIRY", "24h"), 24*time.Hour),
 		},
 		Matching: MatchingConfig{
-			MaxRadiusKM:   getEnvAsFloat64("MAX_MATCHING_RADIUS_KM", 5.0),
-			MaxTimeout:    time.Duration(getEnvAsInt("MAX_MATCHING_TIMEOUT_SECONDS", 30)) * time.Second,
-			MaxCandidates: getEnvAsInt("MAX_DRIVER_CANDIDATES", 10),
+			MaxRadiusKM:        getEnvAsFloat64("MAX_MATCHING_RADIUS_KM", 5.0),
+			MaxExpandedRadius:  getEnvAsFloat64("MAX_EXPANDED_MATCHING_RADIUS_KM", 50.0),
+			MaxTimeout:         time.Duration(getEnvAsInt("MAX_MATCHING_TIMEOUT_SECONDS", 30)) * time.Second,
+			SearchRadiiKM:      getEnvAsFloat64Slice("MATCHING_SEARCH_RADII_KM", nil),
+			MaxCandidates:      getEnvAsInt("MAX_DRIVER_CANDIDATES", 10),
+			InitialBatchSize:   getEnvAsInt("MATCHING_INITIAL_BATCH_SIZE", 5),
+			AvgCitySpeedKMH:    getEnvAsFloat64("AVG_CITY_SPEED_KMH", 25.0),
+			MaxRematchAttempts: getEnvAsInt("MAX_REMATCH_ATTEMPTS", 3),
+			ETASurgeSlowdown:   getEnvAsFloat64("ETA_SURGE_SLOWDOWN", 0.3),
+			NearbyMaxRadiusKM:  getEnvAsFloat64("NEARBY_DRIVERS_MAX_RADIUS_KM", 10.0),
+			NearbyMaxResults:   getEnvAsInt("NEARBY_DRIVERS_MAX_RESULTS", 20),
+
+			PoolCapacity:    getEnvAsInt("POOL_CAPACITY", 2),
+			PoolMaxDetourKM: getEnvAsFloat64("POOL_MAX_DETOUR_KM", 2.0),
+
+			FavoriteDriverBandKM: getEnvAsFloat64("FAVORITE_DRIVER_BAND_KM", 0.5),
+
+			RatingBucketKM: getEnvAsFloat64("MATCHING_RATING_BUCKET_KM", 0.5),
+
+			ClaimReconcileInterval: time.Duration(getEnvAsInt("CLAIM_RECONCILE_INTERVAL_SECONDS", 60)) * time.Second,
+
+			StaleDriverThreshold:     time.Duration(getEnvAsInt("STALE_DRIVER_THRESHOLD_SECONDS", 120)) * time.Second,
+			StaleDriverSweepInterval: time.Duration(getEnvAsInt("STALE_DRIVER_SWEEP_INTERVAL_SECONDS", 60)) * time.Second,
+
+			AssignmentTimeout:       time.Duration(getEnvAsInt("RIDE_ASSIGNMENT_TIMEOUT_SECONDS", 15)) * time.Second,
+			AssignmentCheckInterval: time.Duration(getEnvAsInt("RIDE_ASSIGNMENT_CHECK_INTERVAL_SECONDS", 5)) * time.Second,
+		},
+		Routing: RoutingConfig{
+			WindingFactor: getEnvAsFloat64("ROUTE_WINDING_FACTOR", 1.3),
+			MaxWaypoints:  getEnvAsInt("MAX_RIDE_WAYPOINTS", 5),
+		},
+		Scheduling: SchedulingConfig{
+			DispatchLeadTime: time.Duration(getEnvAsInt("SCHEDULED_RIDE_DISPATCH_LEAD_MINUTES", 10)) * time.Minute,
+			PollInterval:     time.Duration(getEnvAsInt("SCHEDULED_RIDE_POLL_INTERVAL_SECONDS", 30)) * time.Second,
+			MatchGracePeriod: time.Duration(getEnvAsInt("SCHEDULED_RIDE_MATCH_GRACE_MINUTES", 10)) * time.Minute,
+		},
+		Trips: TripsConfig{
+			DurationDiscrepancyMinutes: getEnvAsInt("TRIP_DURATION_DISCREPANCY_MINUTES", 5),
+
+			MaxRoadFactor:    getEnvAsFloat64("TRIP_MAX_ROAD_FACTOR", 2.0),
+			DistanceMarginKM: getEnvAsFloat64("TRIP_DISTANCE_MARGIN_KM", 1.0),
+		},
+		Payment: PaymentConfig{
+			ChargeMaxAttempts: getEnvAsInt("PAYMENT_CHARGE_MAX_ATTEMPTS", 3),
+			ChargeBackoff:     time.Duration(getEnvAsInt("PAYMENT_CHARGE_BACKOFF_MS", 200)) * time.Millisecond,
+			MockFailureRate:   getEnvAsFloat64("PAYMENT_MOCK_FAILURE_RATE", 0),
 		},
 		RateLimit: RateLimitConfig{
 			LocationUpdatesPerSecond: getEnvAsInt("RATE_LIMIT_LOCATION_UPDATES_PER_SECOND", 2),
@@ -184,24 +350,44 @@ func Load() (*Config, error) {
 			GeneralPerMinute:         getEnvAsInt("RATE_LIMIT_GENERAL_PER_MINUTE", 100),
 		},
 		WebSocket: WebSocketConfig{
-			ReadBufferSize:    getEnvAsInt("WS_READ_BUFFER_SIZE", 1024),
-			WriteBufferSize:   getEnvAsInt("WS_WRITE_BUFFER_SIZE", 1024),
-			HeartbeatInterval: time.Duration(getEnvAsInt("WS_HEARTBEAT_INTERVAL_SECONDS", 30)) * time.Second,
+			ReadBufferSize:      getEnvAsInt("WS_READ_BUFFER_SIZE", 1024),
+			WriteBufferSize:     getEnvAsInt("WS_WRITE_BUFFER_SIZE", 1024),
+			HeartbeatInterval:   time.Duration(getEnvAsInt("WS_HEARTBEAT_INTERVAL_SECONDS", 30)) * time.Second,
+			PongWait:            time.Duration(getEnvAsInt("WS_PONG_WAIT_SECONDS", 60)) * time.Second,
+			WriteWait:           time.Duration(getEnvAsInt("WS_WRITE_WAIT_SECONDS", 10)) * time.Second,
+			MaxMessageSize:      int64(getEnvAsInt("WS_MAX_MESSAGE_SIZE", 512)),
+			HistorySize:         getEnvAsInt("WS_HISTORY_SIZE", 100),
+			HistoryTTL:          time.Duration(getEnvAsInt("WS_HISTORY_TTL_MINUTES", 15)) * time.Minute,
+			HistoryTimeout:      time.Duration(getEnvAsInt("WS_HISTORY_TIMEOUT_MS", 250)) * time.Millisecond,
+			SendTimeout:         time.Duration(getEnvAsInt("WS_SEND_TIMEOUT_MS", 50)) * time.Millisecond,
+			SendBufferSize:      getEnvAsInt("WS_SEND_BUFFER_SIZE", 256),
+			BroadcastBufferSize: getEnvAsInt("WS_BROADCAST_BUFFER_SIZE", 256),
 		},
 		Cache: CacheConfig{
 			TTLActiveRides:     time.Duration(getEnvAsInt("CACHE_TTL_ACTIVE_RIDES", 300)) * time.Second,
 			TTLDriverLocations: time.Duration(getEnvAsInt("CACHE_TTL_DRIVER_LOCATIONS", 300)) * time.Second,
 			TTLIdempotency:     time.Duration(getEnvAsInt("CACHE_TTL_IDEMPOTENCY", 86400)) * time.Second,
 		},
+		CORS: CORSConfig{
+			AllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000", "http://localhost:8080"}),
+			AllowedMethods: getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "Idempotency-Key"}),
+		},
 		Log: LogConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
-			Output: getEnv("LOG_OUTPUT", "stdout"),
+			Level:              getEnv("LOG_LEVEL", "info"),
+			Format:             getEnv("LOG_FORMAT", "json"),
+			Output:             getEnv("LOG_OUTPUT", "stdout"),
+			MaxSizeMB:          getEnvAsInt("LOG_MAX_SIZE_MB", 100),
+			MaxBackups:         getEnvAsInt("LOG_MAX_BACKUPS", 5),
+			MaxAgeDays:         getEnvAsInt("LOG_MAX_AGE_DAYS", 7),
+			SamplingInitial:    getEnvAsInt("LOG_SAMPLING_INITIAL", 0),
+			SamplingThereafter: getEnvAsInt("LOG_SAMPLING_THEREAFTER", 100),
 		},
 		Features: FeatureFlags{
-			EnableSurgePricing:    getEnvAsBool("ENABLE_SURGE_PRICING", true),
-			EnableAutoMatching:    getEnvAsBool("ENABLE_AUTO_MATCHING", true),
-			EnableRealTimeUpdates: getEnvAsBool("ENABLE_REAL_TIME_UPDATES", true),
+			EnableSurgePricing:      getEnvAsBool("ENABLE_SURGE_PRICING", true),
+			EnableAutoMatching:      getEnvAsBool("ENABLE_AUTO_MATCHING", true),
+			EnableRealTimeUpdates:   getEnvAsBool("ENABLE_REAL_TIME_UPDATES", true),
+			EnablePrometheusMetrics: getEnvAsBool("ENABLE_PROMETHEUS_METRICS", true),
 		},
 	}
 
@@ -218,8 +404,24 @@ func Load() (*Config, error) {
 	cfg.Pricing.PerMinuteRate.Premium = getEnvAsInt("PER_MINUTE_RATE_PREMIUM", 3)
 	cfg.Pricing.PerMinuteRate.Luxury = getEnvAsInt("PER_MINUTE_RATE_LUXURY", 5)
 
+	// Waiting at pickup is charged at the per-minute rate unless overridden
+	cfg.Pricing.PerWaitMinuteRate.Economy = getEnvAsInt("PER_WAIT_MINUTE_RATE_ECONOMY", cfg.Pricing.PerMinuteRate.Economy)
+	cfg.Pricing.PerWaitMinuteRate.Premium = getEnvAsInt("PER_WAIT_MINUTE_RATE_PREMIUM", cfg.Pricing.PerMinuteRate.Premium)
+	cfg.Pricing.PerWaitMinuteRate.Luxury = getEnvAsInt("PER_WAIT_MINUTE_RATE_LUXURY", cfg.Pricing.PerMinuteRate.Luxury)
+
+	cfg.Pricing.MinimumFare.Economy = getEnvAsInt("MIN_FARE_ECONOMY", 80)
+	cfg.Pricing.MinimumFare.Premium = getEnvAsInt("MIN_FARE_PREMIUM", 150)
+	cfg.Pricing.MinimumFare.Luxury = getEnvAsInt("MIN_FARE_LUXURY", 300)
+
 	cfg.Pricing.MaxSurgeMultiplier = getEnvAsFloat64("MAX_SURGE_MULTIPLIER", 3.0)
 	cfg.Pricing.MinSurgeMultiplier = getEnvAsFloat64("MIN_SURGE_MULTIPLIER", 1.0)
+	cfg.Pricing.SurgeRecomputeInterval = time.Duration(getEnvAsInt("SURGE_RECOMPUTE_INTERVAL_SECONDS", 60)) * time.Second
+	cfg.Pricing.SurgeRegionPrecision = getEnvAsInt("SURGE_REGION_PRECISION", 5)
+	cfg.Pricing.SurgeOverrideTTL = time.Duration(getEnvAsInt("SURGE_OVERRIDE_TTL_MINUTES", 30)) * time.Minute
+	cfg.Pricing.SurgeNotifyThreshold = getEnvAsFloat64("SURGE_NOTIFY_THRESHOLD", 0.2)
+	cfg.Pricing.CancellationFee = getEnvAsInt("CANCELLATION_FEE", 50)
+	cfg.Pricing.CancellationGracePeriod = time.Duration(getEnvAsInt("CANCELLATION_GRACE_MINUTES", 2)) * time.Minute
+	cfg.Pricing.PoolDiscountPercent = getEnvAsInt("POOL_DISCOUNT_PERCENT", 25)
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
@@ -229,6 +431,21 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// IsDevelopment reports whether the server runs in development mode
+func (c *Config) IsDevelopment() bool {
+	return c.Server.Env == "development"
+}
+
+// AllowsOrigin reports whether a browser origin is allowed; "*" allows any origin
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	if c.Server.Port == "" {
@@ -240,11 +457,38 @@ func (c *Config) Validate() error {
 	if c.Database.Name == "" {
 		return fmt.Errorf("DB_NAME is required")
 	}
+	if _, err := strconv.Atoi(c.Database.Port); err != nil {
+		return fmt.Errorf("DB_PORT must be a number: %w", err)
+	}
 	if c.Redis.Host == "" {
 		return fmt.Errorf("REDIS_HOST is required")
 	}
-	if c.JWT.Secret == "your_jwt_secret_key_here" && c.Server.Env == "production" {
-		return fmt.Errorf("JWT_SECRET must be set in production")
+	if err := c.Matching.validateSearchRadii(); err != nil {
+		return err
+	}
+	if c.WebSocket.HeartbeatInterval >= c.WebSocket.PongWait {
+		return fmt.Errorf("WS_HEARTBEAT_INTERVAL_SECONDS (%v) must be shorter than WS_PONG_WAIT_SECONDS (%v)", c.WebSocket.HeartbeatInterval, c.WebSocket.PongWait)
+	}
+	// The default secret is public, so only development may sign with it
+	if c.JWT.Secret == "your_jwt_secret_key_here" && !c.IsDevelopment() {
+		return fmt.Errorf("JWT_SECRET must be set outside development")
+	}
+	return nil
+}
+
+// validateSearchRadii checks that a configured search schedule is positive, strictly
+// ascending and within MaxExpandedRadius
+func (m MatchingConfig) validateSearchRadii() error {
+	for i, r := range m.SearchRadiiKM {
+		if r <= 0 {
+			return fmt.Errorf("MATCHING_SEARCH_RADII_KM must be positive, got %v", r)
+		}
+		if i > 0 && r <= m.SearchRadiiKM[i-1] {
+			return fmt.Errorf("MATCHING_SEARCH_RADII_KM must be ascending, got %v after %v", r, m.SearchRadiiKM[i-1])
+		}
+		if m.MaxExpandedRadius > 0 && r > m.MaxExpandedRadius {
+			return fmt.Errorf("MATCHING_SEARCH_RADII_KM must not exceed MAX_EXPANDED_MATCHING_RADIUS_KM (%v), got %v", m.MaxExpandedRadius, r)
+		}
 	}
 	return nil
 }
@@ -274,6 +518,41 @@ func getEnvAsFloat64(key string, defaultValue float64) float64 {
 	return defaultValue
 }
 
+// getEnvAsSlice splits a comma-separated value, trimming whitespace and dropping empty entries
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, v := range strings.Split(valueStr, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// getEnvAsFloat64Slice parses a comma-separated list of numbers, falling back to defaultValue
+// if any entry isn't one
+func getEnvAsFloat64Slice(key string, defaultValue []float64) []float64 {
+	entries := getEnvAsSlice(key, nil)
+	if len(entries) == 0 {
+		return defaultValue
+	}
+
+	values := make([]float64, 0, len(entries))
+	for _, entry := range entries {
+		value, err := strconv.ParseFloat(entry, 64)
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := getEnv(key, "")
 	if value, err := strconv.ParseBool(valueStr); err == nil {
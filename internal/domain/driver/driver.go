@@ -40,6 +40,12 @@ type Driver struct {
 	UpdatedAt        time.Time   `json:"updated_at"`
 }
 
+// Summary holds a driver's lifetime totals
+type Summary struct {
+	CompletedRides int
+	TotalEarnings  float64
+}
+
 // Location represents a geographic location
 type Location struct {
 	Latitude  float64
@@ -84,6 +90,53 @@ func (v VehicleType) IsValid() bool {
 	return false
 }
 
+// vehicleSizes orders vehicle types from smallest to largest; matching upgrades along it
+var vehicleSizes = []VehicleType{VehicleEconomy, VehiclePremium, VehicleLuxury}
+
+// VehicleTypes returns every vehicle type, smallest first
+func VehicleTypes() []VehicleType {
+	return append([]VehicleType(nil), vehicleSizes...)
+}
+
+// seatCapacity is the number of passengers each vehicle type carries
+var seatCapacity = map[VehicleType]int{
+	VehicleEconomy: 4,
+	VehiclePremium: 4,
+	VehicleLuxury:  6,
+}
+
+// Seats returns the passenger capacity of the vehicle type, or 0 if it is unknown
+func (v VehicleType) Seats() int {
+	return seatCapacity[v]
+}
+
+// MaxSeats returns the largest passenger capacity of any vehicle type
+func MaxSeats() int {
+	largest := 0
+	for _, seats := range seatCapacity {
+		if seats > largest {
+			largest = seats
+		}
+	}
+	return largest
+}
+
+// TypesForSeats returns v and every larger vehicle type that carries at least seats
+// passengers, smallest first
+func (v VehicleType) TypesForSeats(seats int) []VehicleType {
+	var types []VehicleType
+	larger := false
+	for _, vt := range vehicleSizes {
+		if vt == v {
+			larger = true
+		}
+		if larger && vt.Seats() >= seats {
+			types = append(types, vt)
+		}
+	}
+	return types
+}
+
 // CanAcceptRides returns true if driver can accept new rides
 func (d *Driver) CanAcceptRides() bool {
 	return d.Status == StatusOnline
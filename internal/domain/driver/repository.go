@@ -17,6 +17,9 @@ type Repository interface {
 	// GetByEmail retrieves a driver by email
 	GetByEmail(ctx context.Context, email string) (*Driver, error)
 
+	// List retrieves a page of drivers matching filter and the total number of matches
+	List(ctx context.Context, filter ListFilter) ([]*Driver, int, error)
+
 	// Update updates a driver
 	Update(ctx context.Context, driver *Driver) error
 
@@ -32,6 +35,17 @@ type Repository interface {
 	// GetAvailableDrivers retrieves all online drivers
 	GetAvailableDrivers(ctx context.Context, vehicleType VehicleType) ([]*Driver, error)
 
-	// Delete deletes a driver
+	// GetSummaries retrieves lifetime ride and earnings totals for drivers
+	GetSummaries(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Summary, error)
+
+	// Delete soft-deletes a driver, keeping their ride history
 	Delete(ctx context.Context, id uuid.UUID) error
 }
+
+// ListFilter narrows and pages a driver listing; empty Status or VehicleType match any value
+type ListFilter struct {
+	Status      Status
+	VehicleType VehicleType
+	Limit       int
+	Offset      int
+}
@@ -0,0 +1,42 @@
+package driver
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Utilization summarizes the sessions a driver started in a window, plus any session still open
+type Utilization struct {
+	// OnlineSince is when the open session started; nil while the driver is offline
+	OnlineSince   *time.Time
+	OnlineSeconds float64
+	BusySeconds   float64
+}
+
+// Rate returns the fraction of online time spent on rides, or 0 with no online time
+func (u Utilization) Rate() float64 {
+	if u.OnlineSeconds <= 0 {
+		return 0
+	}
+	return u.BusySeconds / u.OnlineSeconds
+}
+
+// SessionRepository records the periods drivers spend online and on rides
+type SessionRepository interface {
+	// Start opens a session at at; it does nothing if the driver already has one open
+	Start(ctx context.Context, driverID uuid.UUID, at time.Time) error
+
+	// End closes the driver's open session at at, settling any ride in progress
+	End(ctx context.Context, driverID uuid.UUID, at time.Time) error
+
+	// StartBusy marks the driver busy from at; it does nothing without an open session
+	StartBusy(ctx context.Context, driverID uuid.UUID, at time.Time) error
+
+	// EndBusy adds the time since StartBusy to the open session's busy total
+	EndBusy(ctx context.Context, driverID uuid.UUID, at time.Time) error
+
+	// Utilization totals the sessions started since since, plus the open one, as of until
+	Utilization(ctx context.Context, driverID uuid.UUID, since, until time.Time) (Utilization, error)
+}
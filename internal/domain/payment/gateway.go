@@ -0,0 +1,69 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Gateway moves money through a payment service provider (PSP)
+type Gateway interface {
+	// Charge collects amount with method and returns the PSP's transaction ID
+	Charge(ctx context.Context, amount float64, method Method) (txnID string, err error)
+	// Refund returns amount of the charge txnID and returns the PSP's refund transaction ID
+	Refund(ctx context.Context, txnID string, amount float64) (refundTxnID string, err error)
+}
+
+// ErrGatewayUnavailable marks a transient PSP failure (timeout, outage); calls failing with
+// an error wrapping it may succeed on retry
+var ErrGatewayUnavailable = errors.New("payment gateway unavailable")
+
+// MockGateway stands in for a real PSP: every call takes latency and fails transiently with
+// probability failureRate
+type MockGateway struct {
+	latency     time.Duration
+	failureRate float64
+}
+
+var _ Gateway = (*MockGateway)(nil)
+
+// NewMockGateway creates a mock PSP; a zero latency and failure rate make it instant and reliable
+func NewMockGateway(latency time.Duration, failureRate float64) *MockGateway {
+	return &MockGateway{
+		latency:     latency,
+		failureRate: failureRate,
+	}
+}
+
+// Charge simulates a charge round trip
+func (g *MockGateway) Charge(ctx context.Context, amount float64, method Method) (string, error) {
+	if err := g.roundTrip(ctx); err != nil {
+		return "", fmt.Errorf("charge failed: %w", err)
+	}
+	return fmt.Sprintf("txn_%d_%s", time.Now().Unix(), uuid.NewString()[:8]), nil
+}
+
+// Refund simulates a refund round trip
+func (g *MockGateway) Refund(ctx context.Context, txnID string, amount float64) (string, error) {
+	if err := g.roundTrip(ctx); err != nil {
+		return "", fmt.Errorf("refund failed: %w", err)
+	}
+	return fmt.Sprintf("rfnd_%d_%s", time.Now().Unix(), uuid.NewString()[:8]), nil
+}
+
+// roundTrip waits out the simulated latency and rolls for a transient failure
+func (g *MockGateway) roundTrip(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(g.latency):
+	}
+	if rand.Float64() < g.failureRate {
+		return fmt.Errorf("timed out: %w", ErrGatewayUnavailable)
+	}
+	return nil
+}
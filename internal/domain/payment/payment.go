@@ -27,10 +27,20 @@ const (
 	MethodUPI    Method = "upi"
 )
 
+// WalletTransactionType labels an entry in a rider's wallet ledger
+type WalletTransactionType string
+
+const (
+	WalletTopUp   WalletTransactionType = "topup"
+	WalletPayment WalletTransactionType = "payment"
+	WalletRefund  WalletTransactionType = "refund"
+)
+
 type Payment struct {
 	ID                      uuid.UUID   `json:"id"`
 	TripID                  uuid.UUID   `json:"trip_id"`
 	Amount                  float64     `json:"amount"`
+	RefundedAmount          float64     `json:"refunded_amount"`
 	Status                  Status      `json:"status"`
 	PaymentMethod           Method      `json:"payment_method"`
 	ExternalTransactionID   string      `json:"external_transaction_id,omitempty"`
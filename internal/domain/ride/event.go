@@ -0,0 +1,43 @@
+package ride
+
+import (
+	"context"
+	"time"
+)
+
+// EventType names a step in a ride's lifecycle
+type EventType string
+
+const (
+	EventRequested EventType = "requested"
+	EventAssigned  EventType = "assigned"
+	EventAccepted  EventType = "accepted"
+	EventStarted   EventType = "started"
+	EventCompleted EventType = "completed"
+	EventCancelled EventType = "cancelled"
+	// EventRematched is a ride taken from a driver who declined or let the offer lapse
+	EventRematched EventType = "rematched"
+)
+
+// ActorSystem is the actor type of events no user caused, like offer timeouts and scheduled dispatch
+const ActorSystem = "system"
+
+// Event records one lifecycle transition of a ride and who caused it
+type Event struct {
+	ID        string                 `json:"id"`
+	RideID    string                 `json:"ride_id"`
+	Type      EventType              `json:"type"`
+	ActorType string                 `json:"actor_type"`
+	ActorID   string                 `json:"actor_id,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// EventRepository stores the ride event log
+type EventRepository interface {
+	// Record appends an event, filling in its ID and CreatedAt
+	Record(ctx context.Context, event *Event) error
+
+	// ListByRide returns a ride's events, oldest first
+	ListByRide(ctx context.Context, rideID string) ([]*Event, error)
+}
@@ -3,6 +3,7 @@ package ride
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,6 +13,7 @@ import (
 type Status string
 
 const (
+	StatusScheduled Status = "scheduled"
 	StatusRequested Status = "requested"
 	StatusAssigned  Status = "assigned"
 	StatusAccepted  Status = "accepted"
@@ -30,12 +32,16 @@ const (
 )
 
 // Ride represents a ride request/assignment
+// IDs are strings since rides use timestamp-based IDs (see migration 000008).
 type Ride struct {
-	ID                       uuid.UUID    `json:"id"`
+	ID                       string       `json:"id"`
 	RiderID                  uuid.UUID    `json:"rider_id"`
 	DriverID                 *uuid.UUID   `json:"driver_id,omitempty"`
 	Status                   Status       `json:"status"`
 	VehicleType              VehicleType  `json:"vehicle_type"`
+	Seats                    int          `json:"seats"`
+	Pool                     bool         `json:"pool"`
+	AllowUpgrade             bool         `json:"allow_upgrade"`
 	PickupLatitude           float64      `json:"pickup_latitude"`
 	PickupLongitude          float64      `json:"pickup_longitude"`
 	DropoffLatitude          float64      `json:"dropoff_latitude"`
@@ -45,6 +51,9 @@ type Ride struct {
 	EstimatedFare            *float64     `json:"estimated_fare,omitempty"`
 	EstimatedDistanceKM      *float64     `json:"estimated_distance_km,omitempty"`
 	EstimatedDurationMinutes *int         `json:"estimated_duration_minutes,omitempty"`
+	QuotedSurge              *float64     `json:"quoted_surge,omitempty"`
+	ScheduledAt              *time.Time   `json:"scheduled_at,omitempty"`
+	Waypoints                []Waypoint   `json:"waypoints,omitempty"`
 	RequestedAt              time.Time    `json:"requested_at"`
 	AssignedAt               *time.Time   `json:"assigned_at,omitempty"`
 	AcceptedAt               *time.Time   `json:"accepted_at,omitempty"`
@@ -52,21 +61,36 @@ type Ride struct {
 	CompletedAt              *time.Time   `json:"completed_at,omitempty"`
 	CancelledAt              *time.Time   `json:"cancelled_at,omitempty"`
 	CancellationReason       string       `json:"cancellation_reason,omitempty"`
+	CancellationFee          *float64     `json:"cancellation_fee,omitempty"`
 	IdempotencyKey           string       `json:"-"`
 	CreatedAt                time.Time    `json:"created_at"`
 	UpdatedAt                time.Time    `json:"updated_at"`
 }
 
+// Waypoint is an intermediate stop between pickup and dropoff; Sequence starts at 1
+type Waypoint struct {
+	Sequence  int     `json:"sequence"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
 // Repository interface
 type Repository interface {
 	Create(ctx context.Context, ride *Ride) error
-	GetByID(ctx context.Context, id uuid.UUID) (*Ride, error)
-	GetByIdempotencyKey(ctx context.Context, key string) (*Ride, error)
-	Update(ctx context.Context, ride *Ride) error
-	UpdateStatus(ctx context.Context, id uuid.UUID, status Status) error
-	AssignDriver(ctx context.Context, rideID, driverID uuid.UUID) error
+	GetByID(ctx context.Context, id string) (*Ride, error)
+	GetByIdempotencyKey(ctx context.Context, riderID uuid.UUID, key string) (*Ride, error)
+	// Update writes the ride only while its stored status is still from, so a transition
+	// decided on a stale read can't overwrite a newer one; otherwise it returns ErrStatusChanged
+	Update(ctx context.Context, ride *Ride, from Status) error
+	// UpdateStatus moves the ride from one status to another, returning ErrStatusChanged if
+	// it is no longer in from
+	UpdateStatus(ctx context.Context, id string, from, to Status) error
+	AssignDriver(ctx context.Context, rideID string, driverID uuid.UUID) error
 	GetActiveRideByDriver(ctx context.Context, driverID uuid.UUID) (*Ride, error)
 	GetActiveRideByRider(ctx context.Context, riderID uuid.UUID) (*Ride, error)
+	ListScheduledByRider(ctx context.Context, riderID uuid.UUID) ([]*Ride, error)
+	ListDueScheduled(ctx context.Context, before time.Time) ([]*Ride, error)
+	GetWaypoints(ctx context.Context, rideID string) ([]Waypoint, error)
 }
 
 // Errors
@@ -74,6 +98,7 @@ var (
 	ErrRideNotFound        = errors.New("ride not found")
 	ErrInvalidStatus       = errors.New("invalid status transition")
 	ErrRideAlreadyAssigned = errors.New("ride already assigned")
+	ErrStatusChanged       = errors.New("ride status changed")
 )
 
 // CanAssignDriver checks if a driver can be assigned to this ride
@@ -95,3 +120,48 @@ func (r *Ride) CanStart() bool {
 func (r *Ride) CanComplete() bool {
 	return r.Status == StatusStarted
 }
+
+// IsActive reports whether a ride in this status is in progress: dispatched and not yet finished
+func (s Status) IsActive() bool {
+	switch s {
+	case StatusRequested, StatusAssigned, StatusAccepted, StatusStarted:
+		return true
+	}
+	return false
+}
+
+// IsValid reports whether s is a known ride status
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusScheduled, StatusRequested, StatusAssigned, StatusAccepted, StatusStarted, StatusCompleted, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// transitions lists the statuses each status may legally move to
+var transitions = map[Status][]Status{
+	StatusScheduled: {StatusRequested, StatusCancelled},
+	StatusRequested: {StatusAssigned, StatusCancelled},
+	StatusAssigned:  {StatusAccepted, StatusRequested, StatusCancelled},
+	StatusAccepted:  {StatusStarted, StatusCancelled},
+	StatusStarted:   {StatusCompleted},
+}
+
+// CanTransitionTo checks if a ride in this status may move to the target status
+func (s Status) CanTransitionTo(to Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition validates a status change, returning ErrInvalidStatus for illegal jumps
+func Transition(from, to Status) error {
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStatus, from, to)
+	}
+	return nil
+}
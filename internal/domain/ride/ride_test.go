@@ -0,0 +1,56 @@
+package ride
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTransition_AllowedLifecycle tests the happy-path ride lifecycle
+func TestTransition_AllowedLifecycle(t *testing.T) {
+	lifecycle := []Status{StatusRequested, StatusAssigned, StatusAccepted, StatusStarted, StatusCompleted}
+
+	for i := 0; i < len(lifecycle)-1; i++ {
+		assert.NoError(t, Transition(lifecycle[i], lifecycle[i+1]))
+	}
+}
+
+// TestTransition_ScheduledRide tests that a scheduled ride is dispatched into the normal lifecycle or cancelled
+func TestTransition_ScheduledRide(t *testing.T) {
+	assert.NoError(t, Transition(StatusScheduled, StatusRequested))
+	assert.NoError(t, Transition(StatusScheduled, StatusCancelled))
+}
+
+// TestTransition_RejectsIllegalJumps tests that skipping states is rejected
+func TestTransition_RejectsIllegalJumps(t *testing.T) {
+	tests := []struct {
+		name string
+		from Status
+		to   Status
+	}{
+		{"Complete a requested ride", StatusRequested, StatusCompleted},
+		{"Complete an accepted ride", StatusAccepted, StatusCompleted},
+		{"Start an assigned ride", StatusAssigned, StatusStarted},
+		{"Reopen a completed ride", StatusCompleted, StatusStarted},
+		{"Cancel a started ride", StatusStarted, StatusCancelled},
+		{"Assign a scheduled ride before dispatch", StatusScheduled, StatusAssigned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Transition(tt.from, tt.to)
+			assert.True(t, errors.Is(err, ErrInvalidStatus))
+		})
+	}
+}
+
+// TestStatus_IsActive tests that only dispatched, unfinished rides count as active
+func TestStatus_IsActive(t *testing.T) {
+	for _, s := range []Status{StatusRequested, StatusAssigned, StatusAccepted, StatusStarted} {
+		assert.True(t, s.IsActive(), s)
+	}
+	for _, s := range []Status{StatusScheduled, StatusCompleted, StatusCancelled} {
+		assert.False(t, s.IsActive(), s)
+	}
+}
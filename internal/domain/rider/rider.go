@@ -33,3 +33,9 @@ type Repository interface {
 	Update(ctx context.Context, rider *Rider) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
+
+// FavoriteRepository reads the drivers riders have marked as favorites
+type FavoriteRepository interface {
+	// ListFavoriteDriverIDs returns the rider's favorite drivers that haven't been deleted
+	ListFavoriteDriverIDs(ctx context.Context, riderID uuid.UUID) ([]uuid.UUID, error)
+}
@@ -26,6 +26,8 @@ type Trip struct {
 	BaseFare        float64    `json:"base_fare"`
 	DistanceFare    float64    `json:"distance_fare"`
 	TimeFare        float64    `json:"time_fare"`
+	WaitingMinutes  int        `json:"waiting_minutes"`
+	WaitingFare     float64    `json:"waiting_fare"`
 	SurgeMultiplier float64    `json:"surge_multiplier"`
 	TotalFare       *float64   `json:"total_fare,omitempty"`
 	Status          Status     `json:"status"`
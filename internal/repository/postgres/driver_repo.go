@@ -0,0 +1,250 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DriverRepository implements driver.Repository on PostgreSQL
+type DriverRepository struct {
+	db *sql.DB
+}
+
+// NewDriverRepository creates a new PostgreSQL driver repository
+func NewDriverRepository(db *sql.DB) *DriverRepository {
+	return &DriverRepository{db: db}
+}
+
+var _ driver.Repository = (*DriverRepository)(nil)
+
+const driverColumns = `
+	id, name, email, phone, status, vehicle_type,
+	current_latitude, current_longitude, rating, total_rides,
+	created_at, updated_at
+`
+
+// Create inserts a new driver
+func (r *DriverRepository) Create(ctx context.Context, d *driver.Driver) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO drivers (
+			id, name, email, phone, status, vehicle_type,
+			current_latitude, current_longitude, rating, total_rides
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING created_at, updated_at
+	`, d.ID, d.Name, d.Email, d.Phone, string(d.Status), string(d.VehicleType),
+		d.CurrentLatitude, d.CurrentLongitude, d.Rating, d.TotalRides,
+	).Scan(&d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create driver: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a driver by ID
+func (r *DriverRepository) GetByID(ctx context.Context, id uuid.UUID) (*driver.Driver, error) {
+	return r.getOne(ctx, "id = $1", id)
+}
+
+// GetByEmail retrieves a driver by email
+func (r *DriverRepository) GetByEmail(ctx context.Context, email string) (*driver.Driver, error) {
+	return r.getOne(ctx, "email = $1", email)
+}
+
+// driverListFilter matches the optional status and vehicle type of a ListFilter
+const driverListFilter = `deleted_at IS NULL AND ($1 = '' OR status::text = $1) AND ($2 = '' OR vehicle_type::text = $2)`
+
+// List returns a page of drivers ordered by name along with the total number of matches
+func (r *DriverRepository) List(ctx context.Context, filter driver.ListFilter) ([]*driver.Driver, int, error) {
+	status, vehicleType := string(filter.Status), string(filter.VehicleType)
+
+	var total int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM drivers WHERE "+driverListFilter, status, vehicleType).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count drivers: %w", err)
+	}
+
+	drivers, err := r.query(ctx, `
+		SELECT `+driverColumns+` FROM drivers
+		WHERE `+driverListFilter+`
+		ORDER BY name
+		LIMIT $3 OFFSET $4
+	`, status, vehicleType, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return drivers, total, nil
+}
+
+// Update writes all mutable driver fields
+func (r *DriverRepository) Update(ctx context.Context, d *driver.Driver) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE drivers
+		SET name = $2, email = $3, phone = $4, status = $5, vehicle_type = $6,
+		    current_latitude = $7, current_longitude = $8, rating = $9, total_rides = $10,
+		    updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, d.ID, d.Name, d.Email, d.Phone, string(d.Status), string(d.VehicleType),
+		d.CurrentLatitude, d.CurrentLongitude, d.Rating, d.TotalRides)
+	if err != nil {
+		return fmt.Errorf("failed to update driver: %w", err)
+	}
+	return expectOneRow(result, driver.ErrDriverNotFound)
+}
+
+// UpdateStatus updates driver status
+func (r *DriverRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status driver.Status) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE drivers SET status = $2, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL
+	`, id, string(status))
+	if err != nil {
+		return fmt.Errorf("failed to update driver status: %w", err)
+	}
+	return expectOneRow(result, driver.ErrDriverNotFound)
+}
+
+// UpdateLocation updates driver location
+func (r *DriverRepository) UpdateLocation(ctx context.Context, id uuid.UUID, lat, lng float64) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE drivers
+		SET current_latitude = $2, current_longitude = $3, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id, lat, lng)
+	if err != nil {
+		return fmt.Errorf("failed to update driver location: %w", err)
+	}
+	return expectOneRow(result, driver.ErrDriverNotFound)
+}
+
+// GetNearbyDrivers finds online drivers within radiusKM of a point, nearest first.
+// An empty vehicleType matches any vehicle.
+func (r *DriverRepository) GetNearbyDrivers(ctx context.Context, lat, lng, radiusKM float64, vehicleType driver.VehicleType, limit int) ([]*driver.Driver, error) {
+	return r.query(ctx, `
+		SELECT `+driverColumns+` FROM (
+			SELECT *, 6371 * 2 * ASIN(SQRT(
+				POWER(SIN(RADIANS(current_latitude - $1) / 2), 2) +
+				COS(RADIANS($1)) * COS(RADIANS(current_latitude)) *
+				POWER(SIN(RADIANS(current_longitude - $2) / 2), 2)
+			)) AS distance_km
+			FROM drivers
+			WHERE status = 'online' AND deleted_at IS NULL
+			  AND current_latitude IS NOT NULL AND current_longitude IS NOT NULL
+			  AND ($4 = '' OR vehicle_type::text = $4)
+		) d
+		WHERE distance_km <= $3
+		ORDER BY distance_km
+		LIMIT $5
+	`, lat, lng, radiusKM, string(vehicleType), limit)
+}
+
+// GetAvailableDrivers retrieves all online drivers; an empty vehicleType matches any vehicle
+func (r *DriverRepository) GetAvailableDrivers(ctx context.Context, vehicleType driver.VehicleType) ([]*driver.Driver, error) {
+	return r.query(ctx, `
+		SELECT `+driverColumns+` FROM drivers
+		WHERE status = 'online' AND deleted_at IS NULL AND ($1 = '' OR vehicle_type::text = $1)
+		ORDER BY name
+	`, string(vehicleType))
+}
+
+// GetSummaries returns completed-ride and earnings totals for the given drivers
+func (r *DriverRepository) GetSummaries(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]driver.Summary, error) {
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			d.id,
+			(SELECT COUNT(*) FROM rides WHERE driver_id = d.id AND status = 'completed'),
+			COALESCE((SELECT SUM(total_earnings) FROM driver_earnings WHERE driver_id = d.id), 0)
+		FROM drivers d
+		WHERE d.id = ANY($1::uuid[])
+	`, pq.Array(idStrings))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make(map[uuid.UUID]driver.Summary, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		var s driver.Summary
+		if err := rows.Scan(&id, &s.CompletedRides, &s.TotalEarnings); err != nil {
+			return nil, fmt.Errorf("failed to scan driver summary: %w", err)
+		}
+		summaries[id] = s
+	}
+	return summaries, rows.Err()
+}
+
+// Delete soft-deletes a driver and takes them offline; their rides and earnings are kept
+func (r *DriverRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE drivers SET deleted_at = NOW(), status = 'offline', updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete driver: %w", err)
+	}
+	return expectOneRow(result, driver.ErrDriverNotFound)
+}
+
+// getOne runs a single-row query for a driver that isn't deleted and matches cond
+func (r *DriverRepository) getOne(ctx context.Context, cond string, arg interface{}) (*driver.Driver, error) {
+	d, err := scanDriver(r.db.QueryRowContext(ctx, "SELECT "+driverColumns+" FROM drivers WHERE "+cond+" AND deleted_at IS NULL", arg))
+	if err == sql.ErrNoRows {
+		return nil, driver.ErrDriverNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver: %w", err)
+	}
+	return d, nil
+}
+
+// query runs a multi-row driver query
+func (r *DriverRepository) query(ctx context.Context, query string, args ...interface{}) ([]*driver.Driver, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query drivers: %w", err)
+	}
+	defer rows.Close()
+
+	var drivers []*driver.Driver
+	for rows.Next() {
+		d, err := scanDriver(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan driver: %w", err)
+		}
+		drivers = append(drivers, d)
+	}
+	return drivers, rows.Err()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDriver maps a row selected with driverColumns
+func scanDriver(row rowScanner) (*driver.Driver, error) {
+	var d driver.Driver
+	var status, vehicleType string
+	err := row.Scan(&d.ID, &d.Name, &d.Email, &d.Phone, &status, &vehicleType,
+		&d.CurrentLatitude, &d.CurrentLongitude, &d.Rating, &d.TotalRides,
+		&d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	d.Status = driver.Status(status)
+	d.VehicleType = driver.VehicleType(vehicleType)
+	return &d, nil
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var driverRowColumns = []string{
+	"id", "name", "email", "phone", "status", "vehicle_type",
+	"current_latitude", "current_longitude", "rating", "total_rides",
+	"created_at", "updated_at",
+}
+
+// TestDriverRepository_GetAvailableDrivers tests scanning of driver rows and the vehicle filter argument
+func TestDriverRepository_GetAvailableDrivers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	id := uuid.New()
+	now := time.Now()
+	mock.ExpectQuery("FROM drivers\\s+WHERE status = 'online'").
+		WithArgs("premium").
+		WillReturnRows(sqlmock.NewRows(driverRowColumns).
+			AddRow(id, "Asha", "asha@example.com", "+911", "online", "premium", 12.97, nil, 4.9, 12, now, now))
+
+	drivers, err := NewDriverRepository(db).GetAvailableDrivers(context.Background(), driver.VehiclePremium)
+	assert.NoError(t, err)
+	assert.Len(t, drivers, 1)
+	assert.Equal(t, id, drivers[0].ID)
+	assert.Equal(t, driver.StatusOnline, drivers[0].Status)
+	assert.Equal(t, driver.VehiclePremium, drivers[0].VehicleType)
+	assert.NotNil(t, drivers[0].CurrentLatitude)
+	assert.Nil(t, drivers[0].CurrentLongitude)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestDriverRepository_UpdateStatus_NotFound tests that updating a missing driver returns ErrDriverNotFound
+func TestDriverRepository_UpdateStatus_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE drivers SET status").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewDriverRepository(db).UpdateStatus(context.Background(), uuid.New(), driver.StatusBusy)
+	assert.ErrorIs(t, err, driver.ErrDriverNotFound)
+
+	mock.ExpectQuery("FROM drivers WHERE email").WillReturnError(sql.ErrNoRows)
+	_, err = NewDriverRepository(db).GetByEmail(context.Background(), "nobody@example.com")
+	assert.ErrorIs(t, err, driver.ErrDriverNotFound)
+}
+
+// TestDriverRepository_List tests that filters and paging are passed through and the total is returned
+func TestDriverRepository_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM drivers").
+		WithArgs("online", "").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("ORDER BY name\\s+LIMIT \\$3 OFFSET \\$4").
+		WithArgs("online", "", 1, 2).
+		WillReturnRows(sqlmock.NewRows(driverRowColumns).
+			AddRow(uuid.New(), "Ravi", "ravi@example.com", "+912", "online", "economy", nil, nil, 4.7, 3, now, now))
+
+	drivers, total, err := NewDriverRepository(db).List(context.Background(), driver.ListFilter{
+		Status: driver.StatusOnline,
+		Limit:  1,
+		Offset: 2,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Len(t, drivers, 1)
+	assert.Equal(t, "Ravi", drivers[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestDriverRepository_Delete tests that Delete stamps deleted_at instead of removing the row
+// and that an already deleted driver is reported as not found
+func TestDriverRepository_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	id := uuid.New()
+	mock.ExpectExec("UPDATE drivers SET deleted_at = NOW\\(\\)").
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE drivers SET deleted_at = NOW\\(\\)").
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := NewDriverRepository(db)
+	assert.NoError(t, repo.Delete(context.Background(), id))
+	assert.ErrorIs(t, repo.Delete(context.Background(), id), driver.ErrDriverNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/google/uuid"
+)
+
+// DriverSessionRepository implements driver.SessionRepository on PostgreSQL
+type DriverSessionRepository struct {
+	db *sql.DB
+}
+
+// NewDriverSessionRepository creates a new PostgreSQL driver session repository
+func NewDriverSessionRepository(db *sql.DB) *DriverSessionRepository {
+	return &DriverSessionRepository{db: db}
+}
+
+var _ driver.SessionRepository = (*DriverSessionRepository)(nil)
+
+// Start opens a session unless the driver already has one; the partial unique index on
+// open sessions turns a second Start into a no-op
+func (r *DriverSessionRepository) Start(ctx context.Context, driverID uuid.UUID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO driver_sessions (driver_id, started_at)
+		VALUES ($1, $2)
+		ON CONFLICT (driver_id) WHERE ended_at IS NULL DO NOTHING
+	`, driverID, at)
+	if err != nil {
+		return fmt.Errorf("failed to start driver session: %w", err)
+	}
+	return nil
+}
+
+// End closes the open session, settling its length and any ride still in progress
+func (r *DriverSessionRepository) End(ctx context.Context, driverID uuid.UUID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE driver_sessions
+		SET ended_at = $2,
+		    online_seconds = EXTRACT(EPOCH FROM ($2 - started_at)),
+		    busy_seconds = busy_seconds + COALESCE(EXTRACT(EPOCH FROM ($2 - busy_since)), 0),
+		    busy_since = NULL,
+		    updated_at = NOW()
+		WHERE driver_id = $1 AND ended_at IS NULL
+	`, driverID, at)
+	if err != nil {
+		return fmt.Errorf("failed to end driver session: %w", err)
+	}
+	return nil
+}
+
+// StartBusy marks the open session busy, keeping the earlier start if it already is
+func (r *DriverSessionRepository) StartBusy(ctx context.Context, driverID uuid.UUID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE driver_sessions
+		SET busy_since = COALESCE(busy_since, $2), updated_at = NOW()
+		WHERE driver_id = $1 AND ended_at IS NULL
+	`, driverID, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark driver busy: %w", err)
+	}
+	return nil
+}
+
+// EndBusy adds the ride just finished to the open session's busy time
+func (r *DriverSessionRepository) EndBusy(ctx context.Context, driverID uuid.UUID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE driver_sessions
+		SET busy_seconds = busy_seconds + EXTRACT(EPOCH FROM ($2 - busy_since)),
+		    busy_since = NULL,
+		    updated_at = NOW()
+		WHERE driver_id = $1 AND ended_at IS NULL AND busy_since IS NOT NULL
+	`, driverID, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark driver free: %w", err)
+	}
+	return nil
+}
+
+// Utilization totals sessions started since since, counting the open session and any ride
+// in progress up to until
+func (r *DriverSessionRepository) Utilization(ctx context.Context, driverID uuid.UUID, since, until time.Time) (driver.Utilization, error) {
+	var u driver.Utilization
+	var onlineSince sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(CASE WHEN ended_at IS NULL
+				THEN EXTRACT(EPOCH FROM ($3 - started_at))
+				ELSE online_seconds END), 0),
+			COALESCE(SUM(busy_seconds + COALESCE(EXTRACT(EPOCH FROM ($3 - busy_since)), 0)), 0),
+			MAX(started_at) FILTER (WHERE ended_at IS NULL)
+		FROM driver_sessions
+		WHERE driver_id = $1 AND (started_at >= $2 OR ended_at IS NULL)
+	`, driverID, since, until).Scan(&u.OnlineSeconds, &u.BusySeconds, &onlineSince)
+	if err != nil {
+		return driver.Utilization{}, fmt.Errorf("failed to get driver utilization: %w", err)
+	}
+	if onlineSince.Valid {
+		u.OnlineSince = &onlineSince.Time
+	}
+	return u, nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDriverSessionRepository_Transitions tests that each transition only touches the open session
+func TestDriverSessionRepository_Transitions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	id := uuid.New()
+	at := time.Now()
+	mock.ExpectExec("INSERT INTO driver_sessions .* ON CONFLICT \\(driver_id\\) WHERE ended_at IS NULL DO NOTHING").
+		WithArgs(id, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SET busy_since = COALESCE\\(busy_since, \\$2\\).*WHERE driver_id = \\$1 AND ended_at IS NULL").
+		WithArgs(id, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SET busy_seconds = .*AND busy_since IS NOT NULL").
+		WithArgs(id, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("SET ended_at = \\$2.*WHERE driver_id = \\$1 AND ended_at IS NULL").
+		WithArgs(id, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := NewDriverSessionRepository(db)
+	ctx := context.Background()
+	assert.NoError(t, repo.Start(ctx, id, at))
+	assert.NoError(t, repo.StartBusy(ctx, id, at))
+	assert.NoError(t, repo.EndBusy(ctx, id, at))
+	assert.NoError(t, repo.End(ctx, id, at))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestDriverSessionRepository_Utilization tests that totals are scanned and the open session
+// start is reported as OnlineSince
+func TestDriverSessionRepository_Utilization(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	id := uuid.New()
+	until := time.Now()
+	since := until.Add(-24 * time.Hour)
+	started := until.Add(-2 * time.Hour)
+	mock.ExpectQuery("FROM driver_sessions\\s+WHERE driver_id = \\$1 AND \\(started_at >= \\$2 OR ended_at IS NULL\\)").
+		WithArgs(id, since, until).
+		WillReturnRows(sqlmock.NewRows([]string{"online", "busy", "online_since"}).AddRow(7200.0, 5400.0, started))
+
+	u, err := NewDriverSessionRepository(db).Utilization(context.Background(), id, since, until)
+	assert.NoError(t, err)
+	assert.Equal(t, 7200.0, u.OnlineSeconds)
+	assert.Equal(t, 5400.0, u.BusySeconds)
+	assert.Equal(t, 0.75, u.Rate())
+	if assert.NotNil(t, u.OnlineSince) {
+		assert.Equal(t, started, *u.OnlineSince)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gocomet/ride-hailing/internal/domain/payment"
+	"github.com/google/uuid"
+)
+
+// PaymentRepository implements payment.Repository on PostgreSQL
+type PaymentRepository struct {
+	db *sql.DB
+}
+
+// NewPaymentRepository creates a new PostgreSQL payment repository
+func NewPaymentRepository(db *sql.DB) *PaymentRepository {
+	return &PaymentRepository{db: db}
+}
+
+var _ payment.Repository = (*PaymentRepository)(nil)
+
+const paymentColumns = `
+	id, trip_id, amount, refunded_amount, status, payment_method,
+	external_transaction_id, payment_gateway_response, failure_reason,
+	idempotency_key, processed_at, created_at, updated_at
+`
+
+// Create inserts a new payment
+func (r *PaymentRepository) Create(ctx context.Context, p *payment.Payment) error {
+	gatewayResponse, err := marshalGatewayResponse(p.PaymentGatewayResponse)
+	if err != nil {
+		return err
+	}
+
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO payments (
+			id, trip_id, amount, status, payment_method,
+			external_transaction_id, payment_gateway_response, failure_reason,
+			idempotency_key, processed_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING created_at, updated_at
+	`, p.ID, p.TripID, p.Amount, string(p.Status), string(p.PaymentMethod),
+		nullString(p.ExternalTransactionID), gatewayResponse, nullString(p.FailureReason),
+		nullString(p.IdempotencyKey), p.ProcessedAt,
+	).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a payment by ID
+func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*payment.Payment, error) {
+	return r.getOne(ctx, "WHERE id = $1", id)
+}
+
+// GetByTripID retrieves the payment for a trip. Failed attempts stay behind when the rider
+// pays again, so the live payment wins and otherwise the latest failed one is returned.
+func (r *PaymentRepository) GetByTripID(ctx context.Context, tripID uuid.UUID) (*payment.Payment, error) {
+	return r.getOne(ctx, "WHERE trip_id = $1 ORDER BY status = 'failed', created_at DESC LIMIT 1", tripID)
+}
+
+// GetByIdempotencyKey retrieves a payment by its idempotency key
+func (r *PaymentRepository) GetByIdempotencyKey(ctx context.Context, key string) (*payment.Payment, error) {
+	return r.getOne(ctx, "WHERE idempotency_key = $1", key)
+}
+
+// Update writes all mutable payment fields
+func (r *PaymentRepository) Update(ctx context.Context, p *payment.Payment) error {
+	gatewayResponse, err := marshalGatewayResponse(p.PaymentGatewayResponse)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE payments
+		SET amount = $2, refunded_amount = $3, status = $4, payment_method = $5,
+		    external_transaction_id = $6, payment_gateway_response = $7,
+		    failure_reason = $8, processed_at = $9, updated_at = NOW()
+		WHERE id = $1
+	`, p.ID, p.Amount, p.RefundedAmount, string(p.Status), string(p.PaymentMethod),
+		nullString(p.ExternalTransactionID), gatewayResponse, nullString(p.FailureReason), p.ProcessedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return expectOneRow(result, payment.ErrPaymentNotFound)
+}
+
+// UpdateStatus changes only the payment status
+func (r *PaymentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status payment.Status) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE payments SET status = $2, updated_at = NOW() WHERE id = $1
+	`, id, string(status))
+	if err != nil {
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+	return expectOneRow(result, payment.ErrPaymentNotFound)
+}
+
+// getOne runs a single-row payment query with the given WHERE clause
+func (r *PaymentRepository) getOne(ctx context.Context, where string, arg interface{}) (*payment.Payment, error) {
+	var (
+		p                                            payment.Payment
+		status, method                               string
+		externalTxnID, failureReason, idempotencyKey sql.NullString
+		gatewayResponse                              []byte
+	)
+
+	err := r.db.QueryRowContext(ctx, "SELECT "+paymentColumns+" FROM payments "+where, arg).Scan(
+		&p.ID, &p.TripID, &p.Amount, &p.RefundedAmount, &status, &method,
+		&externalTxnID, &gatewayResponse, &failureReason,
+		&idempotencyKey, &p.ProcessedAt, &p.CreatedAt, &p.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, payment.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	p.Status = payment.Status(status)
+	p.PaymentMethod = payment.Method(method)
+	p.ExternalTransactionID = externalTxnID.String
+	p.FailureReason = failureReason.String
+	p.IdempotencyKey = idempotencyKey.String
+	if len(gatewayResponse) > 0 {
+		p.PaymentGatewayResponse = json.RawMessage(gatewayResponse)
+	}
+
+	return &p, nil
+}
+
+// marshalGatewayResponse encodes the PSP response for the JSONB column
+func marshalGatewayResponse(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode gateway response: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gocomet/ride-hailing/internal/domain/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPaymentRepository_GetByID tests row mapping including NULL columns
+func TestPaymentRepository_GetByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	id, tripID := uuid.New(), uuid.New()
+	now := time.Now()
+	mock.ExpectQuery("FROM payments WHERE id = \\$1").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "trip_id", "amount", "refunded_amount", "status", "payment_method",
+			"external_transaction_id", "payment_gateway_response", "failure_reason",
+			"idempotency_key", "processed_at", "created_at", "updated_at",
+		}).AddRow(id, tripID, 250.0, 0.0, "completed", "upi", "txn_1", nil, nil, "key-1", nil, now, now))
+
+	p, err := NewPaymentRepository(db).GetByID(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, tripID, p.TripID)
+	assert.Equal(t, payment.StatusCompleted, p.Status)
+	assert.Equal(t, payment.MethodUPI, p.PaymentMethod)
+	assert.Equal(t, "txn_1", p.ExternalTransactionID)
+	assert.Empty(t, p.FailureReason)
+	assert.Nil(t, p.PaymentGatewayResponse)
+}
+
+// TestPaymentRepository_GetByTripID_PrefersLivePayment tests that a trip paid again after a
+// failed charge resolves to the new payment
+func TestPaymentRepository_GetByTripID_PrefersLivePayment(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	id, tripID := uuid.New(), uuid.New()
+	now := time.Now()
+	mock.ExpectQuery("FROM payments WHERE trip_id = \\$1 ORDER BY status = 'failed', created_at DESC LIMIT 1").
+		WithArgs(tripID).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "trip_id", "amount", "refunded_amount", "status", "payment_method",
+			"external_transaction_id", "payment_gateway_response", "failure_reason",
+			"idempotency_key", "processed_at", "created_at", "updated_at",
+		}).AddRow(id, tripID, 250.0, 0.0, "completed", "card", "txn_2", nil, nil, "key-2", now, now, now))
+
+	p, err := NewPaymentRepository(db).GetByTripID(context.Background(), tripID)
+	assert.NoError(t, err)
+	assert.Equal(t, id, p.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestPaymentRepository_GetByID_NotFound tests that a missing row maps to ErrPaymentNotFound
+func TestPaymentRepository_GetByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM payments").WillReturnError(sql.ErrNoRows)
+
+	_, err = NewPaymentRepository(db).GetByID(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, payment.ErrPaymentNotFound)
+}
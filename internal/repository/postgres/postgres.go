@@ -0,0 +1,24 @@
+// Package postgres implements the domain repositories on PostgreSQL.
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// nullString stores empty strings as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// expectOneRow returns notFound when an UPDATE/DELETE matched no rows
+func expectOneRow(result sql.Result, notFound error) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
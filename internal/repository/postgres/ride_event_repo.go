@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+)
+
+// RideEventRepository implements ride.EventRepository on PostgreSQL
+type RideEventRepository struct {
+	db *sql.DB
+}
+
+// NewRideEventRepository creates a new PostgreSQL ride event repository
+func NewRideEventRepository(db *sql.DB) *RideEventRepository {
+	return &RideEventRepository{db: db}
+}
+
+var _ ride.EventRepository = (*RideEventRepository)(nil)
+
+// Record inserts an event; empty details are stored as NULL
+func (r *RideEventRepository) Record(ctx context.Context, e *ride.Event) error {
+	var details []byte
+	if len(e.Details) > 0 {
+		var err error
+		if details, err = json.Marshal(e.Details); err != nil {
+			return fmt.Errorf("failed to encode ride event details: %w", err)
+		}
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO ride_events (ride_id, event_type, actor_type, actor_id, details)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		RETURNING id, created_at
+	`, e.RideID, string(e.Type), e.ActorType, e.ActorID, details).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record ride event: %w", err)
+	}
+	return nil
+}
+
+// ListByRide returns the ride's events in the order they happened
+func (r *RideEventRepository) ListByRide(ctx context.Context, rideID string) ([]*ride.Event, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, ride_id, event_type, actor_type, COALESCE(actor_id, ''), details, created_at
+		FROM ride_events
+		WHERE ride_id = $1
+		ORDER BY created_at, id
+	`, rideID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ride events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*ride.Event{}
+	for rows.Next() {
+		var e ride.Event
+		var eventType string
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.RideID, &eventType, &e.ActorType, &e.ActorID, &details, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ride event: %w", err)
+		}
+		e.Type = ride.EventType(eventType)
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &e.Details); err != nil {
+				return nil, fmt.Errorf("failed to decode ride event details: %w", err)
+			}
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
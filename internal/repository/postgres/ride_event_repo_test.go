@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRideEventRepository_RecordAndList tests that details round-trip as JSON and that a
+// system event is stored without an actor ID
+func TestRideEventRepository_RecordAndList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO ride_events").
+		WithArgs("ride-1", "rematched", "system", "", []byte(`{"reason":"offer_timeout"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("evt-1", now))
+	mock.ExpectQuery("FROM ride_events\\s+WHERE ride_id = \\$1\\s+ORDER BY created_at").
+		WithArgs("ride-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "ride_id", "event_type", "actor_type", "actor_id", "details", "created_at"}).
+			AddRow("evt-0", "ride-1", "accepted", "driver", "driver-1", nil, now.Add(-time.Minute)).
+			AddRow("evt-1", "ride-1", "rematched", "system", "", []byte(`{"reason":"offer_timeout"}`), now))
+
+	repo := NewRideEventRepository(db)
+	e := &ride.Event{RideID: "ride-1", Type: ride.EventRematched, ActorType: ride.ActorSystem,
+		Details: map[string]interface{}{"reason": "offer_timeout"}}
+	assert.NoError(t, repo.Record(context.Background(), e))
+	assert.Equal(t, "evt-1", e.ID)
+
+	events, err := repo.ListByRide(context.Background(), "ride-1")
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, ride.EventAccepted, events[0].Type)
+		assert.Nil(t, events[0].Details)
+		assert.Equal(t, "offer_timeout", events[1].Details["reason"])
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
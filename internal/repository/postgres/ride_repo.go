@@ -0,0 +1,289 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/google/uuid"
+)
+
+// RideRepository implements ride.Repository on PostgreSQL
+type RideRepository struct {
+	db *sql.DB
+}
+
+// NewRideRepository creates a new PostgreSQL ride repository
+func NewRideRepository(db *sql.DB) *RideRepository {
+	return &RideRepository{db: db}
+}
+
+var _ ride.Repository = (*RideRepository)(nil)
+
+const rideColumns = `
+	id, rider_id, driver_id, status, vehicle_type, seats, pool, allow_upgrade,
+	pickup_latitude, pickup_longitude, dropoff_latitude, dropoff_longitude,
+	pickup_address, dropoff_address,
+	estimated_fare, estimated_distance_km, estimated_duration_minutes, quoted_surge,
+	requested_at, assigned_at, accepted_at, started_at, completed_at, cancelled_at,
+	cancellation_reason, cancellation_fee, idempotency_key, scheduled_at, created_at, updated_at
+`
+
+// activeRideFilter matches rides that haven't reached a terminal status
+const activeRideFilter = `status IN ('requested', 'assigned', 'accepted', 'started')`
+
+// Create inserts a new ride along with its waypoints, if any
+func (r *RideRepository) Create(ctx context.Context, rd *ride.Ride) error {
+	// Single-stop rides skip the transaction; multi-stop rides insert the ride and its stops atomically
+	if len(rd.Waypoints) == 0 {
+		return insertRide(ctx, r.db, rd)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin ride transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertRide(ctx, tx, rd); err != nil {
+		return err
+	}
+	for _, wp := range rd.Waypoints {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO ride_waypoints (ride_id, sequence, latitude, longitude)
+			VALUES ($1, $2, $3, $4)
+		`, rd.ID, wp.Sequence, wp.Latitude, wp.Longitude)
+		if err != nil {
+			return fmt.Errorf("failed to create ride waypoint: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit ride: %w", err)
+	}
+	return nil
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// insertRide writes the rides row and fills in the generated timestamps
+func insertRide(ctx context.Context, db queryRower, rd *ride.Ride) error {
+	err := db.QueryRowContext(ctx, `
+		INSERT INTO rides (
+			id, rider_id, driver_id, status, vehicle_type, seats, pool, allow_upgrade,
+			pickup_latitude, pickup_longitude, dropoff_latitude, dropoff_longitude,
+			pickup_address, dropoff_address,
+			estimated_fare, estimated_distance_km, estimated_duration_minutes, quoted_surge,
+			requested_at, assigned_at, idempotency_key, scheduled_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+		RETURNING created_at, updated_at
+	`, rd.ID, rd.RiderID, rd.DriverID, string(rd.Status), string(rd.VehicleType), rd.Seats, rd.Pool, rd.AllowUpgrade,
+		rd.PickupLatitude, rd.PickupLongitude, rd.DropoffLatitude, rd.DropoffLongitude,
+		nullString(rd.PickupAddress), nullString(rd.DropoffAddress),
+		rd.EstimatedFare, rd.EstimatedDistanceKM, rd.EstimatedDurationMinutes, rd.QuotedSurge,
+		rd.RequestedAt, rd.AssignedAt, nullString(rd.IdempotencyKey), rd.ScheduledAt,
+	).Scan(&rd.CreatedAt, &rd.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create ride: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a ride by ID
+func (r *RideRepository) GetByID(ctx context.Context, id string) (*ride.Ride, error) {
+	return r.getOne(ctx, "WHERE id = $1", id)
+}
+
+// GetByIdempotencyKey retrieves a rider's ride by its idempotency key
+func (r *RideRepository) GetByIdempotencyKey(ctx context.Context, riderID uuid.UUID, key string) (*ride.Ride, error) {
+	return r.getOne(ctx, "WHERE rider_id = $1 AND idempotency_key = $2", riderID, key)
+}
+
+// Update writes all mutable ride fields if the ride is still in status from
+func (r *RideRepository) Update(ctx context.Context, rd *ride.Ride, from ride.Status) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE rides
+		SET driver_id = $2, status = $3, estimated_fare = $4,
+		    estimated_distance_km = $5, estimated_duration_minutes = $6,
+		    assigned_at = $7, accepted_at = $8, started_at = $9,
+		    completed_at = $10, cancelled_at = $11, cancellation_reason = $12,
+		    cancellation_fee = $13, updated_at = NOW()
+		WHERE id = $1 AND status = $14
+	`, rd.ID, rd.DriverID, string(rd.Status), rd.EstimatedFare,
+		rd.EstimatedDistanceKM, rd.EstimatedDurationMinutes,
+		rd.AssignedAt, rd.AcceptedAt, rd.StartedAt,
+		rd.CompletedAt, rd.CancelledAt, nullString(rd.CancellationReason),
+		rd.CancellationFee, string(from))
+	if err != nil {
+		return fmt.Errorf("failed to update ride: %w", err)
+	}
+	return expectOneRow(result, ride.ErrStatusChanged)
+}
+
+// UpdateStatus changes only the ride status, and only while it is still from
+func (r *RideRepository) UpdateStatus(ctx context.Context, id string, from, to ride.Status) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE rides SET status = $2, updated_at = NOW() WHERE id = $1 AND status = $3
+	`, id, string(to), string(from))
+	if err != nil {
+		return fmt.Errorf("failed to update ride status: %w", err)
+	}
+	return expectOneRow(result, ride.ErrStatusChanged)
+}
+
+// AssignDriver assigns a driver to a ride that is still waiting for one
+func (r *RideRepository) AssignDriver(ctx context.Context, rideID string, driverID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE rides
+		SET driver_id = $2, status = 'assigned', assigned_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'requested'
+	`, rideID, driverID)
+	if err != nil {
+		return fmt.Errorf("failed to assign driver: %w", err)
+	}
+	return expectOneRow(result, ride.ErrRideAlreadyAssigned)
+}
+
+// GetActiveRideByDriver returns the driver's most recent non-terminal ride
+func (r *RideRepository) GetActiveRideByDriver(ctx context.Context, driverID uuid.UUID) (*ride.Ride, error) {
+	return r.getOne(ctx, "WHERE driver_id = $1 AND "+activeRideFilter+" ORDER BY requested_at DESC LIMIT 1", driverID)
+}
+
+// GetActiveRideByRider returns the rider's most recent non-terminal ride
+func (r *RideRepository) GetActiveRideByRider(ctx context.Context, riderID uuid.UUID) (*ride.Ride, error) {
+	return r.getOne(ctx, "WHERE rider_id = $1 AND "+activeRideFilter+" ORDER BY requested_at DESC LIMIT 1", riderID)
+}
+
+// ListScheduledByRider returns the rider's rides still waiting for dispatch, soonest first
+func (r *RideRepository) ListScheduledByRider(ctx context.Context, riderID uuid.UUID) ([]*ride.Ride, error) {
+	return r.query(ctx, `
+		SELECT `+rideColumns+` FROM rides
+		WHERE rider_id = $1 AND status = 'scheduled'
+		ORDER BY scheduled_at
+	`, riderID)
+}
+
+// ListDueScheduled returns scheduled rides whose pickup time is at or before the given time,
+// including ones already dispatched that are still waiting for a driver
+func (r *RideRepository) ListDueScheduled(ctx context.Context, before time.Time) ([]*ride.Ride, error) {
+	return r.query(ctx, `
+		SELECT `+rideColumns+` FROM rides
+		WHERE scheduled_at IS NOT NULL AND scheduled_at <= $1 AND status IN ('scheduled', 'requested')
+		ORDER BY scheduled_at
+		LIMIT 100
+	`, before)
+}
+
+// GetWaypoints returns a ride's intermediate stops in order
+func (r *RideRepository) GetWaypoints(ctx context.Context, rideID string) ([]ride.Waypoint, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT sequence, latitude, longitude
+		FROM ride_waypoints
+		WHERE ride_id = $1
+		ORDER BY sequence
+	`, rideID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ride waypoints: %w", err)
+	}
+	defer rows.Close()
+
+	var waypoints []ride.Waypoint
+	for rows.Next() {
+		var wp ride.Waypoint
+		if err := rows.Scan(&wp.Sequence, &wp.Latitude, &wp.Longitude); err != nil {
+			return nil, fmt.Errorf("failed to scan ride waypoint: %w", err)
+		}
+		waypoints = append(waypoints, wp)
+	}
+	return waypoints, rows.Err()
+}
+
+// getOne runs a single-row ride query with the given WHERE clause
+func (r *RideRepository) getOne(ctx context.Context, where string, args ...interface{}) (*ride.Ride, error) {
+	rd, err := scanRide(r.db.QueryRowContext(ctx, "SELECT "+rideColumns+" FROM rides "+where, args...))
+	if err == sql.ErrNoRows {
+		return nil, ride.ErrRideNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ride: %w", err)
+	}
+	return rd, nil
+}
+
+// query runs a multi-row ride query
+func (r *RideRepository) query(ctx context.Context, query string, args ...interface{}) ([]*ride.Ride, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rides: %w", err)
+	}
+	defer rows.Close()
+
+	var rides []*ride.Ride
+	for rows.Next() {
+		rd, err := scanRide(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ride: %w", err)
+		}
+		rides = append(rides, rd)
+	}
+	return rides, rows.Err()
+}
+
+// scanRide maps a row selected with rideColumns
+func scanRide(row rowScanner) (*ride.Ride, error) {
+	var (
+		rd                                 ride.Ride
+		status, vehicleType                string
+		driverID                           uuid.NullUUID
+		pickupAddress, dropoffAddress      sql.NullString
+		cancellationReason, idempotencyKey sql.NullString
+		estimatedFare, estimatedDistance   sql.NullFloat64
+		quotedSurge, cancellationFee       sql.NullFloat64
+		estimatedDuration                  sql.NullInt64
+	)
+
+	err := row.Scan(
+		&rd.ID, &rd.RiderID, &driverID, &status, &vehicleType, &rd.Seats, &rd.Pool, &rd.AllowUpgrade,
+		&rd.PickupLatitude, &rd.PickupLongitude, &rd.DropoffLatitude, &rd.DropoffLongitude,
+		&pickupAddress, &dropoffAddress,
+		&estimatedFare, &estimatedDistance, &estimatedDuration, &quotedSurge,
+		&rd.RequestedAt, &rd.AssignedAt, &rd.AcceptedAt, &rd.StartedAt, &rd.CompletedAt, &rd.CancelledAt,
+		&cancellationReason, &cancellationFee, &idempotencyKey, &rd.ScheduledAt, &rd.CreatedAt, &rd.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rd.Status = ride.Status(status)
+	rd.VehicleType = ride.VehicleType(vehicleType)
+	if driverID.Valid {
+		rd.DriverID = &driverID.UUID
+	}
+	rd.PickupAddress = pickupAddress.String
+	rd.DropoffAddress = dropoffAddress.String
+	rd.CancellationReason = cancellationReason.String
+	rd.IdempotencyKey = idempotencyKey.String
+	if estimatedFare.Valid {
+		rd.EstimatedFare = &estimatedFare.Float64
+	}
+	if estimatedDistance.Valid {
+		rd.EstimatedDistanceKM = &estimatedDistance.Float64
+	}
+	if estimatedDuration.Valid {
+		minutes := int(estimatedDuration.Int64)
+		rd.EstimatedDurationMinutes = &minutes
+	}
+	if quotedSurge.Valid {
+		rd.QuotedSurge = &quotedSurge.Float64
+	}
+	if cancellationFee.Valid {
+		rd.CancellationFee = &cancellationFee.Float64
+	}
+
+	return &rd, nil
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRideRows returns an empty result set with rideColumns
+func newRideRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "rider_id", "driver_id", "status", "vehicle_type", "seats", "pool", "allow_upgrade",
+		"pickup_latitude", "pickup_longitude", "dropoff_latitude", "dropoff_longitude",
+		"pickup_address", "dropoff_address",
+		"estimated_fare", "estimated_distance_km", "estimated_duration_minutes", "quoted_surge",
+		"requested_at", "assigned_at", "accepted_at", "started_at", "completed_at", "cancelled_at",
+		"cancellation_reason", "cancellation_fee", "idempotency_key", "scheduled_at", "created_at", "updated_at",
+	})
+}
+
+// TestRideRepository_GetActiveRideByRider tests that only non-terminal rides are looked up and mapped
+func TestRideRepository_GetActiveRideByRider(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	riderID := uuid.New()
+	now := time.Now()
+	mock.ExpectQuery("WHERE rider_id = \\$1 AND status IN \\('requested', 'assigned', 'accepted', 'started'\\)").
+		WithArgs(riderID).
+		WillReturnRows(newRideRows().AddRow("ride-1", riderID, nil, "requested", "economy", 1, false, false,
+			12.97, 77.59, 12.93, 77.62, nil, nil,
+			250.0, nil, nil, 1.5,
+			now, nil, nil, nil, nil, nil,
+			nil, nil, nil, nil, now, now))
+
+	rd, err := NewRideRepository(db).GetActiveRideByRider(context.Background(), riderID)
+	assert.NoError(t, err)
+	assert.Equal(t, "ride-1", rd.ID)
+	assert.Equal(t, ride.StatusRequested, rd.Status)
+	assert.Nil(t, rd.DriverID)
+	assert.Equal(t, 250.0, *rd.EstimatedFare)
+	assert.Nil(t, rd.EstimatedDistanceKM)
+	assert.Equal(t, 1.5, *rd.QuotedSurge)
+}
+
+// TestRideRepository_GetActiveRideByRider_None tests that no active ride maps to ErrRideNotFound
+func TestRideRepository_GetActiveRideByRider_None(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM rides").WillReturnError(sql.ErrNoRows)
+
+	_, err = NewRideRepository(db).GetActiveRideByRider(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ride.ErrRideNotFound)
+}
+
+// TestRideRepository_ListDueScheduled tests that due scheduled and still-unmatched rides are returned
+func TestRideRepository_ListDueScheduled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	pickupAt := now.Add(5 * time.Minute)
+	mock.ExpectQuery("scheduled_at <= \\$1 AND status IN \\('scheduled', 'requested'\\)").
+		WithArgs(pickupAt).
+		WillReturnRows(newRideRows().
+			AddRow("ride-1", uuid.New(), nil, "scheduled", "economy", 1, false, false,
+				12.97, 77.59, 12.93, 77.62, nil, nil,
+				250.0, 4.2, 12, 1.0,
+				now, nil, nil, nil, nil, nil,
+				nil, nil, nil, pickupAt, now, now).
+			AddRow("ride-2", uuid.New(), nil, "requested", "premium", 4, false, false,
+				12.97, 77.59, 12.93, 77.62, nil, nil,
+				400.0, 4.2, 12, 1.0,
+				now, nil, nil, nil, nil, nil,
+				nil, nil, nil, now, now, now))
+
+	rides, err := NewRideRepository(db).ListDueScheduled(context.Background(), pickupAt)
+	assert.NoError(t, err)
+	assert.Len(t, rides, 2)
+	assert.Equal(t, ride.StatusScheduled, rides[0].Status)
+	assert.Equal(t, pickupAt, *rides[0].ScheduledAt)
+	assert.Equal(t, ride.StatusRequested, rides[1].Status)
+	assert.Equal(t, 4, rides[1].Seats)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRideRepository_CreateWithWaypoints tests that a multi-stop ride and its stops are inserted in one transaction
+func TestRideRepository_CreateWithWaypoints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	rd := &ride.Ride{
+		ID:          "ride-1",
+		RiderID:     uuid.New(),
+		Status:      ride.StatusRequested,
+		VehicleType: ride.VehicleEconomy,
+		RequestedAt: now,
+		Waypoints: []ride.Waypoint{
+			{Sequence: 1, Latitude: 12.95, Longitude: 77.60},
+			{Sequence: 2, Latitude: 12.94, Longitude: 77.61},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO rides").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now))
+	mock.ExpectExec("INSERT INTO ride_waypoints").
+		WithArgs("ride-1", 1, 12.95, 77.60).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO ride_waypoints").
+		WithArgs("ride-1", 2, 12.94, 77.61).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, NewRideRepository(db).Create(context.Background(), rd))
+	assert.Equal(t, now, rd.CreatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRideRepository_UpdateGuardsStatus tests that an update only applies while the ride is
+// still in the status it was read in
+func TestRideRepository_UpdateGuardsStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rd := &ride.Ride{ID: "ride-1", RiderID: uuid.New(), Status: ride.StatusCancelled}
+	mock.ExpectExec("UPDATE rides").
+		WithArgs("ride-1", sqlmock.AnyArg(), "cancelled", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), "accepted").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = NewRideRepository(db).Update(context.Background(), rd, ride.StatusAccepted)
+	assert.ErrorIs(t, err, ride.ErrStatusChanged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/gocomet/ride-hailing/internal/domain/rider"
+	"github.com/google/uuid"
+)
+
+// RiderFavoriteRepository implements rider.FavoriteRepository on PostgreSQL
+type RiderFavoriteRepository struct {
+	db *sql.DB
+}
+
+// NewRiderFavoriteRepository creates a new PostgreSQL rider favorites repository
+func NewRiderFavoriteRepository(db *sql.DB) *RiderFavoriteRepository {
+	return &RiderFavoriteRepository{db: db}
+}
+
+var _ rider.FavoriteRepository = (*RiderFavoriteRepository)(nil)
+
+// ListFavoriteDriverIDs returns the rider's favorite drivers, skipping deleted ones
+func (r *RiderFavoriteRepository) ListFavoriteDriverIDs(ctx context.Context, riderID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT f.driver_id
+		FROM rider_favorite_drivers f
+		JOIN drivers d ON d.id = f.driver_id AND d.deleted_at IS NULL
+		WHERE f.rider_id = $1
+	`, riderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list favorite drivers: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan favorite driver: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list favorite drivers: %w", err)
+	}
+	return ids, nil
+}
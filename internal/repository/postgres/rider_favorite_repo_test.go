@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRiderFavoriteRepository_ListFavoriteDriverIDs tests that favorites are read for the rider
+// and that a row error fails the list instead of cutting it short
+func TestRiderFavoriteRepository_ListFavoriteDriverIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	riderID, first, second := uuid.New(), uuid.New(), uuid.New()
+	mock.ExpectQuery("FROM rider_favorite_drivers f").
+		WithArgs(riderID).
+		WillReturnRows(sqlmock.NewRows([]string{"driver_id"}).AddRow(first).AddRow(second))
+	mock.ExpectQuery("FROM rider_favorite_drivers f").
+		WithArgs(riderID).
+		WillReturnRows(sqlmock.NewRows([]string{"driver_id"}).AddRow(first).AddRow(second).
+			RowError(1, errors.New("connection reset")))
+
+	repo := NewRiderFavoriteRepository(db)
+	ids, err := repo.ListFavoriteDriverIDs(context.Background(), riderID)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{first, second}, ids)
+
+	_, err = repo.ListFavoriteDriverIDs(context.Background(), riderID)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
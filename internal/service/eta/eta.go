@@ -0,0 +1,63 @@
+package eta
+
+import (
+	"fmt"
+
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/gocomet/ride-hailing/internal/service/routing"
+)
+
+// Estimator estimates how long a driver needs to reach a pickup at the configured average
+// city speed. Surge stands in for traffic: busier regions are assumed to be slower to drive.
+type Estimator struct {
+	avgSpeedKMH   float64
+	surgeSlowdown float64
+}
+
+// NewEstimator creates an estimator. surgeSlowdown is how much slower traffic is per 1.0x
+// of surge above 1 (e.g. 0.3 makes a 2.0x surge region 30% slower); 0 ignores surge.
+func NewEstimator(avgSpeedKMH, surgeSlowdown float64) *Estimator {
+	if surgeSlowdown < 0 {
+		surgeSlowdown = 0
+	}
+	return &Estimator{
+		avgSpeedKMH:   avgSpeedKMH,
+		surgeSlowdown: surgeSlowdown,
+	}
+}
+
+// EstimateArrivalMinutes estimates the minutes a driver at driverLoc needs to reach pickup
+// in a straight line at avgSpeedKMH, rounded up to the nearest whole minute
+func EstimateArrivalMinutes(driverLoc, pickup routing.Point, avgSpeedKMH float64) int {
+	distance := matching.CalculateDistance(driverLoc.Latitude, driverLoc.Longitude, pickup.Latitude, pickup.Longitude)
+	return matching.EstimateArrivalMinutes(distance, avgSpeedKMH)
+}
+
+// Format renders minutes the way riders and drivers see an ETA
+func Format(minutes int) string {
+	return fmt.Sprintf("%d mins", minutes)
+}
+
+// Arrival estimates the minutes from driverLoc to pickup at the average city speed
+func (e *Estimator) Arrival(driverLoc, pickup routing.Point) int {
+	return EstimateArrivalMinutes(driverLoc, pickup, e.avgSpeedKMH)
+}
+
+// ArrivalInTraffic behaves like Arrival but slows the driver down in a surging region
+func (e *Estimator) ArrivalInTraffic(driverLoc, pickup routing.Point, surgeMultiplier float64) int {
+	return EstimateArrivalMinutes(driverLoc, pickup, e.trafficSpeed(surgeMultiplier))
+}
+
+// ArrivalForDistance estimates the minutes to cover distanceKM in a region at surgeMultiplier;
+// pass 1 to ignore traffic
+func (e *Estimator) ArrivalForDistance(distanceKM, surgeMultiplier float64) int {
+	return matching.EstimateArrivalMinutes(distanceKM, e.trafficSpeed(surgeMultiplier))
+}
+
+// trafficSpeed returns the average speed after the surge slowdown; surge at or below 1 leaves it unchanged
+func (e *Estimator) trafficSpeed(surgeMultiplier float64) float64 {
+	if surgeMultiplier <= 1 {
+		return e.avgSpeedKMH
+	}
+	return e.avgSpeedKMH / (1 + e.surgeSlowdown*(surgeMultiplier-1))
+}
@@ -0,0 +1,36 @@
+package eta
+
+import (
+	"testing"
+
+	"github.com/gocomet/ride-hailing/internal/service/routing"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEstimateArrivalMinutes_RoundsUp tests that a straight-line ETA rounds up to whole minutes
+func TestEstimateArrivalMinutes_RoundsUp(t *testing.T) {
+	pickup := routing.Point{Latitude: 12.9716, Longitude: 77.5946}
+	driverLoc := routing.Point{Latitude: 12.9931, Longitude: 77.5946} // ~2.4 km north
+
+	assert.Equal(t, 6, EstimateArrivalMinutes(driverLoc, pickup, 25.0), "2.4km at 25km/h rounds up to 6 minutes")
+	assert.Equal(t, 0, EstimateArrivalMinutes(pickup, pickup, 25.0), "Driver at pickup arrives immediately")
+	assert.Equal(t, 0, EstimateArrivalMinutes(driverLoc, pickup, 0), "No speed configured means no estimate")
+}
+
+// TestEstimator_ArrivalInTraffic tests that surge slows the estimate and no surge leaves it alone
+func TestEstimator_ArrivalInTraffic(t *testing.T) {
+	e := NewEstimator(30.0, 0.5)
+
+	assert.Equal(t, 10, e.ArrivalForDistance(5.0, 1.0), "5km at 30km/h is 10 minutes")
+	assert.Equal(t, 15, e.ArrivalForDistance(5.0, 2.0), "A 2.0x surge is 50% slower")
+	assert.Equal(t, 10, e.ArrivalForDistance(5.0, 0.8), "A discount multiplier doesn't speed drivers up")
+
+	pickup := routing.Point{Latitude: 12.9716, Longitude: 77.5946}
+	driverLoc := routing.Point{Latitude: 12.9941, Longitude: 77.5946}
+	assert.Greater(t, e.ArrivalInTraffic(driverLoc, pickup, 2.0), e.Arrival(driverLoc, pickup))
+}
+
+// TestFormat tests the rendered ETA string
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "7 mins", Format(7))
+}
@@ -0,0 +1,138 @@
+package location
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocomet/ride-hailing/pkg/logger"
+)
+
+// MetricsRecorder receives the size of each flushed batch
+type MetricsRecorder interface {
+	RecordLocationFlush(batchSize int)
+}
+
+// Position is the latest known location of a driver
+type Position struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Batcher coalesces driver location updates in memory and writes them to
+// PostgreSQL in a single batched UPDATE per interval. Redis remains the source
+// of truth for real-time lookups; the drivers table is eventually consistent.
+type Batcher struct {
+	db       *sql.DB
+	logger   *logger.Logger
+	metrics  MetricsRecorder
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]Position
+}
+
+// NewBatcher creates a new location batcher; metrics may be nil
+func NewBatcher(db *sql.DB, logger *logger.Logger, metrics MetricsRecorder, interval time.Duration) *Batcher {
+	return &Batcher{
+		db:       db,
+		logger:   logger,
+		metrics:  metrics,
+		interval: interval,
+		pending:  make(map[string]Position),
+	}
+}
+
+// Add records a driver's latest position, replacing any unflushed earlier one
+func (b *Batcher) Add(driverID string, lat, lng float64) {
+	b.mu.Lock()
+	b.pending[driverID] = Position{Latitude: lat, Longitude: lng}
+	b.mu.Unlock()
+}
+
+// Run flushes on every tick until the context is cancelled, then flushes once more
+func (b *Batcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	b.logger.Info("Location batcher started", logger.Duration("interval", b.interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Use a fresh context so the final flush isn't cancelled with the worker
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if _, err := b.Flush(flushCtx); err != nil {
+				b.logger.Error("Failed to flush driver locations on shutdown", logger.Err(err))
+			}
+			cancel()
+			b.logger.Info("Location batcher stopped")
+			return
+		case <-ticker.C:
+			if _, err := b.Flush(ctx); err != nil {
+				b.logger.Error("Failed to flush driver locations", logger.Err(err))
+			}
+		}
+	}
+}
+
+// Flush writes all pending positions in one statement and returns how many drivers were written.
+// On failure the batch is re-queued unless a newer position arrived in the meantime.
+func (b *Batcher) Flush(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = make(map[string]Position, len(batch))
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	query, args := buildBatchUpdate(batch)
+	if _, err := b.db.ExecContext(ctx, query, args...); err != nil {
+		b.requeue(batch)
+		return 0, fmt.Errorf("failed to flush %d driver locations: %w", len(batch), err)
+	}
+
+	if b.metrics != nil {
+		b.metrics.RecordLocationFlush(len(batch))
+	}
+	b.logger.Debug("Flushed driver locations", logger.Int("batch_size", len(batch)))
+
+	return len(batch), nil
+}
+
+// requeue puts a failed batch back without overwriting newer positions
+func (b *Batcher) requeue(batch map[string]Position) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, pos := range batch {
+		if _, newer := b.pending[id]; !newer {
+			b.pending[id] = pos
+		}
+	}
+}
+
+// buildBatchUpdate renders an UPDATE ... FROM (VALUES ...) statement for the batch
+func buildBatchUpdate(batch map[string]Position) (string, []interface{}) {
+	values := make([]string, 0, len(batch))
+	args := make([]interface{}, 0, len(batch)*3)
+	for id, pos := range batch {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d::uuid, $%d::double precision, $%d::double precision)", n+1, n+2, n+3))
+		args = append(args, id, pos.Latitude, pos.Longitude)
+	}
+
+	query := `
+		UPDATE drivers AS d
+		SET current_latitude = v.lat,
+		    current_longitude = v.lng,
+		    updated_at = NOW()
+		FROM (VALUES ` + strings.Join(values, ", ") + `) AS v(id, lat, lng)
+		WHERE d.id = v.id
+	`
+	return query, args
+}
@@ -0,0 +1,69 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedFlushes struct {
+	sizes []int
+}
+
+func (r *recordedFlushes) RecordLocationFlush(batchSize int) {
+	r.sizes = append(r.sizes, batchSize)
+}
+
+func newTestBatcher(t *testing.T) (*Batcher, sqlmock.Sqlmock, *recordedFlushes) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	assert.NoError(t, err)
+
+	metrics := &recordedFlushes{}
+	return NewBatcher(db, log, metrics, time.Second), mock, metrics
+}
+
+// TestBatcher_CoalescesPerDriver tests that repeated pings for a driver collapse into one row
+func TestBatcher_CoalescesPerDriver(t *testing.T) {
+	b, mock, metrics := newTestBatcher(t)
+
+	b.Add("11111111-1111-1111-1111-111111111111", 12.90, 77.50)
+	b.Add("11111111-1111-1111-1111-111111111111", 12.91, 77.51)
+	b.Add("22222222-2222-2222-2222-222222222222", 12.95, 77.55)
+
+	mock.ExpectExec("UPDATE drivers AS d").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	n, err := b.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []int{2}, metrics.sizes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Nothing left to write
+	n, err = b.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+// TestBatcher_RequeuesOnFailure tests that a failed flush keeps positions without clobbering newer ones
+func TestBatcher_RequeuesOnFailure(t *testing.T) {
+	b, mock, metrics := newTestBatcher(t)
+
+	b.Add("11111111-1111-1111-1111-111111111111", 12.90, 77.50)
+	mock.ExpectExec("UPDATE drivers AS d").WillReturnError(errors.New("connection reset"))
+
+	_, err := b.Flush(context.Background())
+	assert.Error(t, err)
+	assert.Empty(t, metrics.sizes)
+	assert.Equal(t, Position{Latitude: 12.90, Longitude: 77.50}, b.pending["11111111-1111-1111-1111-111111111111"])
+}
@@ -0,0 +1,127 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// SweepRecorder receives the number of stale drivers removed per sweep
+type SweepRecorder interface {
+	RecordStaleDriversSwept(count int)
+}
+
+// LastSeenKey holds the Unix time of a driver's latest location update
+func LastSeenKey(driverID string) string {
+	return fmt.Sprintf("driver:%s:last_seen", driverID)
+}
+
+// StaleSweeper removes drivers from drivers:locations and drivers:available once they stop
+// reporting their position, so matching isn't offered drivers who went offline or crashed.
+// Their database status is left alone; the claim reconciler skips drivers missing from the
+// geo index, so they are only restored once they report their location again.
+type StaleSweeper struct {
+	redis     *redis.Client
+	logger    *logger.Logger
+	metrics   SweepRecorder
+	interval  time.Duration
+	threshold time.Duration
+}
+
+// NewStaleSweeper creates a new stale driver sweeper; metrics may be nil
+func NewStaleSweeper(redis *redis.Client, logger *logger.Logger, metrics SweepRecorder, interval, threshold time.Duration) *StaleSweeper {
+	return &StaleSweeper{
+		redis:     redis,
+		logger:    logger,
+		metrics:   metrics,
+		interval:  interval,
+		threshold: threshold,
+	}
+}
+
+// Run sweeps on every tick until the context is cancelled
+func (s *StaleSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Stale driver sweeper started",
+		logger.Duration("interval", s.interval),
+		logger.Duration("threshold", s.threshold),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stale driver sweeper stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.Sweep(ctx); err != nil {
+				s.logger.Error("Failed to sweep stale drivers", logger.Err(err))
+			}
+		}
+	}
+}
+
+// Sweep removes every indexed driver whose last_seen is older than the threshold, or
+// missing altogether, and returns how many were removed
+func (s *StaleSweeper) Sweep(ctx context.Context) (int, error) {
+	driverIDs, err := s.redis.ZRange(ctx, matching.LocationsKey, 0, -1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list indexed drivers: %w", err)
+	}
+	if len(driverIDs) == 0 {
+		return 0, nil
+	}
+
+	// Read every last_seen in one round trip
+	pipe := s.redis.Pipeline()
+	lastSeen := make([]*redis.StringCmd, len(driverIDs))
+	for i, id := range driverIDs {
+		lastSeen[i] = pipe.Get(ctx, LastSeenKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, fmt.Errorf("failed to load driver last_seen: %w", err)
+	}
+
+	cutoff := time.Now().Add(-s.threshold).Unix()
+	var stale []string
+	for i, id := range driverIDs {
+		seenAt, err := strconv.ParseInt(lastSeen[i].Val(), 10, 64)
+		if err == nil && seenAt >= cutoff {
+			continue
+		}
+		stale = append(stale, id)
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+
+	members := make([]interface{}, len(stale))
+	lastSeenKeys := make([]string, len(stale))
+	for i, id := range stale {
+		members[i] = id
+		lastSeenKeys[i] = LastSeenKey(id)
+	}
+
+	pipe = s.redis.TxPipeline()
+	matching.RemoveDriverLocations(ctx, pipe, stale...)
+	pipe.SRem(ctx, "drivers:available", members...)
+	pipe.Del(ctx, lastSeenKeys...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to remove stale drivers: %w", err)
+	}
+
+	for _, id := range stale {
+		s.logger.Info("Swept stale driver location", logger.String("driver_id", id))
+	}
+	if s.metrics != nil {
+		s.metrics.RecordStaleDriversSwept(len(stale))
+	}
+	return len(stale), nil
+}
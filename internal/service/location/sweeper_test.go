@@ -0,0 +1,56 @@
+package location
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedSweeps struct {
+	counts []int
+}
+
+func (r *recordedSweeps) RecordStaleDriversSwept(count int) {
+	r.counts = append(r.counts, count)
+}
+
+// TestStaleSweeper_RemovesSilentDrivers tests that drivers past the threshold or with no
+// heartbeat are dropped from the geo index and available set, and fresh ones are kept
+func TestStaleSweeper_RemovesSilentDrivers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	const fresh, stale, unknown = "driver-fresh", "driver-stale", "driver-unknown"
+	for _, id := range []string{fresh, stale, unknown} {
+		client.GeoAdd(ctx, "drivers:locations", &redis.GeoLocation{Name: id, Latitude: 12.97, Longitude: 77.59})
+		client.SAdd(ctx, "drivers:available", id)
+	}
+	client.Set(ctx, LastSeenKey(fresh), time.Now().Unix(), 0)
+	client.Set(ctx, LastSeenKey(stale), time.Now().Add(-10*time.Minute).Unix(), 0)
+
+	metrics := &recordedSweeps{}
+	swept, err := NewStaleSweeper(client, log, metrics, time.Minute, 2*time.Minute).Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, swept)
+	assert.Equal(t, []int{2}, metrics.counts)
+
+	indexed, err := client.ZRange(ctx, "drivers:locations", 0, -1).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{fresh}, indexed)
+
+	available, err := client.SMembers(ctx, "drivers:available").Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{fresh}, available)
+	assert.False(t, mr.Exists(LastSeenKey(stale)))
+}
@@ -0,0 +1,111 @@
+package matching
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// assignmentDeadlinesKey is a sorted set of assigned ride IDs scored by the Unix time their
+// driver's offer lapses. It lives in Redis so deadlines survive an API restart.
+const assignmentDeadlinesKey = "rides:assignment_deadlines"
+
+// AssignmentExpirer re-offers a ride whose assigned driver didn't accept in time
+type AssignmentExpirer interface {
+	ExpireAssignment(ctx context.Context, rideID string) error
+}
+
+// TrackAssignment starts (or restarts) the acceptance countdown for a ride's current offer
+func TrackAssignment(ctx context.Context, client *redis.Client, rideID string, deadline time.Time) error {
+	err := client.ZAdd(ctx, assignmentDeadlinesKey, redis.Z{Score: float64(deadline.Unix()), Member: rideID}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to track ride assignment: %w", err)
+	}
+	return nil
+}
+
+// ClearAssignment stops the countdown once the ride is accepted or no longer assigned
+func ClearAssignment(ctx context.Context, client *redis.Client, rideID string) error {
+	if err := client.ZRem(ctx, assignmentDeadlinesKey, rideID).Err(); err != nil {
+		return fmt.Errorf("failed to clear ride assignment: %w", err)
+	}
+	return nil
+}
+
+// AssignmentTimeoutWorker hands rides whose offers have lapsed to the expirer
+type AssignmentTimeoutWorker struct {
+	redis    *redis.Client
+	expirer  AssignmentExpirer
+	logger   *logger.Logger
+	interval time.Duration
+}
+
+// NewAssignmentTimeoutWorker creates a worker that checks for lapsed offers every interval
+func NewAssignmentTimeoutWorker(redis *redis.Client, expirer AssignmentExpirer, logger *logger.Logger, interval time.Duration) *AssignmentTimeoutWorker {
+	return &AssignmentTimeoutWorker{
+		redis:    redis,
+		expirer:  expirer,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+// Run expires lapsed offers on every tick until the context is cancelled
+func (w *AssignmentTimeoutWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.logger.Info("Assignment timeout worker started", logger.Duration("interval", w.interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Assignment timeout worker stopped")
+			return
+		case <-ticker.C:
+			if _, err := w.ExpireDue(ctx); err != nil {
+				w.logger.Error("Failed to expire ride assignments", logger.Err(err))
+			}
+		}
+	}
+}
+
+// ExpireDue expires every offer past its deadline and returns how many were handled. Each
+// ride is claimed with ZREM so only one API instance expires it; a failed expiry is put
+// back to be retried on the next tick.
+func (w *AssignmentTimeoutWorker) ExpireDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	rideIDs, err := w.redis.ZRangeByScore(ctx, assignmentDeadlinesKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list lapsed assignments: %w", err)
+	}
+
+	expired := 0
+	for _, rideID := range rideIDs {
+		claimed, err := w.redis.ZRem(ctx, assignmentDeadlinesKey, rideID).Result()
+		if err != nil {
+			w.logger.Warn("Failed to claim lapsed assignment", logger.String("ride_id", rideID), logger.Err(err))
+			continue
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		if err := w.expirer.ExpireAssignment(ctx, rideID); err != nil {
+			w.logger.Error("Failed to expire ride assignment", logger.String("ride_id", rideID), logger.Err(err))
+			if err := TrackAssignment(ctx, w.redis, rideID, now); err != nil {
+				w.logger.Error("Failed to requeue ride assignment", logger.String("ride_id", rideID), logger.Err(err))
+			}
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
@@ -0,0 +1,56 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedExpiries struct {
+	rideIDs []string
+	failFor string
+}
+
+func (r *recordedExpiries) ExpireAssignment(ctx context.Context, rideID string) error {
+	if rideID == r.failFor {
+		return errors.New("database unavailable")
+	}
+	r.rideIDs = append(r.rideIDs, rideID)
+	return nil
+}
+
+// TestAssignmentTimeoutWorker_ExpiresLapsedOffers tests that only lapsed offers are expired
+// and that a failed expiry stays tracked for the next tick
+func TestAssignmentTimeoutWorker_ExpiresLapsedOffers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Now()
+	require.NoError(t, TrackAssignment(ctx, client, "ride-lapsed", now.Add(-time.Second)))
+	require.NoError(t, TrackAssignment(ctx, client, "ride-failing", now.Add(-time.Second)))
+	require.NoError(t, TrackAssignment(ctx, client, "ride-open", now.Add(time.Minute)))
+	require.NoError(t, TrackAssignment(ctx, client, "ride-accepted", now.Add(-time.Second)))
+	require.NoError(t, ClearAssignment(ctx, client, "ride-accepted"))
+
+	expirer := &recordedExpiries{failFor: "ride-failing"}
+	expired, err := NewAssignmentTimeoutWorker(client, expirer, log, time.Second).ExpireDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Equal(t, []string{"ride-lapsed"}, expirer.rideIDs)
+
+	tracked, err := client.ZRange(ctx, assignmentDeadlinesKey, 0, -1).Result()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"ride-failing", "ride-open"}, tracked)
+}
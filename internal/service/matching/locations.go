@@ -0,0 +1,50 @@
+package matching
+
+import (
+	"context"
+
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/redis/go-redis/v9"
+)
+
+// LocationsKey is the geo index of every driver's last known position
+const LocationsKey = "drivers:locations"
+
+// VehicleLocationsKey returns the geo index holding only drivers of vehicleType, which
+// matching searches so candidates of other types never come back
+func VehicleLocationsKey(vehicleType driver.VehicleType) string {
+	return LocationsKey + ":" + string(vehicleType)
+}
+
+// IndexDriverLocation queues the writes placing driverID at lat/lng in the shared index and
+// in vehicleType's index. The driver is removed from every other vehicle type's index, so one
+// whose vehicle changed stops being matched for the old type. An unknown vehicleType only
+// updates the shared index.
+func IndexDriverLocation(ctx context.Context, pipe redis.Pipeliner, driverID string, vehicleType driver.VehicleType, lat, lng float64) {
+	location := &redis.GeoLocation{
+		Name:      driverID,
+		Longitude: lng,
+		Latitude:  lat,
+	}
+	pipe.GeoAdd(ctx, LocationsKey, location)
+	for _, vt := range driver.VehicleTypes() {
+		if vt == vehicleType {
+			pipe.GeoAdd(ctx, VehicleLocationsKey(vt), location)
+		} else {
+			pipe.ZRem(ctx, VehicleLocationsKey(vt), driverID)
+		}
+	}
+}
+
+// RemoveDriverLocations queues the writes dropping driverIDs from the shared index and every
+// vehicle type's index. A geo index is a sorted set, so ZREM is the GEODEL Redis doesn't have.
+func RemoveDriverLocations(ctx context.Context, pipe redis.Pipeliner, driverIDs ...string) {
+	members := make([]interface{}, len(driverIDs))
+	for i, id := range driverIDs {
+		members[i] = id
+	}
+	pipe.ZRem(ctx, LocationsKey, members...)
+	for _, vt := range driver.VehicleTypes() {
+		pipe.ZRem(ctx, VehicleLocationsKey(vt), members...)
+	}
+}
@@ -0,0 +1,56 @@
+package matching
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestIndexDriverLocation_MovesDriverBetweenVehicleTypes tests that re-indexing a driver
+// under a new vehicle type drops them from the old type's index
+func TestIndexDriverLocation_MovesDriverBetweenVehicleTypes(t *testing.T) {
+	_, client := newTestService(t)
+	ctx := context.Background()
+
+	pipe := client.Pipeline()
+	IndexDriverLocation(ctx, pipe, "driver-1", driver.VehicleEconomy, 12.97, 77.59)
+	_, err := pipe.Exec(ctx)
+	assert.NoError(t, err)
+
+	pipe = client.Pipeline()
+	IndexDriverLocation(ctx, pipe, "driver-1", driver.VehiclePremium, 12.98, 77.60)
+	_, err = pipe.Exec(ctx)
+	assert.NoError(t, err)
+
+	economy, err := client.ZRange(ctx, VehicleLocationsKey(driver.VehicleEconomy), 0, -1).Result()
+	assert.NoError(t, err)
+	assert.Empty(t, economy)
+
+	premium, err := client.ZRange(ctx, VehicleLocationsKey(driver.VehiclePremium), 0, -1).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"driver-1"}, premium)
+
+	all, err := client.ZRange(ctx, LocationsKey, 0, -1).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"driver-1"}, all)
+}
+
+// TestRemoveDriverLocations tests that a removed driver leaves every geo index
+func TestRemoveDriverLocations(t *testing.T) {
+	_, client := newTestService(t)
+	ctx := context.Background()
+	addTestDriver(t, client, "driver-1", driver.VehicleLuxury, 12.97, 77.59)
+
+	pipe := client.Pipeline()
+	RemoveDriverLocations(ctx, pipe, "driver-1")
+	_, err := pipe.Exec(ctx)
+	assert.NoError(t, err)
+
+	for _, key := range []string{LocationsKey, VehicleLocationsKey(driver.VehicleLuxury)} {
+		members, err := client.ZRange(ctx, key, 0, -1).Result()
+		assert.NoError(t, err)
+		assert.Empty(t, members, key)
+	}
+}
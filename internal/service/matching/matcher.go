@@ -2,21 +2,28 @@ package matching
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/internal/domain/rider"
 	"github.com/gocomet/ride-hailing/pkg/logger"
 	"github.com/redis/go-redis/v9"
 )
 
 // Service handles driver-rider matching
 type Service struct {
-	redis  *redis.Client
-	logger *logger.Logger
-	config Config
+	redis     *redis.Client
+	rides     ride.Repository
+	favorites rider.FavoriteRepository
+	logger    *logger.Logger
+	config    Config
 }
 
 // Config holds matching configuration
@@ -25,8 +32,27 @@ type Config struct {
 	MaxExpandedRadius float64      // Maximum expanded radius when no drivers found
 	MaxTimeout       time.Duration
 	MaxCandidates    int
+	InitialBatchSize int     // Candidates fetched first in the smallest radius; 0 always fetches MaxCandidates
+	PoolCapacity     int     // Pool riders one driver may carry at once
+	PoolMaxDetourKM  float64 // Furthest apart the dropoffs of a shared ride may be
+	FavoriteBandKM   float64 // How much further a rider's favorite driver may be and still win
+	RatingBucketKM   float64 // Drivers within this distance of the nearest one are ranked by rating instead
+
+	// SearchRadiiKM are the radii a search expands through, ascending; NewService fills in
+	// DefaultSearchRadii when it is empty
+	SearchRadiiKM []float64
 }
 
+// ErrMatchingTimeout is returned when the search exceeds Config.MaxTimeout
+var ErrMatchingTimeout = errors.New("matching timed out")
+
+// errBatchExhausted means a partial batch of candidates was fetched and none could be claimed,
+// so drivers further away in the same radius haven't been tried yet
+var errBatchExhausted = errors.New("candidate batch exhausted")
+
+// ClaimingMarker is stored in driver:<id>:current_ride while a match is being confirmed
+const ClaimingMarker = "claiming"
+
 // DriverCandidate represents a nearby driver
 type DriverCandidate struct {
 	Driver   *driver.Driver
@@ -34,33 +60,136 @@ type DriverCandidate struct {
 }
 
 // NewService creates a new matching service
-func NewService(redis *redis.Client, logger *logger.Logger, config Config) *Service {
+// rides is used to cross-check claimed drivers against Postgres, and favorites to load a
+// rider's favorites when they aren't cached; either may be nil.
+func NewService(redis *redis.Client, rides ride.Repository, favorites rider.FavoriteRepository, logger *logger.Logger, config Config) *Service {
+	if len(config.SearchRadiiKM) == 0 {
+		config.SearchRadiiKM = DefaultSearchRadii(config.MaxRadiusKM, config.MaxExpandedRadius)
+	}
 	return &Service{
-		redis:  redis,
-		logger: logger,
-		config: config,
+		redis:     redis,
+		rides:     rides,
+		favorites: favorites,
+		logger:    logger,
+		config:    config,
 	}
 }
 
 // FindNearestDriver finds the nearest available driver
-// It starts with the initial radius and expands progressively if no drivers are found
-func (s *Service) FindNearestDriver(ctx context.Context, pickupLat, pickupLng float64, vehicleType driver.VehicleType) (*driver.Driver, error) {
+// It starts with the initial radius and expands progressively if no drivers are found.
+// The returned candidate carries the driver's distance from pickup in km.
+// A driver on riderID's favorites list is preferred over one less than FavoriteBandKM closer;
+// riderID may be empty.
+func (s *Service) FindNearestDriver(ctx context.Context, riderID string, pickupLat, pickupLng float64, vehicleType driver.VehicleType) (*DriverCandidate, error) {
+	return s.FindNearestDriverExcluding(ctx, riderID, pickupLat, pickupLng, vehicleType, nil)
+}
+
+// FindNearestDriverExcluding behaves like FindNearestDriver but never offers a driver in excluded
+// (e.g. drivers who already rejected the ride).
+func (s *Service) FindNearestDriverExcluding(ctx context.Context, riderID string, pickupLat, pickupLng float64, vehicleType driver.VehicleType, excluded map[string]bool) (*DriverCandidate, error) {
+	return s.FindNearestDriverForSeats(ctx, riderID, pickupLat, pickupLng, vehicleType, 0, false, excluded)
+}
+
+// FindNearestDriverForSeats behaves like FindNearestDriverExcluding but only offers vehicles
+// carrying at least seats passengers. If the requested vehicle type is too small, every larger
+// type that fits is tried within each radius, smallest first. If allowUpgrade is set and the
+// requested type comes up empty at every radius, the search is repeated for the larger types.
+// A seats value of 0 places no limit on passengers.
+func (s *Service) FindNearestDriverForSeats(ctx context.Context, riderID string, pickupLat, pickupLng float64, vehicleType driver.VehicleType, seats int, allowUpgrade bool, excluded map[string]bool) (*DriverCandidate, error) {
 	startTime := time.Now()
 
-	// Define search radii - start small and expand progressively
+	vehicleTypes, upgrades := vehicleTiers(vehicleType, seats)
+	if len(vehicleTypes) == 0 {
+		s.logger.Warn("No vehicle type carries the requested seats",
+			logger.String("vehicle_type", string(vehicleType)),
+			logger.Int("seats", seats),
+		)
+		return nil, driver.ErrDriverNotAvailable
+	}
+
+	// Bound the whole search so a slow Redis can't hang the ride request
+	if s.config.MaxTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.MaxTimeout)
+		defer cancel()
+	}
+
+	searchRadii := s.config.SearchRadiiKM
+	maxRadius := s.MaxSearchRadiusKM()
+
+	favorites := s.favoriteDrivers(ctx, riderID)
+
+	// Sweep every radius for the requested tier before any opt-in upgrade
+	tiers := [][]driver.VehicleType{vehicleTypes}
+	if allowUpgrade && len(upgrades) > 0 {
+		tiers = append(tiers, upgrades)
+	}
+
+	for _, types := range tiers {
+		// Try each radius progressively
+		for i, radius := range searchRadii {
+			for _, vt := range types {
+				candidate, err := s.searchRadius(ctx, pickupLat, pickupLng, radius, i == 0, vt, excluded, favorites, startTime)
+				if err == nil && candidate != nil {
+					if vt != vehicleType {
+						s.logger.Info("Upgraded vehicle type",
+							logger.String("requested_vehicle_type", string(vehicleType)),
+							logger.String("matched_vehicle_type", string(vt)),
+							logger.Int("seats", seats),
+							logger.Bool("allow_upgrade", allowUpgrade),
+						)
+					}
+					return candidate, nil
+				}
+				if ctx.Err() != nil {
+					break
+				}
+			}
+
+			if ctx.Err() != nil {
+				s.logger.Warn("Driver matching timed out",
+					logger.Float64("radius_km", radius),
+					logger.Int64("latency_ms", time.Since(startTime).Milliseconds()),
+				)
+				return nil, fmt.Errorf("%w: %v", ErrMatchingTimeout, ctx.Err())
+			}
+
+			// If we found drivers but none were available, log and try larger radius
+			if i+1 < len(searchRadii) {
+				s.logger.Info("No available drivers in radius, expanding search",
+					logger.Float64("current_radius_km", radius),
+					logger.Float64("next_radius_km", searchRadii[i+1]),
+				)
+			}
+		}
+	}
+
+	s.logger.Warn("No drivers available in maximum search radius",
+		logger.Float64("max_radius_km", maxRadius),
+		logger.Float64("pickup_lat", pickupLat),
+		logger.Float64("pickup_lng", pickupLng),
+	)
+
+	return nil, driver.ErrDriverNotAvailable
+}
+
+// DefaultSearchRadii returns the expansion schedule used when none is configured: the initial
+// radius, then 2x, 4x and 10x of it, capped by maxExpandedRadius (50km if 0)
+func DefaultSearchRadii(initialRadius, maxExpandedRadius float64) []float64 {
+	// Start small and expand progressively
 	// Initial: 5km, then expand to 10km, 20km, 50km, up to max expanded radius
-	maxRadius := s.config.MaxExpandedRadius
+	maxRadius := maxExpandedRadius
 	if maxRadius == 0 {
 		maxRadius = 50.0 // Default max 50km if not configured
 	}
 
-	searchRadii := []float64{s.config.MaxRadiusKM}
+	searchRadii := []float64{initialRadius}
 
 	// Add expanded radii: 2x, 4x, 10x of initial radius
 	expandedRadii := []float64{
-		s.config.MaxRadiusKM * 2,  // 10km
-		s.config.MaxRadiusKM * 4,  // 20km
-		s.config.MaxRadiusKM * 10, // 50km
+		initialRadius * 2,  // 10km
+		initialRadius * 4,  // 20km
+		initialRadius * 10, // 50km
 	}
 
 	for _, r := range expandedRadii {
@@ -68,46 +197,72 @@ func (s *Service) FindNearestDriver(ctx context.Context, pickupLat, pickupLng fl
 			searchRadii = append(searchRadii, r)
 		}
 	}
+	return searchRadii
+}
 
-	// Use Redis GEORADIUS to find nearby drivers
-	key := "drivers:locations"
+// MaxSearchRadiusKM returns the widest radius a search expands to before giving up
+func (s *Service) MaxSearchRadiusKM() float64 {
+	return s.config.SearchRadiiKM[len(s.config.SearchRadiiKM)-1]
+}
 
-	// Try each radius progressively
-	for _, radius := range searchRadii {
-		foundDriver, err := s.searchDriversInRadius(ctx, key, pickupLat, pickupLng, radius, vehicleType, startTime)
-		if err == nil && foundDriver != nil {
-			return foundDriver, nil
-		}
+// vehicleTiers returns the vehicle types to search for a ride and the larger types a rider may
+// opt into once those come up empty. A requested type too small for seats is replaced by every
+// type that fits, with no further upgrades.
+func vehicleTiers(requested driver.VehicleType, seats int) (types, upgrades []driver.VehicleType) {
+	fitting := requested.TypesForSeats(seats)
+	if requested.Seats() < seats {
+		return fitting, nil
+	}
+	if len(fitting) > 0 {
+		upgrades = fitting[1:]
+	}
+	return []driver.VehicleType{requested}, upgrades
+}
 
-		// If we found drivers but none were available, log and try larger radius
-		if radius < maxRadius {
-			s.logger.Info("No available drivers in radius, expanding search",
-				logger.Float64("current_radius_km", radius),
-				logger.Float64("next_radius_km", radius*2),
-			)
-		}
+// BatchesCandidates reports whether the smallest radius is searched with a small batch of
+// candidates before the full MaxCandidates
+func (s *Service) BatchesCandidates() bool {
+	return s.config.InitialBatchSize > 0 &&
+		(s.config.MaxCandidates == 0 || s.config.InitialBatchSize < s.config.MaxCandidates)
+}
+
+// searchRadius searches one radius for a driver. The smallest radius usually holds a
+// claimable driver among the nearest few, so when batching it fetches InitialBatchSize
+// candidates first and only fetches the full MaxCandidates if none of those could be claimed.
+// Ranking by rating and favorites then only applies within the first batch.
+func (s *Service) searchRadius(ctx context.Context, pickupLat, pickupLng, radius float64, smallest bool, vehicleType driver.VehicleType, excluded, favorites map[string]bool, startTime time.Time) (*DriverCandidate, error) {
+	if !smallest || !s.BatchesCandidates() {
+		return s.searchDriversInRadius(ctx, pickupLat, pickupLng, radius, s.config.MaxCandidates, vehicleType, excluded, favorites, startTime)
 	}
 
-	s.logger.Warn("No drivers available in maximum search radius",
-		logger.Float64("max_radius_km", maxRadius),
-		logger.Float64("pickup_lat", pickupLat),
-		logger.Float64("pickup_lng", pickupLng),
+	candidate, err := s.searchDriversInRadius(ctx, pickupLat, pickupLng, radius, s.config.InitialBatchSize, vehicleType, excluded, favorites, startTime)
+	if !errors.Is(err, errBatchExhausted) {
+		return candidate, err
+	}
+	s.logger.Debug("No claimable driver in first batch, fetching more candidates",
+		logger.Float64("radius_km", radius),
+		logger.Int("batch_size", s.config.InitialBatchSize),
 	)
-
-	return nil, driver.ErrDriverNotAvailable
+	return s.searchDriversInRadius(ctx, pickupLat, pickupLng, radius, s.config.MaxCandidates, vehicleType, excluded, favorites, startTime)
 }
 
-// searchDriversInRadius searches for available drivers within a specific radius
-func (s *Service) searchDriversInRadius(ctx context.Context, key string, pickupLat, pickupLng, radius float64, vehicleType driver.VehicleType, startTime time.Time) (*driver.Driver, error) {
-	// Search for drivers within radius
+// searchDriversInRadius searches for available drivers among the count nearest within a
+// specific radius (0 means no limit). If all count came back and none could be claimed while
+// fewer than MaxCandidates were asked for, it returns errBatchExhausted.
+func (s *Service) searchDriversInRadius(ctx context.Context, pickupLat, pickupLng, radius float64, count int, vehicleType driver.VehicleType, excluded, favorites map[string]bool, startTime time.Time) (*DriverCandidate, error) {
+	// Search for drivers within radius; each vehicle type has its own geo index, so only
+	// drivers of the requested type come back
+	key := VehicleLocationsKey(vehicleType)
+	seg := redisSegment(ctx, "GEORADIUS", key)
 	results, err := s.redis.GeoRadius(ctx, key, pickupLng, pickupLat, &redis.GeoRadiusQuery{
 		Radius:    radius,
 		Unit:      "km",
 		WithCoord: true,
 		WithDist:  true,
-		Count:     s.config.MaxCandidates,
+		Count:     count,
 		Sort:      "ASC",
 	}).Result()
+	seg.End()
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to search nearby drivers: %w", err)
@@ -116,14 +271,23 @@ func (s *Service) searchDriversInRadius(ctx context.Context, key string, pickupL
 	if len(results) == 0 {
 		return nil, driver.ErrDriverNotAvailable
 	}
+	ratings := s.driverRatings(ctx, results)
+	s.rankByRating(results, ratings)
+	s.preferFavorites(results, favorites)
 
 	// Filter by vehicle type and availability - use atomic claim
 	for _, result := range results {
 		driverID := result.Name
 
+		if excluded[driverID] {
+			continue
+		}
+
 		// Check if driver is already on a ride first (quick check)
 		currentRideKey := fmt.Sprintf("driver:%s:current_ride", driverID)
+		seg = redisSegment(ctx, "GET", "driver:current_ride")
 		currentRide, err := s.redis.Get(ctx, currentRideKey).Result()
+		seg.End()
 		if err == nil && currentRide != "" {
 			// Driver is already on a ride, skip to next nearest driver
 			s.logger.Info("Driver skipped - already on ride",
@@ -136,7 +300,9 @@ func (s *Service) searchDriversInRadius(ctx context.Context, key string, pickupL
 
 		// Atomically claim driver by removing from available set
 		// SREM returns 1 if member was removed, 0 if it wasn't there
+		seg = redisSegment(ctx, "SREM", "drivers:available")
 		removed, err := s.redis.SRem(ctx, "drivers:available", driverID).Result()
+		seg.End()
 		if err != nil {
 			s.logger.Warn("Failed to claim driver", logger.String("driver_id", driverID), logger.Err(err))
 			continue
@@ -152,42 +318,305 @@ func (s *Service) searchDriversInRadius(ctx context.Context, key string, pickupL
 
 		// Successfully claimed the driver - set current ride key to prevent double-assignment
 		// This will be overwritten with actual ride ID in ride_handler
-		s.redis.Set(ctx, currentRideKey, "claiming", 30*time.Second)
-
-		// Create driver object
-		lat := result.Latitude
-		lng := result.Longitude
+		s.redis.Set(ctx, currentRideKey, ClaimingMarker, 30*time.Second)
 
 		// Parse or generate UUID for the driver
 		driverUUID, err := uuid.Parse(driverID)
 		if err != nil {
 			driverUUID = uuid.New()
+		} else if !s.hasNoActiveRideInDB(ctx, driverID, driverUUID) {
+			continue
 		}
 
-		foundDriver := &driver.Driver{
-			ID:               driverUUID,
-			Name:             "Driver " + driverID[:8],
-			Status:           driver.StatusOnline,
-			VehicleType:      vehicleType,
-			CurrentLatitude:  &lat,
-			CurrentLongitude: &lng,
-			Rating:           4.8,
-		}
+		foundDriver := s.loadDriver(ctx, driverUUID, driver.StatusOnline, result)
 
 		elapsed := time.Since(startTime).Milliseconds()
 		s.logger.Info("Driver matched and claimed",
 			logger.String("driver_id", driverID),
 			logger.Float64("distance_km", result.Dist),
 			logger.Float64("search_radius_km", radius),
+			logger.Float64("rating", ratings[driverID]),
+			logger.Bool("favorite", favorites[driverID]),
 			logger.Int64("latency_ms", elapsed),
 		)
 
-		return foundDriver, nil
+		return &DriverCandidate{
+			Driver:   foundDriver,
+			Distance: result.Dist,
+		}, nil
 	}
 
+	if count > 0 && len(results) == count && (s.config.MaxCandidates == 0 || count < s.config.MaxCandidates) {
+		return nil, errBatchExhausted
+	}
 	return nil, driver.ErrDriverNotAvailable
 }
 
+// FavoriteDriversKey returns the Redis set of drivers a rider has marked as favorites
+func FavoriteDriversKey(riderID string) string {
+	return fmt.Sprintf("rider:%s:favorite_drivers", riderID)
+}
+
+// favoritesLoadedMarker is kept in a favorites set filled from Postgres. A set without it
+// (missing, or started by a later add) may be incomplete and is reloaded.
+const favoritesLoadedMarker = "loaded"
+
+// favoriteDriversTTL is how long a favorites set loaded from Postgres is trusted
+const favoriteDriversTTL = 24 * time.Hour
+
+// favoriteDrivers loads the rider's favorite drivers from Redis, filling the set from
+// Postgres when it isn't complete; a failed lookup just means no preference
+func (s *Service) favoriteDrivers(ctx context.Context, riderID string) map[string]bool {
+	if riderID == "" || s.config.FavoriteBandKM <= 0 {
+		return nil
+	}
+	key := FavoriteDriversKey(riderID)
+	ids, err := s.redis.SMembers(ctx, key).Result()
+	if err != nil {
+		s.logger.Warn("Failed to load favorite drivers", logger.String("rider_id", riderID), logger.Err(err))
+		return nil
+	}
+
+	favorites := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		favorites[id] = true
+	}
+	if favorites[favoritesLoadedMarker] || s.favorites == nil {
+		return favorites
+	}
+
+	riderUUID, err := uuid.Parse(riderID)
+	if err != nil {
+		return favorites
+	}
+	stored, err := s.favorites.ListFavoriteDriverIDs(ctx, riderUUID)
+	if err != nil {
+		s.logger.Warn("Failed to load favorite drivers from Postgres", logger.String("rider_id", riderID), logger.Err(err))
+		return favorites
+	}
+
+	members := []interface{}{favoritesLoadedMarker}
+	favorites = make(map[string]bool, len(stored))
+	for _, id := range stored {
+		favorites[id.String()] = true
+		members = append(members, id.String())
+	}
+	pipe := s.redis.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.SAdd(ctx, key, members...)
+	pipe.Expire(ctx, key, favoriteDriversTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Warn("Failed to cache favorite drivers", logger.String("rider_id", riderID), logger.Err(err))
+	}
+	return favorites
+}
+
+// driverRatings reads the ratings cached in each candidate's meta hash in one round trip.
+// Drivers without a cached rating are left out and rank below every rated driver.
+func (s *Service) driverRatings(ctx context.Context, results []redis.GeoLocation) map[string]float64 {
+	pipe := s.redis.Pipeline()
+	cmds := make([]*redis.StringCmd, len(results))
+	for i, result := range results {
+		cmds[i] = pipe.HGet(ctx, DriverMetaKey(result.Name), "rating")
+	}
+	seg := redisSegment(ctx, "HGET", "driver:meta")
+	_, err := pipe.Exec(ctx)
+	seg.End()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		s.logger.Warn("Failed to load driver ratings", logger.Err(err))
+	}
+
+	ratings := make(map[string]float64, len(results))
+	for i, result := range results {
+		if rating, err := cmds[i].Float64(); err == nil {
+			ratings[result.Name] = rating
+		}
+	}
+	return ratings
+}
+
+// rankByRating orders the candidates within RatingBucketKM of the nearest one by rating, so
+// a better-rated driver wins over one only a few hundred metres closer, then does the same
+// for the next window starting at the nearest candidate left. Windows are anchored on actual
+// distances rather than fixed bands, so two drivers 20 m apart always compete on rating.
+// results must be sorted nearest first.
+func (s *Service) rankByRating(results []redis.GeoLocation, ratings map[string]float64) {
+	if s.config.RatingBucketKM <= 0 {
+		return
+	}
+	for start := 0; start < len(results); {
+		end := start + 1
+		for end < len(results) && results[end].Dist-results[start].Dist <= s.config.RatingBucketKM {
+			end++
+		}
+		window := results[start:end]
+		sort.SliceStable(window, func(i, j int) bool {
+			return ratings[window[i].Name] > ratings[window[j].Name]
+		})
+		start = end
+	}
+}
+
+// preferFavorites moves favorite drivers ahead of the non-favorites they are at most
+// FavoriteBandKM further than, so a favorite beats a marginally closer stranger but never
+// one who is clearly nearer. results are expected in ranked order, nearest windows first.
+func (s *Service) preferFavorites(results []redis.GeoLocation, favorites map[string]bool) {
+	if len(favorites) == 0 {
+		return
+	}
+	for i := 1; i < len(results); i++ {
+		if !favorites[results[i].Name] {
+			continue
+		}
+		// Shift the favorite left past every closer non-favorite within the band
+		j := i
+		for j > 0 && !favorites[results[j-1].Name] && results[i].Dist-results[j-1].Dist <= s.config.FavoriteBandKM {
+			j--
+		}
+		if j < i {
+			favorite := results[i]
+			copy(results[j+1:i+1], results[j:i])
+			results[j] = favorite
+		}
+	}
+}
+
+// NearbyDriver is an available driver returned by a read-only proximity search
+type NearbyDriver struct {
+	ID          string
+	VehicleType driver.VehicleType
+	Latitude    float64
+	Longitude   float64
+	Distance    float64
+}
+
+// nearbyOverfetch widens the GEORADIUS count so filtering out busy drivers still fills the page
+const nearbyOverfetch = 3
+
+// FindNearbyDrivers lists available drivers within radius of a point, nearest first, without
+// claiming any of them. An empty vehicleType matches every type.
+func (s *Service) FindNearbyDrivers(ctx context.Context, lat, lng, radius float64, vehicleType driver.VehicleType, limit int) ([]NearbyDriver, error) {
+	key := LocationsKey
+	if vehicleType != "" {
+		key = VehicleLocationsKey(vehicleType)
+	}
+	results, err := s.redis.GeoRadius(ctx, key, lng, lat, &redis.GeoRadiusQuery{
+		Radius:    radius,
+		Unit:      "km",
+		WithCoord: true,
+		WithDist:  true,
+		Count:     limit * nearbyOverfetch,
+		Sort:      "ASC",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search nearby drivers: %w", err)
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	// Fetch availability, ride claims and vehicle types in one round trip
+	pipe := s.redis.Pipeline()
+	available := make([]*redis.BoolCmd, len(results))
+	currentRides := make([]*redis.StringCmd, len(results))
+	vehicleTypes := make([]*redis.StringCmd, len(results))
+	for i, result := range results {
+		available[i] = pipe.SIsMember(ctx, "drivers:available", result.Name)
+		currentRides[i] = pipe.Get(ctx, fmt.Sprintf("driver:%s:current_ride", result.Name))
+		vehicleTypes[i] = pipe.HGet(ctx, DriverMetaKey(result.Name), "vehicle_type")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to load nearby driver state: %w", err)
+	}
+
+	drivers := make([]NearbyDriver, 0, limit)
+	for i, result := range results {
+		if !available[i].Val() || currentRides[i].Val() != "" {
+			continue
+		}
+		vt := driver.VehicleType(vehicleTypes[i].Val())
+		if vehicleType != "" && vt != vehicleType {
+			continue
+		}
+
+		drivers = append(drivers, NearbyDriver{
+			ID:          result.Name,
+			VehicleType: vt,
+			Latitude:    result.Latitude,
+			Longitude:   result.Longitude,
+			Distance:    result.Dist,
+		})
+		if len(drivers) == limit {
+			break
+		}
+	}
+	return drivers, nil
+}
+
+// hasNoActiveRideInDB guards against the current_ride key having expired while
+// Postgres still holds a non-terminal ride for the claimed driver. On drift the
+// Redis key is repaired; on lookup errors the claim is released.
+func (s *Service) hasNoActiveRideInDB(ctx context.Context, driverID string, driverUUID uuid.UUID) bool {
+	if s.rides == nil {
+		return true
+	}
+
+	activeRide, err := s.rides.GetActiveRideByDriver(ctx, driverUUID)
+	if errors.Is(err, ride.ErrRideNotFound) {
+		return true
+	}
+
+	currentRideKey := fmt.Sprintf("driver:%s:current_ride", driverID)
+	if err != nil {
+		s.logger.Warn("Driver skipped - failed to verify active ride",
+			logger.String("driver_id", driverID),
+			logger.Err(err),
+		)
+		s.redis.Del(ctx, currentRideKey)
+		s.redis.SAdd(ctx, "drivers:available", driverID)
+		return false
+	}
+
+	s.logger.Warn("Redis/DB drift: claimed driver already has an active ride",
+		logger.String("driver_id", driverID),
+		logger.String("ride_id", activeRide.ID),
+		logger.String("ride_status", string(activeRide.Status)),
+	)
+	s.redis.Set(ctx, currentRideKey, activeRide.ID, 0)
+	return false
+}
+
+// DriverMetaKey returns the Redis hash holding a driver's matching metadata: name, phone,
+// vehicle_type and rating
+func DriverMetaKey(driverID string) string {
+	return fmt.Sprintf("driver:%s:meta", driverID)
+}
+
+// loadDriver builds the matched driver from the profile cached in their meta hash, placed at
+// the position the geo search found them. Fields missing from the cache are left empty.
+func (s *Service) loadDriver(ctx context.Context, driverID uuid.UUID, status driver.Status, result redis.GeoLocation) *driver.Driver {
+	seg := redisSegment(ctx, "HGETALL", "driver:meta")
+	meta, err := s.redis.HGetAll(ctx, DriverMetaKey(driverID.String())).Result()
+	seg.End()
+	if err != nil {
+		s.logger.Warn("Failed to load driver profile", logger.String("driver_id", driverID.String()), logger.Err(err))
+	}
+
+	lat := result.Latitude
+	lng := result.Longitude
+	rating, _ := strconv.ParseFloat(meta["rating"], 64)
+	return &driver.Driver{
+		ID:               driverID,
+		Name:             meta["name"],
+		Phone:            meta["phone"],
+		Status:           status,
+		VehicleType:      driver.VehicleType(meta["vehicle_type"]),
+		CurrentLatitude:  &lat,
+		CurrentLongitude: &lng,
+		Rating:           rating,
+	}
+}
+
 // CalculateDistance calculates haversine distance between two points
 func CalculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	const earthRadius = 6371 // kilometers
@@ -204,6 +633,15 @@ func CalculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	return earthRadius * c
 }
 
+// EstimateArrivalMinutes estimates how long a driver needs to cover distanceKM
+// at the given average speed, rounded up to the nearest whole minute
+func EstimateArrivalMinutes(distanceKM, avgSpeedKMH float64) int {
+	if avgSpeedKMH <= 0 || distanceKM <= 0 {
+		return 0
+	}
+	return int(math.Ceil(distanceKM / avgSpeedKMH * 60))
+}
+
 func toRadians(deg float64) float64 {
 	return deg * math.Pi / 180
 }
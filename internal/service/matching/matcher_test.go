@@ -1,9 +1,17 @@
 package matching
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -117,3 +125,458 @@ func TestMatchingCriteria_Priority(t *testing.T) {
 	matches := (requestType == driverType)
 	assert.False(t, matches, "Economy request should not match premium driver")
 }
+
+// newTestService creates a matching service backed by an in-memory Redis
+func newTestService(t *testing.T) (*Service, *redis.Client) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	assert.NoError(t, err)
+
+	return NewService(client, nil, nil, log, Config{
+		MaxRadiusKM:       5.0,
+		MaxExpandedRadius: 50.0,
+		MaxCandidates:     10,
+	}), client
+}
+
+// addTestDriver places an available driver in the geo index
+func addTestDriver(t *testing.T, client *redis.Client, id string, vehicleType driver.VehicleType, lat, lng float64) {
+	ctx := context.Background()
+	assert.NoError(t, client.HSet(ctx, DriverMetaKey(id), "vehicle_type", string(vehicleType)).Err())
+	pipe := client.Pipeline()
+	IndexDriverLocation(ctx, pipe, id, vehicleType, lat, lng)
+	_, err := pipe.Exec(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, client.SAdd(ctx, "drivers:available", id).Err())
+}
+
+// TestFindNearestDriver_ReturnsCachedProfile tests that the matched driver carries the name,
+// phone and rating cached in their meta hash rather than placeholder values
+func TestFindNearestDriver_ReturnsCachedProfile(t *testing.T) {
+	service, client := newTestService(t)
+	ctx := context.Background()
+
+	driverID := uuid.New().String()
+	addTestDriver(t, client, driverID, driver.VehicleEconomy, 12.9720, 77.5950)
+	assert.NoError(t, client.HSet(ctx, DriverMetaKey(driverID),
+		"name", "Asha Rao", "phone", "+919800000001", "rating", 4.6).Err())
+
+	candidate, err := service.FindNearestDriver(ctx, "", 12.9716, 77.5946, driver.VehicleEconomy)
+	assert.NoError(t, err)
+	assert.Equal(t, "Asha Rao", candidate.Driver.Name)
+	assert.Equal(t, "+919800000001", candidate.Driver.Phone)
+	assert.Equal(t, 4.6, candidate.Driver.Rating)
+	assert.Equal(t, driver.VehicleEconomy, candidate.Driver.VehicleType)
+}
+
+// TestFindNearestDriver_ReturnsDistance tests that the matched driver's distance is surfaced
+func TestFindNearestDriver_ReturnsDistance(t *testing.T) {
+	service, client := newTestService(t)
+
+	pickupLat, pickupLng := 12.9716, 77.5946
+	nearID := uuid.New().String()
+	farID := uuid.New().String()
+	addTestDriver(t, client, nearID, driver.VehicleEconomy, 12.9800, 77.6000)
+	addTestDriver(t, client, farID, driver.VehicleEconomy, 13.0200, 77.6500)
+
+	candidate, err := service.FindNearestDriver(context.Background(), "", pickupLat, pickupLng, driver.VehicleEconomy)
+	assert.NoError(t, err)
+	assert.Equal(t, nearID, candidate.Driver.ID.String(), "Nearest driver should be matched")
+
+	expected := CalculateDistance(pickupLat, pickupLng, 12.9800, 77.6000)
+	assert.InDelta(t, expected, candidate.Distance, 0.05, "Distance should match the pickup-to-driver distance")
+}
+
+// TestEstimateArrivalMinutes_RoundsUp tests ETA rounding
+func TestEstimateArrivalMinutes_RoundsUp(t *testing.T) {
+	assert.Equal(t, 6, EstimateArrivalMinutes(2.5, 25.0), "2.5km at 25km/h is 6 minutes")
+	assert.Equal(t, 1, EstimateArrivalMinutes(0.1, 25.0), "Partial minutes round up")
+	assert.Equal(t, 0, EstimateArrivalMinutes(0, 25.0), "Driver at pickup arrives immediately")
+}
+
+// TestFindNearestDriver_SkipsOtherVehicleTypes tests that a premium request skips closer economy drivers
+func TestFindNearestDriver_SkipsOtherVehicleTypes(t *testing.T) {
+	service, client := newTestService(t)
+
+	economyID := uuid.New().String()
+	premiumID := uuid.New().String()
+	addTestDriver(t, client, economyID, driver.VehicleEconomy, 12.9720, 77.5950)
+	addTestDriver(t, client, premiumID, driver.VehiclePremium, 12.9900, 77.6100)
+
+	candidate, err := service.FindNearestDriver(context.Background(), "", 12.9716, 77.5946, driver.VehiclePremium)
+	assert.NoError(t, err)
+	assert.Equal(t, premiumID, candidate.Driver.ID.String(), "Premium request should skip the closer economy driver")
+	assert.Equal(t, driver.VehiclePremium, candidate.Driver.VehicleType)
+
+	// The economy driver must not have been claimed
+	available, err := client.SIsMember(context.Background(), "drivers:available", economyID).Result()
+	assert.NoError(t, err)
+	assert.True(t, available, "Skipped driver should remain available")
+}
+
+// TestFindNearestDriver_ExpiredContext tests that an expired context yields ErrMatchingTimeout
+func TestFindNearestDriver_ExpiredContext(t *testing.T) {
+	service, client := newTestService(t)
+
+	driverID := uuid.New().String()
+	addTestDriver(t, client, driverID, driver.VehicleEconomy, 12.9800, 77.6000)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	candidate, err := service.FindNearestDriver(ctx, "", 12.9716, 77.5946, driver.VehicleEconomy)
+	assert.Nil(t, candidate)
+	assert.ErrorIs(t, err, ErrMatchingTimeout)
+	assert.NotErrorIs(t, err, driver.ErrDriverNotAvailable)
+
+	// No claim should have happened
+	available, err := client.SIsMember(context.Background(), "drivers:available", driverID).Result()
+	assert.NoError(t, err)
+	assert.True(t, available)
+}
+
+// TestFindNearestDriverExcluding_SkipsRejectedDrivers tests that excluded drivers are never offered
+func TestFindNearestDriverExcluding_SkipsRejectedDrivers(t *testing.T) {
+	service, client := newTestService(t)
+
+	rejectedID := uuid.New().String()
+	nextID := uuid.New().String()
+	addTestDriver(t, client, rejectedID, driver.VehicleEconomy, 12.9720, 77.5950)
+	addTestDriver(t, client, nextID, driver.VehicleEconomy, 12.9900, 77.6100)
+
+	candidate, err := service.FindNearestDriverExcluding(context.Background(), "", 12.9716, 77.5946, driver.VehicleEconomy,
+		map[string]bool{rejectedID: true})
+	assert.NoError(t, err)
+	assert.Equal(t, nextID, candidate.Driver.ID.String())
+}
+
+// TestFindNearestDriverForSeats_UpgradesVehicleType tests that a seat request too large for
+// economy is matched to a luxury driver rather than a closer economy or premium one
+func TestFindNearestDriverForSeats_UpgradesVehicleType(t *testing.T) {
+	service, client := newTestService(t)
+
+	economyID := uuid.New().String()
+	premiumID := uuid.New().String()
+	luxuryID := uuid.New().String()
+	addTestDriver(t, client, economyID, driver.VehicleEconomy, 12.9720, 77.5950)
+	addTestDriver(t, client, premiumID, driver.VehiclePremium, 12.9730, 77.5960)
+	addTestDriver(t, client, luxuryID, driver.VehicleLuxury, 12.9900, 77.6100)
+
+	candidate, err := service.FindNearestDriverForSeats(context.Background(), "", 12.9716, 77.5946, driver.VehicleEconomy, 6, false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, luxuryID, candidate.Driver.ID.String())
+	assert.GreaterOrEqual(t, candidate.Driver.VehicleType.Seats(), 6)
+}
+
+// TestFindNearestDriverForSeats_PrefersRequestedType tests that the requested vehicle type wins
+// over a closer larger one when it has enough seats
+func TestFindNearestDriverForSeats_PrefersRequestedType(t *testing.T) {
+	service, client := newTestService(t)
+
+	luxuryID := uuid.New().String()
+	economyID := uuid.New().String()
+	addTestDriver(t, client, luxuryID, driver.VehicleLuxury, 12.9720, 77.5950)
+	addTestDriver(t, client, economyID, driver.VehicleEconomy, 12.9900, 77.6100)
+
+	candidate, err := service.FindNearestDriverForSeats(context.Background(), "", 12.9716, 77.5946, driver.VehicleEconomy, 2, false, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, economyID, candidate.Driver.ID.String())
+}
+
+// TestFindNearestDriverForSeats_AllowUpgrade tests that a larger vehicle type is only offered
+// when the rider opted in, and only after the requested type comes up empty at every radius
+func TestFindNearestDriverForSeats_AllowUpgrade(t *testing.T) {
+	service, client := newTestService(t)
+
+	premiumID := uuid.New().String()
+	addTestDriver(t, client, premiumID, driver.VehiclePremium, 12.9720, 77.5950)
+
+	candidate, err := service.FindNearestDriverForSeats(context.Background(), "", 12.9716, 77.5946, driver.VehicleEconomy, 1, false, nil)
+	assert.Nil(t, candidate)
+	assert.ErrorIs(t, err, driver.ErrDriverNotAvailable)
+
+	// A distant economy driver still wins over the nearby premium one
+	economyID := uuid.New().String()
+	addTestDriver(t, client, economyID, driver.VehicleEconomy, 13.0500, 77.5946)
+
+	candidate, err = service.FindNearestDriverForSeats(context.Background(), "", 12.9716, 77.5946, driver.VehicleEconomy, 1, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, economyID, candidate.Driver.ID.String())
+
+	candidate, err = service.FindNearestDriverForSeats(context.Background(), "", 12.9716, 77.5946, driver.VehicleEconomy, 1, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, premiumID, candidate.Driver.ID.String())
+	assert.Equal(t, driver.VehiclePremium, candidate.Driver.VehicleType)
+}
+
+// TestFindNearestDriverForSeats_TooManySeats tests that no driver is claimed when no vehicle fits
+func TestFindNearestDriverForSeats_TooManySeats(t *testing.T) {
+	service, client := newTestService(t)
+
+	luxuryID := uuid.New().String()
+	addTestDriver(t, client, luxuryID, driver.VehicleLuxury, 12.9720, 77.5950)
+
+	candidate, err := service.FindNearestDriverForSeats(context.Background(), "", 12.9716, 77.5946, driver.VehicleEconomy, driver.MaxSeats()+1, false, nil)
+	assert.Nil(t, candidate)
+	assert.ErrorIs(t, err, driver.ErrDriverNotAvailable)
+
+	available, err := client.SIsMember(context.Background(), "drivers:available", luxuryID).Result()
+	assert.NoError(t, err)
+	assert.True(t, available)
+}
+
+// fakeRides reports active rides for a fixed set of drivers
+type fakeRides struct {
+	ride.Repository
+	active map[uuid.UUID]*ride.Ride
+}
+
+func (f *fakeRides) GetActiveRideByDriver(ctx context.Context, driverID uuid.UUID) (*ride.Ride, error) {
+	if r, ok := f.active[driverID]; ok {
+		return r, nil
+	}
+	return nil, ride.ErrRideNotFound
+}
+
+// TestFindNearestDriver_SkipsDriverWithActiveRideInDB tests that Redis/DB drift can't double-book a driver
+func TestFindNearestDriver_SkipsDriverWithActiveRideInDB(t *testing.T) {
+	service, client := newTestService(t)
+
+	busyID := uuid.New()
+	freeID := uuid.New().String()
+	addTestDriver(t, client, busyID.String(), driver.VehicleEconomy, 12.9720, 77.5950)
+	addTestDriver(t, client, freeID, driver.VehicleEconomy, 12.9900, 77.6100)
+
+	// current_ride key is missing for busyID but Postgres still has its ride
+	service.rides = &fakeRides{active: map[uuid.UUID]*ride.Ride{
+		busyID: {ID: "ride-123", Status: ride.StatusStarted},
+	}}
+
+	candidate, err := service.FindNearestDriver(context.Background(), "", 12.9716, 77.5946, driver.VehicleEconomy)
+	assert.NoError(t, err)
+	assert.Equal(t, freeID, candidate.Driver.ID.String())
+
+	// The drifted key is repaired with the real ride
+	currentRide, err := client.Get(context.Background(), "driver:"+busyID.String()+":current_ride").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, "ride-123", currentRide)
+}
+
+// TestFindNearbyDrivers_ReadOnly tests that busy and other-type drivers are filtered and nobody is claimed
+func TestFindNearbyDrivers_ReadOnly(t *testing.T) {
+	service, client := newTestService(t)
+	ctx := context.Background()
+
+	nearID, busyID, premiumID, farID := uuid.New().String(), uuid.New().String(), uuid.New().String(), uuid.New().String()
+	addTestDriver(t, client, nearID, driver.VehicleEconomy, 12.9720, 77.5950)
+	addTestDriver(t, client, busyID, driver.VehicleEconomy, 12.9718, 77.5948)
+	addTestDriver(t, client, premiumID, driver.VehiclePremium, 12.9730, 77.5960)
+	addTestDriver(t, client, farID, driver.VehicleEconomy, 13.2000, 77.9000)
+	assert.NoError(t, client.Set(ctx, fmt.Sprintf("driver:%s:current_ride", busyID), "ride-1", 0).Err())
+
+	drivers, err := service.FindNearbyDrivers(ctx, 12.9716, 77.5946, 5.0, driver.VehicleEconomy, 10)
+	assert.NoError(t, err)
+	assert.Len(t, drivers, 1)
+	assert.Equal(t, nearID, drivers[0].ID)
+	assert.Greater(t, drivers[0].Distance, 0.0)
+
+	all, err := service.FindNearbyDrivers(ctx, 12.9716, 77.5946, 5.0, "", 10)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2, "Every vehicle type is returned when no type is given")
+
+	claimed, err := client.SIsMember(ctx, "drivers:available", nearID).Result()
+	assert.NoError(t, err)
+	assert.True(t, claimed, "Nearby search must not claim drivers")
+}
+
+// TestFindNearestDriver_PrefersFavoriteWithinBand tests that a rider's favorite driver beats a
+// marginally closer stranger but not one who is clearly nearer
+func TestFindNearestDriver_PrefersFavoriteWithinBand(t *testing.T) {
+	service, client := newTestService(t)
+	service.config.FavoriteBandKM = 0.5
+	ctx := context.Background()
+
+	riderID := uuid.New().String()
+	strangerID, favoriteID := uuid.New().String(), uuid.New().String()
+	addTestDriver(t, client, strangerID, driver.VehicleEconomy, 12.9740, 77.5946) // ~0.27 km
+	addTestDriver(t, client, favoriteID, driver.VehicleEconomy, 12.9770, 77.5946) // ~0.6 km
+	assert.NoError(t, client.SAdd(ctx, FavoriteDriversKey(riderID), favoriteID).Err())
+
+	candidate, err := service.FindNearestDriver(ctx, riderID, 12.9716, 77.5946, driver.VehicleEconomy)
+	assert.NoError(t, err)
+	assert.Equal(t, favoriteID, candidate.Driver.ID.String(), "Favorite within the band should win")
+
+	// With that favorite now claimed, a favorite far outside the band loses to the stranger
+	farFavoriteID := uuid.New().String()
+	addTestDriver(t, client, farFavoriteID, driver.VehicleEconomy, 12.9900, 77.5946) // ~2 km
+	assert.NoError(t, client.SAdd(ctx, FavoriteDriversKey(riderID), farFavoriteID).Err())
+
+	candidate, err = service.FindNearestDriver(ctx, riderID, 12.9716, 77.5946, driver.VehicleEconomy)
+	assert.NoError(t, err)
+	assert.Equal(t, strangerID, candidate.Driver.ID.String(), "A clearly nearer stranger should win")
+}
+
+// fakeFavorites serves favorites as if from Postgres and counts lookups
+type fakeFavorites struct {
+	ids   []uuid.UUID
+	calls int
+}
+
+func (f *fakeFavorites) ListFavoriteDriverIDs(ctx context.Context, riderID uuid.UUID) ([]uuid.UUID, error) {
+	f.calls++
+	return f.ids, nil
+}
+
+// TestFindNearestDriver_LoadsFavoritesWhenNotCached tests that favorites missing from Redis
+// are loaded from Postgres once, and that a set started by a later add is reloaded in full
+func TestFindNearestDriver_LoadsFavoritesWhenNotCached(t *testing.T) {
+	service, client := newTestService(t)
+	service.config.FavoriteBandKM = 0.5
+	favoriteID := uuid.New()
+	store := &fakeFavorites{ids: []uuid.UUID{favoriteID}}
+	service.favorites = store
+	ctx := context.Background()
+
+	riderID := uuid.New().String()
+	strangerID := uuid.New().String()
+	addTestDriver(t, client, strangerID, driver.VehicleEconomy, 12.9740, 77.5946)            // ~0.27 km
+	addTestDriver(t, client, favoriteID.String(), driver.VehicleEconomy, 12.9770, 77.5946) // ~0.6 km
+
+	// Only an add made after the cache was lost is in Redis
+	assert.NoError(t, client.SAdd(ctx, FavoriteDriversKey(riderID), uuid.New().String()).Err())
+
+	candidate, err := service.FindNearestDriver(ctx, riderID, 12.9716, 77.5946, driver.VehicleEconomy)
+	assert.NoError(t, err)
+	assert.Equal(t, favoriteID.String(), candidate.Driver.ID.String(), "Favorite stored only in Postgres should win")
+
+	cached, err := client.SMembers(ctx, FavoriteDriversKey(riderID)).Result()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{favoritesLoadedMarker, favoriteID.String()}, cached)
+
+	service.favoriteDrivers(ctx, riderID)
+	assert.Equal(t, 1, store.calls, "A loaded set is served from Redis")
+}
+
+// TestFindNearestDriver_PrefersHigherRatingWithinBucket tests that a better-rated driver beats a
+// marginally closer one within RatingBucketKM but not one clearly nearer
+func TestFindNearestDriver_PrefersHigherRatingWithinBucket(t *testing.T) {
+	service, client := newTestService(t)
+	service.config.RatingBucketKM = 0.5
+	ctx := context.Background()
+
+	closerID, ratedID := uuid.New().String(), uuid.New().String()
+	addTestDriver(t, client, closerID, driver.VehicleEconomy, 12.9730, 77.5946) // ~0.16 km
+	addTestDriver(t, client, ratedID, driver.VehicleEconomy, 12.9750, 77.5946)  // ~0.38 km
+	assert.NoError(t, client.HSet(ctx, DriverMetaKey(closerID), "rating", 4.2).Err())
+	assert.NoError(t, client.HSet(ctx, DriverMetaKey(ratedID), "rating", 4.9).Err())
+
+	candidate, err := service.FindNearestDriver(ctx, "", 12.9716, 77.5946, driver.VehicleEconomy)
+	assert.NoError(t, err)
+	assert.Equal(t, ratedID, candidate.Driver.ID.String(), "Higher rating should win within a bucket")
+	assert.Equal(t, 4.9, candidate.Driver.Rating)
+
+	// A better-rated driver further than RatingBucketKM out loses to the nearer one
+	farID := uuid.New().String()
+	addTestDriver(t, client, farID, driver.VehicleEconomy, 12.9800, 77.5946) // ~0.93 km
+	assert.NoError(t, client.HSet(ctx, DriverMetaKey(farID), "rating", 5.0).Err())
+
+	candidate, err = service.FindNearestDriver(ctx, "", 12.9716, 77.5946, driver.VehicleEconomy)
+	assert.NoError(t, err)
+	assert.Equal(t, closerID, candidate.Driver.ID.String(), "A clearly nearer driver should win over rating")
+}
+
+// TestRankByRating_ComparesAcrossBandEdges tests that drivers just either side of a multiple
+// of RatingBucketKM still compete on rating, while one beyond the nearest's window does not
+func TestRankByRating_ComparesAcrossBandEdges(t *testing.T) {
+	service, _ := newTestService(t)
+	service.config.RatingBucketKM = 0.5
+
+	results := []redis.GeoLocation{
+		{Name: "near", Dist: 0.49},
+		{Name: "rated", Dist: 0.51},
+		{Name: "far", Dist: 1.2},
+		{Name: "farther", Dist: 1.3},
+	}
+	ratings := map[string]float64{"near": 4.2, "rated": 4.9, "far": 4.5, "farther": 5.0}
+	service.rankByRating(results, ratings)
+
+	var order []string
+	for _, r := range results {
+		order = append(order, r.Name)
+	}
+	assert.Equal(t, []string{"rated", "near", "farther", "far"}, order)
+}
+
+// TestMaxSearchRadiusKM tests that the widest radius is capped by MaxExpandedRadius
+func TestMaxSearchRadiusKM(t *testing.T) {
+	service, _ := newTestService(t)
+	assert.Equal(t, 50.0, service.MaxSearchRadiusKM())
+
+	capped := NewService(nil, nil, nil, nil, Config{MaxRadiusKM: 5.0, MaxExpandedRadius: 25.0})
+	assert.Equal(t, 20.0, capped.MaxSearchRadiusKM())
+}
+
+// TestDefaultSearchRadii tests the default 1x/2x/4x/10x expansion and its cap
+func TestDefaultSearchRadii(t *testing.T) {
+	assert.Equal(t, []float64{5, 10, 20, 50}, DefaultSearchRadii(5, 50))
+	assert.Equal(t, []float64{2, 4, 8}, DefaultSearchRadii(2, 10))
+	assert.Equal(t, []float64{10, 20, 40}, DefaultSearchRadii(10, 0), "No cap configured means 50km")
+}
+
+// TestFindNearestDriver_UsesConfiguredSearchRadii tests that a search stops at the last
+// configured radius rather than the default schedule
+func TestFindNearestDriver_UsesConfiguredSearchRadii(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	assert.NoError(t, err)
+
+	service := NewService(client, nil, nil, log, Config{
+		MaxRadiusKM:       5.0,
+		MaxExpandedRadius: 50.0,
+		MaxCandidates:     10,
+		SearchRadiiKM:     []float64{1, 3},
+	})
+
+	driverID := uuid.New().String()
+	addTestDriver(t, client, driverID, driver.VehicleEconomy, 13.0100, 77.5946) // ~4.3 km north
+
+	candidate, err := service.FindNearestDriver(context.Background(), "", 12.9716, 77.5946, driver.VehicleEconomy)
+	assert.Nil(t, candidate)
+	assert.ErrorIs(t, err, driver.ErrDriverNotAvailable)
+	assert.Equal(t, 3.0, service.MaxSearchRadiusKM())
+}
+
+// TestFindNearestDriver_FetchesMoreWhenBatchUnclaimable tests that a first batch of busy
+// drivers falls through to the full candidate list in the same radius
+func TestFindNearestDriver_FetchesMoreWhenBatchUnclaimable(t *testing.T) {
+	service, client := newTestService(t)
+	service.config.InitialBatchSize = 2
+	service.config.SearchRadiiKM = []float64{5.0} // no wider radius to fall back on
+	assert.True(t, service.BatchesCandidates())
+
+	ctx := context.Background()
+	for _, lat := range []float64{12.9720, 12.9725} {
+		busyID := uuid.New().String()
+		addTestDriver(t, client, busyID, driver.VehicleEconomy, lat, 77.5946)
+		assert.NoError(t, client.Set(ctx, fmt.Sprintf("driver:%s:current_ride", busyID), "ride-1", 0).Err())
+	}
+	freeID := uuid.New().String()
+	addTestDriver(t, client, freeID, driver.VehicleEconomy, 12.9760, 77.5946)
+
+	candidate, err := service.FindNearestDriver(ctx, "", 12.9716, 77.5946, driver.VehicleEconomy)
+	assert.NoError(t, err)
+	assert.Equal(t, freeID, candidate.Driver.ID.String())
+}
+
+// TestBatchesCandidates tests that batching only applies when the batch is smaller than MaxCandidates
+func TestBatchesCandidates(t *testing.T) {
+	assert.False(t, NewService(nil, nil, nil, nil, Config{MaxCandidates: 10}).BatchesCandidates())
+	assert.False(t, NewService(nil, nil, nil, nil, Config{MaxCandidates: 10, InitialBatchSize: 10}).BatchesCandidates())
+	assert.True(t, NewService(nil, nil, nil, nil, Config{MaxCandidates: 10, InitialBatchSize: 5}).BatchesCandidates())
+	assert.True(t, NewService(nil, nil, nil, nil, Config{InitialBatchSize: 5}).BatchesCandidates(), "No MaxCandidates means unlimited")
+}
@@ -0,0 +1,170 @@
+package matching
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// poolTTL bounds how long a driver's pool occupancy outlives a trip that never ends
+const poolTTL = 24 * time.Hour
+
+// joinPoolScript adds a ride to a driver's pool only while the driver is already carrying a
+// pool passenger and has room for another, so two requests can't both take the last seat.
+// KEYS: pool. ARGV: ride ID, dropoff, capacity, ttl (seconds).
+var joinPoolScript = redis.NewScript(`
+local aboard = redis.call('HLEN', KEYS[1])
+if aboard == 0 or aboard >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+return 1
+`)
+
+// PoolKey returns the Redis hash of pool rides a driver is carrying, keyed by ride ID with
+// each ride's dropoff stored as "lat,lng"
+func PoolKey(driverID string) string {
+	return fmt.Sprintf("driver:%s:pool", driverID)
+}
+
+// JoinPool records a pool ride against its driver so later pool requests can share the car
+func JoinPool(ctx context.Context, client *redis.Client, driverID, rideID string, dropoffLat, dropoffLng float64) error {
+	pipe := client.TxPipeline()
+	pipe.HSet(ctx, PoolKey(driverID), rideID, formatDropoff(dropoffLat, dropoffLng))
+	pipe.Expire(ctx, PoolKey(driverID), poolTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to join pool: %w", err)
+	}
+	return nil
+}
+
+// LeavePool removes a ride from its driver's pool and returns the pool rides still aboard.
+// left reports whether the ride was in the driver's pool at all.
+func LeavePool(ctx context.Context, client *redis.Client, driverID, rideID string) (remaining []string, left bool, err error) {
+	pipe := client.TxPipeline()
+	removed := pipe.HDel(ctx, PoolKey(driverID), rideID)
+	aboard := pipe.HKeys(ctx, PoolKey(driverID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to leave pool: %w", err)
+	}
+	return aboard.Val(), removed.Val() > 0, nil
+}
+
+// FindPoolDriver looks for a nearby driver already carrying pool passengers who can take this
+// ride too: same vehicle type, below PoolCapacity, and every dropoff aboard within
+// PoolMaxDetourKM of this ride's dropoff. The ride joins the driver's pool as part of the
+// claim. Returns driver.ErrDriverNotAvailable when no pool has room.
+func (s *Service) FindPoolDriver(ctx context.Context, rideID string, pickupLat, pickupLng, dropoffLat, dropoffLng float64, vehicleType driver.VehicleType, excluded map[string]bool) (*DriverCandidate, error) {
+	if s.config.PoolCapacity < 2 {
+		return nil, driver.ErrDriverNotAvailable
+	}
+
+	key := VehicleLocationsKey(vehicleType)
+	seg := redisSegment(ctx, "GEORADIUS", key)
+	results, err := s.redis.GeoRadius(ctx, key, pickupLng, pickupLat, &redis.GeoRadiusQuery{
+		Radius:    s.config.MaxRadiusKM,
+		Unit:      "km",
+		WithCoord: true,
+		WithDist:  true,
+		Count:     s.config.MaxCandidates,
+		Sort:      "ASC",
+	}).Result()
+	seg.End()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search nearby drivers: %w", err)
+	}
+
+	for _, result := range results {
+		driverID := result.Name
+		if excluded[driverID] {
+			continue
+		}
+
+		seg = redisSegment(ctx, "HGETALL", "driver:pool")
+		aboard, err := s.redis.HGetAll(ctx, PoolKey(driverID)).Result()
+		seg.End()
+		if err != nil || len(aboard) == 0 {
+			continue
+		}
+		if len(aboard) >= s.config.PoolCapacity {
+			s.logger.Debug("Driver skipped - pool at capacity",
+				logger.String("driver_id", driverID),
+				logger.Int("pool_riders", len(aboard)),
+			)
+			continue
+		}
+		if !s.withinDetour(aboard, dropoffLat, dropoffLng) {
+			s.logger.Debug("Driver skipped - pool dropoffs too far apart",
+				logger.String("driver_id", driverID),
+			)
+			continue
+		}
+
+		driverUUID, err := uuid.Parse(driverID)
+		if err != nil {
+			continue
+		}
+
+		seg = redisSegment(ctx, "EVALSHA", "driver:pool")
+		joined, err := joinPoolScript.Run(ctx, s.redis, []string{PoolKey(driverID)},
+			rideID, formatDropoff(dropoffLat, dropoffLng), s.config.PoolCapacity, int(poolTTL.Seconds())).Int()
+		seg.End()
+		if err != nil {
+			s.logger.Warn("Failed to join driver pool", logger.String("driver_id", driverID), logger.Err(err))
+			continue
+		}
+		if joined == 0 {
+			// The pool filled up or emptied since it was read
+			continue
+		}
+
+		s.logger.Info("Driver matched for pool ride",
+			logger.String("driver_id", driverID),
+			logger.Int("pool_riders", len(aboard)+1),
+			logger.Float64("distance_km", result.Dist),
+		)
+
+		return &DriverCandidate{
+			Driver:   s.loadDriver(ctx, driverUUID, driver.StatusBusy, result),
+			Distance: result.Dist,
+		}, nil
+	}
+
+	return nil, driver.ErrDriverNotAvailable
+}
+
+// withinDetour reports whether every dropoff already in a pool is close enough to the new one
+// that the driver can serve both without a long detour
+func (s *Service) withinDetour(aboard map[string]string, dropoffLat, dropoffLng float64) bool {
+	for _, dropoff := range aboard {
+		lat, lng, ok := parseDropoff(dropoff)
+		if !ok || CalculateDistance(lat, lng, dropoffLat, dropoffLng) > s.config.PoolMaxDetourKM {
+			return false
+		}
+	}
+	return true
+}
+
+// formatDropoff encodes a dropoff for the pool hash
+func formatDropoff(lat, lng float64) string {
+	return strconv.FormatFloat(lat, 'f', -1, 64) + "," + strconv.FormatFloat(lng, 'f', -1, 64)
+}
+
+// parseDropoff decodes a dropoff written by formatDropoff
+func parseDropoff(value string) (lat, lng float64, ok bool) {
+	latStr, lngStr, found := strings.Cut(value, ",")
+	if !found {
+		return 0, 0, false
+	}
+	lat, latErr := strconv.ParseFloat(latStr, 64)
+	lng, lngErr := strconv.ParseFloat(lngStr, 64)
+	return lat, lng, latErr == nil && lngErr == nil
+}
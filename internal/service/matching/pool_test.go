@@ -0,0 +1,104 @@
+package matching
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPoolTestService creates a matching service that pools up to two riders per driver
+func newPoolTestService(t *testing.T) (*Service, *redis.Client) {
+	service, client := newTestService(t)
+	service.config.PoolCapacity = 2
+	service.config.PoolMaxDetourKM = 2.0
+	return service, client
+}
+
+// addPoolDriver places a driver in the geo index who is busy carrying the given pool rides
+func addPoolDriver(t *testing.T, client *redis.Client, id string, lat, lng float64, rides map[string][2]float64) {
+	ctx := context.Background()
+	addTestDriver(t, client, id, driver.VehicleEconomy, lat, lng)
+	require.NoError(t, client.SRem(ctx, "drivers:available", id).Err())
+	for rideID, dropoff := range rides {
+		require.NoError(t, client.Set(ctx, "driver:"+id+":current_ride", rideID, 0).Err())
+		require.NoError(t, JoinPool(ctx, client, id, rideID, dropoff[0], dropoff[1]))
+	}
+}
+
+// TestFindPoolDriver_JoinsCompatiblePool tests that a pool request shares a driver whose
+// rider is heading close by, and that the ride joins the driver's pool
+func TestFindPoolDriver_JoinsCompatiblePool(t *testing.T) {
+	service, client := newPoolTestService(t)
+	ctx := context.Background()
+
+	driverID := uuid.New().String()
+	addPoolDriver(t, client, driverID, 12.9800, 77.6000, map[string][2]float64{"ride-1": {12.9352, 77.6245}})
+
+	candidate, err := service.FindPoolDriver(ctx, "ride-2", 12.9716, 77.5946, 12.9400, 77.6200, driver.VehicleEconomy, nil)
+	require.NoError(t, err)
+	assert.Equal(t, driverID, candidate.Driver.ID.String())
+
+	aboard, err := client.HKeys(ctx, PoolKey(driverID)).Result()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"ride-1", "ride-2"}, aboard)
+}
+
+// TestFindPoolDriver_SkipsFullAndDistantPools tests that drivers at pool capacity, or whose
+// riders are heading too far from this dropoff, are not offered
+func TestFindPoolDriver_SkipsFullAndDistantPools(t *testing.T) {
+	service, client := newPoolTestService(t)
+	ctx := context.Background()
+
+	fullID := uuid.New().String()
+	addPoolDriver(t, client, fullID, 12.9800, 77.6000, map[string][2]float64{
+		"ride-1": {12.9352, 77.6245},
+		"ride-2": {12.9360, 77.6250},
+	})
+	distantID := uuid.New().String()
+	addPoolDriver(t, client, distantID, 12.9810, 77.6010, map[string][2]float64{"ride-3": {13.1986, 77.7066}})
+
+	_, err := service.FindPoolDriver(ctx, "ride-4", 12.9716, 77.5946, 12.9400, 77.6200, driver.VehicleEconomy, nil)
+	assert.ErrorIs(t, err, driver.ErrDriverNotAvailable)
+
+	full, err := client.HLen(ctx, PoolKey(fullID)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), full)
+}
+
+// TestFindPoolDriver_SkipsDriversWithoutPool tests that a driver on a regular ride is never
+// offered a pool rider
+func TestFindPoolDriver_SkipsDriversWithoutPool(t *testing.T) {
+	service, client := newPoolTestService(t)
+	ctx := context.Background()
+
+	driverID := uuid.New().String()
+	addTestDriver(t, client, driverID, driver.VehicleEconomy, 12.9800, 77.6000)
+	require.NoError(t, client.Set(ctx, "driver:"+driverID+":current_ride", "ride-1", 0).Err())
+
+	_, err := service.FindPoolDriver(ctx, "ride-2", 12.9716, 77.5946, 12.9400, 77.6200, driver.VehicleEconomy, nil)
+	assert.ErrorIs(t, err, driver.ErrDriverNotAvailable)
+}
+
+// TestLeavePool_ReportsRemainingRides tests that leaving a pool returns the riders still aboard
+func TestLeavePool_ReportsRemainingRides(t *testing.T) {
+	_, client := newPoolTestService(t)
+	ctx := context.Background()
+
+	require.NoError(t, JoinPool(ctx, client, "driver-1", "ride-1", 12.9352, 77.6245))
+	require.NoError(t, JoinPool(ctx, client, "driver-1", "ride-2", 12.9400, 77.6200))
+
+	remaining, left, err := LeavePool(ctx, client, "driver-1", "ride-1")
+	require.NoError(t, err)
+	assert.True(t, left)
+	assert.Equal(t, []string{"ride-2"}, remaining)
+
+	remaining, left, err = LeavePool(ctx, client, "driver-1", "ride-9")
+	require.NoError(t, err)
+	assert.False(t, left, "a ride outside the pool was never part of it")
+	assert.Equal(t, []string{"ride-2"}, remaining)
+}
@@ -0,0 +1,138 @@
+package matching
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// ReconcileRecorder receives the number of drivers returned to the available pool per pass
+type ReconcileRecorder interface {
+	RecordClaimsReconciled(count int)
+}
+
+// ClaimReconciler returns drivers to drivers:available when a matching claim was
+// abandoned, e.g. the API crashed between SREM and assignment. The claiming marker
+// expires on its own, but nothing re-adds the driver to the set.
+type ClaimReconciler struct {
+	db       *sql.DB
+	redis    *redis.Client
+	logger   *logger.Logger
+	metrics  ReconcileRecorder
+	interval time.Duration
+}
+
+// NewClaimReconciler creates a new claim reconciler; metrics may be nil
+func NewClaimReconciler(db *sql.DB, redis *redis.Client, logger *logger.Logger, metrics ReconcileRecorder, interval time.Duration) *ClaimReconciler {
+	return &ClaimReconciler{
+		db:       db,
+		redis:    redis,
+		logger:   logger,
+		metrics:  metrics,
+		interval: interval,
+	}
+}
+
+// Run reconciles on every tick until the context is cancelled
+func (r *ClaimReconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.logger.Info("Claim reconciler started", logger.Duration("interval", r.interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Claim reconciler stopped")
+			return
+		case <-ticker.C:
+			if _, err := r.Reconcile(ctx); err != nil {
+				r.logger.Error("Failed to reconcile driver claims", logger.Err(err))
+			}
+		}
+	}
+}
+
+// Reconcile re-adds online drivers that have no active ride, are missing from the
+// available set and hold no current_ride key, and returns how many were restored.
+// Drivers with a live claiming marker are mid-match and left alone, and drivers missing
+// from the geo index stopped reporting and stay out until their next location update.
+func (r *ClaimReconciler) Reconcile(ctx context.Context) (int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT d.id
+		FROM drivers d
+		WHERE d.status = 'online'
+		  AND d.deleted_at IS NULL
+		  AND NOT EXISTS (
+		      SELECT 1 FROM rides ri
+		      WHERE ri.driver_id = d.id
+		        AND ri.status IN ('requested', 'assigned', 'accepted', 'started')
+		  )
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query idle online drivers: %w", err)
+	}
+	defer rows.Close()
+
+	var driverIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("failed to scan driver: %w", err)
+		}
+		driverIDs = append(driverIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read drivers: %w", err)
+	}
+	if len(driverIDs) == 0 {
+		return 0, nil
+	}
+
+	// Check set membership, claims and geo index entries in one round trip
+	pipe := r.redis.Pipeline()
+	available := make([]*redis.BoolCmd, len(driverIDs))
+	claims := make([]*redis.IntCmd, len(driverIDs))
+	located := make([]*redis.FloatCmd, len(driverIDs))
+	for i, id := range driverIDs {
+		available[i] = pipe.SIsMember(ctx, "drivers:available", id)
+		claims[i] = pipe.Exists(ctx, fmt.Sprintf("driver:%s:current_ride", id))
+		located[i] = pipe.ZScore(ctx, LocationsKey, id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, fmt.Errorf("failed to load driver availability: %w", err)
+	}
+
+	restored := 0
+	for i, id := range driverIDs {
+		if available[i].Val() || claims[i].Val() > 0 {
+			continue
+		}
+		// The stale sweeper dropped this driver; restoring it would undo the sweep
+		if located[i].Err() != nil {
+			continue
+		}
+
+		added, err := r.redis.SAdd(ctx, "drivers:available", id).Result()
+		if err != nil {
+			r.logger.Warn("Failed to restore driver availability", logger.String("driver_id", id), logger.Err(err))
+			continue
+		}
+		if added == 0 {
+			continue
+		}
+
+		restored++
+		r.logger.Warn("Reconciled orphaned driver claim", logger.String("driver_id", id))
+	}
+
+	if r.metrics != nil {
+		r.metrics.RecordClaimsReconciled(restored)
+	}
+	return restored, nil
+}
@@ -0,0 +1,59 @@
+package matching
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedReconciles struct {
+	counts []int
+}
+
+func (r *recordedReconciles) RecordClaimsReconciled(count int) {
+	r.counts = append(r.counts, count)
+}
+
+// TestClaimReconciler_RestoresOrphanedDrivers tests that only located drivers with no set
+// membership and no claim are returned to the pool
+func TestClaimReconciler_RestoresOrphanedDrivers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	const orphaned, available, claiming, swept = "driver-orphaned", "driver-available", "driver-claiming", "driver-swept"
+	for _, id := range []string{orphaned, available, claiming} {
+		client.GeoAdd(ctx, LocationsKey, &redis.GeoLocation{Name: id, Longitude: 77.59, Latitude: 12.97})
+	}
+	client.SAdd(ctx, "drivers:available", available)
+	client.Set(ctx, "driver:"+claiming+":current_ride", ClaimingMarker, 30*time.Second)
+
+	mock.ExpectQuery("SELECT d.id\\s+FROM drivers d").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(orphaned).AddRow(available).AddRow(claiming).AddRow(swept))
+
+	metrics := &recordedReconciles{}
+	restored, err := NewClaimReconciler(db, client, log, metrics, time.Minute).Reconcile(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored)
+	assert.Equal(t, []int{1}, metrics.counts)
+
+	members, err := client.SMembers(ctx, "drivers:available").Result()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{orphaned, available}, members)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
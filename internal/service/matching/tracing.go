@@ -0,0 +1,20 @@
+package matching
+
+import (
+	"context"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// redisSegment starts a New Relic datastore segment for one Redis call on the transaction
+// carried by ctx. Without a transaction (New Relic disabled, background work) the segment
+// is a no-op, so callers can always End it. collection names the key family rather than
+// the full key so per-driver keys don't fan out into separate metrics.
+func redisSegment(ctx context.Context, operation, collection string) *newrelic.DatastoreSegment {
+	return &newrelic.DatastoreSegment{
+		StartTime:  newrelic.FromContext(ctx).StartSegmentNow(),
+		Product:    newrelic.DatastoreRedis,
+		Collection: collection,
+		Operation:  operation,
+	}
+}
@@ -2,9 +2,10 @@ package pricing
 
 import (
 	"context"
-	"fmt"
+	"time"
 
 	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -19,8 +20,21 @@ type Config struct {
 	BaseFare map[driver.VehicleType]float64
 	PerKMRate map[driver.VehicleType]float64
 	PerMinuteRate map[driver.VehicleType]float64
+	// PerWaitMinuteRate is charged while the driver waits at pickup; falls back to PerMinuteRate
+	PerWaitMinuteRate map[driver.VehicleType]float64
+	// MinimumFare is the floor for a trip's total, applied after surge
+	MinimumFare map[driver.VehicleType]float64
 	MaxSurgeMultiplier float64
 	MinSurgeMultiplier float64
+	// SurgeOverrideTTL is how long a manual surge override holds off automatic recomputes
+	SurgeOverrideTTL time.Duration
+	// RegionPrecision is the geohash length of a surge region; DefaultRegionPrecision when unset
+	RegionPrecision int
+	// CancellationFee is charged when a rider cancels an accepted ride after CancellationGracePeriod
+	CancellationFee float64
+	CancellationGracePeriod time.Duration
+	// PoolDiscount is the fraction taken off a shared ride's fare, e.g. 0.25 for 25% off
+	PoolDiscount float64
 }
 
 // FareBreakdown represents the breakdown of a fare
@@ -28,8 +42,10 @@ type FareBreakdown struct {
 	BaseFare        float64 `json:"base_fare"`
 	DistanceFare    float64 `json:"distance_fare"`
 	TimeFare        float64 `json:"time_fare"`
+	WaitingFare     float64 `json:"waiting_fare"`
 	SurgeMultiplier float64 `json:"surge_multiplier"`
 	Subtotal        float64 `json:"subtotal"`
+	PoolDiscount    float64 `json:"pool_discount,omitempty"`
 	Total           float64 `json:"total"`
 }
 
@@ -41,29 +57,45 @@ func NewService(redis *redis.Client, config Config) *Service {
 	}
 }
 
-// CalculateFare calculates the total fare for a trip
-func (s *Service) CalculateFare(ctx context.Context, vehicleType driver.VehicleType, distanceKM float64, durationMinutes int, region string) (*FareBreakdown, error) {
+// CalculateFare calculates the total fare for a trip, including time the driver spent waiting at pickup
+func (s *Service) CalculateFare(ctx context.Context, vehicleType driver.VehicleType, distanceKM float64, durationMinutes, waitingMinutes int, region string) (*FareBreakdown, error) {
+	// Get surge multiplier
+	surgeMultiplier := s.GetSurgeMultiplier(ctx, region)
+
+	return s.CalculateFareWithSurge(vehicleType, distanceKM, durationMinutes, waitingMinutes, surgeMultiplier), nil
+}
+
+// CalculateFareWithSurge calculates the total fare using a fixed surge multiplier,
+// e.g. the one quoted to the rider when the ride was requested
+func (s *Service) CalculateFareWithSurge(vehicleType driver.VehicleType, distanceKM float64, durationMinutes, waitingMinutes int, surgeMultiplier float64) *FareBreakdown {
 	baseFare := s.config.BaseFare[vehicleType]
 	perKM := s.config.PerKMRate[vehicleType]
 	perMinute := s.config.PerMinuteRate[vehicleType]
 
 	distanceFare := distanceKM * perKM
 	timeFare := float64(durationMinutes) * perMinute
-	subtotal := baseFare + distanceFare + timeFare
-
-	// Get surge multiplier
-	surgeMultiplier := s.GetSurgeMultiplier(ctx, region)
+	waitingFare := float64(waitingMinutes) * s.perWaitMinuteRate(vehicleType)
+	subtotal := baseFare + distanceFare + timeFare + waitingFare
 
-	total := subtotal * surgeMultiplier
+	total := s.applyMinimumFare(vehicleType, subtotal*surgeMultiplier)
 
 	return &FareBreakdown{
 		BaseFare:        baseFare,
 		DistanceFare:    distanceFare,
 		TimeFare:        timeFare,
+		WaitingFare:     waitingFare,
 		SurgeMultiplier: surgeMultiplier,
 		Subtotal:        subtotal,
 		Total:           total,
-	}, nil
+	}
+}
+
+// ApplyPoolDiscount takes the pool discount off a shared ride's fare. It comes after the
+// minimum fare so a shared ride is always cheaper than riding alone.
+func (s *Service) ApplyPoolDiscount(fare *FareBreakdown) *FareBreakdown {
+	fare.PoolDiscount = fare.Total * s.config.PoolDiscount
+	fare.Total -= fare.PoolDiscount
+	return fare
 }
 
 // EstimateFare estimates fare before trip starts
@@ -72,13 +104,41 @@ func (s *Service) EstimateFare(vehicleType driver.VehicleType, distanceKM float6
 	perKM := s.config.PerKMRate[vehicleType]
 	perMinute := s.config.PerMinuteRate[vehicleType]
 
-	return baseFare + (distanceKM * perKM) + (float64(estimatedMinutes) * perMinute)
+	return s.applyMinimumFare(vehicleType, baseFare+(distanceKM*perKM)+(float64(estimatedMinutes)*perMinute))
+}
+
+// perWaitMinuteRate returns the waiting charge per minute, defaulting to the per-minute rate
+func (s *Service) perWaitMinuteRate(vehicleType driver.VehicleType) float64 {
+	if rate, ok := s.config.PerWaitMinuteRate[vehicleType]; ok {
+		return rate
+	}
+	return s.config.PerMinuteRate[vehicleType]
+}
+
+// applyMinimumFare raises total to the vehicle type's minimum fare, if one is configured
+func (s *Service) applyMinimumFare(vehicleType driver.VehicleType, total float64) float64 {
+	if minimum := s.config.MinimumFare[vehicleType]; total < minimum {
+		return minimum
+	}
+	return total
+}
+
+// CalculateCancellationFee returns the fee for cancelling rd elapsedSinceAssign after a
+// driver was assigned. Cancelling is free until the driver has accepted and the grace
+// period has passed.
+func (s *Service) CalculateCancellationFee(ctx context.Context, rd *ride.Ride, elapsedSinceAssign time.Duration) float64 {
+	if rd.Status != ride.StatusAccepted || rd.AcceptedAt == nil {
+		return 0
+	}
+	if elapsedSinceAssign <= s.config.CancellationGracePeriod {
+		return 0
+	}
+	return s.config.CancellationFee
 }
 
 // GetSurgeMultiplier gets the current surge multiplier for a region
 func (s *Service) GetSurgeMultiplier(ctx context.Context, region string) float64 {
-	key := fmt.Sprintf("surge:%s", region)
-	val, err := s.redis.Get(ctx, key).Float64()
+	val, err := s.redis.Get(ctx, surgeKey(region)).Float64()
 	if err != nil {
 		return 1.0 // Default no surge
 	}
@@ -93,17 +153,9 @@ func (s *Service) GetSurgeMultiplier(ctx context.Context, region string) float64
 	return val
 }
 
-// SetSurgeMultiplier sets the surge multiplier for a region
+// SetSurgeMultiplier sets a manual surge override for a region that holds for SurgeOverrideTTL
 func (s *Service) SetSurgeMultiplier(ctx context.Context, region string, multiplier float64) error {
-	if multiplier > s.config.MaxSurgeMultiplier {
-		multiplier = s.config.MaxSurgeMultiplier
-	}
-	if multiplier < s.config.MinSurgeMultiplier {
-		multiplier = s.config.MinSurgeMultiplier
-	}
-
-	key := fmt.Sprintf("surge:%s", region)
-	return s.redis.Set(ctx, key, multiplier, 0).Err()
+	return s.SetSurgeOverride(ctx, region, multiplier, s.config.SurgeOverrideTTL)
 }
 
 // CalculateSurgeBasedOnDemand calculates surge based on demand/supply ratio
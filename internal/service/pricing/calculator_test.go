@@ -1,9 +1,12 @@
 package pricing
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/gocomet/ride-hailing/internal/domain/driver"
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,8 +28,16 @@ func getTestConfig() Config {
 			driver.VehiclePremium: 3.0,
 			driver.VehicleLuxury:  5.0,
 		},
-		MaxSurgeMultiplier: 3.0,
-		MinSurgeMultiplier: 1.0,
+		MinimumFare: map[driver.VehicleType]float64{
+			driver.VehicleEconomy: 60.0,
+			driver.VehiclePremium: 120.0,
+			driver.VehicleLuxury:  240.0,
+		},
+		MaxSurgeMultiplier:      3.0,
+		MinSurgeMultiplier:      1.0,
+		CancellationFee:         40.0,
+		CancellationGracePeriod: 2 * time.Minute,
+		PoolDiscount:            0.25,
 	}
 }
 
@@ -76,10 +87,24 @@ func TestEstimateFare_BaseCalculation(t *testing.T) {
 func TestEstimateFare_MinimumFare(t *testing.T) {
 	service := &Service{config: getTestConfig()}
 
-	// Very short trip - should still have base fare
+	// Very short trip: 50 base + 0.5km*10 + 2min*2 = 59, below the 60 floor
 	fare := service.EstimateFare(driver.VehicleEconomy, 0.5, 2)
 
-	assert.GreaterOrEqual(t, fare, 50.0, "Fare should be at least the base fare")
+	assert.Equal(t, 60.0, fare, "Fare should be raised to the configured minimum")
+}
+
+// TestCalculateFareWithSurge_MinimumFareAfterSurge tests that the floor applies to the surged total
+func TestCalculateFareWithSurge_MinimumFareAfterSurge(t *testing.T) {
+	service := &Service{config: getTestConfig()}
+
+	// 100 base + 0.2km*15 + 1min*3 = 106, surged to 116.6, still below the 120 floor
+	fare := service.CalculateFareWithSurge(driver.VehiclePremium, 0.2, 1, 0, 1.1)
+	assert.Equal(t, 120.0, fare.Total)
+	assert.InDelta(t, 106.0, fare.Subtotal, 0.001)
+
+	// Surge that lifts the total past the floor is charged in full
+	fare = service.CalculateFareWithSurge(driver.VehiclePremium, 0.2, 1, 0, 2.0)
+	assert.InDelta(t, 212.0, fare.Total, 0.001)
 }
 
 // TestEstimateFare_ZeroDistance tests edge case of zero distance
@@ -107,6 +132,51 @@ func TestEstimateFare_DifferentVehicleTypes(t *testing.T) {
 	assert.Less(t, premiumFare, luxuryFare, "Premium should be cheaper than Luxury")
 }
 
+// TestCalculateFareWithSurge tests that the given surge multiplies the subtotal
+func TestCalculateFareWithSurge(t *testing.T) {
+	service := &Service{config: getTestConfig()}
+
+	fare := service.CalculateFareWithSurge(driver.VehicleEconomy, 10.0, 20, 0, 1.5)
+
+	// 50 base + 10km*10 + 20min*2 = 190, surged to 285
+	assert.Equal(t, 190.0, fare.Subtotal)
+	assert.Equal(t, 1.5, fare.SurgeMultiplier)
+	assert.InDelta(t, 285.0, fare.Total, 0.001)
+}
+
+// TestCalculateFareWithSurge_WaitingFare tests that waiting time is a separate, surged line item
+func TestCalculateFareWithSurge_WaitingFare(t *testing.T) {
+	cfg := getTestConfig()
+	service := &Service{config: cfg}
+
+	// Without a waiting rate the per-minute rate applies: 5min*2 = 10
+	fare := service.CalculateFareWithSurge(driver.VehicleEconomy, 10.0, 20, 5, 1.5)
+	assert.Equal(t, 10.0, fare.WaitingFare)
+	assert.Equal(t, 200.0, fare.Subtotal)
+	assert.InDelta(t, 300.0, fare.Total, 0.001)
+
+	cfg.PerWaitMinuteRate = map[driver.VehicleType]float64{driver.VehicleEconomy: 1.0}
+	service = &Service{config: cfg}
+
+	fare = service.CalculateFareWithSurge(driver.VehicleEconomy, 10.0, 20, 5, 1.0)
+	assert.Equal(t, 5.0, fare.WaitingFare)
+	assert.Equal(t, 195.0, fare.Total)
+}
+
+// TestApplyPoolDiscount tests that the discount comes off the surged total, below the minimum fare
+func TestApplyPoolDiscount(t *testing.T) {
+	service := &Service{config: getTestConfig()}
+
+	// 190 surged to 285, less 25%
+	fare := service.ApplyPoolDiscount(service.CalculateFareWithSurge(driver.VehicleEconomy, 10.0, 20, 0, 1.5))
+	assert.InDelta(t, 71.25, fare.PoolDiscount, 0.001)
+	assert.InDelta(t, 213.75, fare.Total, 0.001)
+
+	// A fare held at the 60 floor is still discounted
+	fare = service.ApplyPoolDiscount(service.CalculateFareWithSurge(driver.VehicleEconomy, 0.5, 2, 0, 1.0))
+	assert.InDelta(t, 45.0, fare.Total, 0.001)
+}
+
 // TestSurgeCalculation_DemandSupplyRatio tests surge calculation
 func TestSurgeCalculation_DemandSupplyRatio(t *testing.T) {
 	service := &Service{config: getTestConfig()}
@@ -168,6 +238,31 @@ func TestSurgeCalculation_NoDrivers(t *testing.T) {
 	assert.Equal(t, 3.0, surge, "Surge should be max when no drivers")
 }
 
+// TestCalculateCancellationFee tests that only late cancellations of accepted rides are charged
+func TestCalculateCancellationFee(t *testing.T) {
+	service := &Service{config: getTestConfig()}
+	acceptedAt := time.Now()
+
+	tests := []struct {
+		name     string
+		ride     *ride.Ride
+		elapsed  time.Duration
+		expected float64
+	}{
+		{"assigned but not accepted", &ride.Ride{Status: ride.StatusAssigned}, 10 * time.Minute, 0},
+		{"accepted within grace period", &ride.Ride{Status: ride.StatusAccepted, AcceptedAt: &acceptedAt}, 90 * time.Second, 0},
+		{"accepted after grace period", &ride.Ride{Status: ride.StatusAccepted, AcceptedAt: &acceptedAt}, 3 * time.Minute, 40.0},
+		{"still searching", &ride.Ride{Status: ride.StatusRequested}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee := service.CalculateCancellationFee(context.Background(), tt.ride, tt.elapsed)
+			assert.Equal(t, tt.expected, fee)
+		})
+	}
+}
+
 // BenchmarkEstimateFare benchmarks fare calculation
 func BenchmarkEstimateFare(b *testing.B) {
 	service := &Service{config: getTestConfig()}
@@ -177,3 +272,23 @@ func BenchmarkEstimateFare(b *testing.B) {
 		service.EstimateFare(driver.VehicleEconomy, 10.0, 20)
 	}
 }
+
+// TestEncodeGeohash_KnownValues tests geohash encoding against reference values
+func TestEncodeGeohash_KnownValues(t *testing.T) {
+	assert.Equal(t, "tdr1v", EncodeGeohash(12.9716, 77.5946, 5), "Bangalore should encode to tdr1v")
+	assert.Equal(t, "u4pruydqqvj", EncodeGeohash(57.64911, 10.40744, 11), "Reference point from the geohash spec")
+	assert.Equal(t, "", EncodeGeohash(12.9716, 77.5946, 0))
+}
+
+// TestRegionForCoordinates_BucketsNearbyPoints tests that nearby pickups share a region
+func TestRegionForCoordinates_BucketsNearbyPoints(t *testing.T) {
+	service := &Service{config: getTestConfig()}
+	a := service.RegionForCoordinates(12.9716, 77.5946)
+	b := service.RegionForCoordinates(12.9720, 77.5950)
+	far := service.RegionForCoordinates(19.0760, 72.8777)
+
+	assert.Len(t, a, DefaultRegionPrecision)
+
+	assert.Equal(t, a, b, "Points a few hundred meters apart should share a region")
+	assert.NotEqual(t, a, far, "Different cities should be in different regions")
+}
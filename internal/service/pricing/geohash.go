@@ -0,0 +1,81 @@
+package pricing
+
+import "strings"
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// DefaultRegionPrecision is the geohash length used for surge regions when Config leaves
+// RegionPrecision unset (5 chars ~ 5km cells)
+const DefaultRegionPrecision = 5
+
+// RegionPrecision returns the geohash length of the service's surge regions
+func (s *Service) RegionPrecision() int {
+	if s.config.RegionPrecision > 0 {
+		return s.config.RegionPrecision
+	}
+	return DefaultRegionPrecision
+}
+
+// RegionForCoordinates buckets coordinates into the surge region they belong to
+func (s *Service) RegionForCoordinates(lat, lng float64) string {
+	return EncodeGeohash(lat, lng, s.RegionPrecision())
+}
+
+// ValidRegion reports whether region is a geohash of the configured region precision
+func (s *Service) ValidRegion(region string) bool {
+	if len(region) != s.RegionPrecision() {
+		return false
+	}
+	for i := 0; i < len(region); i++ {
+		if strings.IndexByte(geohashBase32, region[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodeGeohash encodes coordinates into a geohash string of the given precision
+// Nearby points share a common prefix, so a short geohash works as a region bucket
+func EncodeGeohash(lat, lng float64, precision int) string {
+	if precision <= 0 {
+		return ""
+	}
+
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+
+	hash := make([]byte, 0, precision)
+	bit, ch := 0, 0
+	evenBit := true
+
+	for len(hash) < precision {
+		if evenBit {
+			mid := (lngRange[0] + lngRange[1]) / 2
+			if lng >= mid {
+				ch = ch<<1 | 1
+				lngRange[0] = mid
+			} else {
+				ch = ch << 1
+				lngRange[1] = mid
+			}
+		} else {
+			mid := (latRange[0] + latRange[1]) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				latRange[0] = mid
+			} else {
+				ch = ch << 1
+				latRange[1] = mid
+			}
+		}
+		evenBit = !evenBit
+
+		bit++
+		if bit == 5 {
+			hash = append(hash, geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+
+	return string(hash)
+}
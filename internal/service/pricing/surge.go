@@ -0,0 +1,135 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// setSurgeIfNewerScript writes a computed multiplier only when no manual override is active
+// and computedAt is newer than the stored one, so a slow recompute can't clobber fresher data.
+// KEYS: value, computed_at, override. ARGV: multiplier, computedAt (unix ms).
+var setSurgeIfNewerScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[3]) == 1 then
+	return 0
+end
+local current = tonumber(redis.call('GET', KEYS[2]))
+if current and current >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+redis.call('SET', KEYS[2], ARGV[2])
+return 1
+`)
+
+// surgeKey holds the multiplier read by GetSurgeMultiplier
+func surgeKey(region string) string {
+	return fmt.Sprintf("surge:%s", region)
+}
+
+// surgeComputedAtKey holds the unix ms time the stored multiplier was computed
+func surgeComputedAtKey(region string) string {
+	return fmt.Sprintf("surge:%s:computed_at", region)
+}
+
+// surgeOverrideKey is set while a manual override is in force
+func surgeOverrideKey(region string) string {
+	return fmt.Sprintf("surge:%s:override", region)
+}
+
+// SurgeRegion is a region's stored surge multiplier and any manual override on it
+type SurgeRegion struct {
+	Region     string  `json:"region"`
+	Multiplier float64 `json:"multiplier"`
+	Override   bool    `json:"override"`
+	// OverrideExpiresIn is the override's remaining lifetime; zero when it never expires
+	OverrideExpiresIn time.Duration `json:"-"`
+}
+
+// SetSurgeOverride sets a manual surge multiplier for a region. It always wins over the
+// automatic value and blocks recomputes for ttl, or indefinitely when ttl is zero.
+func (s *Service) SetSurgeOverride(ctx context.Context, region string, multiplier float64, ttl time.Duration) error {
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, surgeKey(region), s.clampSurge(multiplier), 0)
+	pipe.Set(ctx, surgeComputedAtKey(region), time.Now().UnixMilli(), 0)
+	pipe.Set(ctx, surgeOverrideKey(region), 1, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set surge override: %w", err)
+	}
+	return nil
+}
+
+// ListSurgeMultipliers returns every region with a stored multiplier, sorted by region
+func (s *Service) ListSurgeMultipliers(ctx context.Context) ([]SurgeRegion, error) {
+	var regions []string
+	iter := s.redis.Scan(ctx, 0, surgeKey("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		// Skip the computed_at and override companions of each region
+		region := strings.TrimPrefix(iter.Val(), surgeKey(""))
+		if !strings.Contains(region, ":") {
+			regions = append(regions, region)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan surge regions: %w", err)
+	}
+	sort.Strings(regions)
+
+	pipe := s.redis.Pipeline()
+	values := make([]*redis.StringCmd, len(regions))
+	ttls := make([]*redis.DurationCmd, len(regions))
+	for i, region := range regions {
+		values[i] = pipe.Get(ctx, surgeKey(region))
+		ttls[i] = pipe.PTTL(ctx, surgeOverrideKey(region))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to load surge multipliers: %w", err)
+	}
+
+	result := make([]SurgeRegion, 0, len(regions))
+	for i, region := range regions {
+		multiplier, err := values[i].Float64()
+		if err != nil {
+			continue
+		}
+
+		entry := SurgeRegion{Region: region, Multiplier: s.clampSurge(multiplier)}
+		// PTTL is -2 for a missing key and -1 for one without expiry
+		switch ttl := ttls[i].Val(); {
+		case ttl > 0:
+			entry.Override = true
+			entry.OverrideExpiresIn = ttl
+		case ttl == -1:
+			entry.Override = true
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// SetSurgeMultiplierIfNewer stores an automatically computed multiplier unless a newer value
+// or a manual override is already in place, and reports whether it was written
+func (s *Service) SetSurgeMultiplierIfNewer(ctx context.Context, region string, multiplier float64, computedAt time.Time) (bool, error) {
+	keys := []string{surgeKey(region), surgeComputedAtKey(region), surgeOverrideKey(region)}
+	written, err := setSurgeIfNewerScript.Run(ctx, s.redis, keys, s.clampSurge(multiplier), computedAt.UnixMilli()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to set surge multiplier: %w", err)
+	}
+	return written == 1, nil
+}
+
+// clampSurge limits multiplier to the configured surge range
+func (s *Service) clampSurge(multiplier float64) float64 {
+	if multiplier > s.config.MaxSurgeMultiplier {
+		return s.config.MaxSurgeMultiplier
+	}
+	if multiplier < s.config.MinSurgeMultiplier {
+		return s.config.MinSurgeMultiplier
+	}
+	return multiplier
+}
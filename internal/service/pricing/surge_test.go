@@ -0,0 +1,49 @@
+package pricing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSetSurgeMultiplierIfNewer tests that stale computations and active overrides don't
+// overwrite the stored surge
+func TestSetSurgeMultiplierIfNewer(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	cfg := getTestConfig()
+	cfg.SurgeOverrideTTL = time.Minute
+	service := NewService(client, cfg)
+	ctx := context.Background()
+	now := time.Now()
+
+	written, err := service.SetSurgeMultiplierIfNewer(ctx, "tdr1v", 1.5, now)
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	// A recompute that started earlier finishes late and is ignored
+	written, err = service.SetSurgeMultiplierIfNewer(ctx, "tdr1v", 2.5, now.Add(-time.Second))
+	require.NoError(t, err)
+	assert.False(t, written)
+	assert.Equal(t, 1.5, service.GetSurgeMultiplier(ctx, "tdr1v"))
+
+	// A manual override wins until its TTL lapses
+	require.NoError(t, service.SetSurgeMultiplier(ctx, "tdr1v", 2.0))
+	written, err = service.SetSurgeMultiplierIfNewer(ctx, "tdr1v", 1.0, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.False(t, written)
+	assert.Equal(t, 2.0, service.GetSurgeMultiplier(ctx, "tdr1v"))
+
+	mr.FastForward(2 * time.Minute)
+	written, err = service.SetSurgeMultiplierIfNewer(ctx, "tdr1v", 1.0, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, written)
+	assert.Equal(t, 1.0, service.GetSurgeMultiplier(ctx, "tdr1v"))
+}
@@ -0,0 +1,181 @@
+package pricing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// SurgeWorker periodically recomputes surge multipliers from live demand and supply
+type SurgeWorker struct {
+	db       *sql.DB
+	redis    *redis.Client
+	pricing  *Service
+	logger   *logger.Logger
+	interval time.Duration
+
+	notifier        SurgeNotifier
+	notifyThreshold float64
+}
+
+// SurgeNotifier is told about recomputed multipliers that moved far enough to tell riders about
+type SurgeNotifier interface {
+	NotifySurgeChange(region string, previous, multiplier float64)
+}
+
+// regionLoad holds demand and supply counts for a single region
+type regionLoad struct {
+	activeRides      int
+	availableDrivers int
+}
+
+// NewSurgeWorker creates a new surge recomputation worker
+func NewSurgeWorker(db *sql.DB, redis *redis.Client, pricing *Service, logger *logger.Logger, interval time.Duration) *SurgeWorker {
+	return &SurgeWorker{
+		db:       db,
+		redis:    redis,
+		pricing:  pricing,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+// SetNotifier reports recomputes that move a region's multiplier by at least threshold.
+// Call before Run.
+func (w *SurgeWorker) SetNotifier(notifier SurgeNotifier, threshold float64) {
+	w.notifier = notifier
+	w.notifyThreshold = threshold
+}
+
+// Run recomputes surge on every tick until the context is cancelled
+func (w *SurgeWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.logger.Info("Surge worker started", logger.Duration("interval", w.interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Surge worker stopped")
+			return
+		case <-ticker.C:
+			if err := w.recompute(ctx); err != nil {
+				w.logger.Error("Failed to recompute surge", logger.Err(err))
+			}
+		}
+	}
+}
+
+// recompute counts demand and supply per region and writes the resulting multipliers.
+// Regions still holding a multiplier with no demand or supply left are reset to 1.0, so
+// surge doesn't linger after an area goes quiet or its manual override expires.
+func (w *SurgeWorker) recompute(ctx context.Context) error {
+	computedAt := time.Now()
+	loads := make(map[string]*regionLoad)
+	regionFor := func(lat, lng float64) *regionLoad {
+		region := w.pricing.RegionForCoordinates(lat, lng)
+		if loads[region] == nil {
+			loads[region] = &regionLoad{}
+		}
+		return loads[region]
+	}
+
+	// Demand: active rides bucketed by pickup location
+	rows, err := w.db.QueryContext(ctx, `
+		SELECT pickup_latitude, pickup_longitude
+		FROM rides
+		WHERE status IN ('requested', 'assigned', 'accepted', 'started')
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query active rides: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lat, lng float64
+		if err := rows.Scan(&lat, &lng); err != nil {
+			return fmt.Errorf("failed to scan active ride: %w", err)
+		}
+		regionFor(lat, lng).activeRides++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read active rides: %w", err)
+	}
+
+	// Supply: available drivers bucketed by their last known location
+	driverIDs, err := w.redis.SMembers(ctx, "drivers:available").Result()
+	if err != nil {
+		return fmt.Errorf("failed to get available drivers: %w", err)
+	}
+
+	if len(driverIDs) > 0 {
+		positions, err := w.redis.GeoPos(ctx, "drivers:locations", driverIDs...).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get driver positions: %w", err)
+		}
+		for _, pos := range positions {
+			if pos == nil {
+				continue
+			}
+			regionFor(pos.Latitude, pos.Longitude).availableDrivers++
+		}
+	}
+
+	stored, err := w.pricing.ListSurgeMultipliers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, entry := range stored {
+		if loads[entry.Region] == nil && !entry.Override && entry.Multiplier != 1.0 {
+			loads[entry.Region] = &regionLoad{}
+		}
+	}
+
+	for region, load := range loads {
+		multiplier := 1.0
+		if load.activeRides > 0 || load.availableDrivers > 0 {
+			multiplier = w.pricing.CalculateSurgeBasedOnDemand(load.activeRides, load.availableDrivers)
+		}
+		previous := w.pricing.GetSurgeMultiplier(ctx, region)
+		written, err := w.pricing.SetSurgeMultiplierIfNewer(ctx, region, multiplier, computedAt)
+		if err != nil {
+			w.logger.Warn("Failed to set surge multiplier", logger.String("region", region), logger.Err(err))
+			continue
+		}
+		if !written {
+			w.logger.Debug("Surge left unchanged by override or newer value", logger.String("region", region))
+			continue
+		}
+
+		if multiplier > 1.0 {
+			w.logger.Info("Surge updated",
+				logger.String("region", region),
+				logger.Int("active_rides", load.activeRides),
+				logger.Int("available_drivers", load.availableDrivers),
+				logger.Float64("multiplier", multiplier),
+			)
+		}
+		w.notify(region, previous, w.pricing.clampSurge(multiplier))
+	}
+
+	return nil
+}
+
+// notify passes a written multiplier on to the notifier when it moved past the threshold
+func (w *SurgeWorker) notify(region string, previous, multiplier float64) {
+	if w.notifier == nil {
+		return
+	}
+	// Allow for float error so a 1.0 -> 1.2 step meets a 0.2 threshold
+	change := math.Abs(multiplier - previous)
+	if change < 1e-9 || change < w.notifyThreshold-1e-9 {
+		return
+	}
+	w.notifier.NotifySurgeChange(region, previous, multiplier)
+}
@@ -0,0 +1,111 @@
+package pricing
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier collects surge changes passed to it
+type recordingNotifier struct {
+	changes []string
+}
+
+func (n *recordingNotifier) NotifySurgeChange(region string, previous, multiplier float64) {
+	n.changes = append(n.changes, region)
+}
+
+// TestSurgeWorker_NotifiesOnThresholdChange tests that only recomputes moving a region's
+// multiplier by at least the threshold are reported
+func TestSurgeWorker_NotifiesOnThresholdChange(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	require.NoError(t, err)
+
+	service := NewService(client, getTestConfig())
+	worker := NewSurgeWorker(db, client, service, log, time.Minute)
+	notifier := &recordingNotifier{}
+	worker.SetNotifier(notifier, 0.5)
+
+	ctx := context.Background()
+	region := service.RegionForCoordinates(12.97, 77.59)
+	expectActiveRides := func() {
+		mock.ExpectQuery("SELECT pickup_latitude, pickup_longitude").
+			WillReturnRows(sqlmock.NewRows([]string{"pickup_latitude", "pickup_longitude"}).AddRow(12.97, 77.59))
+	}
+
+	// No drivers around: surge jumps from 1.0x to the 3.0x cap
+	expectActiveRides()
+	require.NoError(t, worker.recompute(ctx))
+	assert.Equal(t, []string{region}, notifier.changes)
+
+	// The same demand again leaves the multiplier where it is
+	expectActiveRides()
+	require.NoError(t, worker.recompute(ctx))
+	assert.Len(t, notifier.changes, 1)
+
+	// A small move stays under the threshold
+	require.NoError(t, service.SetSurgeMultiplier(ctx, region, 2.8))
+	mr.Del(surgeOverrideKey(region))
+	expectActiveRides()
+	require.NoError(t, worker.recompute(ctx))
+	assert.Len(t, notifier.changes, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSurgeWorker_ResetsQuietRegions tests that a region whose demand is gone, or whose
+// override expired, drops back to 1.0x while a live override is left alone
+func TestSurgeWorker_ResetsQuietRegions(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	require.NoError(t, err)
+
+	service := NewService(client, getTestConfig())
+	worker := NewSurgeWorker(db, client, service, log, time.Minute)
+
+	ctx := context.Background()
+	quiet := service.RegionForCoordinates(12.97, 77.59)
+	expired := service.RegionForCoordinates(28.61, 77.21)
+	overridden := service.RegionForCoordinates(19.08, 72.88)
+
+	// The quiet region surged on an earlier pass when it still had demand
+	_, err = service.SetSurgeMultiplierIfNewer(ctx, quiet, 3.0, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	require.NoError(t, service.SetSurgeOverride(ctx, expired, 2.0, time.Minute))
+	require.NoError(t, service.SetSurgeOverride(ctx, overridden, 2.0, time.Hour))
+	// FastForward only moves Redis TTLs, so backdate the override to match
+	mr.FastForward(2 * time.Minute)
+	require.NoError(t, mr.Set(surgeComputedAtKey(expired), strconv.FormatInt(time.Now().Add(-2*time.Minute).UnixMilli(), 10)))
+
+	mock.ExpectQuery("SELECT pickup_latitude, pickup_longitude").
+		WillReturnRows(sqlmock.NewRows([]string{"pickup_latitude", "pickup_longitude"}))
+	require.NoError(t, worker.recompute(ctx))
+
+	assert.Equal(t, 1.0, service.GetSurgeMultiplier(ctx, quiet))
+	assert.Equal(t, 1.0, service.GetSurgeMultiplier(ctx, expired))
+	assert.Equal(t, 2.0, service.GetSurgeMultiplier(ctx, overridden))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,91 @@
+package routing
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// ErrInvalidPolyline is returned when a polyline string cannot be decoded
+var ErrInvalidPolyline = errors.New("invalid polyline")
+
+// EncodePolyline encodes points with the Google encoded polyline algorithm (precision 5)
+func EncodePolyline(points []Point) string {
+	var sb strings.Builder
+	var prevLat, prevLng int64
+
+	for _, p := range points {
+		lat := int64(math.Round(p.Latitude * 1e5))
+		lng := int64(math.Round(p.Longitude * 1e5))
+		encodeValue(&sb, lat-prevLat)
+		encodeValue(&sb, lng-prevLng)
+		prevLat, prevLng = lat, lng
+	}
+
+	return sb.String()
+}
+
+// DecodePolyline decodes a Google encoded polyline (precision 5)
+func DecodePolyline(encoded string) ([]Point, error) {
+	var points []Point
+	var lat, lng int64
+
+	for i := 0; i < len(encoded); {
+		dLat, next, err := decodeValue(encoded, i)
+		if err != nil {
+			return nil, err
+		}
+		dLng, next, err := decodeValue(encoded, next)
+		if err != nil {
+			return nil, err
+		}
+		i = next
+
+		lat += dLat
+		lng += dLng
+		points = append(points, Point{Latitude: float64(lat) / 1e5, Longitude: float64(lng) / 1e5})
+	}
+
+	return points, nil
+}
+
+func encodeValue(sb *strings.Builder, v int64) {
+	// Zig-zag so small negative deltas stay short
+	u := v << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		sb.WriteByte(byte((0x20 | (u & 0x1f)) + 63))
+		u >>= 5
+	}
+	sb.WriteByte(byte(u + 63))
+}
+
+func decodeValue(encoded string, i int) (int64, int, error) {
+	var result int64
+	var shift uint
+	for {
+		if i >= len(encoded) {
+			return 0, 0, ErrInvalidPolyline
+		}
+		b := int64(encoded[i]) - 63
+		i++
+		if b < 0 || b > 0x3f {
+			return 0, 0, ErrInvalidPolyline
+		}
+		result |= (b & 0x1f) << shift
+		shift += 5
+		if b < 0x20 {
+			break
+		}
+		if shift > 60 {
+			return 0, 0, ErrInvalidPolyline
+		}
+	}
+
+	if result&1 != 0 {
+		return ^(result >> 1), i, nil
+	}
+	return result >> 1, i, nil
+}
@@ -0,0 +1,88 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gocomet/ride-hailing/internal/service/matching"
+)
+
+// Point is a latitude/longitude pair
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Router estimates the road route between two points. Implementations may call
+// an external routing engine (OSRM, Google) or approximate locally.
+type Router interface {
+	EstimateRoute(ctx context.Context, pickup, dropoff Point) (distanceKM float64, durationMin int, polyline string, err error)
+}
+
+// ErrTooFewStops is returned when a multi-stop route has no dropoff
+var ErrTooFewStops = errors.New("route needs at least two stops")
+
+// EstimateRouteVia estimates a route through stops in order (pickup, waypoints..., dropoff)
+// by summing each leg; the legs' polylines are joined into one
+func EstimateRouteVia(ctx context.Context, r Router, stops []Point) (float64, int, string, error) {
+	if len(stops) < 2 {
+		return 0, 0, "", ErrTooFewStops
+	}
+
+	var distance float64
+	var duration int
+	var path []Point
+	for i := 1; i < len(stops); i++ {
+		legDistance, legDuration, legPolyline, err := r.EstimateRoute(ctx, stops[i-1], stops[i])
+		if err != nil {
+			return 0, 0, "", fmt.Errorf("failed to estimate leg %d: %w", i, err)
+		}
+		leg, err := DecodePolyline(legPolyline)
+		if err != nil {
+			return 0, 0, "", fmt.Errorf("failed to decode leg %d: %w", i, err)
+		}
+
+		// Consecutive legs share a stop; keep it once
+		if len(path) > 0 && len(leg) > 0 {
+			leg = leg[1:]
+		}
+		path = append(path, leg...)
+		distance += legDistance
+		duration += legDuration
+	}
+
+	return distance, duration, EncodePolyline(path), nil
+}
+
+// HaversineRouter approximates road distance as the straight-line distance
+// scaled by a winding factor, driven at a constant average speed
+type HaversineRouter struct {
+	windingFactor float64
+	avgSpeedKMH   float64
+}
+
+var _ Router = (*HaversineRouter)(nil)
+
+// NewHaversineRouter creates a router; a winding factor below 1 is treated as 1
+func NewHaversineRouter(windingFactor, avgSpeedKMH float64) *HaversineRouter {
+	if windingFactor < 1 {
+		windingFactor = 1
+	}
+	return &HaversineRouter{
+		windingFactor: windingFactor,
+		avgSpeedKMH:   avgSpeedKMH,
+	}
+}
+
+// EstimateRoute returns the scaled distance, travel time and a straight-line polyline
+func (r *HaversineRouter) EstimateRoute(ctx context.Context, pickup, dropoff Point) (float64, int, string, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, "", fmt.Errorf("route estimate cancelled: %w", err)
+	}
+
+	distance := matching.CalculateDistance(pickup.Latitude, pickup.Longitude, dropoff.Latitude, dropoff.Longitude) * r.windingFactor
+	duration := matching.EstimateArrivalMinutes(distance, r.avgSpeedKMH)
+
+	return distance, duration, EncodePolyline([]Point{pickup, dropoff}), nil
+}
@@ -0,0 +1,72 @@
+package routing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEncodePolyline_KnownValue tests encoding against Google's reference example
+func TestEncodePolyline_KnownValue(t *testing.T) {
+	points := []Point{
+		{Latitude: 38.5, Longitude: -120.2},
+		{Latitude: 40.7, Longitude: -120.95},
+		{Latitude: 43.252, Longitude: -126.453},
+	}
+
+	assert.Equal(t, "_p~iF~ps|U_ulLnnqC_mqNvxq`@", EncodePolyline(points))
+}
+
+// TestDecodePolyline_RoundTrip tests that decoding reverses encoding
+func TestDecodePolyline_RoundTrip(t *testing.T) {
+	points := []Point{{Latitude: 12.9716, Longitude: 77.5946}, {Latitude: 12.2958, Longitude: 76.6394}}
+
+	decoded, err := DecodePolyline(EncodePolyline(points))
+	assert.NoError(t, err)
+	assert.Equal(t, points, decoded)
+
+	_, err = DecodePolyline("_p~iF~ps|U_")
+	assert.ErrorIs(t, err, ErrInvalidPolyline)
+}
+
+// TestHaversineRouter_AppliesWindingFactor tests that road distance exceeds straight-line distance
+func TestHaversineRouter_AppliesWindingFactor(t *testing.T) {
+	pickup := Point{Latitude: 12.9716, Longitude: 77.5946}
+	dropoff := Point{Latitude: 13.0716, Longitude: 77.5946}
+
+	straight, _, _, err := NewHaversineRouter(1.0, 30).EstimateRoute(context.Background(), pickup, dropoff)
+	assert.NoError(t, err)
+
+	distance, duration, polyline, err := NewHaversineRouter(1.3, 30).EstimateRoute(context.Background(), pickup, dropoff)
+	assert.NoError(t, err)
+	assert.InDelta(t, straight*1.3, distance, 0.001)
+	assert.Equal(t, 29, duration) // ~14.5 km at 30 km/h
+	assert.NotEmpty(t, polyline)
+}
+
+// TestEstimateRouteVia_SumsLegs tests that a multi-stop route sums its legs and joins the polyline
+func TestEstimateRouteVia_SumsLegs(t *testing.T) {
+	router := NewHaversineRouter(1.0, 30)
+	ctx := context.Background()
+	pickup := Point{Latitude: 12.9716, Longitude: 77.5946}
+	stop := Point{Latitude: 13.0716, Longitude: 77.5946}
+	dropoff := Point{Latitude: 13.0716, Longitude: 77.6946}
+
+	first, firstMin, _, err := router.EstimateRoute(ctx, pickup, stop)
+	assert.NoError(t, err)
+	second, secondMin, _, err := router.EstimateRoute(ctx, stop, dropoff)
+	assert.NoError(t, err)
+
+	distance, minutes, polyline, err := EstimateRouteVia(ctx, router, []Point{pickup, stop, dropoff})
+	assert.NoError(t, err)
+	assert.InDelta(t, first+second, distance, 0.0001)
+	assert.Equal(t, firstMin+secondMin, minutes)
+
+	path, err := DecodePolyline(polyline)
+	assert.NoError(t, err)
+	assert.Equal(t, []Point{pickup, stop, dropoff}, path)
+
+	_, _, _, err = EstimateRouteVia(ctx, router, []Point{pickup})
+	assert.ErrorIs(t, err, ErrTooFewStops)
+}
@@ -0,0 +1,77 @@
+package scheduling
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+)
+
+// Dispatcher starts matching for a scheduled ride that has come due
+type Dispatcher interface {
+	DispatchScheduledRide(ctx context.Context, rd *ride.Ride) error
+}
+
+// Scheduler periodically hands scheduled rides to the dispatcher shortly before pickup
+type Scheduler struct {
+	rides      ride.Repository
+	dispatcher Dispatcher
+	logger     *logger.Logger
+	interval   time.Duration
+	leadTime   time.Duration
+}
+
+// NewScheduler creates a scheduler that dispatches rides leadTime before their pickup time
+func NewScheduler(rides ride.Repository, dispatcher Dispatcher, logger *logger.Logger, interval, leadTime time.Duration) *Scheduler {
+	return &Scheduler{
+		rides:      rides,
+		dispatcher: dispatcher,
+		logger:     logger,
+		interval:   interval,
+		leadTime:   leadTime,
+	}
+}
+
+// Run dispatches due rides on every tick until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Ride scheduler started",
+		logger.Duration("interval", s.interval),
+		logger.Duration("lead_time", s.leadTime),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Ride scheduler stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.DispatchDue(ctx); err != nil {
+				s.logger.Error("Failed to dispatch scheduled rides", logger.Err(err))
+			}
+		}
+	}
+}
+
+// DispatchDue dispatches every ride whose pickup falls within the lead time and returns
+// how many were handed off. A failed dispatch is logged and retried on the next tick.
+func (s *Scheduler) DispatchDue(ctx context.Context) (int, error) {
+	due, err := s.rides.ListDueScheduled(ctx, time.Now().Add(s.leadTime))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due rides: %w", err)
+	}
+
+	dispatched := 0
+	for _, rd := range due {
+		if err := s.dispatcher.DispatchScheduledRide(ctx, rd); err != nil {
+			s.logger.Error("Failed to dispatch scheduled ride", logger.Err(err), logger.String("ride_id", rd.ID))
+			continue
+		}
+		dispatched++
+	}
+	return dispatched, nil
+}
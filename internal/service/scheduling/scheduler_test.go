@@ -0,0 +1,54 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gocomet/ride-hailing/internal/domain/ride"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRides returns a fixed list of due rides and records the cutoff it was asked for
+type fakeRides struct {
+	ride.Repository
+	due    []*ride.Ride
+	before time.Time
+}
+
+func (f *fakeRides) ListDueScheduled(ctx context.Context, before time.Time) ([]*ride.Ride, error) {
+	f.before = before
+	return f.due, nil
+}
+
+// fakeDispatcher records dispatched ride IDs and fails for the given ones
+type fakeDispatcher struct {
+	dispatched []string
+	fail       map[string]bool
+}
+
+func (f *fakeDispatcher) DispatchScheduledRide(ctx context.Context, rd *ride.Ride) error {
+	f.dispatched = append(f.dispatched, rd.ID)
+	if f.fail[rd.ID] {
+		return errors.New("matching unavailable")
+	}
+	return nil
+}
+
+// TestScheduler_DispatchDue tests that due rides are dispatched with the lead time applied and failures skipped
+func TestScheduler_DispatchDue(t *testing.T) {
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	assert.NoError(t, err)
+
+	rides := &fakeRides{due: []*ride.Ride{{ID: "ride-1"}, {ID: "ride-2"}, {ID: "ride-3"}}}
+	dispatcher := &fakeDispatcher{fail: map[string]bool{"ride-2": true}}
+	s := NewScheduler(rides, dispatcher, log, time.Minute, 10*time.Minute)
+
+	n, err := s.DispatchDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"ride-1", "ride-2", "ride-3"}, dispatcher.dispatched)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), rides.before, time.Second)
+}
@@ -0,0 +1,3 @@
+-- Drop payment_refunds table
+DROP TABLE IF EXISTS payment_refunds CASCADE;
+ALTER TABLE payments DROP COLUMN IF EXISTS refunded_amount;
@@ -0,0 +1,20 @@
+-- Track how much of each payment has been refunded (supports partial refunds)
+ALTER TABLE payments ADD COLUMN refunded_amount DECIMAL(10, 2) NOT NULL DEFAULT 0.00 CHECK (refunded_amount >= 0);
+
+-- Create payment_refunds table, one row per refund transaction
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    reason TEXT,
+    external_transaction_id VARCHAR(255),
+    idempotency_key VARCHAR(255) UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX idx_payment_refunds_payment_id ON payment_refunds(payment_id);
+
+-- Add comments for documentation
+COMMENT ON TABLE payment_refunds IS 'Refund transactions issued against payments';
+COMMENT ON COLUMN payments.refunded_amount IS 'Total amount refunded so far';
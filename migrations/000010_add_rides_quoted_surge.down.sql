@@ -0,0 +1,2 @@
+-- Drop quoted_surge column
+ALTER TABLE rides DROP COLUMN IF EXISTS quoted_surge;
@@ -0,0 +1,4 @@
+-- Surge multiplier shown to the rider at request time; NULL for rides created before this column existed
+ALTER TABLE rides ADD COLUMN quoted_surge DECIMAL(3, 2) CHECK (quoted_surge >= 1.00 AND quoted_surge <= 5.00);
+
+COMMENT ON COLUMN rides.quoted_surge IS 'Surge multiplier quoted to the rider when the ride was requested';
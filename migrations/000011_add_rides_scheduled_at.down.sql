@@ -0,0 +1,10 @@
+-- Drop scheduled_at and cancel rides still waiting for dispatch
+DROP INDEX IF EXISTS idx_rides_scheduled_at;
+
+UPDATE rides
+SET status = 'cancelled', cancelled_at = NOW(), cancellation_reason = 'Scheduling removed'
+WHERE status = 'scheduled';
+
+ALTER TABLE rides DROP COLUMN IF EXISTS scheduled_at;
+
+-- PostgreSQL cannot drop enum values; 'scheduled' remains in ride_status unused
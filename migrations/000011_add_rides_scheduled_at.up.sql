@@ -0,0 +1,10 @@
+-- Rides booked in advance wait in 'scheduled' until the dispatcher starts matching them
+ALTER TYPE ride_status ADD VALUE IF NOT EXISTS 'scheduled' BEFORE 'requested';
+
+ALTER TABLE rides ADD COLUMN scheduled_at TIMESTAMP WITH TIME ZONE;
+
+-- The dispatcher polls for scheduled rides coming due
+CREATE INDEX idx_rides_scheduled_at ON rides(scheduled_at) WHERE scheduled_at IS NOT NULL;
+
+COMMENT ON COLUMN rides.scheduled_at IS 'Requested pickup time for advance bookings; NULL for immediate rides';
+COMMENT ON COLUMN rides.status IS 'Current ride status: scheduled, requested, assigned, accepted, started, completed, cancelled';
@@ -0,0 +1,2 @@
+-- Drop ride_waypoints table
+DROP TABLE IF EXISTS ride_waypoints;
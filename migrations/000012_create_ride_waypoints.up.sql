@@ -0,0 +1,14 @@
+-- Create ride_waypoints table, one row per intermediate stop between pickup and dropoff
+CREATE TABLE IF NOT EXISTS ride_waypoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ride_id VARCHAR(255) NOT NULL REFERENCES rides(id) ON DELETE CASCADE,
+    sequence INTEGER NOT NULL CHECK (sequence >= 1),
+    latitude DECIMAL(10, 8) NOT NULL,
+    longitude DECIMAL(11, 8) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (ride_id, sequence)
+);
+
+-- Add comments for documentation
+COMMENT ON TABLE ride_waypoints IS 'Ordered intermediate stops of multi-stop rides';
+COMMENT ON COLUMN ride_waypoints.sequence IS 'Stop order, starting at 1 after pickup';
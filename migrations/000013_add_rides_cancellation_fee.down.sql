@@ -0,0 +1,2 @@
+-- Drop cancellation_fee column
+ALTER TABLE rides DROP COLUMN IF EXISTS cancellation_fee;
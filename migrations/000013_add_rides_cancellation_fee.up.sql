@@ -0,0 +1,4 @@
+-- Fee charged when a rider cancels after the driver accepted and the grace window passed
+ALTER TABLE rides ADD COLUMN cancellation_fee DECIMAL(10, 2) CHECK (cancellation_fee >= 0);
+
+COMMENT ON COLUMN rides.cancellation_fee IS 'Fee charged for a late cancellation; NULL when none was charged';
@@ -0,0 +1,3 @@
+-- Drop waiting charge columns
+ALTER TABLE trips DROP COLUMN IF EXISTS waiting_fare;
+ALTER TABLE trips DROP COLUMN IF EXISTS waiting_minutes;
@@ -0,0 +1,5 @@
+-- Time the driver spent waiting at pickup is billed as its own line item
+ALTER TABLE trips ADD COLUMN waiting_minutes INTEGER NOT NULL DEFAULT 0 CHECK (waiting_minutes >= 0);
+ALTER TABLE trips ADD COLUMN waiting_fare DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN trips.waiting_fare IS 'Charge for driver waiting time at pickup, before surge';
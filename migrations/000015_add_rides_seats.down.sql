@@ -0,0 +1,2 @@
+-- Drop seats column
+ALTER TABLE rides DROP COLUMN IF EXISTS seats;
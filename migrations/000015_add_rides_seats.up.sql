@@ -0,0 +1,4 @@
+-- Minimum passenger seats the rider asked for; matching only offers vehicles that carry at least this many
+ALTER TABLE rides ADD COLUMN seats SMALLINT NOT NULL DEFAULT 1 CHECK (seats >= 1);
+
+COMMENT ON COLUMN rides.seats IS 'Minimum seat count requested by the rider';
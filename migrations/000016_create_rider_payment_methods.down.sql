@@ -0,0 +1,3 @@
+-- Drop rider wallets and payment methods
+DROP TABLE IF EXISTS rider_wallets;
+DROP TABLE IF EXISTS rider_payment_methods;
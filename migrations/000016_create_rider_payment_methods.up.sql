@@ -0,0 +1,34 @@
+-- Create rider_payment_methods table, one row per payment method a rider has set up
+CREATE TABLE IF NOT EXISTS rider_payment_methods (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rider_id UUID NOT NULL REFERENCES riders(id) ON DELETE CASCADE,
+    payment_method payment_method NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (rider_id, payment_method)
+);
+
+CREATE TRIGGER update_rider_payment_methods_updated_at BEFORE UPDATE ON rider_payment_methods
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Create rider_wallets table holding each rider's prepaid balance
+CREATE TABLE IF NOT EXISTS rider_wallets (
+    rider_id UUID PRIMARY KEY REFERENCES riders(id) ON DELETE CASCADE,
+    balance DECIMAL(10, 2) NOT NULL DEFAULT 0.00 CHECK (balance >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_rider_wallets_updated_at BEFORE UPDATE ON rider_wallets
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Existing riders could always pay cash; keep that working
+INSERT INTO rider_payment_methods (rider_id, payment_method, is_default)
+SELECT id, 'cash', TRUE FROM riders
+ON CONFLICT (rider_id, payment_method) DO NOTHING;
+
+-- Add comments for documentation
+COMMENT ON TABLE rider_payment_methods IS 'Payment methods each rider has set up; payments must use one of them';
+COMMENT ON COLUMN rider_payment_methods.is_default IS 'Method preselected for the rider''s payments';
+COMMENT ON TABLE rider_wallets IS 'Prepaid rider balances debited by wallet payments';
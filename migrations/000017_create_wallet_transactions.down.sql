@@ -0,0 +1,3 @@
+-- Drop wallet ledger
+DROP TABLE IF EXISTS wallet_transactions;
+DROP TYPE IF EXISTS wallet_transaction_type;
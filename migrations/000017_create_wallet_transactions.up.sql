@@ -0,0 +1,22 @@
+-- Create wallet_transaction_type enum
+CREATE TYPE wallet_transaction_type AS ENUM ('topup', 'payment', 'refund');
+
+-- Create wallet_transactions ledger, one row per change to a rider's wallet balance
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rider_id UUID NOT NULL REFERENCES riders(id) ON DELETE CASCADE,
+    type wallet_transaction_type NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount <> 0),
+    balance_after DECIMAL(10, 2) NOT NULL CHECK (balance_after >= 0),
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    idempotency_key VARCHAR(255) UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX idx_wallet_transactions_rider_created ON wallet_transactions(rider_id, created_at DESC);
+
+-- Add comments for documentation
+COMMENT ON TABLE wallet_transactions IS 'Audit ledger of rider wallet top-ups, payments and refunds';
+COMMENT ON COLUMN wallet_transactions.amount IS 'Signed change to the balance: positive credits, negative debits';
+COMMENT ON COLUMN wallet_transactions.balance_after IS 'Wallet balance immediately after this entry';
@@ -0,0 +1,4 @@
+-- Drop payout tracking
+DROP INDEX IF EXISTS idx_driver_earnings_unsettled;
+ALTER TABLE driver_earnings DROP COLUMN IF EXISTS payout_id;
+DROP TABLE IF EXISTS driver_payouts CASCADE;
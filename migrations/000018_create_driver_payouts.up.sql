@@ -0,0 +1,24 @@
+-- Create driver_payouts table, one settlement per driver per payout period
+CREATE TABLE IF NOT EXISTS driver_payouts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    driver_id UUID NOT NULL REFERENCES drivers(id) ON DELETE CASCADE,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    total_rides INTEGER NOT NULL DEFAULT 0,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (period_start <= period_end),
+    UNIQUE (driver_id, period_start, period_end)
+);
+
+CREATE INDEX idx_driver_payouts_period ON driver_payouts(period_start, period_end);
+
+-- Earnings days are settled by exactly one payout
+ALTER TABLE driver_earnings
+    ADD COLUMN payout_id UUID REFERENCES driver_payouts(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_driver_earnings_unsettled ON driver_earnings(date) WHERE payout_id IS NULL;
+
+-- Add comments for documentation
+COMMENT ON TABLE driver_payouts IS 'Driver settlements covering unsettled earnings in a date range';
+COMMENT ON COLUMN driver_earnings.payout_id IS 'Payout that settled this day; NULL while unsettled';
@@ -0,0 +1,13 @@
+-- Drop soft delete; fails if a deleted user's email or phone has been reused
+DROP INDEX IF EXISTS idx_riders_phone_active;
+DROP INDEX IF EXISTS idx_riders_email_active;
+ALTER TABLE riders ADD CONSTRAINT riders_email_key UNIQUE (email);
+ALTER TABLE riders ADD CONSTRAINT riders_phone_key UNIQUE (phone);
+
+DROP INDEX IF EXISTS idx_drivers_phone_active;
+DROP INDEX IF EXISTS idx_drivers_email_active;
+ALTER TABLE drivers ADD CONSTRAINT drivers_email_key UNIQUE (email);
+ALTER TABLE drivers ADD CONSTRAINT drivers_phone_key UNIQUE (phone);
+
+ALTER TABLE riders DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE drivers DROP COLUMN IF EXISTS deleted_at;
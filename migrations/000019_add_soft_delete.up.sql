@@ -0,0 +1,18 @@
+-- Soft delete drivers and riders so rides, trips and payments keep pointing at real rows
+ALTER TABLE drivers ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE riders ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Only live accounts hold on to an email or phone, so a deleted user can sign up again
+ALTER TABLE drivers DROP CONSTRAINT IF EXISTS drivers_email_key;
+ALTER TABLE drivers DROP CONSTRAINT IF EXISTS drivers_phone_key;
+CREATE UNIQUE INDEX idx_drivers_email_active ON drivers(email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_drivers_phone_active ON drivers(phone) WHERE deleted_at IS NULL;
+
+ALTER TABLE riders DROP CONSTRAINT IF EXISTS riders_email_key;
+ALTER TABLE riders DROP CONSTRAINT IF EXISTS riders_phone_key;
+CREATE UNIQUE INDEX idx_riders_email_active ON riders(email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_riders_phone_active ON riders(phone) WHERE deleted_at IS NULL;
+
+-- Add comments for documentation
+COMMENT ON COLUMN drivers.deleted_at IS 'Set when the driver is deleted; deleted drivers are hidden from every lookup';
+COMMENT ON COLUMN riders.deleted_at IS 'Set when the rider is deleted; deleted riders are hidden from every lookup';
@@ -0,0 +1,2 @@
+-- Drop pool column
+ALTER TABLE rides DROP COLUMN IF EXISTS pool;
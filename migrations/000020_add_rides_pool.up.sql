@@ -0,0 +1,4 @@
+-- Pool rides may share their driver with other pool riders heading the same way
+ALTER TABLE rides ADD COLUMN pool BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN rides.pool IS 'Whether the rider opted into a shared ride at the pool discount';
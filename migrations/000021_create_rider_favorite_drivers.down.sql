@@ -0,0 +1,2 @@
+-- Drop rider favorites
+DROP TABLE IF EXISTS rider_favorite_drivers CASCADE;
@@ -0,0 +1,12 @@
+-- Create rider_favorite_drivers table; matching prefers a rider's favorites over marginally closer drivers
+CREATE TABLE IF NOT EXISTS rider_favorite_drivers (
+    rider_id UUID NOT NULL REFERENCES riders(id) ON DELETE CASCADE,
+    driver_id UUID NOT NULL REFERENCES drivers(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (rider_id, driver_id)
+);
+
+CREATE INDEX idx_rider_favorite_drivers_driver ON rider_favorite_drivers(driver_id);
+
+-- Add comments for documentation
+COMMENT ON TABLE rider_favorite_drivers IS 'Drivers a rider asked to be matched with when they are about as close as anyone else';
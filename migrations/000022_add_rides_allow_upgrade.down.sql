@@ -0,0 +1,2 @@
+-- Drop allow_upgrade column
+ALTER TABLE rides DROP COLUMN IF EXISTS allow_upgrade;
@@ -0,0 +1,4 @@
+-- Riders may accept a larger vehicle at their requested tier's price when none is nearby
+ALTER TABLE rides ADD COLUMN allow_upgrade BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN rides.allow_upgrade IS 'Whether matching may fall back to a larger vehicle type, billed at the requested type';
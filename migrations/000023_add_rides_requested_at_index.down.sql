@@ -0,0 +1,2 @@
+-- Drop requested_at index
+DROP INDEX IF EXISTS idx_rides_requested_at;
@@ -0,0 +1,2 @@
+-- Admin ride search pages through rides newest first, optionally within a requested_at window
+CREATE INDEX IF NOT EXISTS idx_rides_requested_at ON rides(requested_at DESC);
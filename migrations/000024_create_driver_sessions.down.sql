@@ -0,0 +1,2 @@
+-- Drop driver session tracking
+DROP TABLE IF EXISTS driver_sessions CASCADE;
@@ -0,0 +1,23 @@
+-- Create driver_sessions table, one row per stretch a driver spends online
+CREATE TABLE IF NOT EXISTS driver_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    driver_id UUID NOT NULL REFERENCES drivers(id) ON DELETE CASCADE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE,
+    busy_since TIMESTAMP WITH TIME ZONE,
+    online_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    busy_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ended_at IS NULL OR ended_at >= started_at)
+);
+
+-- A driver has at most one open session
+CREATE UNIQUE INDEX idx_driver_sessions_open ON driver_sessions(driver_id) WHERE ended_at IS NULL;
+CREATE INDEX idx_driver_sessions_driver_started ON driver_sessions(driver_id, started_at);
+
+-- Add comments for documentation
+COMMENT ON TABLE driver_sessions IS 'Periods drivers were online, with the time spent on rides for utilization';
+COMMENT ON COLUMN driver_sessions.busy_since IS 'Start of the ride the driver is on now; NULL while free';
+COMMENT ON COLUMN driver_sessions.online_seconds IS 'Session length, settled when the session ends';
+COMMENT ON COLUMN driver_sessions.busy_seconds IS 'Time spent on finished rides during the session';
@@ -0,0 +1,2 @@
+-- Drop the ride event log
+DROP TABLE IF EXISTS ride_events CASCADE;
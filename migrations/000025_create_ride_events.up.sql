@@ -0,0 +1,17 @@
+-- Create ride_events table, an append-only log of each ride's lifecycle for support and disputes
+CREATE TABLE IF NOT EXISTS ride_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    ride_id VARCHAR(255) NOT NULL REFERENCES rides(id) ON DELETE CASCADE,
+    event_type VARCHAR(20) NOT NULL,
+    actor_type VARCHAR(20) NOT NULL,
+    actor_id VARCHAR(255),
+    details JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_ride_events_ride_created ON ride_events(ride_id, created_at);
+
+-- Add comments for documentation
+COMMENT ON TABLE ride_events IS 'Ride lifecycle transitions with when they happened and who caused them';
+COMMENT ON COLUMN ride_events.actor_type IS 'rider, driver, dashboard, admin or system (timeouts and the scheduler)';
+COMMENT ON COLUMN ride_events.details IS 'Event specifics such as the assigned driver or cancellation reason';
@@ -0,0 +1,2 @@
+-- Drop refund status
+ALTER TABLE payment_refunds DROP COLUMN IF EXISTS status;
@@ -0,0 +1,7 @@
+-- Card refunds are recorded as pending before the PSP is called and settled afterwards, so a
+-- replayed or concurrent request with the same idempotency key finds the refund instead of
+-- reaching the PSP again
+ALTER TABLE payment_refunds ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'completed'
+    CHECK (status IN ('pending', 'completed', 'failed'));
+
+COMMENT ON COLUMN payment_refunds.status IS 'pending while the PSP refund is in flight; only completed refunds count toward payments.refunded_amount';
@@ -0,0 +1,4 @@
+-- Restore globally unique ride idempotency keys
+DROP INDEX IF EXISTS idx_rides_rider_idempotency_key;
+ALTER TABLE rides ADD CONSTRAINT rides_idempotency_key_key UNIQUE (idempotency_key);
+CREATE INDEX idx_rides_idempotency_key ON rides(idempotency_key) WHERE idempotency_key IS NOT NULL;
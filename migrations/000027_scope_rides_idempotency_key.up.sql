@@ -0,0 +1,4 @@
+-- Idempotency keys are chosen by clients, so they are only unique per rider
+ALTER TABLE rides DROP CONSTRAINT IF EXISTS rides_idempotency_key_key;
+DROP INDEX IF EXISTS idx_rides_idempotency_key;
+CREATE UNIQUE INDEX idx_rides_rider_idempotency_key ON rides(rider_id, idempotency_key) WHERE idempotency_key IS NOT NULL;
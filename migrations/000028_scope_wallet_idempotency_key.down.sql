@@ -0,0 +1,3 @@
+-- Restore globally unique wallet idempotency keys
+DROP INDEX IF EXISTS idx_wallet_transactions_rider_idempotency_key;
+ALTER TABLE wallet_transactions ADD CONSTRAINT wallet_transactions_idempotency_key_key UNIQUE (idempotency_key);
@@ -0,0 +1,3 @@
+-- Top-up idempotency keys are chosen by clients, so they are only unique per rider
+ALTER TABLE wallet_transactions DROP CONSTRAINT IF EXISTS wallet_transactions_idempotency_key_key;
+CREATE UNIQUE INDEX idx_wallet_transactions_rider_idempotency_key ON wallet_transactions(rider_id, idempotency_key) WHERE idempotency_key IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Restore one payment per trip
+DROP INDEX IF EXISTS idx_payments_trip_id_active;
+ALTER TABLE payments ADD CONSTRAINT payments_trip_id_key UNIQUE (trip_id);
@@ -0,0 +1,4 @@
+-- A failed charge leaves its payment row behind, so a trip is only unique among payments that
+-- haven't failed and the rider can pay again
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_trip_id_key;
+CREATE UNIQUE INDEX idx_payments_trip_id_active ON payments(trip_id) WHERE status <> 'failed';
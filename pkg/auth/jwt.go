@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// User types carried in the role claim
+const (
+	UserTypeRider     = "rider"
+	UserTypeDriver    = "driver"
+	UserTypeDashboard = "dashboard"
+	UserTypeAdmin     = "admin"
+)
+
+// ValidUserType reports whether userType is one of the known user types
+func ValidUserType(userType string) bool {
+	switch userType {
+	case UserTypeRider, UserTypeDriver, UserTypeDashboard, UserTypeAdmin:
+		return true
+	}
+	return false
+}
+
+var (
+	ErrMissingToken = errors.New("missing token")
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// Claims identifies an authenticated user; the user ID is the JWT subject
+type Claims struct {
+	UserType string `json:"user_type"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the authenticated user's ID
+func (c *Claims) UserID() string {
+	return c.Subject
+}
+
+// GenerateToken issues an HS256 token for the given user
+func GenerateToken(secret, userID, userType string, expiry time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserType: userType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, nil
+}
+
+// ParseToken validates an HS256 token and returns its claims
+func ParseToken(secret, tokenString string) (*Claims, error) {
+	if tokenString == "" {
+		return nil, ErrMissingToken
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Subject == "" || claims.UserType == "" {
+		return nil, fmt.Errorf("%w: missing subject or user_type", ErrInvalidToken)
+	}
+	if !ValidUserType(claims.UserType) {
+		return nil, fmt.Errorf("%w: unknown user_type %q", ErrInvalidToken, claims.UserType)
+	}
+	return claims, nil
+}
+
+// TokenFromRequest extracts a bearer token from the Authorization header,
+// falling back to the token query param for clients that cannot set headers
+func TokenFromRequest(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return r.URL.Query().Get("token")
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseToken_RoundTrip tests that a generated token parses back to the same identity
+func TestParseToken_RoundTrip(t *testing.T) {
+	token, err := GenerateToken("secret", "rider-1", UserTypeRider, time.Hour)
+	assert.NoError(t, err)
+
+	claims, err := ParseToken("secret", token)
+	assert.NoError(t, err)
+	assert.Equal(t, "rider-1", claims.UserID())
+	assert.Equal(t, UserTypeRider, claims.UserType)
+}
+
+// TestParseToken_Rejects tests wrong secrets, expired, missing and unknown-role tokens
+func TestParseToken_Rejects(t *testing.T) {
+	token, err := GenerateToken("secret", "rider-1", UserTypeRider, time.Hour)
+	assert.NoError(t, err)
+	_, err = ParseToken("other-secret", token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	expired, err := GenerateToken("secret", "rider-1", UserTypeRider, -time.Minute)
+	assert.NoError(t, err)
+	_, err = ParseToken("secret", expired)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = ParseToken("secret", "")
+	assert.ErrorIs(t, err, ErrMissingToken)
+
+	unknown, err := GenerateToken("secret", "root-1", "root", time.Hour)
+	assert.NoError(t, err)
+	_, err = ParseToken("secret", unknown)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
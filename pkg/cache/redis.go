@@ -2,6 +2,10 @@ package cache
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -19,10 +23,17 @@ type Config struct {
 	MinIdleConn int
 	DialTimeout time.Duration
 	ReadTimeout time.Duration
+	// WriteTimeout defaults to 3s when unset
+	WriteTimeout time.Duration
 }
 
 // NewRedisClient creates a new Redis client
 func NewRedisClient(cfg Config) (*redis.Client, error) {
+	writeTimeout := cfg.WriteTimeout
+	if writeTimeout <= 0 {
+		writeTimeout = 3 * time.Second
+	}
+
 	client := redis.NewClient(&redis.Options{
 		Addr:         fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
 		Password:     cfg.Password,
@@ -32,7 +43,7 @@ func NewRedisClient(cfg Config) (*redis.Client, error) {
 		MinIdleConns: cfg.MinIdleConn,
 		DialTimeout:  cfg.DialTimeout,
 		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: 3 * time.Second,
+		WriteTimeout: writeTimeout,
 		PoolTimeout:  4 * time.Second,
 	})
 
@@ -75,6 +86,30 @@ func SetWithExpiry(ctx context.Context, client *redis.Client, key string, value
 	return client.Set(ctx, key, value, expiry).Err()
 }
 
+// SetJSON stores v under key as JSON with expiration
+func SetJSON(ctx context.Context, client *redis.Client, key string, v interface{}, expiry time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	return client.Set(ctx, key, data, expiry).Err()
+}
+
+// GetJSON decodes the JSON stored under key into v; found is false when the key doesn't exist
+func GetJSON(ctx context.Context, client *redis.Client, key string, v interface{}) (found bool, err error) {
+	data, err := client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode %s: %w", key, err)
+	}
+	return true, nil
+}
+
 // Get retrieves a value by key
 func Get(ctx context.Context, client *redis.Client, key string) (string, error) {
 	return client.Get(ctx, key).Result()
@@ -96,6 +131,41 @@ func SetNX(ctx context.Context, client *redis.Client, key string, value interfac
 	return client.SetNX(ctx, key, value, expiry).Result()
 }
 
+// ErrLockHeld is returned by Lock when another holder already has the lock
+var ErrLockHeld = errors.New("lock is held by another holder")
+
+// unlockScript deletes a lock only while it still holds the caller's token, so a holder whose
+// lock expired can't release one taken since. KEYS: lock. ARGV: token.
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Lock takes a distributed lock on key for at most ttl, returning ErrLockHeld if it is taken.
+// The returned unlock releases it and is safe to call after the lock has expired.
+func Lock(ctx context.Context, client *redis.Client, key string, ttl time.Duration) (unlock func(), err error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	value := hex.EncodeToString(token)
+
+	acquired, err := client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", key, err)
+	}
+	if !acquired {
+		return nil, ErrLockHeld
+	}
+
+	return func() {
+		// Release even if the caller's context was cancelled while holding the lock
+		unlockScript.Run(context.WithoutCancel(ctx), client, []string{key}, value)
+	}, nil
+}
+
 // Incr increments a counter
 func Incr(ctx context.Context, client *redis.Client, key string) (int64, error) {
 	return client.Incr(ctx, key).Result()
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient creates a client backed by an in-memory Redis
+func newTestClient(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, mr
+}
+
+// TestJSON_RoundTrip tests that a value stored with SetJSON decodes with GetJSON and expires
+func TestJSON_RoundTrip(t *testing.T) {
+	client, mr := newTestClient(t)
+	ctx := context.Background()
+
+	type response struct {
+		ID     string  `json:"id"`
+		Amount float64 `json:"amount"`
+	}
+	require.NoError(t, SetJSON(ctx, client, "resp", response{ID: "p-1", Amount: 250}, time.Minute))
+
+	var got response
+	found, err := GetJSON(ctx, client, "resp", &got)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, response{ID: "p-1", Amount: 250}, got)
+
+	mr.FastForward(2 * time.Minute)
+	found, err = GetJSON(ctx, client, "resp", &got)
+	assert.NoError(t, err)
+	assert.False(t, found, "An expired key is a miss, not an error")
+}
+
+// TestGetJSON_CorruptValue tests that a value that isn't valid JSON is reported as an error
+func TestGetJSON_CorruptValue(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+	require.NoError(t, client.Set(ctx, "resp", "not json", 0).Err())
+
+	var got map[string]interface{}
+	found, err := GetJSON(ctx, client, "resp", &got)
+	assert.Error(t, err)
+	assert.False(t, found)
+}
+
+// TestLock_OneHolderAtATime tests that concurrent callers can't share a lock and that it can
+// be taken again once released
+func TestLock_OneHolderAtATime(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var acquired atomic.Int32
+	unlocks := make(chan func(), 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := Lock(ctx, client, "lock", time.Minute)
+			if err == nil {
+				acquired.Add(1)
+				unlocks <- unlock
+				return
+			}
+			assert.ErrorIs(t, err, ErrLockHeld)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), acquired.Load())
+
+	(<-unlocks)()
+	unlock, err := Lock(ctx, client, "lock", time.Minute)
+	require.NoError(t, err, "A released lock can be taken again")
+	unlock()
+}
+
+// TestLock_ExpiredHolderCannotReleaseNewHolder tests that unlocking after expiry leaves a lock
+// taken by someone else in place
+func TestLock_ExpiredHolderCannotReleaseNewHolder(t *testing.T) {
+	client, mr := newTestClient(t)
+	ctx := context.Background()
+
+	staleUnlock, err := Lock(ctx, client, "lock", time.Second)
+	require.NoError(t, err)
+	mr.FastForward(2 * time.Second)
+
+	_, err = Lock(ctx, client, "lock", time.Minute)
+	require.NoError(t, err)
+
+	staleUnlock()
+	_, err = Lock(ctx, client, "lock", time.Minute)
+	assert.ErrorIs(t, err, ErrLockHeld)
+}
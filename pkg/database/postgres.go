@@ -18,6 +18,9 @@ type Config struct {
 	SSLMode  string
 	MaxConns int
 	MaxIdle  int
+
+	// MaxLifetime caps how long a pooled connection is reused; 0 keeps the default
+	MaxLifetime time.Duration
 }
 
 // NewPostgresDB creates a new PostgreSQL database connection pool
@@ -47,7 +50,11 @@ func NewPostgresDB(config Config) (*sql.DB, error) {
 		db.SetMaxIdleConns(5) // Default
 	}
 
-	db.SetConnMaxLifetime(5 * time.Minute)
+	if config.MaxLifetime > 0 {
+		db.SetConnMaxLifetime(config.MaxLifetime)
+	} else {
+		db.SetConnMaxLifetime(5 * time.Minute) // Default
+	}
 	db.SetConnMaxIdleTime(2 * time.Minute)
 
 	// Verify connection
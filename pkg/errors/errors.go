@@ -121,12 +121,30 @@ var (
 	ErrNoDriversAvailable  = NotFound("No drivers available in the area", nil)
 	ErrDriverNotAvailable  = Conflict("Driver is not available", nil)
 	ErrRideAlreadyAssigned = Conflict("Ride is already assigned to a driver", nil)
+	ErrMatchingTimeout     = ServiceUnavailable("Timed out searching for drivers, please retry", nil)
 	ErrTripAlreadyCompleted = Conflict("Trip is already completed", nil)
+	ErrTripAlreadyPaid     = Conflict("Trip has already been paid", nil)
 
-	ErrInvalidStatus       = BadRequest("Invalid status transition", nil)
+	ErrInvalidStatus       = Conflict("Invalid status transition", nil)
 	ErrInvalidCoordinates  = BadRequest("Invalid coordinates", nil)
+	ErrMissingCoordinates  = BadRequest("Latitude and longitude are required", nil)
 	ErrInvalidVehicleType  = BadRequest("Invalid vehicle type", nil)
 	ErrInvalidPaymentMethod = BadRequest("Invalid payment method", nil)
+	ErrInsufficientBalance = &AppError{
+		Code:    "INSUFFICIENT_BALANCE",
+		Message: "Insufficient wallet balance",
+		Status:  http.StatusPaymentRequired,
+	}
+	ErrPaymentFailed = &AppError{
+		Code:    "PAYMENT_FAILED",
+		Message: "Payment could not be processed, please try again",
+		Status:  http.StatusBadGateway,
+	}
+	ErrRefundFailed = &AppError{
+		Code:    "REFUND_FAILED",
+		Message: "Refund could not be processed, please try again",
+		Status:  http.StatusBadGateway,
+	}
 
 	ErrDuplicateRequest    = Conflict("Duplicate request detected", nil)
 	ErrRateLimitExceeded   = &AppError{
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// ctxKey is the context key WithContext stores the logger under
+type ctxKey struct{}
+
+// defaultLogger is returned by FromContext when the context carries no logger
+var defaultLogger atomic.Pointer[Logger]
+
+func init() {
+	defaultLogger.Store(&Logger{zap.NewNop()})
+}
+
+// SetDefault sets the logger FromContext falls back to; startup code sets it to the app logger
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+}
+
+// WithContext returns a copy of ctx that carries l
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger stored by WithContext, or the default logger if there is none
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok && l != nil {
+		return l
+	}
+	return defaultLogger.Load()
+}
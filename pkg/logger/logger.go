@@ -1,10 +1,13 @@
 package logger
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // Logger wraps zap.Logger
@@ -16,7 +19,19 @@ type Logger struct {
 type Config struct {
 	Level  string
 	Format string
+	// Output is stdout, stderr or a file path
 	Output string
+
+	// File rotation; MaxSizeMB 0 appends to the file without rotating
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+
+	// Per second, the first SamplingInitial entries with the same message at info level
+	// or below are logged, then one in every SamplingThereafter. Warnings and errors are
+	// never sampled. SamplingInitial 0 disables sampling.
+	SamplingInitial    int
+	SamplingThereafter int
 }
 
 // New creates a new logger instance
@@ -52,12 +67,23 @@ func New(cfg Config) (*Logger, error) {
 		encoder = zapcore.NewConsoleEncoder(encoderConfig)
 	}
 
+	sink, err := writerForOutput(cfg.Output)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := sink.(*os.File); ok && f != os.Stdout && f != os.Stderr && cfg.MaxSizeMB > 0 {
+		// The path is known to be writable; lumberjack reopens it and rotates by size
+		f.Close()
+		sink = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   cfg.Output,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+		})
+	}
+
 	// Create core
-	core := zapcore.NewCore(
-		encoder,
-		zapcore.AddSync(os.Stdout),
-		level,
-	)
+	core := newCore(encoder, sink, level, cfg)
 
 	// Create logger
 	logger := zap.New(core,
@@ -69,6 +95,47 @@ func New(cfg Config) (*Logger, error) {
 	return &Logger{logger}, nil
 }
 
+// writerForOutput returns stdout, stderr or the file at output opened for appending.
+// Files are opened up front so a bad path fails at startup instead of losing logs.
+func writerForOutput(output string) (zapcore.WriteSyncer, error) {
+	switch output {
+	case "", "stdout":
+		return zapcore.AddSync(os.Stdout), nil
+	case "stderr":
+		return zapcore.AddSync(os.Stderr), nil
+	}
+
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log output %q: %w", output, err)
+	}
+	return zapcore.AddSync(f), nil
+}
+
+// newCore builds the core for level, sampling entries at info and below when enabled
+// so a hot path can't flood the sink while warnings and errors always get through
+func newCore(encoder zapcore.Encoder, sink zapcore.WriteSyncer, level zapcore.Level, cfg Config) zapcore.Core {
+	if cfg.SamplingInitial <= 0 {
+		return zapcore.NewCore(encoder, sink, level)
+	}
+
+	thereafter := cfg.SamplingThereafter
+	if thereafter <= 0 {
+		thereafter = cfg.SamplingInitial
+	}
+
+	low := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= level && l <= zapcore.InfoLevel
+	})
+	high := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= level && l > zapcore.InfoLevel
+	})
+	return zapcore.NewTee(
+		zapcore.NewSamplerWithOptions(zapcore.NewCore(encoder, sink, low), time.Second, cfg.SamplingInitial, thereafter),
+		zapcore.NewCore(encoder, sink, high),
+	)
+}
+
 // Helper methods for common logging patterns
 
 // Info logs an info message
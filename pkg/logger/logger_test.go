@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestNew_FileOutputSamplesInfoOnly tests that a file path output is written to, and that
+// sampling thins repeated info messages while every warning is kept
+func TestNew_FileOutputSamplesInfoOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	log, err := New(Config{
+		Level:              "info",
+		Format:             "json",
+		Output:             path,
+		MaxSizeMB:          1,
+		SamplingInitial:    2,
+		SamplingThereafter: 100,
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		log.Info("location update")
+	}
+	for i := 0; i < 3; i++ {
+		log.Warn("slow query")
+	}
+	require.NoError(t, log.Sync())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "location update"))
+	assert.Equal(t, 3, strings.Count(string(data), "slow query"))
+}
+
+// TestNew_StderrOutput tests that Output "stderr" routes entries to os.Stderr
+func TestNew_StderrOutput(t *testing.T) {
+	sink, err := writerForOutput("stderr")
+	require.NoError(t, err)
+	assert.Same(t, os.Stderr, sink)
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stderr := os.Stderr
+	os.Stderr = w
+	t.Cleanup(func() { os.Stderr = stderr })
+
+	log, err := New(Config{Level: "info", Format: "json", Output: "stderr"})
+	require.NoError(t, err)
+	log.Info("to stderr")
+	require.NoError(t, w.Close())
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"message":"to stderr"`)
+}
+
+// TestNew_UnwritableOutput tests that a file output that can't be opened fails New
+func TestNew_UnwritableOutput(t *testing.T) {
+	_, err := New(Config{Level: "info", Output: filepath.Join(t.TempDir(), "missing", "app.log")})
+	assert.Error(t, err)
+}
+
+// TestFromContext tests that a stored logger is returned and the default is used otherwise
+func TestFromContext(t *testing.T) {
+	scoped := &Logger{zap.NewNop()}
+	assert.Same(t, scoped, FromContext(WithContext(context.Background(), scoped)))
+	assert.NotNil(t, FromContext(context.Background()))
+
+	fallback := &Logger{zap.NewNop()}
+	SetDefault(fallback)
+	t.Cleanup(func() { SetDefault(&Logger{zap.NewNop()}) })
+	assert.Same(t, fallback, FromContext(context.Background()))
+}
@@ -44,7 +44,7 @@ func New(cfg Config) (*NewRelicApp, error) {
 
 // StartTransaction starts a new transaction
 func (nr *NewRelicApp) StartTransaction(name string) *newrelic.Transaction {
-	if !nr.enabled || nr.Application == nil {
+	if nr == nil || !nr.enabled || nr.Application == nil {
 		return nil
 	}
 	return nr.Application.StartTransaction(name)
@@ -52,7 +52,7 @@ func (nr *NewRelicApp) StartTransaction(name string) *newrelic.Transaction {
 
 // RecordCustomEvent records a custom event
 func (nr *NewRelicApp) RecordCustomEvent(eventType string, params map[string]interface{}) {
-	if !nr.enabled || nr.Application == nil {
+	if nr == nil || !nr.enabled || nr.Application == nil {
 		return
 	}
 	nr.Application.RecordCustomEvent(eventType, params)
@@ -60,7 +60,7 @@ func (nr *NewRelicApp) RecordCustomEvent(eventType string, params map[string]int
 
 // RecordCustomMetric records a custom metric
 func (nr *NewRelicApp) RecordCustomMetric(name string, value float64) {
-	if !nr.enabled || nr.Application == nil {
+	if nr == nil || !nr.enabled || nr.Application == nil {
 		return
 	}
 	nr.Application.RecordCustomMetric(name, value)
@@ -68,7 +68,7 @@ func (nr *NewRelicApp) RecordCustomMetric(name string, value float64) {
 
 // Shutdown gracefully shuts down the New Relic application
 func (nr *NewRelicApp) Shutdown(timeout time.Duration) {
-	if !nr.enabled || nr.Application == nil {
+	if nr == nil || !nr.enabled || nr.Application == nil {
 		return
 	}
 	nr.Application.Shutdown(timeout)
@@ -76,9 +76,11 @@ func (nr *NewRelicApp) Shutdown(timeout time.Duration) {
 
 // Custom metric helpers
 
-// RecordMatchingLatency records driver matching latency
-func (nr *NewRelicApp) RecordMatchingLatency(latencyMs float64) {
+// RecordMatchingLatency records driver matching latency, overall and per search mode so
+// batched and full candidate fetches can be compared
+func (nr *NewRelicApp) RecordMatchingLatency(latencyMs float64, mode string) {
 	nr.RecordCustomMetric("custom/ride/matching_latency_ms", latencyMs)
+	nr.RecordCustomMetric(fmt.Sprintf("custom/ride/matching_latency_ms/%s", mode), latencyMs)
 }
 
 // RecordLocationUpdate records driver location update
@@ -86,6 +88,11 @@ func (nr *NewRelicApp) RecordLocationUpdate() {
 	nr.RecordCustomMetric("custom/driver/location_update", 1)
 }
 
+// RecordLocationFlush records the number of driver locations written in one batch
+func (nr *NewRelicApp) RecordLocationFlush(batchSize int) {
+	nr.RecordCustomMetric("custom/driver/location_flush_batch_size", float64(batchSize))
+}
+
 // RecordRideCreated records ride creation
 func (nr *NewRelicApp) RecordRideCreated(vehicleType string) {
 	nr.RecordCustomEvent("RideCreated", map[string]interface{}{
@@ -94,6 +101,17 @@ func (nr *NewRelicApp) RecordRideCreated(vehicleType string) {
 	})
 }
 
+// RecordNoDriverFound records a ride request that matched no driver within maxRadiusKM of a
+// pickup in region
+func (nr *NewRelicApp) RecordNoDriverFound(vehicleType string, maxRadiusKM float64, region string) {
+	nr.RecordCustomMetric("custom/ride/no_driver_found", 1)
+	nr.RecordCustomEvent("NoDriverFound", map[string]interface{}{
+		"vehicle_type":  vehicleType,
+		"max_radius_km": maxRadiusKM,
+		"region":        region,
+	})
+}
+
 // RecordRideCompleted records ride completion
 func (nr *NewRelicApp) RecordRideCompleted(rideID string, fare float64, distance float64, duration int) {
 	nr.RecordCustomEvent("RideCompleted", map[string]interface{}{
@@ -104,6 +122,15 @@ func (nr *NewRelicApp) RecordRideCompleted(rideID string, fare float64, distance
 	})
 }
 
+// RecordRideCancelled records a ride cancellation and any fee charged for it
+func (nr *NewRelicApp) RecordRideCancelled(rideID string, fee float64, reason string) {
+	nr.RecordCustomEvent("RideCancelled", map[string]interface{}{
+		"ride_id": rideID,
+		"fee":     fee,
+		"reason":  reason,
+	})
+}
+
 // RecordPaymentProcessed records payment processing
 func (nr *NewRelicApp) RecordPaymentProcessed(amount float64, method string, status string) {
 	nr.RecordCustomEvent("PaymentProcessed", map[string]interface{}{
@@ -113,6 +140,15 @@ func (nr *NewRelicApp) RecordPaymentProcessed(amount float64, method string, sta
 	})
 }
 
+// RecordPaymentRefunded records a (possibly partial) refund
+func (nr *NewRelicApp) RecordPaymentRefunded(paymentID string, amount float64, method string) {
+	nr.RecordCustomEvent("PaymentRefunded", map[string]interface{}{
+		"payment_id": paymentID,
+		"amount":     amount,
+		"method":     method,
+	})
+}
+
 // RecordSurgeMultiplier records surge pricing multiplier
 func (nr *NewRelicApp) RecordSurgeMultiplier(region string, multiplier float64) {
 	nr.RecordCustomMetric(fmt.Sprintf("custom/pricing/surge_multiplier/%s", region), multiplier)
@@ -146,5 +182,13 @@ func (nr *NewRelicApp) RecordRedisPoolStats(stats map[string]interface{}) {
 
 // IsEnabled returns whether New Relic is enabled
 func (nr *NewRelicApp) IsEnabled() bool {
-	return nr.enabled
+	return nr != nil && nr.enabled
+}
+
+// App returns the underlying agent, or nil when New Relic is disabled or failed to start
+func (nr *NewRelicApp) App() *newrelic.Application {
+	if !nr.IsEnabled() {
+		return nil
+	}
+	return nr.Application
 }
@@ -0,0 +1,32 @@
+package monitoring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNew_DisabledReturnsNilApp tests that a disabled agent hands out a nil application
+func TestNew_DisabledReturnsNilApp(t *testing.T) {
+	nrApp, err := New(Config{Enabled: false})
+	assert.NoError(t, err)
+	assert.False(t, nrApp.IsEnabled())
+	assert.Nil(t, nrApp.App())
+	assert.Nil(t, nrApp.StartTransaction("test"))
+}
+
+// TestNewRelicApp_NilReceiver tests that a nil app (failed initialization) is safe to use
+func TestNewRelicApp_NilReceiver(t *testing.T) {
+	var nrApp *NewRelicApp
+
+	assert.NotPanics(t, func() {
+		assert.False(t, nrApp.IsEnabled())
+		assert.Nil(t, nrApp.App())
+		assert.Nil(t, nrApp.StartTransaction("test"))
+		nrApp.RecordRideCreated("economy")
+		nrApp.RecordMatchingLatency(12.5, "batched")
+		nrApp.RecordNoDriverFound("economy", 50, "tdr1v")
+		nrApp.Shutdown(time.Second)
+	})
+}
@@ -0,0 +1,230 @@
+package monitoring
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocomet/ride-hailing/pkg/auth"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// PrometheusMetrics holds the application's Prometheus collectors
+type PrometheusMetrics struct {
+	registry         *prometheus.Registry
+	httpDuration     *prometheus.HistogramVec
+	rideRequests     *prometheus.CounterVec
+	matchLatency     prometheus.Histogram
+	matchesFailed    *prometheus.CounterVec
+	fareTotal        prometheus.Counter
+	tripsTotal       prometheus.Counter
+	claimsReconciled prometheus.Counter
+	staleSwept       prometheus.Counter
+	wsDropped        prometheus.Counter
+	wsPingFailures   prometheus.Counter
+	wsReadTimeouts   prometheus.Counter
+	wsDisconnects    *prometheus.CounterVec
+}
+
+// NewPrometheus creates a registry with the application metrics; activeConnections and
+// connectionsByType are sampled on every scrape to report open WebSocket connections
+func NewPrometheus(activeConnections func() int, connectionsByType func(userType string) int) *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		registry: prometheus.NewRegistry(),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		rideRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ride_requests_total",
+			Help: "Ride requests received, by vehicle type.",
+		}, []string{"vehicle_type"}),
+		matchLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ride_match_latency_seconds",
+			Help:    "Time spent finding a driver for a ride request.",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}),
+		matchesFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ride_matches_failed_total",
+			Help: "Ride requests that could not be matched to a driver, by vehicle type.",
+		}, []string{"vehicle_type"}),
+		fareTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "trip_fare_total",
+			Help: "Sum of fares charged for completed trips.",
+		}),
+		tripsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "trips_completed_total",
+			Help: "Completed trips.",
+		}),
+		claimsReconciled: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "driver_claims_reconciled_total",
+			Help: "Drivers returned to the available pool after an abandoned matching claim.",
+		}),
+		staleSwept: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "stale_drivers_swept_total",
+			Help: "Drivers removed from the location index after their updates stopped.",
+		}),
+		wsDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "websocket_messages_dropped_total",
+			Help: "WebSocket messages not delivered because the client's send buffer stayed full.",
+		}),
+		wsPingFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "websocket_ping_failures_total",
+			Help: "WebSocket pings that could not be written to the client.",
+		}),
+		wsReadTimeouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "websocket_read_timeouts_total",
+			Help: "WebSocket connections closed because no pong arrived before the read deadline.",
+		}),
+		wsDisconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "websocket_disconnects_total",
+			Help: "Closed WebSocket connections, by user type, reason and whether the close was clean.",
+		}, []string{"user_type", "reason", "kind"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpDuration,
+		m.rideRequests,
+		m.matchLatency,
+		m.matchesFailed,
+		m.fareTotal,
+		m.tripsTotal,
+		m.claimsReconciled,
+		m.staleSwept,
+		m.wsDropped,
+		m.wsPingFailures,
+		m.wsReadTimeouts,
+		m.wsDisconnects,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "websocket_active_connections",
+			Help: "Open WebSocket connections.",
+		}, func() float64 {
+			return float64(activeConnections())
+		}),
+	)
+
+	// One gauge per known user type keeps the label set fixed
+	for _, userType := range []string{auth.UserTypeRider, auth.UserTypeDriver, auth.UserTypeDashboard, auth.UserTypeAdmin} {
+		m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "websocket_active_connections_by_user_type",
+			Help:        "Open WebSocket connections, by user type.",
+			ConstLabels: prometheus.Labels{"user_type": userType},
+		}, func() float64 {
+			return float64(connectionsByType(userType))
+		}))
+	}
+
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func (m *PrometheusMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records request duration labelled by the matched route pattern
+func (m *PrometheusMetrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// Use the route pattern (e.g. /v1/rides/:id) to keep label cardinality bounded
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.httpDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// RecordRideRequested counts a ride request
+func (m *PrometheusMetrics) RecordRideRequested(vehicleType string) {
+	if m == nil {
+		return
+	}
+	m.rideRequests.WithLabelValues(vehicleType).Inc()
+}
+
+// RecordMatchLatency records how long driver matching took
+func (m *PrometheusMetrics) RecordMatchLatency(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.matchLatency.Observe(d.Seconds())
+}
+
+// RecordMatchFailed counts a ride request that found no driver
+func (m *PrometheusMetrics) RecordMatchFailed(vehicleType string) {
+	if m == nil {
+		return
+	}
+	m.matchesFailed.WithLabelValues(vehicleType).Inc()
+}
+
+// RecordTripFare adds a completed trip's fare to the running total
+func (m *PrometheusMetrics) RecordTripFare(fare float64) {
+	if m == nil {
+		return
+	}
+	m.tripsTotal.Inc()
+	m.fareTotal.Add(fare)
+}
+
+// RecordClaimsReconciled counts drivers restored to the available pool by the claim reconciler
+func (m *PrometheusMetrics) RecordClaimsReconciled(count int) {
+	if m == nil {
+		return
+	}
+	m.claimsReconciled.Add(float64(count))
+}
+
+// RecordStaleDriversSwept counts drivers removed from the location index by the stale sweeper
+func (m *PrometheusMetrics) RecordStaleDriversSwept(count int) {
+	if m == nil {
+		return
+	}
+	m.staleSwept.Add(float64(count))
+}
+
+// RecordWebSocketMessageDropped counts a message lost to a full client send buffer
+func (m *PrometheusMetrics) RecordWebSocketMessageDropped() {
+	if m == nil {
+		return
+	}
+	m.wsDropped.Inc()
+}
+
+// RecordWebSocketPingFailure counts a ping the server couldn't write to a client
+func (m *PrometheusMetrics) RecordWebSocketPingFailure() {
+	if m == nil {
+		return
+	}
+	m.wsPingFailures.Inc()
+}
+
+// RecordWebSocketReadTimeout counts a connection whose read deadline passed without a pong
+func (m *PrometheusMetrics) RecordWebSocketReadTimeout() {
+	if m == nil {
+		return
+	}
+	m.wsReadTimeouts.Inc()
+}
+
+// RecordWebSocketDisconnect counts a closed connection by user type and reason
+func (m *PrometheusMetrics) RecordWebSocketDisconnect(userType, reason string, clean bool) {
+	if m == nil {
+		return
+	}
+	kind := "abnormal"
+	if clean {
+		kind = "clean"
+	}
+	m.wsDisconnects.WithLabelValues(userType, reason, kind).Inc()
+}
@@ -0,0 +1,60 @@
+package monitoring
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPrometheus_ExposesMetrics tests that route durations and the WebSocket gauge are scraped
+func TestPrometheus_ExposesMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	metrics := NewPrometheus(func() int { return 3 }, func(userType string) int {
+		if userType == "driver" {
+			return 2
+		}
+		return 1
+	})
+
+	r := gin.New()
+	r.Use(metrics.Middleware())
+	r.GET("/v1/rides/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/rides/ride-1", nil))
+	metrics.RecordRideRequested("economy")
+	metrics.RecordTripFare(120.5)
+	metrics.RecordWebSocketPingFailure()
+	metrics.RecordWebSocketReadTimeout()
+	metrics.RecordWebSocketDisconnect("driver", "read_timeout", false)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/v1/rides/:id",status="200"} 1`)
+	assert.Contains(t, body, `ride_requests_total{vehicle_type="economy"} 1`)
+	assert.Contains(t, body, "trip_fare_total 120.5")
+	assert.Contains(t, body, "websocket_active_connections 3")
+	assert.Contains(t, body, `websocket_active_connections_by_user_type{user_type="driver"} 2`)
+	assert.Contains(t, body, `websocket_active_connections_by_user_type{user_type="rider"} 1`)
+	assert.Contains(t, body, "websocket_ping_failures_total 1")
+	assert.Contains(t, body, "websocket_read_timeouts_total 1")
+	assert.Contains(t, body, `websocket_disconnects_total{kind="abnormal",reason="read_timeout",user_type="driver"} 1`)
+}
+
+// TestPrometheus_NilSafe tests that recording on a nil collector set is a no-op
+func TestPrometheus_NilSafe(t *testing.T) {
+	var metrics *PrometheusMetrics
+	assert.NotPanics(t, func() {
+		metrics.RecordRideRequested("economy")
+		metrics.RecordMatchFailed("economy")
+		metrics.RecordMatchLatency(0)
+		metrics.RecordTripFare(10)
+		metrics.RecordWebSocketDisconnect("rider", "client_closed", true)
+	})
+}
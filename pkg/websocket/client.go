@@ -1,52 +1,127 @@
 package websocket
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
 	"sync"
 	"time"
 
+	"github.com/gocomet/ride-hailing/pkg/auth"
 	"github.com/gocomet/ride-hailing/pkg/logger"
 	"github.com/gorilla/websocket"
 )
 
+// Defaults for ClientConfig fields left unset
 const (
-	writeWait      = 10 * time.Second
-	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 512
+	DefaultWriteWait      = 10 * time.Second
+	DefaultPongWait       = 60 * time.Second
+	DefaultMaxMessageSize = 512
 )
 
+// ClientConfig tunes one connection's buffers and keepalive timing
+type ClientConfig struct {
+	// SendBufferSize is how many outgoing messages may queue; a larger buffer rides out
+	// longer stalls before the client is dropped, at the cost of memory per connection
+	SendBufferSize int
+
+	// WriteWait bounds each write; PongWait is how long the connection may go without a
+	// pong before it's closed, and PingPeriod how often pings are sent. PingPeriod must be
+	// shorter than PongWait and defaults to nine tenths of it.
+	WriteWait  time.Duration
+	PongWait   time.Duration
+	PingPeriod time.Duration
+
+	// MaxMessageSize is the largest message accepted from the client, in bytes
+	MaxMessageSize int64
+}
+
+// withDefaults fills unset fields with the package defaults
+func (cfg ClientConfig) withDefaults() ClientConfig {
+	if cfg.SendBufferSize <= 0 {
+		cfg.SendBufferSize = DefaultBufferSize
+	}
+	if cfg.WriteWait <= 0 {
+		cfg.WriteWait = DefaultWriteWait
+	}
+	if cfg.PongWait <= 0 {
+		cfg.PongWait = DefaultPongWait
+	}
+	if cfg.PingPeriod <= 0 || cfg.PingPeriod >= cfg.PongWait {
+		cfg.PingPeriod = (cfg.PongWait * 9) / 10
+	}
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = DefaultMaxMessageSize
+	}
+	return cfg
+}
+
 // Client represents a WebSocket client connection
 type Client struct {
 	ID            string
 	UserID        string
-	UserType      string // "rider" or "driver"
+	UserType      string // "rider", "driver" or "dashboard"
 	Hub           *Hub
 	Conn          *websocket.Conn
 	Send          chan []byte
 	subscriptions map[string]bool // rideIDs this client is subscribed to
+	drivers       map[string]bool // driverIDs this client is subscribed to (dashboards only)
+	regions       map[string]bool // surge regions this client is subscribed to
+	events        map[string]bool // message types the client opted into; nil means all
 	mu            sync.RWMutex
 	logger        *logger.Logger
+	config        ClientConfig
+	connectedAt   time.Time
+	closeOnce     sync.Once
+	closeReason   string
+	closeClean    bool
 }
 
+// Disconnect reasons reported in logs and metrics
+const (
+	ReasonClientClosed  = "client_closed"
+	ReasonReadTimeout   = "read_timeout"
+	ReasonAbnormalClose = "abnormal_close"
+	ReasonReadError     = "read_error"
+	ReasonPingFailed    = "ping_failed"
+	ReasonWriteError    = "write_error"
+	ReasonDropped       = "dropped_slow_client"
+)
+
+// Entity types a client can subscribe to
+const (
+	EntityRide   = "ride"
+	EntityDriver = "driver"
+	EntityRegion = "region"
+)
+
 // ClientMessage represents a message from the client
 type ClientMessage struct {
-	Type     string                 `json:"type"`
-	EntityID string                 `json:"entity_id,omitempty"`
-	Data     map[string]interface{} `json:"data,omitempty"`
+	Type       string                 `json:"type"`
+	EntityType string                 `json:"entity_type,omitempty"` // "ride" (default), "driver" or "region"
+	EntityID   string                 `json:"entity_id,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
 }
 
-// NewClient creates a new WebSocket client
-func NewClient(hub *Hub, conn *websocket.Conn, userID, userType string, logger *logger.Logger) *Client {
+// NewClient creates a new WebSocket client; zero fields of config take the package defaults
+func NewClient(hub *Hub, conn *websocket.Conn, userID, userType string, logger *logger.Logger, config ClientConfig) *Client {
+	config = config.withDefaults()
 	return &Client{
 		ID:            generateClientID(),
 		UserID:        userID,
 		UserType:      userType,
 		Hub:           hub,
 		Conn:          conn,
-		Send:          make(chan []byte, 256),
+		Send:          make(chan []byte, config.SendBufferSize),
 		subscriptions: make(map[string]bool),
+		drivers:       make(map[string]bool),
+		regions:       make(map[string]bool),
 		logger:        logger,
+		config:        config,
+		connectedAt:   time.Now(),
 	}
 }
 
@@ -55,18 +130,24 @@ func (c *Client) ReadPump() {
 	defer func() {
 		c.Hub.Unregister(c)
 		c.Conn.Close()
+		c.logDisconnect()
 	}()
 
-	c.Conn.SetReadLimit(maxMessageSize)
-	c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.Conn.SetReadLimit(c.config.MaxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(c.config.PongWait))
 	c.Conn.SetPongHandler(func(string) error {
-		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.Conn.SetReadDeadline(time.Now().Add(c.config.PongWait))
 		return nil
 	})
 
 	for {
 		_, message, err := c.Conn.ReadMessage()
 		if err != nil {
+			reason, clean := classifyReadError(err)
+			if reason == ReasonReadTimeout {
+				c.Hub.recordReadTimeout()
+			}
+			c.setCloseReason(reason, clean)
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				c.logger.Error("WebSocket read error",
 					logger.Err(err),
@@ -82,7 +163,7 @@ func (c *Client) ReadPump() {
 
 // WritePump pumps messages from the hub to the WebSocket connection
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(c.config.PingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
@@ -91,14 +172,18 @@ func (c *Client) WritePump() {
 	for {
 		select {
 		case message, ok := <-c.Send:
-			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.Conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
 			if !ok {
+				// The hub closed Send; if the read pump hasn't ended the connection, the
+				// client was dropped for falling behind
+				c.setCloseReason(ReasonDropped, false)
 				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
 			w, err := c.Conn.NextWriter(websocket.TextMessage)
 			if err != nil {
+				c.setCloseReason(ReasonWriteError, false)
 				return
 			}
 			w.Write(message)
@@ -111,18 +196,77 @@ func (c *Client) WritePump() {
 			}
 
 			if err := w.Close(); err != nil {
+				c.setCloseReason(ReasonWriteError, false)
 				return
 			}
 
 		case <-ticker.C:
-			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.Conn.SetWriteDeadline(time.Now().Add(c.config.WriteWait))
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.Hub.recordPingFailure()
+				c.setCloseReason(ReasonPingFailed, false)
+				c.logger.Warn("WebSocket ping failed",
+					logger.Err(err),
+					logger.String("client_id", c.ID),
+				)
 				return
 			}
 		}
 	}
 }
 
+// setCloseReason records why the connection ended. Only the first reason sticks: once one
+// pump closes the connection the other fails too, and that failure is a consequence.
+func (c *Client) setCloseReason(reason string, clean bool) {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.closeReason = reason
+		c.closeClean = clean
+	})
+}
+
+// logDisconnect reports the connection's end with its reason and how long it lasted
+func (c *Client) logDisconnect() {
+	c.setCloseReason(ReasonReadError, false)
+
+	c.mu.RLock()
+	reason, clean := c.closeReason, c.closeClean
+	c.mu.RUnlock()
+
+	c.Hub.recordDisconnect(c.UserType, reason, clean)
+
+	logFn, msg := c.logger.Warn, "WebSocket client disconnected abnormally"
+	if clean {
+		logFn, msg = c.logger.Info, "WebSocket client disconnected"
+	}
+	logFn(msg,
+		logger.String("client_id", c.ID),
+		logger.String("user_id", c.UserID),
+		logger.String("user_type", c.UserType),
+		logger.String("reason", reason),
+		logger.Bool("clean", clean),
+		logger.Duration("duration", time.Since(c.connectedAt)),
+	)
+}
+
+// classifyReadError maps a read pump error to a disconnect reason and whether the client
+// closed the connection cleanly
+func classifyReadError(err error) (string, bool) {
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return ReasonClientClosed, true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ReasonReadTimeout, false
+	}
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return ReasonAbnormalClose, false
+	}
+	return ReasonReadError, false
+}
+
 // handleMessage processes incoming messages from the client
 func (c *Client) handleMessage(message []byte) {
 	var msg ClientMessage
@@ -136,9 +280,27 @@ func (c *Client) handleMessage(message []byte) {
 
 	switch msg.Type {
 	case "subscribe":
-		c.Subscribe(msg.EntityID)
+		switch msg.EntityType {
+		case EntityDriver:
+			c.SubscribeToDriver(msg.EntityID)
+		case EntityRegion:
+			c.SubscribeToRegion(msg.EntityID)
+		default:
+			c.Subscribe(msg.EntityID)
+		}
 	case "unsubscribe":
-		c.Unsubscribe(msg.EntityID)
+		switch msg.EntityType {
+		case EntityDriver:
+			c.UnsubscribeFromDriver(msg.EntityID)
+		case EntityRegion:
+			c.UnsubscribeFromRegion(msg.EntityID)
+		default:
+			c.Unsubscribe(msg.EntityID)
+		}
+	case "subscribe_events":
+		c.SetEventFilter(eventsFromData(msg.Data))
+	case "resume":
+		c.resume(msg.Data)
 	case "ping":
 		c.SendMessage(Message{Type: "pong"})
 	default:
@@ -149,8 +311,17 @@ func (c *Client) handleMessage(message []byte) {
 	}
 }
 
-// Subscribe subscribes the client to a ride
+// Subscribe subscribes the client to a ride it is the rider or driver on
 func (c *Client) Subscribe(rideID string) {
+	if !c.canFollowRide(rideID) {
+		c.logger.Warn("Ride subscription rejected for non-participant",
+			logger.String("client_id", c.ID),
+			logger.String("user_id", c.UserID),
+			logger.String("ride_id", rideID),
+		)
+		return
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.subscriptions[rideID] = true
@@ -160,6 +331,24 @@ func (c *Client) Subscribe(rideID string) {
 	)
 }
 
+// canFollowRide checks with the hub's RideAccess that the client's user is on the ride
+func (c *Client) canFollowRide(rideID string) bool {
+	if c.Hub == nil || c.Hub.rides == nil || c.UserID == "" || rideID == "" {
+		return false
+	}
+
+	ok, err := c.Hub.rides.IsRideParticipant(context.Background(), rideID, c.UserID)
+	if err != nil {
+		c.logger.Error("Failed to check ride participant",
+			logger.Err(err),
+			logger.String("client_id", c.ID),
+			logger.String("ride_id", rideID),
+		)
+		return false
+	}
+	return ok
+}
+
 // Unsubscribe unsubscribes the client from a ride
 func (c *Client) Unsubscribe(rideID string) {
 	c.mu.Lock()
@@ -178,6 +367,131 @@ func (c *Client) IsSubscribedToRide(rideID string) bool {
 	return c.subscriptions[rideID]
 }
 
+// SubscribeToDriver subscribes a dashboard client to a driver's updates
+func (c *Client) SubscribeToDriver(driverID string) {
+	// Only operator dashboards may follow individual drivers
+	if c.UserType != auth.UserTypeDashboard {
+		c.logger.Warn("Driver subscription rejected for non-dashboard client",
+			logger.String("client_id", c.ID),
+			logger.String("user_type", c.UserType),
+		)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drivers[driverID] = true
+	c.logger.Info("Client subscribed to driver",
+		logger.String("client_id", c.ID),
+		logger.String("driver_id", driverID),
+	)
+}
+
+// UnsubscribeFromDriver unsubscribes the client from a driver
+func (c *Client) UnsubscribeFromDriver(driverID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.drivers, driverID)
+	c.logger.Info("Client unsubscribed from driver",
+		logger.String("client_id", c.ID),
+		logger.String("driver_id", driverID),
+	)
+}
+
+// IsSubscribedToDriver checks if client is subscribed to a driver
+func (c *Client) IsSubscribedToDriver(driverID string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.drivers[driverID]
+}
+
+// SubscribeToRegion subscribes the client to surge updates for a pricing region
+func (c *Client) SubscribeToRegion(region string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.regions[region] = true
+	c.logger.Info("Client subscribed to region",
+		logger.String("client_id", c.ID),
+		logger.String("region", region),
+	)
+}
+
+// UnsubscribeFromRegion unsubscribes the client from a region
+func (c *Client) UnsubscribeFromRegion(region string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.regions, region)
+	c.logger.Info("Client unsubscribed from region",
+		logger.String("client_id", c.ID),
+		logger.String("region", region),
+	)
+}
+
+// IsSubscribedToRegion checks if client is subscribed to a region
+func (c *Client) IsSubscribedToRegion(region string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.regions[region]
+}
+
+// SetEventFilter limits type broadcasts to the given message types; an empty list restores all events
+func (c *Client) SetEventFilter(events []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(events) == 0 {
+		c.events = nil
+	} else {
+		c.events = make(map[string]bool, len(events))
+		for _, e := range events {
+			c.events[e] = true
+		}
+	}
+	c.logger.Info("Client event filter updated",
+		logger.String("client_id", c.ID),
+		logger.Int("events", len(events)),
+	)
+}
+
+// WantsEvent checks if the client's event filter allows a message type
+func (c *Client) WantsEvent(eventType string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.events == nil || eventType == "" || c.events[eventType]
+}
+
+// eventsFromData reads the "events" string list from a subscribe_events payload
+func eventsFromData(data map[string]interface{}) []string {
+	raw, _ := data["events"].([]interface{})
+	events := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok && s != "" {
+			events = append(events, s)
+		}
+	}
+	return events
+}
+
+// resume replays the messages recorded after the client's "after_seq" cursor, then sends
+// resume_complete with the number replayed
+func (c *Client) resume(data map[string]interface{}) {
+	afterSeq, _ := data["after_seq"].(float64)
+
+	replayed, err := c.Hub.Replay(c, int64(afterSeq))
+	if err != nil {
+		c.logger.Warn("Failed to replay missed messages",
+			logger.Err(err),
+			logger.String("client_id", c.ID),
+		)
+	}
+	c.logger.Info("Client resumed",
+		logger.String("client_id", c.ID),
+		logger.Int64("after_seq", int64(afterSeq)),
+		logger.Int("replayed", replayed),
+	)
+	c.SendMessage(Message{Type: "resume_complete", Data: map[string]interface{}{"replayed": replayed}})
+}
+
 // SendMessage sends a message to the client
 func (c *Client) SendMessage(msg Message) {
 	data, err := json.Marshal(msg)
@@ -189,9 +503,7 @@ func (c *Client) SendMessage(msg Message) {
 		return
 	}
 
-	select {
-	case c.Send <- data:
-	default:
+	if !c.Hub.sendToClient(c, data) {
 		c.logger.Warn("Client send buffer full",
 			logger.String("client_id", c.ID),
 		)
@@ -203,11 +515,17 @@ func generateClientID() string {
 	return time.Now().Format("20060102150405") + "-" + randomString(8)
 }
 
+// randomString returns n characters drawn uniformly from crypto/rand
 func randomString(n int) string {
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	max := big.NewInt(int64(len(letters)))
 	b := make([]byte, n)
 	for i := range b {
-		b[i] = letters[time.Now().UnixNano()%int64(len(letters))]
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic("websocket: crypto/rand unavailable: " + err.Error())
+		}
+		b[i] = letters[idx.Int64()]
 	}
 	return string(b)
 }
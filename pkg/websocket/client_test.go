@@ -0,0 +1,118 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGenerateClientID_NoCollisions tests that client IDs stay unique under a burst of connections
+func TestGenerateClientID_NoCollisions(t *testing.T) {
+	const count = 10000
+	seen := make(map[string]struct{}, count)
+
+	for i := 0; i < count; i++ {
+		id := generateClientID()
+		_, dup := seen[id]
+		assert.False(t, dup, "Duplicate client ID generated: %s", id)
+		seen[id] = struct{}{}
+	}
+}
+
+// TestRandomString_Distribution tests that every character of the alphabet is used roughly evenly
+func TestRandomString_Distribution(t *testing.T) {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	counts := make(map[rune]int)
+	total := 0
+
+	for i := 0; i < 10000; i++ {
+		s := randomString(8)
+		assert.Len(t, s, 8)
+		for _, r := range s {
+			counts[r]++
+			total++
+		}
+	}
+
+	assert.Len(t, counts, len(letters), "Every character should appear")
+	expected := float64(total) / float64(len(letters))
+	for r, n := range counts {
+		assert.Contains(t, letters, string(r))
+		assert.InDelta(t, expected, float64(n), expected*0.25, "Character %q is over/under represented", r)
+	}
+}
+
+// TestClassifyReadError tests that read pump errors map to the right disconnect reason
+func TestClassifyReadError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		reason string
+		clean  bool
+	}{
+		{"normal close", &websocket.CloseError{Code: websocket.CloseNormalClosure}, ReasonClientClosed, true},
+		{"going away", &websocket.CloseError{Code: websocket.CloseGoingAway}, ReasonClientClosed, true},
+		{"abnormal close", &websocket.CloseError{Code: websocket.CloseAbnormalClosure}, ReasonAbnormalClose, false},
+		{"read deadline", fmt.Errorf("read: %w", os.ErrDeadlineExceeded), ReasonReadTimeout, false},
+		{"other", errors.New("connection reset by peer"), ReasonReadError, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, clean := classifyReadError(tt.err)
+			assert.Equal(t, tt.reason, reason)
+			assert.Equal(t, tt.clean, clean)
+		})
+	}
+}
+
+type connectionEvents struct {
+	countedDrops
+	pingFailures int
+	readTimeouts int
+	disconnects  []string
+}
+
+func (c *connectionEvents) RecordWebSocketPingFailure() { c.pingFailures++ }
+func (c *connectionEvents) RecordWebSocketReadTimeout() { c.readTimeouts++ }
+func (c *connectionEvents) RecordWebSocketDisconnect(userType, reason string, clean bool) {
+	c.disconnects = append(c.disconnects, fmt.Sprintf("%s/%s/%t", userType, reason, clean))
+}
+
+// TestLogDisconnect_FirstReasonWins tests that the disconnect is recorded once, with the
+// reason of whichever pump failed first
+func TestLogDisconnect_FirstReasonWins(t *testing.T) {
+	driver := NewClient(nil, nil, "driver-1", "driver", nil, ClientConfig{})
+	hub := newTestHub(t, driver)
+	events := &connectionEvents{}
+	hub.SetMetrics(events)
+
+	driver.setCloseReason(ReasonPingFailed, false)
+	driver.setCloseReason(ReasonClientClosed, true)
+	driver.logDisconnect()
+
+	assert.Equal(t, []string{"driver/ping_failed/false"}, events.disconnects)
+}
+
+// TestClientConfig_Defaults tests that unset fields take the defaults and that a ping period
+// which wouldn't beat the pong deadline is derived from it instead
+func TestClientConfig_Defaults(t *testing.T) {
+	cfg := ClientConfig{}.withDefaults()
+	assert.Equal(t, DefaultBufferSize, cfg.SendBufferSize)
+	assert.Equal(t, DefaultWriteWait, cfg.WriteWait)
+	assert.Equal(t, DefaultPongWait, cfg.PongWait)
+	assert.Equal(t, 54*time.Second, cfg.PingPeriod)
+	assert.Equal(t, int64(DefaultMaxMessageSize), cfg.MaxMessageSize)
+
+	cfg = ClientConfig{PongWait: 120 * time.Second, PingPeriod: 30 * time.Second}.withDefaults()
+	assert.Equal(t, 120*time.Second, cfg.PongWait)
+	assert.Equal(t, 30*time.Second, cfg.PingPeriod, "A ping period shorter than the pong wait is kept")
+
+	cfg = ClientConfig{PongWait: 20 * time.Second, PingPeriod: 30 * time.Second}.withDefaults()
+	assert.Equal(t, 18*time.Second, cfg.PingPeriod, "A ping period past the pong wait is derived from it")
+}
@@ -0,0 +1,105 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// History keeps each user's recent messages under a monotonic sequence so a client that
+// reconnects can replay what it missed
+type History interface {
+	// NextSeqs reserves the next sequence number for each user, in the order given
+	NextSeqs(ctx context.Context, userIDs []string) ([]int64, error)
+	// Store records stamped payloads under their sequence numbers
+	Store(ctx context.Context, entries []Entry) error
+	// After returns stored payloads with a sequence number above afterSeq, oldest first
+	After(ctx context.Context, userID string, afterSeq int64) ([][]byte, error)
+}
+
+// Entry is one stamped payload recorded for a user
+type Entry struct {
+	UserID  string
+	Seq     int64
+	Payload []byte
+}
+
+// RedisHistory stores the last size messages per user in a sorted set scored by sequence
+type RedisHistory struct {
+	redis *redis.Client
+	size  int64
+	ttl   time.Duration
+}
+
+// NewRedisHistory creates a Redis-backed history keeping size messages per user for ttl
+// after the user's last message
+func NewRedisHistory(redis *redis.Client, size int, ttl time.Duration) *RedisHistory {
+	return &RedisHistory{
+		redis: redis,
+		size:  int64(size),
+		ttl:   ttl,
+	}
+}
+
+func historyKey(userID string) string {
+	return fmt.Sprintf("ws:history:%s", userID)
+}
+
+func seqKey(userID string) string {
+	return fmt.Sprintf("ws:seq:%s", userID)
+}
+
+// NextSeqs increments each user's sequence counter in one pipeline; the counters outlive
+// the history so sequence numbers never repeat while a client may still hold an old cursor
+func (r *RedisHistory) NextSeqs(ctx context.Context, userIDs []string) ([]int64, error) {
+	pipe := r.redis.Pipeline()
+	cmds := make([]*redis.IntCmd, len(userIDs))
+	for i, userID := range userIDs {
+		cmds[i] = pipe.Incr(ctx, seqKey(userID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to allocate message sequence: %w", err)
+	}
+
+	seqs := make([]int64, len(cmds))
+	for i, cmd := range cmds {
+		seqs[i] = cmd.Val()
+	}
+	return seqs, nil
+}
+
+// Store adds each payload and trims its user's history to the newest size entries, all in
+// one transaction
+func (r *RedisHistory) Store(ctx context.Context, entries []Entry) error {
+	pipe := r.redis.TxPipeline()
+	for _, e := range entries {
+		key := historyKey(e.UserID)
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(e.Seq), Member: e.Payload})
+		pipe.ZRemRangeByRank(ctx, key, 0, -r.size-1)
+		pipe.Expire(ctx, key, r.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store message history: %w", err)
+	}
+	return nil
+}
+
+// After returns the payloads newer than afterSeq still held in the history
+func (r *RedisHistory) After(ctx context.Context, userID string, afterSeq int64) ([][]byte, error) {
+	members, err := r.redis.ZRangeByScore(ctx, historyKey(userID), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(afterSeq, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load message history: %w", err)
+	}
+
+	payloads := make([][]byte, len(members))
+	for i, m := range members {
+		payloads[i] = []byte(m)
+	}
+	return payloads, nil
+}
@@ -1,8 +1,11 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gocomet/ride-hailing/pkg/logger"
 )
@@ -15,6 +18,51 @@ type Hub struct {
 	unregister chan *Client
 	mu         sync.RWMutex
 	logger     *logger.Logger
+	history    History
+	config     HubConfig
+	metrics    DropRecorder
+	conns      ConnectionRecorder
+	rides      RideAccess
+}
+
+// DropRecorder counts messages lost because a client's send buffer stayed full
+type DropRecorder interface {
+	RecordWebSocketMessageDropped()
+}
+
+// ConnectionRecorder counts connection health events: failed pings, read timeouts and how
+// each connection ended
+type ConnectionRecorder interface {
+	RecordWebSocketPingFailure()
+	RecordWebSocketReadTimeout()
+	RecordWebSocketDisconnect(userType, reason string, clean bool)
+}
+
+// RideAccess reports whether a user is the rider or driver on a ride
+type RideAccess interface {
+	IsRideParticipant(ctx context.Context, rideID, userID string) (bool, error)
+}
+
+// DefaultBufferSize is used for send and broadcast buffers left unset
+const DefaultBufferSize = 256
+
+// DefaultHistoryTimeout bounds history round trips when HubConfig.HistoryTimeout is unset
+const DefaultHistoryTimeout = 250 * time.Millisecond
+
+// HubConfig tunes how the hub copes with clients that fall behind
+type HubConfig struct {
+	// BroadcastBufferSize is how many Broadcast messages may queue for the broadcast loop
+	// before Broadcast callers block
+	BroadcastBufferSize int
+
+	// SendTimeout is how long a send waits on a full client buffer before the client is
+	// dropped as too slow; zero drops it straight away. Sends happen under the hub's read
+	// lock, so this also bounds how long one slow client can stall a broadcast.
+	SendTimeout time.Duration
+
+	// HistoryTimeout bounds each history round trip; a message whose seq can't be allocated
+	// in time goes out unstamped and can't be resumed
+	HistoryTimeout time.Duration
 }
 
 // Message represents a WebSocket message
@@ -24,18 +72,44 @@ type Message struct {
 }
 
 // NewHub creates a new WebSocket hub
-func NewHub(logger *logger.Logger) *Hub {
+// history numbers and records messages per user for resume; it may be nil.
+func NewHub(logger *logger.Logger, history History, config HubConfig) *Hub {
+	if config.BroadcastBufferSize <= 0 {
+		config.BroadcastBufferSize = DefaultBufferSize
+	}
+	if config.HistoryTimeout <= 0 {
+		config.HistoryTimeout = DefaultHistoryTimeout
+	}
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan []byte, config.BroadcastBufferSize),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		logger:     logger,
+		history:    history,
+		config:     config,
 	}
 }
 
-// Run starts the hub's main loop
+// SetMetrics reports dropped messages to metrics, and connection health too when metrics
+// also implements ConnectionRecorder; call it before Run. metrics may be nil.
+func (h *Hub) SetMetrics(metrics DropRecorder) {
+	h.metrics = metrics
+	h.conns, _ = metrics.(ConnectionRecorder)
+}
+
+// SetRideAccess lets clients subscribe to rides they take part in; without it every ride
+// subscription is refused. Call it before clients connect.
+func (h *Hub) SetRideAccess(rides RideAccess) {
+	h.rides = rides
+}
+
+// Run starts the hub's main loop. It is the only place clients are added to or removed
+// from h.clients; other goroutines go through Register and Unregister. Broadcast messages
+// are fanned out on a separate goroutine so history writes never hold up registration.
 func (h *Hub) Run() {
+	go h.runBroadcasts()
+
 	for {
 		select {
 		case client := <-h.register:
@@ -48,31 +122,35 @@ func (h *Hub) Run() {
 			)
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.Send)
-				h.logger.Info("Client unregistered",
-					logger.String("client_id", client.ID),
-				)
-			}
-			h.mu.Unlock()
-
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.clients, client)
-				}
-			}
-			h.mu.RUnlock()
+			h.removeClient(client)
 		}
 	}
 }
 
+// runBroadcasts sends queued Broadcast messages to every client. They aren't recorded, so
+// one broadcast never lands in every user's history.
+func (h *Hub) runBroadcasts() {
+	for message := range h.broadcast {
+		_, slow := h.fanOut(message, false, func(*Client) bool { return true })
+		h.dropClients(slow)
+	}
+}
+
+// removeClient deletes the client and closes its buffer if it is still registered; only
+// Run calls it
+func (h *Hub) removeClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.Send)
+		h.logger.Info("Client unregistered",
+			logger.String("client_id", client.ID),
+		)
+	}
+}
+
 // Register registers a new client
 func (h *Hub) Register(client *Client) {
 	h.register <- client
@@ -101,21 +179,10 @@ func (h *Hub) BroadcastToUser(userID, userType string, message Message) {
 		return
 	}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	for client := range h.clients {
-		if client.UserID == userID && client.UserType == userType {
-			select {
-			case client.Send <- data:
-			default:
-				h.logger.Warn("Failed to send message to client",
-					logger.String("user_id", userID),
-					logger.String("client_id", client.ID),
-				)
-			}
-		}
-	}
+	_, slow := h.fanOut(data, true, func(client *Client) bool {
+		return client.UserID == userID && client.UserType == userType
+	})
+	h.dropClients(slow)
 }
 
 // BroadcastToRide sends a message to all participants of a ride
@@ -126,22 +193,39 @@ func (h *Hub) BroadcastToRide(rideID string, message Message) {
 		return
 	}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	for client := range h.clients {
+	_, slow := h.fanOut(data, true, func(client *Client) bool {
 		// Check if client is subscribed to this ride
-		if client.IsSubscribedToRide(rideID) {
-			select {
-			case client.Send <- data:
-			default:
-				h.logger.Warn("Failed to send ride message to client",
-					logger.String("ride_id", rideID),
-					logger.String("client_id", client.ID),
-				)
-			}
-		}
+		return client.IsSubscribedToRide(rideID)
+	})
+	h.dropClients(slow)
+}
+
+// BroadcastToDriverSubscribers sends a message to all clients following a driver
+func (h *Hub) BroadcastToDriverSubscribers(driverID string, message Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Error("Failed to marshal driver message", logger.Err(err))
+		return
+	}
+
+	_, slow := h.fanOut(data, true, func(client *Client) bool {
+		return client.IsSubscribedToDriver(driverID)
+	})
+	h.dropClients(slow)
+}
+
+// BroadcastToRegion sends a message to all clients subscribed to a pricing region
+func (h *Hub) BroadcastToRegion(region string, message Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Error("Failed to marshal region message", logger.Err(err))
+		return
 	}
+
+	_, slow := h.fanOut(data, true, func(client *Client) bool {
+		return client.IsSubscribedToRegion(region)
+	})
+	h.dropClients(slow)
 }
 
 // GetActiveConnections returns the number of active connections
@@ -173,34 +257,24 @@ func (h *Hub) SendToUser(userID string, message interface{}) {
 		return
 	}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	sent, slow := h.fanOut(data, true, func(client *Client) bool {
+		return client.UserID == userID
+	})
+	h.dropClients(slow)
 
-	sent := false
-	for client := range h.clients {
-		if client.UserID == userID {
-			select {
-			case client.Send <- data:
-				sent = true
-				h.logger.Info("Message sent to user",
-					logger.String("user_id", userID),
-					logger.String("user_type", client.UserType),
-				)
-			default:
-				h.logger.Warn("Failed to send message to client",
-					logger.String("user_id", userID),
-					logger.String("client_id", client.ID),
-				)
-			}
-		}
+	for _, client := range sent {
+		h.logger.Info("Message sent to user",
+			logger.String("user_id", userID),
+			logger.String("user_type", client.UserType),
+		)
 	}
-
-	if !sent {
+	if len(sent) == 0 {
 		h.logger.Warn("No client found for user", logger.String("user_id", userID))
 	}
 }
 
-// BroadcastToType sends a message to all clients of a specific type
+// BroadcastToType sends a message to all clients of a specific type. Like Broadcast it
+// isn't recorded for resume, so messages meant for one user belong in BroadcastToUser.
 func (h *Hub) BroadcastToType(userType string, message interface{}) {
 	data, err := json.Marshal(message)
 	if err != nil {
@@ -208,26 +282,231 @@ func (h *Hub) BroadcastToType(userType string, message interface{}) {
 		return
 	}
 
+	eventType := messageType(message)
+
+	sent, slow := h.fanOut(data, false, func(client *Client) bool {
+		return client.UserType == userType && client.WantsEvent(eventType)
+	})
+	h.dropClients(slow)
+
+	h.logger.Info("Message broadcast to user type",
+		logger.String("user_type", userType),
+		logger.Int("count", len(sent)),
+	)
+}
+
+// fanOut queues data for every registered client that match accepts and returns the
+// clients that got it and the ones too slow to take it. When recorded, each recipient user is
+// stamped once, before the read lock is taken, so all of a user's connections share one seq
+// and one history entry and no history round trip runs while the lock is held.
+func (h *Hub) fanOut(data []byte, recorded bool, match func(*Client) bool) (sent, slow []*Client) {
+	var recipients []*Client
+	h.mu.RLock()
+	for client := range h.clients {
+		if match(client) {
+			recipients = append(recipients, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	var stamped map[string][]byte
+	if recorded {
+		stamped = h.stamp(recipients, data)
+	}
+
 	h.mu.RLock()
 	defer h.mu.RUnlock()
+	for _, client := range recipients {
+		// Skip clients unregistered while stamping; their buffer is already closed
+		if _, ok := h.clients[client]; !ok {
+			continue
+		}
+		payload, ok := stamped[client.UserID]
+		if !ok {
+			payload = data
+		}
+		if h.deliver(client, payload) {
+			sent = append(sent, client)
+		} else {
+			slow = append(slow, client)
+		}
+	}
+	return sent, slow
+}
 
-	count := 0
-	for client := range h.clients {
-		if client.UserType == userType {
-			select {
-			case client.Send <- data:
-				count++
-			default:
-				h.logger.Warn("Failed to send message to client",
-					logger.String("user_type", userType),
-					logger.String("client_id", client.ID),
-				)
-			}
+// stamp adds each recipient user's next sequence number as "seq" to a JSON object payload
+// and records it for resume, with one round trip for the sequence numbers and one for the
+// history however many users there are. It returns the stamped payload per user; users are
+// missing when there is no history, no user ID or the history couldn't be reached.
+func (h *Hub) stamp(recipients []*Client, payload []byte) map[string][]byte {
+	if h.history == nil {
+		return nil
+	}
+
+	var userIDs []string
+	seen := make(map[string]bool, len(recipients))
+	for _, client := range recipients {
+		if client.UserID != "" && !seen[client.UserID] {
+			seen[client.UserID] = true
+			userIDs = append(userIDs, client.UserID)
 		}
 	}
+	if len(userIDs) == 0 {
+		return nil
+	}
 
-	h.logger.Info("Message broadcast to user type",
-		logger.String("user_type", userType),
-		logger.Int("count", count),
-	)
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.HistoryTimeout)
+	defer cancel()
+
+	seqs, err := h.history.NextSeqs(ctx, userIDs)
+	if err != nil {
+		h.logger.Warn("Failed to sequence message", logger.Int("users", len(userIDs)), logger.Err(err))
+		return nil
+	}
+
+	stamped := make(map[string][]byte, len(userIDs))
+	entries := make([]Entry, len(userIDs))
+	for i, userID := range userIDs {
+		stamped[userID] = withSeq(payload, seqs[i])
+		entries[i] = Entry{UserID: userID, Seq: seqs[i], Payload: stamped[userID]}
+	}
+	if err := h.history.Store(ctx, entries); err != nil {
+		h.logger.Warn("Failed to record message history", logger.Int("users", len(userIDs)), logger.Err(err))
+	}
+	return stamped
+}
+
+// Replay queues every recorded message after afterSeq for the client and returns how many
+// were queued; messages older than the history window are gone
+func (h *Hub) Replay(client *Client, afterSeq int64) (int, error) {
+	if h.history == nil || client.UserID == "" {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.HistoryTimeout)
+	defer cancel()
+	payloads, err := h.history.After(ctx, client.UserID, afterSeq)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, p := range payloads {
+		if !h.sendToClient(client, p) {
+			h.logger.Warn("Client fell behind during resume",
+				logger.String("client_id", client.ID),
+				logger.Int("replayed", replayed),
+			)
+			break
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+// deliver queues payload on the client's buffer, waiting up to SendTimeout while it is full.
+// Callers must hold h.mu for reading so the buffer can't be closed underneath them.
+func (h *Hub) deliver(client *Client, payload []byte) bool {
+	select {
+	case client.Send <- payload:
+		return true
+	default:
+	}
+
+	if h.config.SendTimeout <= 0 {
+		h.recordDropped()
+		return false
+	}
+	timer := time.NewTimer(h.config.SendTimeout)
+	defer timer.Stop()
+
+	select {
+	case client.Send <- payload:
+		return true
+	case <-timer.C:
+		h.recordDropped()
+		return false
+	}
+}
+
+// recordDropped counts one undelivered message
+func (h *Hub) recordDropped() {
+	if h.metrics != nil {
+		h.metrics.RecordWebSocketMessageDropped()
+	}
+}
+
+// recordPingFailure counts one ping the write pump couldn't send
+func (h *Hub) recordPingFailure() {
+	if h.conns != nil {
+		h.conns.RecordWebSocketPingFailure()
+	}
+}
+
+// recordReadTimeout counts one connection whose read deadline passed without a pong
+func (h *Hub) recordReadTimeout() {
+	if h.conns != nil {
+		h.conns.RecordWebSocketReadTimeout()
+	}
+}
+
+// recordDisconnect counts one closed connection by user type and reason
+func (h *Hub) recordDisconnect(userType, reason string, clean bool) {
+	if h.conns != nil {
+		h.conns.RecordWebSocketDisconnect(userType, reason, clean)
+	}
+}
+
+// sendToClient delivers payload to one registered client, dropping it if it's too slow
+func (h *Hub) sendToClient(client *Client, payload []byte) bool {
+	h.mu.RLock()
+	_, registered := h.clients[client]
+	delivered := registered && h.deliver(client, payload)
+	h.mu.RUnlock()
+
+	if registered && !delivered {
+		h.dropClients([]*Client{client})
+	}
+	return delivered
+}
+
+// dropClients disconnects clients that couldn't keep up by unregistering them through Run.
+// Closing Send makes the write pump close the connection, and the read pump's own
+// unregister then finds the client already gone.
+func (h *Hub) dropClients(clients []*Client) {
+	for _, client := range clients {
+		h.logger.Warn("Dropping slow client",
+			logger.String("client_id", client.ID),
+			logger.String("user_id", client.UserID),
+		)
+		h.Unregister(client)
+	}
+}
+
+// withSeq inserts "seq" as the first field of a compact JSON object
+func withSeq(payload []byte, seq int64) []byte {
+	if len(payload) < 2 || payload[0] != '{' {
+		return payload
+	}
+
+	field := `{"seq":` + strconv.FormatInt(seq, 10)
+	if len(payload) == 2 {
+		return []byte(field + "}")
+	}
+	stamped := make([]byte, 0, len(field)+len(payload))
+	stamped = append(stamped, field...)
+	stamped = append(stamped, ',')
+	return append(stamped, payload[1:]...)
+}
+
+// messageType returns the "type" of a broadcast payload, or "" when it has none
+func messageType(message interface{}) string {
+	switch m := message.(type) {
+	case Message:
+		return m.Type
+	case map[string]interface{}:
+		t, _ := m["type"].(string)
+		return t
+	}
+	return ""
 }
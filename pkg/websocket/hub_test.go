@@ -0,0 +1,379 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gocomet/ride-hailing/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHub returns a hub with the given clients registered directly, without running the hub loop
+func newTestHub(t *testing.T, clients ...*Client) *Hub {
+	log, err := logger.New(logger.Config{Level: "error", Format: "json"})
+	assert.NoError(t, err)
+
+	hub := NewHub(log, nil, HubConfig{})
+	for _, c := range clients {
+		c.Hub = hub
+		c.logger = log
+		hub.clients[c] = true
+	}
+	return hub
+}
+
+// rideParticipants maps ride IDs to the users on them
+type rideParticipants map[string][]string
+
+func (r rideParticipants) IsRideParticipant(ctx context.Context, rideID, userID string) (bool, error) {
+	for _, id := range r[rideID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// TestBroadcastToDriverSubscribers tests that only dashboards following the driver receive updates
+func TestBroadcastToDriverSubscribers(t *testing.T) {
+	following := NewClient(nil, nil, "dash-1", "dashboard", nil, ClientConfig{})
+	other := NewClient(nil, nil, "dash-2", "dashboard", nil, ClientConfig{})
+	rider := NewClient(nil, nil, "rider-1", "rider", nil, ClientConfig{})
+	hub := newTestHub(t, following, other, rider)
+	hub.SetRideAccess(rideParticipants{"driver-1": {"dash-2"}})
+
+	following.handleMessage([]byte(`{"type":"subscribe","entity_type":"driver","entity_id":"driver-1"}`))
+	rider.handleMessage([]byte(`{"type":"subscribe","entity_type":"driver","entity_id":"driver-1"}`))
+	other.handleMessage([]byte(`{"type":"subscribe","entity_id":"driver-1"}`)) // ride subscription, not driver
+
+	assert.True(t, following.IsSubscribedToDriver("driver-1"))
+	assert.False(t, rider.IsSubscribedToDriver("driver-1"), "Riders cannot follow drivers")
+	assert.False(t, other.IsSubscribedToDriver("driver-1"))
+	assert.True(t, other.IsSubscribedToRide("driver-1"))
+
+	hub.BroadcastToDriverSubscribers("driver-1", Message{Type: "driver_location"})
+
+	assert.Len(t, following.Send, 1)
+	assert.Len(t, other.Send, 0)
+	assert.Len(t, rider.Send, 0)
+
+	following.handleMessage([]byte(`{"type":"unsubscribe","entity_type":"driver","entity_id":"driver-1"}`))
+	assert.False(t, following.IsSubscribedToDriver("driver-1"))
+}
+
+// TestBroadcastToRide_OnlyParticipantsSubscribe tests that a client can't follow a ride it
+// isn't the rider or driver on
+func TestBroadcastToRide_OnlyParticipantsSubscribe(t *testing.T) {
+	rider := NewClient(nil, nil, "rider-1", "rider", nil, ClientConfig{})
+	driver := NewClient(nil, nil, "driver-1", "driver", nil, ClientConfig{})
+	stranger := NewClient(nil, nil, "rider-2", "rider", nil, ClientConfig{})
+	hub := newTestHub(t, rider, driver, stranger)
+	hub.SetRideAccess(rideParticipants{"ride-1": {"rider-1", "driver-1"}})
+
+	for _, c := range []*Client{rider, driver, stranger} {
+		c.handleMessage([]byte(`{"type":"subscribe","entity_id":"ride-1"}`))
+	}
+	assert.False(t, stranger.IsSubscribedToRide("ride-1"))
+
+	hub.BroadcastToRide("ride-1", Message{Type: "driver_location"})
+
+	assert.Len(t, rider.Send, 1)
+	assert.Len(t, driver.Send, 1)
+	assert.Len(t, stranger.Send, 0)
+}
+
+// TestBroadcastToRegion tests that only clients subscribed to the region get its updates
+func TestBroadcastToRegion(t *testing.T) {
+	subscribed := NewClient(nil, nil, "rider-1", "rider", nil, ClientConfig{})
+	elsewhere := NewClient(nil, nil, "rider-2", "rider", nil, ClientConfig{})
+	hub := newTestHub(t, subscribed, elsewhere)
+
+	subscribed.handleMessage([]byte(`{"type":"subscribe","entity_type":"region","entity_id":"tdr1v"}`))
+	elsewhere.handleMessage([]byte(`{"type":"subscribe","entity_type":"region","entity_id":"tdr1y"}`))
+
+	hub.BroadcastToRegion("tdr1v", Message{Type: "surge_update"})
+
+	assert.Len(t, subscribed.Send, 1)
+	assert.Len(t, elsewhere.Send, 0)
+	assert.False(t, subscribed.IsSubscribedToRide("tdr1v"), "Region subscriptions don't leak into rides")
+
+	subscribed.handleMessage([]byte(`{"type":"unsubscribe","entity_type":"region","entity_id":"tdr1v"}`))
+	assert.False(t, subscribed.IsSubscribedToRegion("tdr1v"))
+}
+
+// TestBroadcastToType_EventFilter tests that clients only get the event types they opted into
+func TestBroadcastToType_EventFilter(t *testing.T) {
+	filtered := NewClient(nil, nil, "dash-1", "dashboard", nil, ClientConfig{})
+	unfiltered := NewClient(nil, nil, "dash-2", "dashboard", nil, ClientConfig{})
+	hub := newTestHub(t, filtered, unfiltered)
+
+	filtered.handleMessage([]byte(`{"type":"subscribe_events","data":{"events":["trip_completed"]}}`))
+
+	hub.BroadcastToType("dashboard", map[string]interface{}{"type": "ride_request"})
+	hub.BroadcastToType("dashboard", Message{Type: "trip_completed"})
+
+	assert.Len(t, filtered.Send, 1)
+	assert.Len(t, unfiltered.Send, 2)
+
+	// An empty list clears the filter
+	filtered.handleMessage([]byte(`{"type":"subscribe_events","data":{"events":[]}}`))
+	hub.BroadcastToType("dashboard", map[string]interface{}{"type": "ride_request"})
+	assert.Len(t, filtered.Send, 2)
+}
+
+// TestResume_ReplaysMissedMessages tests that messages are sequenced per user and that a
+// reconnecting client gets everything after its cursor, in order
+func TestResume_ReplaysMissedMessages(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	rider := NewClient(nil, nil, "rider-1", "rider", nil, ClientConfig{})
+	hub := newTestHub(t, rider)
+	hub.history = NewRedisHistory(client, 2, time.Minute)
+
+	for _, msgType := range []string{"ride_assigned", "ride_accepted", "trip_started"} {
+		hub.SendToUser("rider-1", Message{Type: msgType})
+	}
+	require.Len(t, rider.Send, 3)
+
+	var first struct {
+		Seq  int64  `json:"seq"`
+		Type string `json:"type"`
+	}
+	require.NoError(t, json.Unmarshal(<-rider.Send, &first))
+	assert.Equal(t, int64(1), first.Seq)
+	assert.Equal(t, "ride_assigned", first.Type)
+	<-rider.Send
+	<-rider.Send
+
+	// Only the newest two messages are kept, so resuming from 0 replays seq 2 and 3
+	rider.handleMessage([]byte(`{"type":"resume","data":{"after_seq":0}}`))
+	require.Len(t, rider.Send, 3)
+	assert.JSONEq(t, `{"seq":2,"type":"ride_accepted","data":null}`, string(<-rider.Send))
+	assert.JSONEq(t, `{"seq":3,"type":"trip_started","data":null}`, string(<-rider.Send))
+	assert.JSONEq(t, `{"type":"resume_complete","data":{"replayed":2}}`, string(<-rider.Send))
+}
+
+// TestSendToUser_StampsOncePerUser tests that a user connected from two devices gets the
+// same seq on both and a single history entry per message
+func TestSendToUser_StampsOncePerUser(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	phone := NewClient(nil, nil, "rider-1", "rider", nil, ClientConfig{})
+	laptop := NewClient(nil, nil, "rider-1", "rider", nil, ClientConfig{})
+	hub := newTestHub(t, phone, laptop)
+	hub.history = NewRedisHistory(client, 10, time.Minute)
+
+	hub.SendToUser("rider-1", Message{Type: "ride_assigned"})
+	hub.SendToUser("rider-1", Message{Type: "trip_started"})
+
+	for _, c := range []*Client{phone, laptop} {
+		require.Len(t, c.Send, 2)
+		assert.JSONEq(t, `{"seq":1,"type":"ride_assigned","data":null}`, string(<-c.Send))
+		assert.JSONEq(t, `{"seq":2,"type":"trip_started","data":null}`, string(<-c.Send))
+	}
+
+	history, err := hub.history.After(context.Background(), "rider-1", 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+// TestBroadcastToRegion_StampsEachUser tests that one broadcast gives every recipient user
+// their own next seq and history entry
+func TestBroadcastToRegion_StampsEachUser(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	first := NewClient(nil, nil, "rider-1", "rider", nil, ClientConfig{})
+	second := NewClient(nil, nil, "rider-2", "rider", nil, ClientConfig{})
+	hub := newTestHub(t, first, second)
+	hub.history = NewRedisHistory(client, 10, time.Minute)
+	first.SubscribeToRegion("r1")
+	second.SubscribeToRegion("r1")
+
+	hub.SendToUser("rider-1", Message{Type: "ride_assigned"})
+	hub.BroadcastToRegion("r1", Message{Type: "surge_update"})
+
+	<-first.Send
+	assert.JSONEq(t, `{"seq":2,"type":"surge_update","data":null}`, string(<-first.Send))
+	assert.JSONEq(t, `{"seq":1,"type":"surge_update","data":null}`, string(<-second.Send))
+
+	for userID, want := range map[string]int{"rider-1": 2, "rider-2": 1} {
+		history, err := hub.history.After(context.Background(), userID, 0)
+		require.NoError(t, err)
+		assert.Len(t, history, want, userID)
+	}
+}
+
+// TestBroadcastToType_SkipsHistory tests that type-wide broadcasts go out unstamped and
+// never land in a user's resume history
+func TestBroadcastToType_SkipsHistory(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	rider := NewClient(nil, nil, "rider-1", "rider", nil, ClientConfig{})
+	hub := newTestHub(t, rider)
+	hub.history = NewRedisHistory(client, 10, time.Minute)
+
+	hub.BroadcastToType("rider", Message{Type: "surge_update"})
+
+	require.Len(t, rider.Send, 1)
+	assert.JSONEq(t, `{"type":"surge_update","data":null}`, string(<-rider.Send))
+	history, err := hub.history.After(context.Background(), "rider-1", 0)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	assert.False(t, mr.Exists(seqKey("rider-1")))
+}
+
+// TestSendToUser_DeliversWhenHistoryUnreachable tests that a message still goes out,
+// unstamped, when the history can't be reached
+func TestSendToUser_DeliversWhenHistoryUnreachable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	mr.Close()
+
+	rider := NewClient(nil, nil, "rider-1", "rider", nil, ClientConfig{})
+	hub := newTestHub(t, rider)
+	hub.history = NewRedisHistory(client, 10, time.Minute)
+
+	hub.SendToUser("rider-1", Message{Type: "ride_assigned"})
+
+	require.Len(t, rider.Send, 1)
+	assert.JSONEq(t, `{"type":"ride_assigned","data":null}`, string(<-rider.Send))
+}
+
+// TestBroadcast_DropsSlowClientsConcurrently tests that concurrent broadcasts drop clients
+// that stop reading without racing on the client map; run with -race
+func TestBroadcast_DropsSlowClientsConcurrently(t *testing.T) {
+	const clients = 50
+	var fast, slow []*Client
+	for i := 0; i < clients; i++ {
+		c := NewClient(nil, nil, fmt.Sprintf("rider-%d", i), "rider", nil, ClientConfig{})
+		if i%5 == 0 {
+			c.Send = make(chan []byte, 1)
+			slow = append(slow, c)
+		} else {
+			fast = append(fast, c)
+		}
+	}
+	hub := newTestHub(t, append(append([]*Client{}, fast...), slow...)...)
+	hub.config.SendTimeout = time.Millisecond
+
+	// Fast clients keep draining until their buffer is closed
+	var readers sync.WaitGroup
+	for _, c := range fast {
+		readers.Add(1)
+		go func(c *Client) {
+			defer readers.Done()
+			for range c.Send {
+			}
+		}(c)
+	}
+	go hub.Run()
+
+	var senders sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		senders.Add(4)
+		go func() { defer senders.Done(); hub.Broadcast(Message{Type: "surge_update"}) }()
+		go func() { defer senders.Done(); hub.BroadcastToType("rider", Message{Type: "surge_update"}) }()
+		go func() { defer senders.Done(); hub.SendToUser("rider-0", Message{Type: "ride_assigned"}) }()
+		go func(i int) {
+			defer senders.Done()
+			hub.BroadcastToUser(fmt.Sprintf("rider-%d", i), "rider", Message{Type: "ride_assigned"})
+		}(i)
+	}
+	senders.Wait()
+
+	require.Eventually(t, func() bool {
+		return hub.GetActiveConnections() == len(fast)
+	}, time.Second, 10*time.Millisecond)
+
+	for _, c := range fast {
+		hub.Unregister(c)
+	}
+	readers.Wait()
+	assert.Equal(t, 0, hub.GetActiveConnections())
+}
+
+// TestHub_ConcurrentRegisterAndBroadcast stresses registration churn against every broadcast
+// path so -race can catch map access outside the hub's locks
+func TestHub_ConcurrentRegisterAndBroadcast(t *testing.T) {
+	hub := newTestHub(t)
+	hub.config.SendTimeout = time.Millisecond
+	hub.SetRideAccess(rideParticipants{"ride-1": {"user-0", "user-1", "user-2", "user-3", "user-4"}})
+	go hub.Run()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				c := NewClient(hub, nil, fmt.Sprintf("user-%d", i), "rider", hub.logger, ClientConfig{})
+				c.Subscribe("ride-1")
+				hub.Register(c)
+				c.SendMessage(Message{Type: "pong"})
+				hub.Unregister(c)
+				for range c.Send {
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				hub.Broadcast(Message{Type: "surge_update"})
+				hub.BroadcastToRide("ride-1", Message{Type: "eta_update"})
+				hub.BroadcastToType("rider", Message{Type: "surge_update"})
+				hub.SendToUser(fmt.Sprintf("user-%d", i), Message{Type: "ride_assigned"})
+				_ = hub.GetClientsByUserType("rider")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 0, hub.GetActiveConnections())
+}
+
+type countedDrops struct {
+	dropped int
+}
+
+func (c *countedDrops) RecordWebSocketMessageDropped() {
+	c.dropped++
+}
+
+// TestSendBufferSize_DropsAreCounted tests that the configured buffer size is honoured and
+// messages that don't fit are counted as dropped
+func TestSendBufferSize_DropsAreCounted(t *testing.T) {
+	rider := NewClient(nil, nil, "rider-1", "rider", nil, ClientConfig{SendBufferSize: 2})
+	hub := newTestHub(t, rider)
+	drops := &countedDrops{}
+	hub.SetMetrics(drops)
+	go hub.Run()
+
+	for i := 0; i < 3; i++ {
+		hub.BroadcastToUser("rider-1", "rider", Message{Type: "eta_update"})
+	}
+
+	assert.Equal(t, 2, cap(rider.Send))
+	assert.Equal(t, 1, drops.dropped)
+	assert.Eventually(t, func() bool {
+		return hub.GetActiveConnections() == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -2,6 +2,7 @@
 let map;
 let ws;
 let pendingRequests = {}; // Map of driverID -> rideRequest
+let authTokens = {}; // Map of userID -> JWT
 
 // Initialize map
 function initMap() {
@@ -12,10 +13,33 @@ function initMap() {
     }).addTo(map);
 }
 
+// Fetch (and cache) a development access token for the given user
+async function getAuthToken(userID, userType) {
+    if (authTokens[userID]) {
+        return authTokens[userID];
+    }
+
+    const response = await fetch('/v1/auth/token', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ user_id: userID, user_type: userType })
+    });
+    if (!response.ok) {
+        throw new Error(`Failed to get auth token: ${response.status}`);
+    }
+
+    const data = await response.json();
+    authTokens[userID] = data.token;
+    return data.token;
+}
+
 // Connect to WebSocket as Dashboard
-function connectWebSocket() {
+async function connectWebSocket() {
     // Connect as "dashboard" user type to receive all driver notifications
-    ws = new WebSocket(`ws://localhost:8080/v1/ws?user_id=dashboard&user_type=dashboard`);
+    const token = await getAuthToken('dashboard', 'dashboard');
+    ws = new WebSocket(`ws://localhost:8080/v1/ws?token=${encodeURIComponent(token)}`);
 
     ws.onopen = function() {
         console.log('[Dashboard] WebSocket connected');
@@ -126,15 +150,18 @@ function handleTripCompleted(data) {
 function acceptRide(driverID, rideID) {
     console.log('[Dashboard] Accepting ride:', rideID, 'for driver:', driverID);
 
-    fetch(`/v1/drivers/${driverID}/accept`, {
+    // Offers are accepted on the driver's behalf, so act with the driver's token
+    getAuthToken(driverID, 'driver')
+    .then(token => fetch(`/v1/drivers/${driverID}/accept`, {
         method: 'POST',
         headers: {
-            'Content-Type': 'application/json'
+            'Content-Type': 'application/json',
+            'Authorization': `Bearer ${token}`
         },
         body: JSON.stringify({
             ride_id: rideID
         })
-    })
+    }))
     .then(response => response.json())
     .then(data => {
         console.log('[Dashboard] Ride accepted:', data);
@@ -174,17 +201,27 @@ function endTrip(driverID, rideID) {
     const distanceKm = (Math.random() * 20 + 5).toFixed(2); // 5-25 km
     const durationMinutes = Math.floor(Math.random() * 40 + 10); // 10-50 minutes
 
-    fetch(`/v1/trips/${rideID}/end`, {
+    // Rides must be started before they can be completed (409 means already started)
+    // Trips can only be started and ended by the assigned driver
+    getAuthToken(driverID, 'driver')
+    .then(token => fetch(`/v1/trips/${rideID}/start`, {
         method: 'POST',
         headers: {
-            'Content-Type': 'application/json'
+            'Authorization': `Bearer ${token}`
+        }
+    }).then(() => token))
+    .then(token => fetch(`/v1/trips/${rideID}/end`, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+            'Authorization': `Bearer ${token}`
         },
         body: JSON.stringify({
             driver_id: driverID,
             distance_km: parseFloat(distanceKm),
             duration_minutes: durationMinutes
         })
-    })
+    }))
     .then(response => response.json())
     .then(data => {
         console.log('[Dashboard] Trip ended:', data);
@@ -204,7 +241,7 @@ function endTrip(driverID, rideID) {
 // Fetch all drivers
 async function fetchAllDrivers() {
     try {
-        const response = await fetch('/v1/drivers/all');
+        const response = await fetch('/v1/drivers/all?limit=200');
         const data = await response.json();
 
         if (data.drivers) {
@@ -373,7 +410,7 @@ document.getElementById('refresh-drivers').addEventListener('click', function()
 window.addEventListener('DOMContentLoaded', async function() {
     initMap();
     await fetchAllDrivers(); // Fetch all drivers list
-    connectWebSocket(); // Connect to dashboard WebSocket
+    await connectWebSocket(); // Connect to dashboard WebSocket
 
     // Auto-refresh drivers list every 10 seconds
     setInterval(() => {
@@ -3,6 +3,7 @@ let map;
 let ws;
 let currentRide = null;
 let markers = {};
+let authTokens = {}; // Map of userID -> JWT
 
 // Initialize map
 function initMap() {
@@ -81,8 +82,30 @@ async function fetchRandomRider() {
     }
 }
 
+// Fetch (and cache) a development access token for the given user
+async function getAuthToken(userID, userType) {
+    if (authTokens[userID]) {
+        return authTokens[userID];
+    }
+
+    const response = await fetch('/v1/auth/token', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ user_id: userID, user_type: userType })
+    });
+    if (!response.ok) {
+        throw new Error(`Failed to get auth token: ${response.status}`);
+    }
+
+    const data = await response.json();
+    authTokens[userID] = data.token;
+    return data.token;
+}
+
 // Connect to WebSocket
-function connectWebSocket() {
+async function connectWebSocket() {
     const riderID = document.getElementById('rider-id').value;
     console.log('[Rider] Connecting WebSocket with rider ID:', riderID);
 
@@ -90,7 +113,8 @@ function connectWebSocket() {
         console.error('[Rider] ✗ WARNING: Rider ID is empty! WebSocket connection will fail.');
     }
 
-    ws = new WebSocket(`ws://localhost:8080/v1/ws?user_id=${riderID}&user_type=rider`);
+    const token = await getAuthToken(riderID, 'rider');
+    ws = new WebSocket(`ws://localhost:8080/v1/ws?token=${encodeURIComponent(token)}`);
 
     ws.onopen = function() {
         console.log('WebSocket connected');
@@ -136,6 +160,12 @@ function handleWebSocketMessage(message) {
         case 'trip_completed':
             handleTripCompleted(message.data);
             break;
+        case 'driver_location':
+            moveDriverMarker(message.data.latitude, message.data.longitude);
+            break;
+        case 'eta_update':
+            document.getElementById('eta').textContent = message.data.eta;
+            break;
         default:
             console.log('Unknown message type:', message.type);
     }
@@ -169,14 +199,16 @@ document.getElementById('request-ride').addEventListener('click', function() {
         vehicle_type: vehicleType
     };
 
-    fetch('/v1/rides', {
+    getAuthToken(riderID, 'rider')
+    .then(token => fetch('/v1/rides', {
         method: 'POST',
         headers: {
             'Content-Type': 'application/json',
+            'Authorization': `Bearer ${token}`,
             'Idempotency-Key': generateUUID()
         },
         body: JSON.stringify(rideRequest)
-    })
+    }))
     .then(response => response.json())
     .then(data => {
         console.log('Ride requested:', data);
@@ -196,12 +228,18 @@ function showRideStatus(ride) {
 
     currentRide = ride;
 
+    // Follow the driver's live position for this ride
+    if (ride.id && ws && ws.readyState === WebSocket.OPEN) {
+        ws.send(JSON.stringify({ type: 'subscribe', entity_id: ride.id }));
+    }
+
     // Check if driver is already assigned
     if (ride.status === 'assigned' && ride.driver) {
         document.getElementById('status-text').textContent = 'Driver Assigned ✓';
         document.getElementById('driver-name').textContent = ride.driver_name || ride.driver.name || 'Unknown Driver';
         document.getElementById('eta').textContent = ride.estimated_arrival || '5 mins';
-        document.getElementById('fare').textContent = ride.estimated_fare || '-';
+        const surge = ride.surge_multiplier > 1 ? ` (${ride.surge_multiplier}x surge)` : '';
+        document.getElementById('fare').textContent = ride.estimated_fare ? `${ride.estimated_fare}${surge}` : '-';
 
         // Add driver marker to map
         if (ride.driver.latitude && ride.driver.longitude) {
@@ -221,6 +259,15 @@ function showRideStatus(ride) {
               ride.dropoff_longitude || document.getElementById('dropoff-lng').value, 'Dropoff');
 }
 
+// Move the driver marker without refitting the map
+function moveDriverMarker(lat, lng) {
+    if (markers['driver']) {
+        markers['driver'].setLatLng([lat, lng]);
+    } else {
+        addDriverMarker(lat, lng);
+    }
+}
+
 // Add driver marker to map
 function addDriverMarker(lat, lng) {
     if (markers['driver']) {
@@ -308,5 +355,5 @@ function generateUUID() {
 window.addEventListener('DOMContentLoaded', async function() {
     initMap();
     await fetchRandomRider(); // Auto-fetch a rider
-    connectWebSocket();
+    await connectWebSocket();
 });